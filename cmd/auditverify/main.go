@@ -0,0 +1,58 @@
+// Command auditverify checks the hash chain of the audit log.
+//
+//	go run ./cmd/auditverify
+//
+// It exits with status 1 when an event was edited or removed. Save the
+// printed last hash somewhere else, events removed from the end of the
+// log are only noticed when it disappears from a later run.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/claytten/golang-simplebank/internal/audit"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	configPath := flag.String("config", ".", "folder of app.env")
+	pageSize := flag.Int("page-size", 500, "events read per query")
+	flag.Parse()
+
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	config, err := util.LoadConfig("app", *configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot load config")
+	}
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to DB")
+	}
+	defer conn.Close()
+
+	result, err := audit.Verify(context.Background(), db.New(conn), int32(*pageSize))
+	if err != nil {
+		var brokenErr *audit.BrokenChainError
+		if errors.As(err, &brokenErr) {
+			log.Error().Int64("event_id", brokenErr.EventID).Int64("verified_events", result.Events).Msg(brokenErr.Reason)
+			os.Exit(1)
+		}
+		log.Fatal().Err(err).Msg("cannot verify audit log")
+	}
+
+	log.Info().
+		Int64("events", result.Events).
+		Int64("last_id", result.LastID).
+		Str("last_hash", result.LastHash).
+		Msg("audit log verified")
+}
@@ -0,0 +1,202 @@
+package activity
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// Channel is the postgres channel NotifyAccountActivity notifies with the account id
+const Channel = "account_activity"
+
+// pingInterval checks the listener connection when no notification arrives
+const pingInterval = 90 * time.Second
+
+// Hub tells the subscribers of an account that its activity changed. A notification
+// only wakes the subscribers up, they read the changes from the database themselves
+// so nothing is lost when notifications are merged or the listener reconnects.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[*Subscription]struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[int64]map[*Subscription]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Subscription is woken up when one of its accounts has new activity
+type Subscription struct {
+	hub        *Hub
+	accountIDs []int64
+	wake       chan struct{}
+
+	mu      sync.Mutex
+	pending map[int64]struct{}
+}
+
+// Subscribe watches the accounts until the subscription is closed
+func (hub *Hub) Subscribe(accountIDs []int64) *Subscription {
+	sub := &Subscription{
+		hub:        hub,
+		accountIDs: accountIDs,
+		wake:       make(chan struct{}, 1),
+		pending:    make(map[int64]struct{}),
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for _, accountID := range accountIDs {
+		if hub.subscribers[accountID] == nil {
+			hub.subscribers[accountID] = make(map[*Subscription]struct{})
+		}
+		hub.subscribers[accountID][sub] = struct{}{}
+	}
+	return sub
+}
+
+// Wake receives once after any number of notifications
+func (sub *Subscription) Wake() <-chan struct{} {
+	return sub.wake
+}
+
+// Pending returns the accounts notified since the last call
+func (sub *Subscription) Pending() []int64 {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	accountIDs := make([]int64, 0, len(sub.pending))
+	for accountID := range sub.pending {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sub.pending = make(map[int64]struct{})
+	return accountIDs
+}
+
+// Done is closed once the hub is closed, the subscriber stops watching
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.hub.done
+}
+
+// Close stops the notifications of the subscription
+func (sub *Subscription) Close() {
+	hub := sub.hub
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for _, accountID := range sub.accountIDs {
+		delete(hub.subscribers[accountID], sub)
+		if len(hub.subscribers[accountID]) == 0 {
+			delete(hub.subscribers, accountID)
+		}
+	}
+}
+
+func (sub *Subscription) notify(accountID int64) {
+	sub.mu.Lock()
+	sub.pending[accountID] = struct{}{}
+	sub.mu.Unlock()
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close tells every subscriber to stop, the streams watching activity
+// would otherwise keep a graceful shutdown waiting
+func (hub *Hub) Close() {
+	hub.closeOnce.Do(func() {
+		close(hub.done)
+	})
+}
+
+// Notify wakes up the subscribers of an account
+func (hub *Hub) Notify(accountID int64) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for sub := range hub.subscribers[accountID] {
+		sub.notify(accountID)
+	}
+}
+
+// NotifyAll wakes up every subscriber for all of its accounts,
+// notifications sent while the listener reconnected are lost
+func (hub *Hub) NotifyAll() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for accountID, subs := range hub.subscribers {
+		for sub := range subs {
+			sub.notify(accountID)
+		}
+	}
+}
+
+// Run dispatches the notifications of a postgres listener until ctx is done,
+// a nil notification means the listener reconnected
+func (hub *Hub) Run(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-notifications:
+			if n == nil {
+				hub.NotifyAll()
+				continue
+			}
+
+			accountID, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				log.Error().Err(err).Str("payload", n.Extra).Msg("invalid account activity notification")
+				continue
+			}
+			hub.Notify(accountID)
+		}
+	}
+}
+
+// Listen dispatches the notifications of Channel until ctx is done
+func (hub *Hub) Listen(ctx context.Context, dataSource string) error {
+	listener := pq.NewListener(dataSource, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error().Err(err).Msg("account activity listener")
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// a failed ping makes the listener reconnect
+				if err := listener.Ping(); err != nil {
+					log.Error().Err(err).Msg("cannot ping account activity listener")
+				}
+			}
+		}
+	}()
+
+	log.Info().Msg("start account activity listener")
+	hub.Run(ctx, listener.Notify)
+	return nil
+}
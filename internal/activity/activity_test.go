@@ -0,0 +1,117 @@
+package activity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/activity"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func requireWoken(t *testing.T, sub *activity.Subscription) {
+	select {
+	case <-sub.Wake():
+	case <-time.After(time.Second):
+		t.Fatal("subscription wasn't woken up")
+	}
+}
+
+func requireNotWoken(t *testing.T, sub *activity.Subscription) {
+	select {
+	case <-sub.Wake():
+		t.Fatal("subscription was woken up")
+	default:
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := activity.NewHub()
+	sub := hub.Subscribe([]int64{1, 2})
+	other := hub.Subscribe([]int64{2, 3})
+
+	// notifications are merged until the subscriber reads them
+	hub.Notify(1)
+	hub.Notify(2)
+	hub.Notify(1)
+	requireWoken(t, sub)
+	requireNotWoken(t, sub)
+	require.ElementsMatch(t, []int64{1, 2}, sub.Pending())
+	require.Empty(t, sub.Pending())
+
+	requireWoken(t, other)
+	require.Equal(t, []int64{2}, other.Pending())
+
+	// an account nobody watches
+	hub.Notify(4)
+	requireNotWoken(t, sub)
+	requireNotWoken(t, other)
+
+	hub.NotifyAll()
+	requireWoken(t, sub)
+	require.ElementsMatch(t, []int64{1, 2}, sub.Pending())
+	requireWoken(t, other)
+	require.ElementsMatch(t, []int64{2, 3}, other.Pending())
+
+	sub.Close()
+	hub.Notify(1)
+	hub.Notify(2)
+	requireNotWoken(t, sub)
+	requireWoken(t, other)
+	require.Equal(t, []int64{2}, other.Pending())
+}
+
+func TestHubRun(t *testing.T) {
+	hub := activity.NewHub()
+	sub := hub.Subscribe([]int64{7, 8})
+	defer sub.Close()
+
+	notifications := make(chan *pq.Notification)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx, notifications)
+		close(done)
+	}()
+
+	notifications <- &pq.Notification{Channel: activity.Channel, Extra: "7"}
+	requireWoken(t, sub)
+	require.Equal(t, []int64{7}, sub.Pending())
+
+	// a broken payload is skipped
+	notifications <- &pq.Notification{Channel: activity.Channel, Extra: "seven"}
+
+	// the listener reconnected, every account may have missed activity
+	notifications <- nil
+	requireWoken(t, sub)
+	require.ElementsMatch(t, []int64{7, 8}, sub.Pending())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after ctx was done")
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := activity.NewHub()
+	sub := hub.Subscribe([]int64{1})
+	defer sub.Close()
+
+	select {
+	case <-sub.Done():
+		t.Fatal("subscription is done before the hub is closed")
+	default:
+	}
+
+	hub.Close()
+	hub.Close()
+
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("subscription isn't done after the hub is closed")
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const authorizationPayloadKey = "authorization_payload"
+
+// AuditContext describes the caller of a route for the audit log
+func AuditContext(ctx *gin.Context, actor string) db.AuditContext {
+	return db.AuditContext{
+		Actor:     actor,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
+
+// AuthUserAuditContext is the AuditContext of an authenticated caller,
+// a request made with an API key is told apart from its owner
+func AuthUserAuditContext(ctx *gin.Context, username string) db.AuditContext {
+	actor := username
+	payload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if ok && payload.IsAPIKey() {
+		actor = fmt.Sprintf("%s/api_key:%d", actor, payload.APIKeyID)
+	}
+	return AuditContext(ctx, actor)
+}
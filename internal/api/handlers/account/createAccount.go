@@ -4,18 +4,16 @@ import (
 	"net/http"
 
 	"github.com/claytten/golang-simplebank/internal/api"
-	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 const (
-	authorizationHeaderKey   = "authorization"
-	authorizationTypeBearer  = "bearer"
-	authorizationPayloadKey  = "authorization_payload"
-	authorizationPassword    = "password"
-	authorizationOldPassword = "oldPassword"
-	authorizationUsername    = "username"
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+	authorizationPassword   = "password"
+	authorizationUsername   = "username"
 )
 
 func PostCreateAccountRoute(api *api.Server, userRg *gin.RouterGroup) {
@@ -30,27 +28,18 @@ func PostCreateAccountHandler(s *api.Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req createAccountRequest
 		if err := ctx.ShouldBindJSON(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Some params are not found"})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
-		username := ctx.GetHeader(authorizationUsername)
+		username := ctx.MustGet(authorizationUsername).(string)
 
-		arg := db.CreateAccountParams{
+		account, err := s.Accounts.Create(ctx, service.CreateAccountParams{
 			Owner:    username,
 			Currency: req.Currency,
-			Balance:  0,
-		}
-
-		account, err := s.DB.CreateAccount(ctx, arg)
+			Audit:    api.AuthUserAuditContext(ctx, username),
+		})
 		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok {
-				switch pqErr.Code.Name() {
-				case "foreign_key_violation", "unique_violation":
-					ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-					return
-				}
-			}
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot Create Account"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
@@ -2,6 +2,7 @@ package account_test
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -20,6 +21,7 @@ import (
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
@@ -32,7 +34,7 @@ func addAuthorization(
 	email string,
 	duration time.Duration,
 ) {
-	token, payload, err := tokenMaker.CreateToken(email, duration)
+	token, payload, err := tokenMaker.CreateToken(email, util.DepositorRole, uuid.New(), duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -40,13 +42,28 @@ func addAuthorization(
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
 }
 
+func addElevatedAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	email string,
+	duration time.Duration,
+) {
+	elevatedToken, payload, err := tokenMaker.CreateScopedToken(email, util.DepositorRole, uuid.New(), token.ScopeSensitive, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, elevatedToken)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
 const (
-	authorizationHeaderKey   = "authorization"
-	authorizationTypeBearer  = "bearer"
-	authorizationPayloadKey  = "authorization_payload"
-	authorizationPassword    = "password"
-	authorizationOldPassword = "oldPassword"
-	authorizationUsername    = "username"
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+	authorizationPassword   = "password"
+	authorizationUsername   = "username"
 )
 
 func TestMain(m *testing.M) {
@@ -55,8 +72,26 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// expectAuditTx runs the transaction of an audited change on the mock store,
+// the change has to be recorded as action
+func expectAuditTx(store *mockdb.MockStore, action string) {
+	store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+			event, err := fn(store)
+			if err != nil {
+				return err
+			}
+			if event.Action != action {
+				return fmt.Errorf("audited %s instead of %s", event.Action, action)
+			}
+			return nil
+		})
+}
+
 func TestPostCreateAccountHandler(t *testing.T) {
-	user, password := util.RandomUser(t)
+	user, _ := util.RandomUser(t)
 	account := db.Accounts{
 		Owner:    user.Username,
 		Balance:  0,
@@ -77,9 +112,8 @@ func TestPostCreateAccountHandler(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, password)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
@@ -89,7 +123,9 @@ func TestPostCreateAccountHandler(t *testing.T) {
 					Currency: account.Currency,
 					Balance:  account.Balance,
 				}
+				expectAuditTx(store, "account.create")
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Return(account, nil).Times(1)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -115,8 +151,7 @@ func TestPostCreateAccountHandler(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
-				request.Header.Set(authorizationUsername, user.Username)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Return(db.Users{}, nil).Times(0)
@@ -132,9 +167,8 @@ func TestPostCreateAccountHandler(t *testing.T) {
 			name: "400 missing some body",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, password)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
@@ -152,9 +186,8 @@ func TestPostCreateAccountHandler(t *testing.T) {
 				"currency": "SGD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, password)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
@@ -172,12 +205,12 @@ func TestPostCreateAccountHandler(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, password)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
+				expectAuditTx(store, "account.create")
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -185,24 +218,24 @@ func TestPostCreateAccountHandler(t *testing.T) {
 			},
 		},
 
-		// TODO: 403 unique violation
+		// TODO: 409 unique violation
 		{
-			name: "403 unique violation",
+			name: "409 unique violation",
 			body: gin.H{
 				"currency": account.Currency,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, password)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
 
+				expectAuditTx(store, "account.create")
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, &pq.Error{Code: "23505"}).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
 			},
 		},
 	}
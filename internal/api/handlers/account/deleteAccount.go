@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,13 +17,18 @@ func DeleteAccountHandler(s *api.Server) gin.HandlerFunc {
 		var req GetAccountRequest
 
 		if err := ctx.ShouldBindHeader(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		err := s.DB.DeleteAccount(ctx, req.ID)
+		username := ctx.MustGet(authorizationUsername).(string)
+		err := s.Accounts.Delete(ctx, service.DeleteAccountParams{
+			Owner: username,
+			ID:    req.ID,
+			Audit: api.AuthUserAuditContext(ctx, username),
+		})
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete account"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
@@ -12,13 +12,14 @@ import (
 	"github.com/claytten/golang-simplebank/internal/api/routes"
 	"github.com/claytten/golang-simplebank/internal/api/token"
 	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
 
 func TestDeleteAccountHandler(t *testing.T) {
-	user, oldPassword := util.RandomUser(t)
+	user, _ := util.RandomUser(t)
 	account := util.RandomAccount(user.Username)
 	tests := []struct {
 		name          string
@@ -30,14 +31,16 @@ func TestDeleteAccountHandler(t *testing.T) {
 		{
 			name: "200 OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 				request.Header.Set("id", strconv.Itoa(int(account.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).Return(nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				expectAuditTx(store, "account.delete")
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -58,9 +61,8 @@ func TestDeleteAccountHandler(t *testing.T) {
 		{
 			name: "400 no id header",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
@@ -74,19 +76,58 @@ func TestDeleteAccountHandler(t *testing.T) {
 		{
 			name: "500 query error",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 				request.Header.Set("id", strconv.Itoa(int(account.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Return(sql.ErrConnDone).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				expectAuditTx(store, "account.delete")
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+
+		// TODO: 404 account not found
+		{
+			name: "404 account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				request.Header.Set(authorizationUsername, user.Username)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Accounts{}, db.ErrRecordNotFound).Times(1)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		// TODO: 403 account of another user
+		{
+			name: "403 account not owned",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				request.Header.Set(authorizationUsername, user.Username)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				other := account
+				other.Owner = util.RandomOwner()
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(other, nil).Times(1)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,10 +1,10 @@
 package account
 
 import (
-	"database/sql"
 	"net/http"
 
 	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/token"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,18 +20,21 @@ func GetAccountHandler(s *api.Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req GetAccountRequest
 		if err := ctx.ShouldBindHeader(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		account, err := s.DB.GetAccount(ctx, req.ID)
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := s.DB.GetUserUsingEmail(ctx, authPayload.Email)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-				return
-			}
+			api.AbortWithProblem(ctx, api.AuthenticatedUserError(err))
+			return
+		}
 
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// only admins can look at other users' accounts, through the admin routes
+		account, err := s.Accounts.GetOwned(ctx, user.Username, req.ID)
+		if err != nil {
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
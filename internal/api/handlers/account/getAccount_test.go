@@ -20,6 +20,7 @@ import (
 
 func TestGetAccountHandler(t *testing.T) {
 	user, _ := util.RandomUser(t)
+	otherUser, _ := util.RandomUser(t)
 	account := util.RandomAccount(user.Username)
 
 	tests := []struct {
@@ -37,12 +38,29 @@ func TestGetAccountHandler(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
 
+		// TODO: 403 account of another user
+		{
+			name: "403 account of another user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.Email, time.Minute)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(otherUser.Email)).Return(otherUser, nil).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
 		// TODO: 400 missing some header
 		{
 			name: "400 missing some header",
@@ -75,6 +93,7 @@ func TestGetAccountHandler(t *testing.T) {
 				request.Header.Set("id", "1234567")
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, sql.ErrNoRows)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -90,6 +109,7 @@ func TestGetAccountHandler(t *testing.T) {
 				request.Header.Set("id", strconv.Itoa(int(account.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -1,13 +1,14 @@
 package account
 
 import (
-	"database/sql"
+	"errors"
 	"math"
 	"net/http"
 
 	"github.com/claytten/golang-simplebank/internal/api"
 	"github.com/claytten/golang-simplebank/internal/api/token"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,14 +25,14 @@ func ListsAccountsHandler(s *api.Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req ListsAccountsRequest
 		if err := ctx.ShouldBindQuery(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
 		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 		user, err := s.DB.GetUserUsingEmail(ctx, authPayload.Email)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.AuthenticatedUserError(err))
 			return
 		}
 
@@ -43,11 +44,11 @@ func ListsAccountsHandler(s *api.Server) gin.HandlerFunc {
 
 		accounts, err := s.DB.ListsAccounts(ctx, args)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": "Accounts not found"})
+			if errors.Is(err, db.ErrRecordNotFound) {
+				api.AbortWithProblem(ctx, domain.ErrAccountNotFound.Wrap(err))
 				return
 			}
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server error while get account"})
+			api.AbortWithProblem(ctx, domain.Internal("cannot list accounts", err))
 			return
 		}
 
@@ -1,11 +1,10 @@
 package account
 
 import (
-	"database/sql"
 	"net/http"
 
 	"github.com/claytten/golang-simplebank/internal/api"
-	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -29,57 +28,29 @@ func PostCreateTransferAccountHandler(s *api.Server) gin.HandlerFunc {
 		var head TransferHeaderRequest
 
 		if err := ctx.ShouldBindHeader(&head); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
 		if err := ctx.ShouldBindJSON(&body); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		ok := head.ValidAccount(ctx, s.DB, body.Currency)
-		if !ok {
-			return
-		}
-
-		arg := db.TransferTxParams{
+		username := ctx.MustGet(authorizationUsername).(string)
+		result, err := s.Transfers.Transfer(ctx, service.TransferParams{
+			Owner:         username,
 			FromAccountID: head.FromAccountID,
 			ToAccountID:   head.ToAccountID,
 			Amount:        body.Amount,
-		}
-
-		result, err := s.DB.TransferTx(ctx, arg)
+			Currency:      body.Currency,
+			Audit:         api.AuthUserAuditContext(ctx, username),
+		})
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
 		ctx.JSON(http.StatusOK, result)
 	}
 }
-
-func (h *TransferHeaderRequest) ValidAccount(ctx *gin.Context, db db.Store, currency string) bool {
-	fromAccount, err := db.GetAccount(ctx, h.FromAccountID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return false
-	}
-
-	toAccount, err := db.GetAccount(ctx, h.ToAccountID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return false
-		}
-
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return false
-	}
-
-	if fromAccount.Currency != currency || toAccount.Currency != currency {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "From/To Account Mismatch Currency"})
-		return false
-	}
-	return true
-}
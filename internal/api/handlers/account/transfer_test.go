@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -21,9 +22,36 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+type EqTransferTxParamsMatcher struct {
+	arg db.TransferTxParams
+}
+
+// Matches compares the transfer and its auditor, the events are only
+// checked to be written
+func (e *EqTransferTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.TransferTxParams)
+	if !ok {
+		return false
+	}
+
+	return arg.FromAccountID == e.arg.FromAccountID &&
+		arg.ToAccountID == e.arg.ToAccountID &&
+		arg.Amount == e.arg.Amount &&
+		arg.Audit.Actor == e.arg.Audit.Actor &&
+		arg.Events != nil
+}
+
+func (e *EqTransferTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v", e.arg)
+}
+
+func EqTransferTxParams(arg db.TransferTxParams) gomock.Matcher {
+	return &EqTransferTxParamsMatcher{arg}
+}
+
 func TestPostCreateTransferAccountHandler(t *testing.T) {
 	amount := int64(10)
-	user1, oldPassword1 := util.RandomUser(t)
+	user1, _ := util.RandomUser(t)
 	user2, _ := util.RandomUser(t)
 	user3, _ := util.RandomUser(t)
 
@@ -50,9 +78,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				"currency": util.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
 			},
@@ -66,9 +93,10 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 					FromAccountID: acc1.ID,
 					ToAccountID:   acc2.ID,
 					Amount:        amount,
+					Audit:         db.AuditContext{Actor: user1.Username},
 				}
 
-				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), EqTransferTxParams(arg)).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -83,9 +111,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				"currency": util.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
@@ -101,9 +128,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 			name: "400 body missing",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
 			},
@@ -126,14 +152,13 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 			},
 		},
 
-		// TODO: 401 token and user mismatch
+		// TODO: 403 token and user mismatch
 		{
-			name: "401 mismatch user and token",
+			name: "403 mismatch user and token",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user2.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
 			},
@@ -141,7 +166,30 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user1.Email)).Return(user1, nil).AnyTimes()
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		// TODO: 403 from account of another user
+		{
+			name: "403 from account not owned",
+			body: gin.H{
+				"amount":   amount,
+				"currency": util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				request.Header.Set(authorizationUsername, user1.Username)
+				request.Header.Set("from_account_id", strconv.Itoa(int(acc2.ID)))
+				request.Header.Set("to_account_id", strconv.Itoa(int(acc1.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user1.Email)).Return(user1, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Return(acc2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 
@@ -153,9 +201,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				"currency": util.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
 			},
@@ -177,9 +224,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				"currency": "XYZ",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
 			},
@@ -191,6 +237,33 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 			},
 		},
 
+		// TODO: 422 frozen account
+		{
+			name: "422 frozen account",
+			body: gin.H{
+				"amount":   amount,
+				"currency": util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				request.Header.Set(authorizationUsername, user1.Username)
+				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
+				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := acc2
+				frozenAccount.IsFrozen = true
+
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user1.Email)).Return(user1, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Return(acc1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Return(frozenAccount, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+
 		// TODO: 500 error query from account
 		{
 			name: "500 error query from account",
@@ -199,9 +272,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				"currency": util.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", "999999")
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc1.ID)))
 			},
@@ -222,9 +294,8 @@ func TestPostCreateTransferAccountHandler(t *testing.T) {
 				"currency": util.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user1.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword1)
 				request.Header.Set("from_account_id", strconv.Itoa(int(acc1.ID)))
 				request.Header.Set("to_account_id", strconv.Itoa(int(acc2.ID)))
 			},
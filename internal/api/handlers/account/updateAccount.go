@@ -2,10 +2,9 @@ package account
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/claytten/golang-simplebank/internal/api"
-	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,24 +22,24 @@ func PutUpdateAccountHandler(s *api.Server) gin.HandlerFunc {
 		var id GetAccountRequest
 
 		if err := ctx.ShouldBindHeader(&id); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
 		if err := ctx.ShouldBindJSON(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		args := db.UpdateAccountParams{
-			ID:        id.ID,
-			Balance:   req.Balance,
-			UpdatedAt: time.Now(),
-		}
-
-		account, err := s.DB.UpdateAccount(ctx, args)
+		username := ctx.MustGet(authorizationUsername).(string)
+		account, err := s.Accounts.Update(ctx, service.UpdateAccountParams{
+			Owner:   username,
+			ID:      id.ID,
+			Balance: req.Balance,
+			Audit:   api.AuthUserAuditContext(ctx, username),
+		})
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update balance account"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
@@ -53,7 +53,7 @@ func EqUpdateAccountParams(arg db.UpdateAccountParams, updatedAt time.Time) gomo
 }
 
 func TestPutUpdateAccountHandler(t *testing.T) {
-	user, oldPassword := util.RandomUser(t)
+	user, _ := util.RandomUser(t)
 	account := util.RandomAccount(user.Username)
 	now := time.Now()
 	addNewBalance := int64(200)
@@ -76,19 +76,22 @@ func TestPutUpdateAccountHandler(t *testing.T) {
 				"balance": addNewBalance,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 				request.Header.Set("id", strconv.Itoa(int(account.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
 
 				arg := db.UpdateAccountParams{
 					ID:      account.ID,
 					Balance: addNewBalance,
 				}
+				expectAuditTx(store, "account.update")
 				store.EXPECT().UpdateAccount(gomock.Any(), EqUpdateAccountParams(arg, now)).Return(newAccount, nil).Times(1)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().NotifyAccountActivity(gomock.Any(), gomock.Eq(account.ID)).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -113,9 +116,8 @@ func TestPutUpdateAccountHandler(t *testing.T) {
 				"balance": addNewBalance,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
@@ -130,9 +132,8 @@ func TestPutUpdateAccountHandler(t *testing.T) {
 			name: "400 missing body balance",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 				request.Header.Set("id", strconv.Itoa(int(account.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
@@ -150,19 +151,44 @@ func TestPutUpdateAccountHandler(t *testing.T) {
 				"balance": addNewBalance,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword)
 				request.Header.Set("id", strconv.Itoa(int(account.ID)))
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				expectAuditTx(store, "account.update")
 				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+
+		// TODO: 422 frozen account
+		{
+			name: "422 frozen account",
+			body: gin.H{
+				"balance": addNewBalance,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				request.Header.Set(authorizationUsername, user.Username)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := account
+				frozenAccount.IsFrozen = true
+
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(frozenAccount, nil).Times(1)
+				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/gin-gonic/gin"
+)
+
+const authorizationPayloadKey = "authorization_payload"
+
+func PostFreezeAccountRoute(api *api.Server, adminRg *gin.RouterGroup) {
+	adminRg.POST("/freezeAccount", PostFreezeAccountHandler(api))
+}
+
+type AccountRequest struct {
+	ID int64 `header:"id" binding:"required,min=1"`
+}
+
+func PostFreezeAccountHandler(s *api.Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req AccountRequest
+		if err := ctx.ShouldBindHeader(&req); err != nil {
+			api.AbortWithProblem(ctx, api.BindingError(err))
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		admin, err := s.DB.GetUserUsingEmail(ctx, authPayload.Email)
+		if err != nil {
+			api.AbortWithProblem(ctx, api.AuthenticatedUserError(err))
+			return
+		}
+
+		account, err := s.Accounts.Freeze(ctx, req.ID, api.AuthUserAuditContext(ctx, admin.Username))
+		if err != nil {
+			api.AbortWithProblem(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, account)
+	}
+}
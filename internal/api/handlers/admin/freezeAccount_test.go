@@ -0,0 +1,163 @@
+package admin_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/routes"
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectAuditTx runs the transaction of an audited change on the mock store,
+// the change has to be recorded as action by actor
+func expectAuditTx(store *mockdb.MockStore, action, actor string) {
+	store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+			event, err := fn(store)
+			if err != nil {
+				return err
+			}
+			if event.Action != action || event.Actor != actor {
+				return fmt.Errorf("%s audited %s instead of %s by %s", event.Actor, event.Action, action, actor)
+			}
+			return nil
+		})
+}
+
+func TestPostFreezeAccountHandler(t *testing.T) {
+	admin, _ := util.RandomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := util.RandomUser(t)
+	account := util.RandomAccount(user.Username)
+
+	frozenAccount := account
+	frozenAccount.IsFrozen = true
+
+	tests := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		// TODO: 200 OK
+		{
+			name: "200 OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(admin.Email)).Return(admin, nil).Times(1)
+				expectAuditTx(store, "account.freeze", admin.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Return(frozenAccount, nil).Times(1)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().NotifyAccountActivity(gomock.Any(), gomock.Eq(account.ID)).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"is_frozen":true`)
+			},
+		},
+
+		// TODO: 400 missing id header
+		{
+			name: "400 no id header",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		// TODO: 403 not an admin
+		{
+			name: "403 not an admin",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, user.Role, time.Minute)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		// TODO: 404 account not found
+		{
+			name: "404 account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(admin.Email)).Return(admin, nil).Times(1)
+				expectAuditTx(store, "account.freeze", admin.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Accounts{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		// TODO: 500 query error
+		{
+			name: "500 query error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+				request.Header.Set("id", strconv.Itoa(int(account.ID)))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(admin.Email)).Return(admin, nil).Times(1)
+				expectAuditTx(store, "account.freeze", admin.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Return(db.Accounts{}, sql.ErrConnDone).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			server := api.NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			freezeAccountPath := "/api/v1/admin/freezeAccount"
+			request, err := http.NewRequest(http.MethodPost, freezeAccountPath, nil)
+			require.NoError(t, err)
+
+			tt.setupAuth(t, request, server.Token)
+			routes.ApplyAllPublicRoutes(server)
+			server.Engine.ServeHTTP(recorder, request)
+			tt.checkResponse(recorder)
+		})
+	}
+}
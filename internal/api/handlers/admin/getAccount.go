@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/gin-gonic/gin"
+)
+
+func GetAccountRoute(api *api.Server, adminRg *gin.RouterGroup) {
+	adminRg.GET("/getAccount", GetAccountHandler(api))
+}
+
+func GetAccountHandler(s *api.Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req AccountRequest
+		if err := ctx.ShouldBindHeader(&req); err != nil {
+			api.AbortWithProblem(ctx, api.BindingError(err))
+			return
+		}
+
+		account, err := s.Accounts.Get(ctx, req.ID)
+		if err != nil {
+			api.AbortWithProblem(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, account)
+	}
+}
@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/handlers/auth"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func ListUsersRoute(api *api.Server, adminRg *gin.RouterGroup) {
+	adminRg.GET("/users", ListUsersHandler(api))
+}
+
+type ListUsersRequest struct {
+	PageID   int64 `form:"page_id" binding:"required,min=1"`
+	PageSize int64 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func ListUsersHandler(s *api.Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req ListUsersRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			api.AbortWithProblem(ctx, api.BindingError(err))
+			return
+		}
+
+		args := db.ListUsersParams{
+			Limit:  int32(req.PageSize),
+			Offset: (int32(req.PageID) - 1) * int32(req.PageSize),
+		}
+
+		users, err := s.DB.ListUsers(ctx, args)
+		if err != nil {
+			api.AbortWithProblem(ctx, domain.Internal("cannot list users", err))
+			return
+		}
+
+		allUsers, err := s.DB.GetTotalPageListsUsers(ctx)
+		if err != nil {
+			api.AbortWithProblem(ctx, domain.Internal("cannot count users", err))
+			return
+		}
+
+		data := make([]*auth.UserResponse, 0, len(users))
+		for _, user := range users {
+			data = append(data, auth.NewUserResponse(user))
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"total_page":   math.Ceil(float64(allUsers) / float64(args.Limit)),
+			"current_page": req.PageID,
+			"limit":        req.PageSize,
+			"data":         data,
+		})
+	}
+}
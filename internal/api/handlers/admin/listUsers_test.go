@@ -0,0 +1,213 @@
+package admin_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/routes"
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+func addAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	email string,
+	role string,
+	duration time.Duration,
+) {
+	token, payload, err := tokenMaker.CreateToken(email, role, uuid.New(), duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	os.Exit(m.Run())
+}
+
+func TestListUsersHandler(t *testing.T) {
+	admin, _ := util.RandomUser(t)
+	admin.Role = util.AdminRole
+
+	n := 5
+	users := make([]db.Users, n)
+	for i := 0; i < n; i++ {
+		users[i], _ = util.RandomUser(t)
+	}
+
+	type Query struct {
+		PageID   int
+		PageSize int
+	}
+	tests := []struct {
+		name          string
+		query         Query
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		// TODO: 200 OK
+		{
+			name: "200 OK",
+			query: Query{
+				PageID:   1,
+				PageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListUsersParams{
+					Limit:  int32(n),
+					Offset: 0,
+				}
+
+				store.EXPECT().ListUsers(gomock.Any(), gomock.Eq(arg)).Return(users, nil).Times(1)
+				store.EXPECT().GetTotalPageListsUsers(gomock.Any()).Return(int64(n), nil).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				RequireBodyMatchUsers(t, recorder.Body, users)
+			},
+		},
+
+		// TODO: 400 InvalidPageSize
+		{
+			name: "400 invalid page size",
+			query: Query{
+				PageID:   1,
+				PageSize: 1000000,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		// TODO: 401 no token
+		{
+			name: "401 no token",
+			query: Query{
+				PageID:   1,
+				PageSize: n,
+			},
+			setupAuth:  func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: 403 not an admin
+		{
+			name: "403 not an admin",
+			query: Query{
+				PageID:   1,
+				PageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, users[0].Email, users[0].Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		// TODO: 500 query error
+		{
+			name: "500 query error",
+			query: Query{
+				PageID:   1,
+				PageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Email, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUsers(gomock.Any(), gomock.Any()).Return([]db.Users{}, sql.ErrConnDone).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			server := api.NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			listUsersPath := "/api/v1/admin/users"
+			request, err := http.NewRequest(http.MethodGet, listUsersPath, nil)
+			require.NoError(t, err)
+
+			// Add query parameters to request URL
+			q := request.URL.Query()
+			q.Add("page_id", fmt.Sprintf("%d", tt.query.PageID))
+			q.Add("page_size", fmt.Sprintf("%d", tt.query.PageSize))
+			request.URL.RawQuery = q.Encode()
+
+			tt.setupAuth(t, request, server.Token)
+			routes.ApplyAllPublicRoutes(server)
+			server.Engine.ServeHTTP(recorder, request)
+			tt.checkResponse(recorder)
+		})
+	}
+}
+
+func RequireBodyMatchUsers(t *testing.T, body *bytes.Buffer, users []db.Users) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotData map[string]interface{}
+	err = json.Unmarshal(data, &gotData)
+	require.NoError(t, err)
+
+	for i, v := range gotData["data"].([]interface{}) {
+		item, _ := v.(map[string]interface{})
+		require.Equal(t, users[i].Username, item["username"])
+		require.Equal(t, users[i].Email, item["email"])
+		require.Equal(t, users[i].Role, item["role"])
+		require.NotContains(t, item, "hashed_password")
+	}
+}
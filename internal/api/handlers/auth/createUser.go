@@ -4,10 +4,8 @@ import (
 	"net/http"
 
 	"github.com/claytten/golang-simplebank/internal/api"
-	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
-	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 func PostCreateUserRoute(api *api.Server, userRg *gin.RouterGroup) {
@@ -16,7 +14,7 @@ func PostCreateUserRoute(api *api.Server, userRg *gin.RouterGroup) {
 
 type UserCreateRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -25,32 +23,20 @@ func PostCreateUserHandler(s *api.Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req UserCreateRequest
 		if err := ctx.ShouldBindJSON(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		hashedPassword, err := util.HashingPassword(req.Password)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Password Can't Use"})
-			return
-		}
-
-		user, err := s.DB.CreateUser(ctx, db.CreateUserParams{
-			Username:       req.Username,
-			Email:          req.Email,
-			FullName:       req.FullName,
-			HashedPassword: hashedPassword,
+		user, err := s.Users.Create(ctx, service.CreateUserParams{
+			Username: req.Username,
+			Email:    req.Email,
+			FullName: req.FullName,
+			Password: req.Password,
+			// users sign up themselves
+			Audit: api.AuditContext(ctx, req.Username),
 		})
-
 		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok {
-				switch pqErr.Code.Name() {
-				case "unique_violation":
-					ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Cannot use. Please Try again"})
-					return
-				}
-			}
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Already Use"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
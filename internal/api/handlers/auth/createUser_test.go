@@ -22,13 +22,14 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-type EqCreateUserParamsMatcher struct {
+type EqCreateUserTxParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
 }
 
-func (e *EqCreateUserParamsMatcher) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUserParams)
+// Matches compares the user, signed up by themselves with a verification email
+func (e *EqCreateUserTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -38,16 +39,20 @@ func (e *EqCreateUserParamsMatcher) Matches(x interface{}) bool {
 		return false
 	}
 
+	if len(arg.Events) != 1 || arg.Audit.Actor != e.arg.Username {
+		return false
+	}
+
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, arg)
+	return reflect.DeepEqual(e.arg, arg.CreateUserParams)
 }
 
-func (e *EqCreateUserParamsMatcher) String() string {
+func (e *EqCreateUserTxParamsMatcher) String() string {
 	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return &EqCreateUserParamsMatcher{arg, password}
+func EqCreateUserTxParams(arg db.CreateUserParams, password string) gomock.Matcher {
+	return &EqCreateUserTxParamsMatcher{arg, password}
 }
 
 func TestPostCreateUserHandler(t *testing.T) {
@@ -75,7 +80,7 @@ func TestPostCreateUserHandler(t *testing.T) {
 					Email:    user.Email,
 				}
 
-				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).Return(user, nil).Times(1)
+				store.EXPECT().CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).Return(db.CreateUserTxResult{User: user}, nil).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -138,9 +143,9 @@ func TestPostCreateUserHandler(t *testing.T) {
 			},
 		},
 
-		// TODO: 403 Duplicate Username
+		// TODO: 409 Duplicate Username
 		{
-			name: "403 duplicate username",
+			name: "409 duplicate username",
 			body: gin.H{
 				"username":  user.Username,
 				"password":  password,
@@ -148,10 +153,10 @@ func TestPostCreateUserHandler(t *testing.T) {
 				"email":     user.Email,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(db.Users{}, &pq.Error{Code: "23505"})
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
 			},
 		},
 
@@ -165,8 +170,7 @@ func TestPostCreateUserHandler(t *testing.T) {
 				"email":     user.Email,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-
-				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(db.Users{}, sql.ErrConnDone).Times(1)
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Return(db.CreateUserTxResult{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/claytten/golang-simplebank/internal/api"
 	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,25 +22,25 @@ func GetUserHandler(s *api.Server) gin.HandlerFunc {
 		var req GetUserRequest
 
 		if err := ctx.ShouldBindHeader(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
 		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 		userHeader, err := s.DB.GetUserUsingEmail(ctx, authPayload.Email)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "account doesn't belong to authenticated user"})
+			api.AbortWithProblem(ctx, api.AuthenticatedUserError(err))
 			return
 		}
 
-		if userHeader.Username != req.Username {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User Status Unauthorized"})
+		if err = service.CheckOwnUser(userHeader, req.Username); err != nil {
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
-		user, err := s.DB.GetUser(ctx, req.Username)
+		user, err := s.Users.Get(ctx, req.Username)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Not Found"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
@@ -15,6 +15,7 @@ import (
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,7 +27,7 @@ func addAuthorization(
 	email string,
 	duration time.Duration,
 ) {
-	token, payload, err := tokenMaker.CreateToken(email, duration)
+	token, payload, err := tokenMaker.CreateToken(email, util.DepositorRole, uuid.New(), duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -34,6 +35,22 @@ func addAuthorization(
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
 }
 
+func addElevatedAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	email string,
+	duration time.Duration,
+) {
+	elevatedToken, payload, err := tokenMaker.CreateScopedToken(email, util.DepositorRole, uuid.New(), token.ScopeSensitive, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, elevatedToken)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
 func TestGetUserHandler(t *testing.T) {
 	user, _ := util.RandomUser(t)
 	tests := []struct {
@@ -64,9 +81,9 @@ func TestGetUserHandler(t *testing.T) {
 			},
 		},
 
-		// TODO: 404 Notfound
+		// TODO: 403 token of another user
 		{
-			name: "404 username payload",
+			name: "403 username payload",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "notfound@email.com", time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
@@ -79,13 +96,13 @@ func TestGetUserHandler(t *testing.T) {
 				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 
-		// TODO: 401 username header and token
+		// TODO: 403 username header and token
 		{
-			name: "401 Unauthorized header and token",
+			name: "403 Forbidden header and token",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, "notfound")
@@ -95,7 +112,7 @@ func TestGetUserHandler(t *testing.T) {
 				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 
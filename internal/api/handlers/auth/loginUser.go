@@ -1,13 +1,12 @@
 package auth
 
 import (
-	"database/sql"
 	"net/http"
 	"time"
 
 	"github.com/claytten/golang-simplebank/internal/api"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
-	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -16,6 +15,7 @@ type UserResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
@@ -26,6 +26,7 @@ func NewUserResponse(user db.Users) *UserResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 		UpdatedAt:         user.UpdatedAt,
@@ -34,7 +35,7 @@ func NewUserResponse(user db.Users) *UserResponse {
 
 type loginUserRequest struct {
 	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 type loginUserResponse struct {
@@ -55,61 +56,30 @@ func PostLoginUserHandler(s *api.Server) gin.HandlerFunc {
 		var req loginUserRequest
 
 		if err := ctx.ShouldBindJSON(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Some Params are not found"})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		user, err := s.DB.GetUserUsingEmail(ctx, req.Email)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-				return
-			}
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot Login!. username/email or password is not match."})
-			return
-		}
-
-		err = util.ComparePassword(user.HashedPassword, req.Password)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot Login!. username/email or password is not match."})
-			return
-		}
-
-		accessToken, accessPayload, err := s.Token.CreateToken(user.Email, s.Config.AccessTokenDuration)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-
-		refreshToken, refreshPayload, err := s.Token.CreateToken(user.Email, s.Config.RefreshTokenDuration)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-
-		session, err := s.DB.CreateSession(ctx, db.CreateSessionParams{
-			ID:           refreshPayload.ID,
-			Email:        user.Email,
-			RefreshToken: refreshToken,
-			UserAgent:    ctx.Request.UserAgent(),
-			ClientIp:     ctx.ClientIP(),
-			IsBlocked:    false,
-			ExpiresAt:    refreshPayload.ExpiredAt,
-			CreatedAt:    time.Now(),
+		result, err := s.Users.Login(ctx, service.LoginParams{
+			Email:    req.Email,
+			Password: req.Password,
+			Client: service.ClientInfo{
+				UserAgent: ctx.Request.UserAgent(),
+				ClientIP:  ctx.ClientIP(),
+			},
 		})
-
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
 		res := loginUserResponse{
-			SessionID:             session.ID,
-			AccessToken:           accessToken,
-			AccessTokenExpiresAt:  accessPayload.ExpiredAt,
-			RefreshToken:          refreshToken,
-			RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
-			User:                  *NewUserResponse(user),
+			SessionID:             result.Session.ID,
+			AccessToken:           result.AccessToken,
+			AccessTokenExpiresAt:  result.AccessPayload.ExpiredAt,
+			RefreshToken:          result.RefreshToken,
+			RefreshTokenExpiresAt: result.RefreshPayload.ExpiredAt,
+			User:                  *NewUserResponse(result.User),
 		}
 
 		ctx.JSON(http.StatusOK, res)
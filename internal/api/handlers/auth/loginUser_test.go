@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
@@ -20,13 +21,12 @@ import (
 )
 
 const (
-	authorizationHeaderKey   = "authorization"
-	authorizationTypeBearer  = "bearer"
-	authorizationPayloadKey  = "authorization_payload"
-	authorizationPassword    = "password"
-	authorizationOldPassword = "oldPassword"
-	authorizationUsername    = "username"
-	authorizationRefreshKey  = "refresh_token"
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+	authorizationPassword   = "password"
+	authorizationUsername   = "username"
+	authorizationRefreshKey = "refresh_token"
 )
 
 func TestMain(m *testing.M) {
@@ -55,7 +55,10 @@ func TestPostLoginUserRoute(t *testing.T) {
 					GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).
 					Times(1).
 					Return(user, nil)
-				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).AnyTimes()
+				expectAuditTx(store, "session.create")
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().CreateUserDevice(gomock.Any(), gomock.Any()).Times(1).Return(db.UserDevices{}, sql.ErrNoRows)
+				store.EXPECT().TouchUserDevice(gomock.Any(), gomock.Any()).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -72,9 +75,9 @@ func TestPostLoginUserRoute(t *testing.T) {
 			},
 		},
 
-		// TODO: 404 Login User Not Found
+		// TODO: 401 Login User Not Found
 		{
-			name: "404 Not Found",
+			name: "401 Not Found",
 			body: gin.H{
 				"email":    "notfound@email.com",
 				"password": plainPassword,
@@ -86,7 +89,7 @@ func TestPostLoginUserRoute(t *testing.T) {
 					Return(db.Users{}, sql.ErrNoRows)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
 
@@ -114,6 +117,13 @@ func TestPostLoginUserRoute(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), user.Email).Return(user, nil).Times(1)
+				store.EXPECT().
+					AuditTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+						_, err := fn(store)
+						return err
+					})
 				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Sessions{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -121,9 +131,9 @@ func TestPostLoginUserRoute(t *testing.T) {
 			},
 		},
 
-		// TODO: 500 Incorrect Password
+		// TODO: 401 Incorrect Password
 		{
-			name: "500 Incorrect Pass",
+			name: "401 Incorrect Pass",
 			body: gin.H{
 				"email":    user.Email,
 				"password": "incorrectPass",
@@ -135,7 +145,7 @@ func TestPostLoginUserRoute(t *testing.T) {
 					Return(user, nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
 	}
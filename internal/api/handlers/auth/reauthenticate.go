@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type reauthenticateRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type reauthenticateResponse struct {
+	ElevatedToken          string    `json:"elevated_token"`
+	ElevatedTokenExpiresAt time.Time `json:"elevated_token_expires_at"`
+}
+
+func PostReauthenticateRoute(api *api.Server, userRg *gin.RouterGroup) {
+	userRg.POST("/reauthenticate", PostReauthenticateHandler(api))
+}
+
+// PostReauthenticateHandler checks the password again and issues a short-lived token
+// for the same session, the routes changing user data only accept that token
+func PostReauthenticateHandler(s *api.Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req reauthenticateRequest
+
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			api.AbortWithProblem(ctx, api.BindingError(err))
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := s.DB.GetUserUsingEmail(ctx, authPayload.Email)
+		if err != nil {
+			api.AbortWithProblem(ctx, api.AuthenticatedUserError(err))
+			return
+		}
+
+		elevatedToken, elevatedPayload, err := s.Users.Reauthenticate(ctx, service.ReauthenticateParams{
+			User:      user,
+			SessionID: authPayload.SessionID,
+			Password:  req.Password,
+			Client: service.ClientInfo{
+				UserAgent: ctx.Request.UserAgent(),
+				ClientIP:  ctx.ClientIP(),
+			},
+		})
+		if err != nil {
+			api.AbortWithProblem(ctx, err)
+			return
+		}
+
+		response := reauthenticateResponse{
+			ElevatedToken:          elevatedToken,
+			ElevatedTokenExpiresAt: elevatedPayload.ExpiredAt,
+		}
+
+		ctx.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,156 @@
+package auth_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/api/routes"
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostReauthenticateHandler(t *testing.T) {
+	user, password := util.RandomUser(t)
+
+	tests := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		// TODO: 200 OK
+		{
+			name: "200 OK",
+			body: gin.H{
+				"password": password,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchElevatedToken(t, recorder.Body, tokenMaker, user)
+			},
+		},
+
+		// TODO: 401 no token
+		{
+			name: "401 no token",
+			body: gin.H{
+				"password": password,
+			},
+			setupAuth:  func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: 400 missing password
+		{
+			name: "400 missing password",
+			body: gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		// TODO: 401 wrong password
+		{
+			name: "401 wrong password",
+			body: gin.H{
+				"password": "wrongPassword",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: 401 user not found
+		{
+			name: "401 user not found",
+			body: gin.H{
+				"password": password,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.Users{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			server := api.NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to json
+			data, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+
+			reauthenticatePath := "/api/v1/auth/reauthenticate"
+			request, err := http.NewRequest(http.MethodPost, reauthenticatePath, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tt.setupAuth(t, request, server.Token)
+			routes.ApplyAllPublicRoutes(server)
+			server.Engine.ServeHTTP(recorder, request)
+			tt.checkResponse(t, recorder, server.Token)
+		})
+	}
+}
+
+func requireBodyMatchElevatedToken(t *testing.T, body *bytes.Buffer, tokenMaker token.Maker, user db.Users) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotData map[string]interface{}
+	err = json.Unmarshal(data, &gotData)
+	require.NoError(t, err)
+
+	elevatedToken, ok := gotData["elevated_token"].(string)
+	require.True(t, ok)
+
+	payload, err := tokenMaker.VerifyToken(elevatedToken)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, payload.Email)
+	require.Equal(t, token.ScopeSensitive, payload.Scope)
+}
@@ -1,11 +1,13 @@
 package auth
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/claytten/golang-simplebank/internal/api"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
 	"github.com/gin-gonic/gin"
 )
 
@@ -27,59 +29,67 @@ func PostRenewTokenUserHandler(s *api.Server) gin.HandlerFunc {
 		var req RenewAccessRequest
 
 		if err := ctx.ShouldBindHeader(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
 		refreshPayload, err := s.Token.VerifyToken(req.RefreshToken)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage(err.Error()).Wrap(err))
 			return
 		}
 
-		session, err := s.DB.GetSession(ctx, refreshPayload.ID)
+		// only a refresh token renews the access token of its session
+		if !refreshPayload.IsRefresh() {
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage("not a refresh token"))
+			return
+		}
+
+		session, err := s.DB.GetSession(ctx, refreshPayload.SessionID)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			if errors.Is(err, db.ErrRecordNotFound) {
+				api.AbortWithProblem(ctx, domain.ErrSessionNotFound.Wrap(err))
 				return
 			}
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, domain.Internal("cannot find session", err))
 			return
 		}
 
 		// session checking
 		// check block sessin
 		if session.IsBlocked {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "blocked session"})
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage("blocked session"))
 			return
 		}
 
 		// check email is matching
 		if session.Email != refreshPayload.Email {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "incorrect session user"})
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage("incorrect session user"))
 			return
 		}
 
 		// check refreshToken is matching
 		if session.RefreshToken != req.RefreshToken {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "mismatched session token"})
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage("mismatched session token"))
 			return
 		}
 
 		// check expired token
 		if time.Now().After(session.ExpiresAt) {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "expired session"})
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage("expired session"))
 			return
 		}
 
 		// create new token
 		accessToken, accessTokenPayload, err := s.Token.CreateToken(
 			refreshPayload.Email,
+			refreshPayload.Role,
+			session.ID,
 			s.Config.AccessTokenDuration,
 		)
 
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, domain.Internal("cannot create access token", err))
 			return
 		}
 
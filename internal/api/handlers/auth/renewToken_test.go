@@ -17,6 +17,7 @@ import (
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,7 +28,7 @@ func addRefreshTokenHeader(
 	email string,
 	duration time.Duration,
 ) (string, *tokens.Payload) {
-	refreshToken, refreshTokenPayload, err := tokenMaker.CreateToken(email, duration)
+	refreshToken, refreshTokenPayload, err := tokenMaker.CreateRefreshToken(email, util.DepositorRole, uuid.New(), duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, refreshTokenPayload)
 
@@ -60,7 +61,7 @@ func TestPostRenewTokenUserHandler(t *testing.T) {
 			setupToken: func(t *testing.T, request *http.Request, tokenMaker tokens.Maker) {
 				refreshToken, refreshTokenPayload := addRefreshTokenHeader(t, request, tokenMaker, user.Email, durationMinute)
 				request.Header.Set(authorizationRefreshKey, refreshToken)
-				session.ID = refreshTokenPayload.ID
+				session.ID = refreshTokenPayload.SessionID
 				session.RefreshToken = refreshToken
 				session.UserAgent = request.UserAgent()
 				session.ClientIp = request.RemoteAddr
@@ -97,13 +98,29 @@ func TestPostRenewTokenUserHandler(t *testing.T) {
 			},
 		},
 
+		// TODO: 401 access token instead of a refresh token
+		{
+			name: "401 access token",
+			setupToken: func(t *testing.T, request *http.Request, tokenMaker tokens.Maker) {
+				accessToken, _, err := tokenMaker.CreateToken(user.Email, util.DepositorRole, uuid.New(), durationMinute)
+				require.NoError(t, err)
+				request.Header.Set(authorizationRefreshKey, accessToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, accessTokenDuration util.Config) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
 		// TODO: 404 not found data session
 		{
 			name: "404 not found data",
 			setupToken: func(t *testing.T, request *http.Request, tokenMaker tokens.Maker) {
 				refreshToken, refreshTokenPayload := addRefreshTokenHeader(t, request, tokenMaker, user.Email, durationMinute)
 				request.Header.Set(authorizationRefreshKey, refreshToken)
-				session.ID = refreshTokenPayload.ID
+				session.ID = refreshTokenPayload.SessionID
 				session.RefreshToken = refreshToken
 				session.UserAgent = request.UserAgent()
 				session.ClientIp = request.RemoteAddr
@@ -122,7 +139,7 @@ func TestPostRenewTokenUserHandler(t *testing.T) {
 			setupToken: func(t *testing.T, request *http.Request, tokenMaker tokens.Maker) {
 				refreshToken, refreshTokenPayload := addRefreshTokenHeader(t, request, tokenMaker, user.Email, durationMinute)
 				request.Header.Set(authorizationRefreshKey, refreshToken)
-				session.ID = refreshTokenPayload.ID
+				session.ID = refreshTokenPayload.SessionID
 				session.RefreshToken = refreshToken
 				session.UserAgent = request.UserAgent()
 				session.ClientIp = request.RemoteAddr
@@ -141,7 +158,7 @@ func TestPostRenewTokenUserHandler(t *testing.T) {
 			setupToken: func(t *testing.T, request *http.Request, tokenMaker tokens.Maker) {
 				refreshToken, refreshTokenPayload := addRefreshTokenHeader(t, request, tokenMaker, user.Email, durationMinute)
 				request.Header.Set(authorizationRefreshKey, refreshToken)
-				session.ID = refreshTokenPayload.ID
+				session.ID = refreshTokenPayload.SessionID
 				session.RefreshToken = refreshToken
 				session.UserAgent = request.UserAgent()
 				session.ClientIp = request.RemoteAddr
@@ -1,13 +1,10 @@
 package auth
 
 import (
-	"database/sql"
 	"net/http"
-	"time"
 
 	"github.com/claytten/golang-simplebank/internal/api"
-	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
-	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,47 +14,27 @@ func UpdateUserPasswordRoute(api *api.Server, userRg *gin.RouterGroup) {
 
 type UpdateUserPasswordRequest struct {
 	Username string `header:"username" binding:"required,alphanum"`
-	Password string `header:"password" binding:"required,min=6"`
+	Password string `header:"password" binding:"required"`
 }
 
 func UpdateUserPasswordHandler(s *api.Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req UpdateUserPasswordRequest
-		username := ctx.GetHeader(authorizationUsername)
+		username := ctx.MustGet(authorizationUsername).(string)
 
 		// checking username on header
 		if err := ctx.ShouldBindHeader(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		user, err := s.DB.GetUser(ctx, username)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Not Found"})
-			return
-		}
-
-		newPassword, err := util.HashingPassword(req.Password)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Password can't use. Please Try Another Password"})
-			return
-		}
-
-		updatedUser, err := s.DB.UpdateUser(ctx, db.UpdateUserParams{
-			Username: user.Username,
-			HashedPassword: sql.NullString{
-				String: newPassword,
-				Valid:  true,
-			},
-			PasswordChangedAt: sql.NullTime{
-				Time:  time.Now(),
-				Valid: true,
-			},
-			UpdatedAt: time.Now(),
+		updatedUser, err := s.Users.UpdatePassword(ctx, service.UpdatePasswordParams{
+			Username: username,
+			Password: req.Password,
+			Audit:    api.AuthUserAuditContext(ctx, username),
 		})
-
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Cannot Updated"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
@@ -2,13 +2,13 @@ package auth_test
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"reflect"
 	"testing"
 	"time"
 
@@ -19,49 +19,37 @@ import (
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
-type eqUpdatePassUserParamsMatcher struct {
-	arg      db.UpdateUserParams
+type eqUpdatePasswordTxParamsMatcher struct {
+	username string
 	password string
 }
 
-func (e *eqUpdatePassUserParamsMatcher) Matches(x interface{}) bool {
-	arg, ok := x.(db.UpdateUserParams)
+// Matches compares the user and the new password, the password history
+// has to be checked before the update
+func (e *eqUpdatePasswordTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.UpdatePasswordTxParams)
 	if !ok {
 		return false
 	}
 
-	err := util.ComparePassword(arg.HashedPassword.String, e.password)
+	err := util.ComparePassword(arg.HashedPassword, e.password)
 	if err != nil {
 		return false
 	}
 
-	argPassChangedAt := arg.PasswordChangedAt.Time.Round(time.Minute)
-	expected := e.arg.PasswordChangedAt.Time.Round(time.Minute)
-	if ok := argPassChangedAt.Equal(expected); !ok {
-		return false
-	}
-
-	argUpdatedAt := arg.UpdatedAt.Round(time.Minute)
-	expected = e.arg.UpdatedAt.Round(time.Minute)
-	if ok := argUpdatedAt.Equal(expected); !ok {
-		return false
-	}
-
-	e.arg.HashedPassword = arg.HashedPassword
-	e.arg.PasswordChangedAt = arg.PasswordChangedAt
-	e.arg.UpdatedAt = arg.UpdatedAt
-	return reflect.DeepEqual(e.arg, arg)
+	return arg.Username == e.username && arg.Audit.Actor == e.username && arg.BeforeUpdate != nil
 }
 
-func (e *eqUpdatePassUserParamsMatcher) String() string {
-	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
+func (e *eqUpdatePasswordTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches username %v and password %v", e.username, e.password)
 }
 
-func EqUpdatePassUserParams(arg db.UpdateUserParams, password string) gomock.Matcher {
-	return &eqUpdatePassUserParamsMatcher{arg, password}
+func EqUpdatePasswordTxParams(username, password string) gomock.Matcher {
+	return &eqUpdatePasswordTxParamsMatcher{username, password}
 }
 
 func TestUpdateUserPasswordHandler(t *testing.T) {
@@ -70,7 +58,7 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 	hashedNewPassword, err := util.HashingPassword(newPassword)
 	require.NoError(t, err)
 
-	oldUser, oldPassword := util.RandomUser(t)
+	oldUser, _ := util.RandomUser(t)
 
 	newUser := oldUser
 	newUser.HashedPassword = hashedNewPassword
@@ -87,24 +75,18 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 		{
 			name: "200 OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
 				request.Header.Set(authorizationUsername, oldUser.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword) //user plain password (oldpassword)
-				request.Header.Set(authorizationPassword, newPassword)    //user new password
+				request.Header.Set(authorizationPassword, newPassword) //user new password
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(oldUser.Email)).Return(oldUser, nil).AnyTimes()
-				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(oldUser.Username)).Return(oldUser, nil).AnyTimes()
-
-				args := db.UpdateUserParams{
-					Username: oldUser.Username,
-					PasswordChangedAt: sql.NullTime{
-						Time:  now,
-						Valid: true,
-					},
-					UpdatedAt: now,
+
+				result := db.UpdatePasswordTxResult{
+					User:     newUser,
+					Sessions: []db.Sessions{{ID: uuid.New(), Email: newUser.Email}},
 				}
-				store.EXPECT().UpdateUser(gomock.Any(), EqUpdatePassUserParams(args, newPassword)).Return(newUser, nil).Times(1)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), EqUpdatePasswordTxParams(oldUser.Username, newPassword)).Return(result, nil).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -122,20 +104,19 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 			},
 		},
 
-		//TODO: 401 old password doesn't match
+		//TODO: 403 access token instead of elevated token
 		{
-			name: "401 old password mismatch",
+			name: "403 not elevated token",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
 				request.Header.Set(authorizationUsername, oldUser.Username)
-				request.Header.Set(authorizationOldPassword, "notfoundoldPassword") //user plain password (oldpassword)
-				request.Header.Set(authorizationPassword, newPassword)              //user new password
+				request.Header.Set(authorizationPassword, newPassword) //user new password
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(oldUser.Email)).Return(db.Users{}, nil).AnyTimes()
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 
@@ -143,8 +124,8 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 		{
 			name: "400 missing header",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
-				request.Header.Set(authorizationUsername, oldUser.Username)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
+				request.Header.Set(authorizationPassword, newPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -156,9 +137,8 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 		{
 			name: "400 header handler mismatch",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
 				request.Header.Set(authorizationUsername, oldUser.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword) //user plain password (oldpassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(oldUser.Email)).Return(oldUser, nil).AnyTimes()
@@ -168,20 +148,19 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 			},
 		},
 
-		//TODO: 401 mismatch header username with payload token
+		//TODO: 403 mismatch header username with payload token
 		{
-			name: "401 mismatch header username",
+			name: "403 mismatch header username",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
 				request.Header.Set(authorizationUsername, "NewUserComing")
-				request.Header.Set(authorizationOldPassword, oldPassword) //user plain password (oldpassword)
-				request.Header.Set(authorizationPassword, newPassword)    //user new password
+				request.Header.Set(authorizationPassword, newPassword) //user new password
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(oldUser.Email)).Return(oldUser, nil).AnyTimes()
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 
@@ -189,21 +168,43 @@ func TestUpdateUserPasswordHandler(t *testing.T) {
 		{
 			name: "500 query error",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
 				request.Header.Set(authorizationUsername, oldUser.Username)
-				request.Header.Set(authorizationOldPassword, oldPassword) //user plain password (oldpassword)
-				request.Header.Set(authorizationPassword, newPassword)    //user new password
+				request.Header.Set(authorizationPassword, newPassword) //user new password
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(oldUser.Email)).Return(oldUser, nil).AnyTimes()
-				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(oldUser.Username)).Return(oldUser, nil).AnyTimes()
-
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Return(db.Users{}, sql.ErrConnDone).Times(1)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Return(db.UpdatePasswordTxResult{}, sql.ErrConnDone).Times(1)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+
+		// TODO: 400 password used recently
+		{
+			name: "400 password reused",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, oldUser.Email, time.Minute)
+				request.Header.Set(authorizationUsername, oldUser.Username)
+				request.Header.Set(authorizationPassword, newPassword) //user new password
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(oldUser.Email)).Return(oldUser, nil).AnyTimes()
+				store.EXPECT().
+					UpdatePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.UpdatePasswordTxParams) (db.UpdatePasswordTxResult, error) {
+						// the new password is the current one
+						err := arg.BeforeUpdate([]string{hashedNewPassword})
+						return db.UpdatePasswordTxResult{}, err
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "PASSWORD_REUSED")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,14 +1,11 @@
 package auth
 
 import (
-	"database/sql"
 	"net/http"
-	"time"
 
 	"github.com/claytten/golang-simplebank/internal/api"
-	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 const (
@@ -30,56 +27,28 @@ type UpdateUserProfileRequest struct {
 func UpdateUserProfileHandler(s *api.Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req UpdateUserProfileRequest
-		username := ctx.GetHeader(authorizationUsername)
+		username := ctx.MustGet(authorizationUsername).(string)
 
 		// binding update user profile
 		if err := ctx.ShouldBindJSON(&req); err != nil {
-			var errors []string
-			errs, ok := err.(validator.ValidationErrors)
-			if !ok {
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-				return
-			}
-			for _, e := range errs {
-				switch e.Tag() {
-				case "required":
-					errors = append(errors, e.Field()+" is required")
-				case "email":
-					errors = append(errors, "Password must be at least 6 characters long")
-				}
-			}
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": errors})
+			api.AbortWithProblem(ctx, api.BindingError(err))
 			return
 		}
 
-		user, err := s.DB.GetUser(ctx, username)
+		user, err := s.Users.Get(ctx, username)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Not Found"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
-		if req.FullName == "" {
-			req.FullName = user.FullName
-		}
-
-		if req.Email == "" {
-			req.Email = user.Email
-		}
 
-		updatedUser, err := s.DB.UpdateUser(ctx, db.UpdateUserParams{
-			Username: user.Username,
-			FullName: sql.NullString{
-				String: req.FullName,
-				Valid:  true,
-			},
-			Email: sql.NullString{
-				String: req.Email,
-				Valid:  true,
-			},
-			UpdatedAt: time.Now(),
+		updatedUser, err := s.Users.UpdateProfile(ctx, service.UpdateProfileParams{
+			User:     user,
+			FullName: req.FullName,
+			Email:    req.Email,
+			Audit:    api.AuthUserAuditContext(ctx, username),
 		})
-
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User Cannot Updated"})
+			api.AbortWithProblem(ctx, err)
 			return
 		}
 
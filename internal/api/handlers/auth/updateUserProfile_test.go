@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -58,9 +59,27 @@ func EqUpdateUserParams(arg db.UpdateUserParams) gomock.Matcher {
 	return &EqUpdateUserParamsMatcher{arg}
 }
 
+// expectAuditTx runs the transaction of an audited change on the mock store,
+// the change has to be recorded as action
+func expectAuditTx(store *mockdb.MockStore, action string) {
+	store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+			event, err := fn(store)
+			if err != nil {
+				return err
+			}
+			if event.Action != action {
+				return fmt.Errorf("audited %s instead of %s", event.Action, action)
+			}
+			return nil
+		})
+}
+
 func TestUpdateUserProfileHandler(t *testing.T) {
 	now := time.Now()
-	user, plainPassword := util.RandomUser(t)
+	user, _ := util.RandomUser(t)
 
 	newUser := user
 	newUser.FullName = util.RandomOwner()
@@ -86,9 +105,8 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 				"email":     newUser.Email,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, plainPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
@@ -106,6 +124,7 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 					},
 					UpdatedAt: now,
 				}
+				expectAuditTx(store, "user.update_profile")
 				store.EXPECT().UpdateUser(gomock.Any(), EqUpdateUserParams(arg)).
 					Times(1).Return(newUser, nil)
 			},
@@ -123,9 +142,8 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 				"email":     newUser1.Email,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, plainPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
@@ -143,6 +161,7 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 					},
 					UpdatedAt: now,
 				}
+				expectAuditTx(store, "user.update_profile")
 				store.EXPECT().UpdateUser(gomock.Any(), EqUpdateUserParams(arg)).
 					Times(1).Return(newUser1, nil)
 			},
@@ -181,9 +200,8 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 			name: "401 user params not match",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, "notfounded")
-				request.Header.Set(authorizationOldPassword, plainPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.Users{}, sql.ErrNoRows)
@@ -193,20 +211,19 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 			},
 		},
 
-		// TODO: 401 oldpassword doesn't match
+		// TODO: 403 access token instead of elevated token
 		{
-			name: "401 mismatch oldpassword",
+			name: "403 not elevated token",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, "notFounded")
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 
@@ -215,9 +232,8 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 			name: "400 badrequest body",
 			body: gin.H{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, plainPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
@@ -248,17 +264,17 @@ func TestUpdateUserProfileHandler(t *testing.T) {
 				"email":     newUser.Email,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
+				addElevatedAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, time.Minute)
 				request.Header.Set(authorizationUsername, user.Username)
-				request.Header.Set(authorizationOldPassword, plainPassword)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).AnyTimes()
 				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).AnyTimes()
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(1).Return(newUser, nil)
+				expectAuditTx(store, "user.update_profile")
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(1).Return(db.Users{}, sql.ErrConnDone)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
 	}
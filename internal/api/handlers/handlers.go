@@ -3,11 +3,36 @@ package handlers
 import (
 	"github.com/claytten/golang-simplebank/internal/api"
 	"github.com/claytten/golang-simplebank/internal/api/handlers/account"
+	"github.com/claytten/golang-simplebank/internal/api/handlers/admin"
 	"github.com/claytten/golang-simplebank/internal/api/handlers/auth"
 	"github.com/claytten/golang-simplebank/internal/api/middlewares"
+	"github.com/claytten/golang-simplebank/internal/authz"
+	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
+// permissions lists the routes limited to some roles,
+// every other route stays open to any authenticated user
+var permissions = authz.Permissions{
+	"GET /api/v1/admin/users":          {util.AdminRole},
+	"POST /api/v1/admin/freezeAccount": {util.AdminRole},
+	"GET /api/v1/admin/getAccount":     {util.AdminRole},
+}
+
+// scopes lists the routes an API key may access, with the scopes granting them
+var scopes = authz.Scopes{
+	"GET /api/v1/auth/getUser":         {authz.ScopeUsersRead},
+	"GET /api/v1/accounts/lists":       {authz.ScopeAccountsRead},
+	"GET /api/v1/accounts/getAccount":  {authz.ScopeAccountsRead},
+	"POST /api/v1/accounts/create":     {authz.ScopeAccountsWrite},
+	"PUT /api/v1/accounts/update":      {authz.ScopeAccountsWrite},
+	"DELETE /api/v1/accounts/delete":   {authz.ScopeAccountsWrite},
+	"POST /api/v1/accounts/transfer":   {authz.ScopeTransfersWrite},
+	"GET /api/v1/admin/users":          {authz.ScopeAdminRead},
+	"GET /api/v1/admin/getAccount":     {authz.ScopeAdminRead},
+	"POST /api/v1/admin/freezeAccount": {authz.ScopeAdminWrite},
+}
+
 type Handler struct {
 	api *api.Server
 	rg  *gin.RouterGroup
@@ -23,11 +48,13 @@ func (h *Handler) ApplyAllAuthRoutes() {
 		auth.PostLoginUserRoute(h.api, user)
 		auth.PostCreateUserRoute(h.api, user)
 		auth.PostRenewTokenUserRoute(h.api, user)
-		user.Use(middlewares.AuthMiddleware(h.api.Token))
+		user.Use(middlewares.AuthMiddleware(h.api.Token, h.api.Revocation, h.api.DB))
+		user.Use(middlewares.ScopeMiddleware(scopes))
 		// just middleware basic authentication
 		auth.GetUserRoute(h.api, user)
+		auth.PostReauthenticateRoute(h.api, user)
 
-		// adding middleware for checking username and old password
+		// adding middleware for checking username and elevated token
 		user.Use(middlewares.CheckOwnUserUpdate(h.api.DB))
 		auth.UpdateUserProfileRoute(h.api, user)
 		auth.UpdateUserPasswordRoute(h.api, user)
@@ -37,12 +64,13 @@ func (h *Handler) ApplyAllAuthRoutes() {
 func (h *Handler) ApplyAllAccountRoutes() {
 	accounts := h.rg.Group("accounts")
 	{
-		accounts.Use(middlewares.AuthMiddleware(h.api.Token))
+		accounts.Use(middlewares.AuthMiddleware(h.api.Token, h.api.Revocation, h.api.DB))
+		accounts.Use(middlewares.ScopeMiddleware(scopes))
 		// just middleware basic authentication
 		account.ListsAccountsRoute(h.api, accounts)
 		account.GetAccountRoute(h.api, accounts)
 
-		// adding middleware for checking username and old password
+		// adding middleware for checking username and elevated token
 		accounts.Use(middlewares.CheckOwnUserUpdate(h.api.DB))
 		account.PostCreateAccountRoute(h.api, accounts)
 		account.PutUpdateAccountRoute(h.api, accounts)
@@ -52,3 +80,15 @@ func (h *Handler) ApplyAllAccountRoutes() {
 		account.PostCreateTransferAccountRoute(h.api, accounts)
 	}
 }
+
+func (h *Handler) ApplyAllAdminRoutes() {
+	admins := h.rg.Group("admin")
+	{
+		admins.Use(middlewares.AuthMiddleware(h.api.Token, h.api.Revocation, h.api.DB))
+		admins.Use(middlewares.ScopeMiddleware(scopes))
+		admins.Use(middlewares.PermissionMiddleware(permissions))
+		admin.ListUsersRoute(h.api, admins)
+		admin.PostFreezeAccountRoute(h.api, admins)
+		admin.GetAccountRoute(h.api, admins)
+	}
+}
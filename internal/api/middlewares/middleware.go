@@ -1,54 +1,89 @@
 package middlewares
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 
+	"github.com/claytten/golang-simplebank/internal/api"
 	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/apikey"
+	"github.com/claytten/golang-simplebank/internal/authz"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
-	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/revocation"
 	"github.com/gin-gonic/gin"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
 	authorizationPassword   = "password"
 	authorizationUsername   = "username"
 )
 
-func AuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// AuthMiddleware accepts a bearer access token, or an API key that
+// ScopeMiddleware then limits to the routes its scopes grant
+func AuthMiddleware(tokenMaker token.Maker, revocationCache revocation.Cache, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, domain.ErrUnauthenticated.WithMessage("authorization header is not provided"))
 			return
 		}
 
 		//split string into slice
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, domain.ErrUnauthenticated.WithMessage("invalid authorization header format"))
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		switch authorizationType {
+		case authorizationTypeBearer:
+		case authorizationTypeAPIKey:
+			payload, err := apikey.Authenticate(ctx, store, fields[1])
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrExpiredKey) || errors.Is(err, apikey.ErrRevokedKey) {
+					api.AbortWithProblem(ctx, domain.ErrUnauthenticated.WithMessage(err.Error()).Wrap(err))
+					return
+				}
+				api.AbortWithProblem(ctx, domain.Internal("cannot check api key", err))
+				return
+			}
+			ctx.Set(authorizationPayloadKey, payload)
+			ctx.Next()
+			return
+		default:
+			api.AbortWithProblem(ctx, domain.ErrUnauthenticated.WithMessage("unsupported authorization type "+authorizationType))
 			return
 		}
 
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			api.AbortWithProblem(ctx, domain.ErrUnauthenticated.WithMessage(err.Error()).Wrap(err))
+			return
+		}
+
+		// a refresh token only renews the access token of its session
+		if payload.IsRefresh() {
+			api.AbortWithProblem(ctx, domain.ErrUnauthenticated.WithMessage(token.ErrInvalidToken.Error()))
+			return
+		}
+
+		// the token is still valid, but its session may have been blocked since
+		revoked, err := revocationCache.IsRevoked(ctx, payload.SessionID)
+		if err != nil {
+			api.AbortWithProblem(ctx, domain.Internal("cannot check access token revocation", err))
+			return
+		}
+
+		if revoked {
+			api.AbortWithProblem(ctx, domain.ErrSessionInvalid.WithMessage(token.ErrRevokedToken.Error()))
 			return
 		}
 		ctx.Set(authorizationPayloadKey, payload)
@@ -56,11 +91,49 @@ func AuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 	}
 }
 
+// PermissionMiddleware rejects requests to a restricted route from a role the
+// permission table doesn't allow, it has to run after AuthMiddleware
+func PermissionMiddleware(permissions authz.Permissions) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		if !permissions.IsAllowed(route, authPayload.Role) {
+			api.AbortWithProblem(ctx, domain.ErrPermissionDenied.
+				WithMessage(fmt.Sprintf("role %s is not allowed to access %s", authPayload.Role, route)))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ScopeMiddleware rejects requests made with an API key to a route none of
+// its scopes grant, it has to run after AuthMiddleware
+func ScopeMiddleware(scopes authz.Scopes) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !authPayload.IsAPIKey() {
+			ctx.Next()
+			return
+		}
+
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		if !scopes.IsGranted(route, authPayload.Scopes) {
+			api.AbortWithProblem(ctx, domain.ErrPermissionDenied.WithMessage("api key has no scope granting "+route))
+			return
+		}
+		ctx.Next()
+	}
+}
+
 type UpdateUserPasswordRequest struct {
-	Username    string `header:"username" binding:"required,alphanum"`
-	OldPassword string `header:"oldPassword" binding:"required,min=6"`
+	Username string `header:"username" binding:"required,alphanum"`
 }
 
+// CheckOwnUserUpdate only lets the authenticated user change its own data
+// with the elevated token issued by the reauthenticate route, or with an API
+// key ScopeMiddleware granted the route. It stores the username of the
+// authenticated user, the handlers act for it instead of the header
 func CheckOwnUserUpdate(db db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
@@ -68,31 +141,25 @@ func CheckOwnUserUpdate(db db.Store) gin.HandlerFunc {
 
 		// checking username on header
 		if err := ctx.ShouldBindHeader(&req); err != nil {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Some params header not provided"})
+			api.AbortWithProblem(ctx, api.BindingError(err))
+			return
+		}
+
+		if authPayload.Scope != token.ScopeSensitive && !authPayload.IsAPIKey() {
+			api.AbortWithProblem(ctx, domain.ErrElevationRequired.WithMessage("elevated token required, reauthenticate first"))
 			return
 		}
 
 		//finding user by email
 		userHeader, err := db.GetUserUsingEmail(ctx, authPayload.Email)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User Not Found and Not Authorized"})
-				return
-			}
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "account doesn't belong to authenticated user"})
+			api.AbortWithProblem(ctx, api.AuthenticatedUserError(err))
 			return
 		}
 
 		// checking if username is provided at header
 		if req.Username != userHeader.Username {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User Status Unauthorized"})
-			return
-		}
-
-		// checking if user typing old password and new password is same
-		err = util.ComparePassword(userHeader.HashedPassword, req.OldPassword)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "new password / old password doesn't match"})
+			api.AbortWithProblem(ctx, domain.ErrNotOwnUser)
 			return
 		}
 
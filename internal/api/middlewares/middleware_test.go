@@ -1,6 +1,7 @@
 package middlewares_test
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,7 +11,15 @@ import (
 	"github.com/claytten/golang-simplebank/internal/api"
 	"github.com/claytten/golang-simplebank/internal/api/middlewares"
 	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/apikey"
+	"github.com/claytten/golang-simplebank/internal/authz"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,27 +37,29 @@ func addAuthorization(
 	tokenMaker token.Maker,
 	authorizationType string,
 	email string,
+	role string,
 	duration time.Duration,
-) {
-	token, payload, err := tokenMaker.CreateToken(email, duration)
+) *token.Payload {
+	token, payload, err := tokenMaker.CreateToken(email, role, uuid.New(), duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+	return payload
 }
 
 func TestAuthMiddleware(t *testing.T) {
 	tests := []struct {
 		name          string
-		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		// TODO: Checking OK
 		{
 			name: "200 OK",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", time.Minute)
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -58,7 +69,7 @@ func TestAuthMiddleware(t *testing.T) {
 		// TODO: Checking NoAuthorization
 		{
 			name:      "401 Unauthorized",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
@@ -67,8 +78,8 @@ func TestAuthMiddleware(t *testing.T) {
 		// TODO: Checking Unsupported Authorization
 		{
 			name: "401 Unsupported",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "unsupported", "testing@email.com", time.Minute)
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {
+				addAuthorization(t, request, tokenMaker, "unsupported", "testing@email.com", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -78,8 +89,34 @@ func TestAuthMiddleware(t *testing.T) {
 		// TODO: Checking Invalid Authorization (empty bearer)
 		{
 			name: "401 Invalid",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "", "testing@email.com", time.Minute)
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {
+				addAuthorization(t, request, tokenMaker, "", "testing@email.com", util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Revoked Session
+		{
+			name: "401 Revoked",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {
+				payload := addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", util.DepositorRole, time.Minute)
+				err := revocationCache.Revoke(request.Context(), payload.SessionID, time.Minute)
+				require.NoError(t, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Refresh Token used after logout, once its revocation expired
+		{
+			name: "401 Refresh",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {
+				refreshToken, _, err := tokenMaker.CreateRefreshToken("testing@email.com", util.DepositorRole, uuid.New(), time.Hour)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, refreshToken))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -89,8 +126,8 @@ func TestAuthMiddleware(t *testing.T) {
 		// TODO: Checking Expired Token
 		{
 			name: "401 Expired",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", -time.Minute)
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocationCache revocation.Cache) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", util.DepositorRole, -time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -104,7 +141,7 @@ func TestAuthMiddleware(t *testing.T) {
 			server.Engine = gin.New()
 			server.Engine.GET(
 				authPath,
-				middlewares.AuthMiddleware(server.Token),
+				middlewares.AuthMiddleware(server.Token, server.Revocation, server.DB),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -114,6 +151,240 @@ func TestAuthMiddleware(t *testing.T) {
 			request, err := http.NewRequest(http.MethodGet, authPath, nil)
 			require.NoError(t, err)
 
+			tt.setupAuth(t, request, server.Token, server.Revocation)
+			server.Engine.ServeHTTP(recorder, request)
+			tt.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	adminPath := "/api/v1/admin"
+	permissions := authz.Permissions{
+		http.MethodGet + " " + adminPath: {util.AdminRole},
+	}
+
+	tests := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		// TODO: Checking OK
+		{
+			name: "200 OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", util.AdminRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Role Not Allowed
+		{
+			name: "403 Forbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "testing@email.com", util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		// TODO: Checking NoAuthorization
+		{
+			name:      "401 Unauthorized",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := api.NewTestServer(t, nil)
+			server.Engine = gin.New()
+			server.Engine.GET(
+				adminPath,
+				middlewares.AuthMiddleware(server.Token, server.Revocation, server.DB),
+				middlewares.PermissionMiddleware(permissions),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, adminPath, nil)
+			require.NoError(t, err)
+
+			tt.setupAuth(t, request, server.Token)
+			server.Engine.ServeHTTP(recorder, request)
+			tt.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomAPIKey(t *testing.T, owner db.Users, scopes ...string) (apikey.Key, db.ApiKeys) {
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKeys{
+		ID:           util.RandomInt(1, 1000),
+		Prefix:       key.Prefix,
+		HashedSecret: apikey.HashSecret(key.Secret),
+		Name:         util.RandomOwner(),
+		Owner:        owner.Username,
+		Scopes:       scopes,
+		CreatedAt:    time.Now(),
+	}
+	return key, apiKey
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	key, apiKey := randomAPIKey(t, user, authz.ScopeAccountsRead)
+
+	accountPath := "/api/v1/accounts/getAccount"
+	transferPath := "/api/v1/accounts/transfer"
+	scopes := authz.Scopes{
+		http.MethodGet + " " + accountPath:   {authz.ScopeAccountsRead},
+		http.MethodPost + " " + transferPath: {authz.ScopeTransfersWrite},
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		// TODO: Checking OK
+		{
+			name:   "200 OK",
+			method: http.MethodGet,
+			path:   accountPath,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Scope Not Granted
+		{
+			name:   "403 Forbidden",
+			method: http.MethodPost,
+			path:   transferPath,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Bearer Token Ignores Scopes
+		{
+			name:   "200 Bearer",
+			method: http.MethodPost,
+			path:   transferPath,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Email, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Unknown Key
+		{
+			name:   "401 Unknown",
+			method: http.MethodGet,
+			path:   accountPath,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(db.ApiKeys{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Expired Key
+		{
+			name:   "401 Expired",
+			method: http.MethodGet,
+			path:   accountPath,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(expired, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		// TODO: Checking Database Down
+		{
+			name:   "500 Internal",
+			method: http.MethodGet,
+			path:   accountPath,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(db.ApiKeys{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			server := api.NewTestServer(t, store)
+			server.Engine = gin.New()
+			server.Engine.Handle(
+				tt.method,
+				tt.path,
+				middlewares.AuthMiddleware(server.Token, server.Revocation, server.DB),
+				middlewares.ScopeMiddleware(scopes),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(tt.method, tt.path, nil)
+			require.NoError(t, err)
+
 			tt.setupAuth(t, request, server.Token)
 			server.Engine.ServeHTTP(recorder, request)
 			tt.checkResponse(t, recorder)
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of the problem details of RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Problem is the body of every error response. Reason, Metadata and Errors carry the
+// ErrorInfo and the field violations the gRPC API returns for the same error.
+type Problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail"`
+	Instance string                  `json:"instance,omitempty"`
+	Reason   string                  `json:"reason"`
+	Metadata map[string]string       `json:"metadata,omitempty"`
+	Errors   []domain.FieldViolation `json:"errors,omitempty"`
+}
+
+// NewProblem describes err, an error that is not a domain error is internal.
+// An internal error only tells it is one.
+func NewProblem(err error, instance string) Problem {
+	domainErr := domain.From(err)
+	status := domainErr.Kind.HTTPStatus()
+
+	detail := domainErr.Message
+	if domainErr.Kind == domain.KindInternal {
+		detail = domain.ErrInternal.Message
+	}
+
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+		Reason:   domainErr.Reason,
+		Metadata: domainErr.Metadata,
+		Errors:   domainErr.Violations,
+	}
+}
+
+// AbortWithProblem stops the request and answers err as problem details.
+// err is kept in the errors of the context, the logger prints its cause.
+func AbortWithProblem(ctx *gin.Context, err error) {
+	ctx.Error(err)
+
+	problem := NewProblem(err, ctx.Request.URL.Path)
+	if retryAfter := domain.From(err).RetryAfter; retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+
+	data, merr := json.Marshal(problem)
+	if merr != nil {
+		ctx.AbortWithStatus(problem.Status)
+		return
+	}
+
+	ctx.Abort()
+	ctx.Data(problem.Status, ProblemContentType, data)
+}
+
+// BindingError is the error of a request gin cannot bind, a field failing
+// its validation is told as a field violation
+func BindingError(err error) *domain.Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return domain.ErrInvalidArgument.WithMessage(err.Error()).Wrap(err)
+	}
+
+	violations := make([]domain.FieldViolation, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		violations = append(violations, domain.FieldViolation{
+			Field:       fieldErr.Field(),
+			Description: fmt.Sprintf("failed on the %s rule", fieldErr.Tag()),
+		})
+	}
+	return domain.InvalidArgument(violations...).Wrap(err)
+}
+
+// AuthenticatedUserError is the error of looking up the user of a valid token,
+// a user missing since the token was issued is no longer authenticated
+func AuthenticatedUserError(err error) *domain.Error {
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return domain.ErrUnauthenticated.WithMessage("authenticated user no longer exists").Wrap(err)
+	}
+	return domain.Internal("cannot find authenticated user", err)
+}
@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/api"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type transferRequest struct {
+	Amount int64 `json:"amount" binding:"required,gt=0"`
+}
+
+func TestAbortWithProblem(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler gin.HandlerFunc
+		check   func(t *testing.T, recorder *httptest.ResponseRecorder, problem api.Problem)
+	}{
+		// TODO: domain error
+		{
+			name: "NotFound",
+			handler: func(ctx *gin.Context) {
+				api.AbortWithProblem(ctx, domain.ErrAccountNotFound.WithMetadata("account_id", "7"))
+			},
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder, problem api.Problem) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, http.StatusNotFound, problem.Status)
+				require.Equal(t, "Not Found", problem.Title)
+				require.Equal(t, "account not found", problem.Detail)
+				require.Equal(t, "ACCOUNT_NOT_FOUND", problem.Reason)
+				require.Equal(t, "/problem", problem.Instance)
+				require.Equal(t, map[string]string{"account_id": "7"}, problem.Metadata)
+			},
+		},
+		// TODO: internal error hides its cause
+		{
+			name: "Internal",
+			handler: func(ctx *gin.Context) {
+				api.AbortWithProblem(ctx, domain.Internal("cannot find account", sql.ErrConnDone))
+			},
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder, problem api.Problem) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, "internal error", problem.Detail)
+				require.Equal(t, "INTERNAL", problem.Reason)
+				require.NotContains(t, recorder.Body.String(), "sql:")
+			},
+		},
+		// TODO: error that is not a domain error
+		{
+			name: "Plain",
+			handler: func(ctx *gin.Context) {
+				api.AbortWithProblem(ctx, sql.ErrConnDone)
+			},
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder, problem api.Problem) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, "internal error", problem.Detail)
+				require.Equal(t, "INTERNAL", problem.Reason)
+			},
+		},
+		// TODO: field violations
+		{
+			name: "BindingError",
+			handler: func(ctx *gin.Context) {
+				var req transferRequest
+				err := ctx.ShouldBindJSON(&req)
+				require.Error(t, err)
+				api.AbortWithProblem(ctx, api.BindingError(err))
+			},
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder, problem api.Problem) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, "INVALID_ARGUMENT", problem.Reason)
+				require.Equal(t, []domain.FieldViolation{
+					{Field: "Amount", Description: "failed on the gt rule"},
+				}, problem.Errors)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/problem", tc.handler, func(ctx *gin.Context) {
+				require.FailNow(t, "handler called after abort")
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/problem", bytes.NewReader([]byte(`{"amount":-1}`)))
+			require.NoError(t, err)
+			router.ServeHTTP(recorder, request)
+
+			require.Equal(t, api.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+			var problem api.Problem
+			err = json.Unmarshal(recorder.Body.Bytes(), &problem)
+			require.NoError(t, err)
+			require.Equal(t, "about:blank", problem.Type)
+			tc.check(t, recorder, problem)
+		})
+	}
+}
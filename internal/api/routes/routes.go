@@ -6,11 +6,17 @@ import (
 	"github.com/claytten/golang-simplebank/internal/api/handlers"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/rs/zerolog/log"
 )
 
 func ApplyAllPublicRoutes(s *api.Server) {
 	s.Engine = gin.Default()
 
+	// X-Forwarded-For is only read on the requests of a trusted proxy
+	if err := s.Engine.SetTrustedProxies(s.Config.TrustedProxies); err != nil {
+		log.Fatal().Err(err).Msg("cannot set trusted proxies")
+	}
+
 	rg := s.Engine.Group("/api")
 
 	rg1 := rg.Group("/v1")
@@ -22,4 +28,5 @@ func ApplyAllPublicRoutes(s *api.Server) {
 
 	handlers.ApplyAllAuthRoutes()
 	handlers.ApplyAllAccountRoutes()
+	handlers.ApplyAllAdminRoutes()
 }
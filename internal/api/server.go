@@ -7,30 +7,42 @@ import (
 
 	"github.com/claytten/golang-simplebank/internal/api/token"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
 
 type Server struct {
-	Engine *gin.Engine
-	DB     db.Store
-	Config util.Config
-	Token  token.Maker
+	Engine     *gin.Engine
+	DB         db.Store
+	Config     util.Config
+	Token      token.Maker
+	Revocation revocation.Cache
+
+	Accounts  *service.AccountService
+	Transfers *service.TransferService
+	Users     *service.UserService
 }
 
-func SetupServer(config util.Config, store db.Store) (*Server, error) {
-	// can change to JWT or Paseto maker for token
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func SetupServer(config util.Config, store db.Store, revocationCache revocation.Cache, loginGuard *loginguard.Guard) (*Server, error) {
+	// the token maker is selected with TOKEN_TYPE
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
 	server := &Server{
-		DB:     store,
-		Config: config,
-		Engine: nil,
-		Token:  tokenMaker,
+		DB:         store,
+		Config:     config,
+		Engine:     nil,
+		Token:      tokenMaker,
+		Revocation: revocationCache,
+		Accounts:   service.NewAccountService(store),
+		Transfers:  service.NewTransferService(store),
+		Users:      service.NewUserService(config, store, tokenMaker, revocationCache, loginGuard),
 	}
 
 	return server, nil
@@ -39,11 +51,17 @@ func SetupServer(config util.Config, store db.Store) (*Server, error) {
 // for testing purpose
 func NewTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		ElevatedTokenDuration: time.Minute,
+		PasswordMinLength:     6,
+		PasswordMaxLength:     100,
 	}
 
-	server, err := SetupServer(config, store)
+	// failed logins are not throttled
+	loginGuard := loginguard.NewGuard(loginguard.NewMemoryStore(), loginguard.Policy{}, loginguard.Policy{})
+
+	server, err := SetupServer(config, store, revocation.NewMemoryCache(), loginGuard)
 	require.NoError(t, err)
 
 	return server
@@ -0,0 +1,105 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// KeyringMaker is a Maker signing with the public keys of a keyring,
+// other services verify its tokens with the keys published as JWKS
+type KeyringMaker interface {
+	Maker
+	Keyring() *Keyring
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the verification keys of the maker, a maker signing
+// with a symmetric key has none to publish
+func PublicJWKS(maker Maker) JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	keyringMaker, ok := maker.(KeyringMaker)
+	if !ok {
+		return jwks
+	}
+
+	for _, key := range keyringMaker.Keyring().Keys() {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+	return jwks
+}
+
+// JWK returns the public part of the key
+func (key *Key) JWK() JWK {
+	jwk := JWK{
+		KeyID:     key.ID,
+		Use:       "sig",
+		Algorithm: key.Algorithm,
+	}
+
+	switch publicKey := key.PublicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	}
+
+	return jwk
+}
+
+// Key parses the public key, so a JWKS published by another party can verify its tokens
+func (jwk JWK) Key() (*Key, error) {
+	switch jwk.KeyType {
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s for key %s", jwk.Curve, jwk.KeyID)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %s", jwk.KeyID)
+		}
+		return NewKey(jwk.KeyID, ed25519.PublicKey(x))
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus for key %s", jwk.KeyID)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent for key %s", jwk.KeyID)
+		}
+
+		publicKey := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return NewKey(jwk.KeyID, publicKey)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s for key %s", jwk.KeyType, jwk.KeyID)
+	}
+}
@@ -0,0 +1,80 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	tokens "github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicJWKS(t *testing.T) {
+	edKey := newEd25519Key(t, "ed25519")
+	rsaKey := newRSAKey(t, "rsa")
+
+	keyring, err := tokens.NewKeyring("ed25519", edKey, rsaKey)
+	require.NoError(t, err)
+
+	maker, err := tokens.NewJWTPublicMaker(keyring)
+	require.NoError(t, err)
+
+	jwks := tokens.PublicJWKS(maker)
+	require.Len(t, jwks.Keys, 2)
+
+	edJWK := jwks.Keys[0]
+	require.Equal(t, "OKP", edJWK.KeyType)
+	require.Equal(t, "Ed25519", edJWK.Curve)
+	require.Equal(t, "ed25519", edJWK.KeyID)
+	require.Equal(t, tokens.AlgorithmEdDSA, edJWK.Algorithm)
+	require.Equal(t, "sig", edJWK.Use)
+
+	x, err := base64.RawURLEncoding.DecodeString(edJWK.X)
+	require.NoError(t, err)
+	require.Equal(t, edKey.PublicKey, ed25519.PublicKey(x))
+
+	rsaJWK := jwks.Keys[1]
+	require.Equal(t, "RSA", rsaJWK.KeyType)
+	require.Equal(t, "rsa", rsaJWK.KeyID)
+	require.Equal(t, tokens.AlgorithmRS256, rsaJWK.Algorithm)
+
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	require.NoError(t, err)
+
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	require.Equal(t, rsaKey.PublicKey, publicKey)
+}
+
+func TestPublicJWKSSymmetric(t *testing.T) {
+	maker, err := tokens.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	jwks := tokens.PublicJWKS(maker)
+	require.NotNil(t, jwks.Keys)
+	require.Empty(t, jwks.Keys)
+}
+
+func TestJWKKey(t *testing.T) {
+	edKey := newEd25519Key(t, "ed25519")
+	rsaKey := newRSAKey(t, "rsa")
+
+	for _, key := range []*tokens.Key{edKey, rsaKey} {
+		parsed, err := key.JWK().Key()
+		require.NoError(t, err)
+		require.Equal(t, key.ID, parsed.ID)
+		require.Equal(t, key.Algorithm, parsed.Algorithm)
+		require.Equal(t, key.PublicKey, parsed.PublicKey)
+		require.Nil(t, parsed.PrivateKey)
+	}
+
+	_, err := tokens.JWK{KeyType: "EC", KeyID: "ec"}.Key()
+	require.Error(t, err)
+
+	_, err = tokens.JWK{KeyType: "OKP", KeyID: "short", Curve: "Ed25519", X: "AAAA"}.Key()
+	require.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 )
 
 const minSecretKeySize = 12
@@ -22,17 +23,36 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 	return &JWTMaker{secretKey: secretKey}, nil
 }
 
-func (maker *JWTMaker) CreateToken(email string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(email, duration)
+func (maker *JWTMaker) CreateToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateScopedToken(email, role, sessionID, "", duration)
+}
+
+func (maker *JWTMaker) CreateScopedToken(email string, role string, sessionID uuid.UUID, scope string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(email, role, sessionID, duration)
 	if err != nil {
 		return "", payload, err
 	}
+	payload.Scope = scope
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *JWTMaker) CreateRefreshToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewRefreshPayload(email, role, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.sign(payload)
 	return token, payload, err
 }
 
+func (maker *JWTMaker) sign(payload *Payload) (string, error) {
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	return jwtToken.SignedString([]byte(maker.secretKey))
+}
+
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	Keyfunc := func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
@@ -0,0 +1,153 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const jwtKeyIDHeader = "kid"
+
+// signingMethodEdDSA signs JWTs with Ed25519, jwt-go only ships RSA, ECDSA and HMAC
+type signingMethodEdDSA struct{}
+
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return AlgorithmEdDSA
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+// JWTPublicMaker is a JWT maker signing with the active key of the keyring,
+// RS256 or EdDSA depending on the key, with its id in the kid header
+type JWTPublicMaker struct {
+	keyring *Keyring
+}
+
+func NewJWTPublicMaker(keyring *Keyring) (Maker, error) {
+	if signingMethod(keyring.ActiveKey().Algorithm) == nil {
+		return nil, fmt.Errorf("invalid key: unsupported algorithm %s", keyring.ActiveKey().Algorithm)
+	}
+
+	return &JWTPublicMaker{keyring: keyring}, nil
+}
+
+func (maker *JWTPublicMaker) Keyring() *Keyring {
+	return maker.keyring
+}
+
+func (maker *JWTPublicMaker) CreateToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateScopedToken(email, role, sessionID, "", duration)
+}
+
+func (maker *JWTPublicMaker) CreateScopedToken(email string, role string, sessionID uuid.UUID, scope string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(email, role, sessionID, duration)
+	if err != nil {
+		return "", payload, err
+	}
+	payload.Scope = scope
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *JWTPublicMaker) CreateRefreshToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewRefreshPayload(email, role, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *JWTPublicMaker) sign(payload *Payload) (string, error) {
+	key := maker.keyring.ActiveKey()
+	jwtToken := jwt.NewWithClaims(signingMethod(key.Algorithm), payload)
+	jwtToken.Header[jwtKeyIDHeader] = key.ID
+
+	return jwtToken.SignedString(key.PrivateKey)
+}
+
+func (maker *JWTPublicMaker) VerifyToken(token string) (*Payload, error) {
+	Keyfunc := func(token *jwt.Token) (interface{}, error) {
+		keyID, ok := token.Header[jwtKeyIDHeader].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		key, err := maker.keyring.Key(keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		// the algorithm comes from the key, never from the token
+		if token.Method.Alg() != key.Algorithm {
+			return nil, ErrInvalidToken
+		}
+
+		return key.PublicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, Keyfunc)
+
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case AlgorithmEdDSA:
+		return SigningMethodEdDSA
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	tokens "github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTPublicMaker(t *testing.T) {
+	for _, key := range []*tokens.Key{newEd25519Key(t, "ed25519"), newRSAKey(t, "rsa")} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			keyring, err := tokens.NewKeyring(key.ID, key)
+			require.NoError(t, err)
+
+			maker, err := tokens.NewJWTPublicMaker(keyring)
+			require.NoError(t, err)
+
+			email := util.RandomEmail()
+			sessionID := uuid.New()
+
+			token, tokenPayload, err := maker.CreateToken(email, util.AdminRole, sessionID, time.Minute)
+			require.NoError(t, err)
+
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token, &tokens.Payload{})
+			require.NoError(t, err)
+			require.Equal(t, key.Algorithm, parsed.Method.Alg())
+			require.Equal(t, key.ID, parsed.Header["kid"])
+
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, tokenPayload.ID, payload.ID)
+			require.Equal(t, sessionID, payload.SessionID)
+			require.Equal(t, email, payload.Email)
+			require.Equal(t, util.AdminRole, payload.Role)
+
+			expiredToken, _, err := maker.CreateToken(email, util.AdminRole, sessionID, -time.Minute)
+			require.NoError(t, err)
+
+			payload, err = maker.VerifyToken(expiredToken)
+			require.EqualError(t, err, tokens.ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestRotateJWTPublicMaker(t *testing.T) {
+	oldKey := newRSAKey(t, "old")
+	newKey := newEd25519Key(t, "new")
+
+	oldKeyring, err := tokens.NewKeyring("old", oldKey)
+	require.NoError(t, err)
+	oldMaker, err := tokens.NewJWTPublicMaker(oldKeyring)
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	rotatedKeyring, err := tokens.NewKeyring("new", oldKey, newKey)
+	require.NoError(t, err)
+	rotatedMaker, err := tokens.NewJWTPublicMaker(rotatedKeyring)
+	require.NoError(t, err)
+
+	_, err = rotatedMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	retiredKeyring, err := tokens.NewKeyring("new", newKey)
+	require.NoError(t, err)
+	retiredMaker, err := tokens.NewJWTPublicMaker(retiredKeyring)
+	require.NoError(t, err)
+
+	_, err = retiredMaker.VerifyToken(oldToken)
+	require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+}
+
+// the algorithm of the token must match its key
+func TestJWTPublicMaker_InvalidAlgo(t *testing.T) {
+	key := newEd25519Key(t, "ed25519")
+	keyring, err := tokens.NewKeyring(key.ID, key)
+	require.NoError(t, err)
+
+	maker, err := tokens.NewJWTPublicMaker(keyring)
+	require.NoError(t, err)
+
+	payload, err := tokens.NewPayload(util.RandomEmail(), util.AdminRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
+	noneToken.Header["kid"] = key.ID
+	token, err := noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+
+	// HMAC keyed with the public key
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	hmacToken.Header["kid"] = key.ID
+	token, err = hmacToken.SignedString([]byte(key.PublicKey.(ed25519.PublicKey)))
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+
+	// no kid header
+	token, _, err = maker.CreateToken(util.RandomEmail(), util.AdminRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &tokens.Payload{})
+	require.NoError(t, err)
+	delete(parsed.Header, "kid")
+	token, err = parsed.SignedString(key.PrivateKey)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+}
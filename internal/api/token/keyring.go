@@ -0,0 +1,186 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+
+	privateKeyExt = ".pem"
+	publicKeyExt  = ".pub.pem"
+)
+
+var ErrUnknownKey = errors.New("token key is unknown or retired")
+
+// Key is one signing key of a Keyring, PrivateKey is nil for a key
+// that is only kept to verify the tokens it signed before
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+func NewKey(id string, key interface{}) (*Key, error) {
+	k := &Key{ID: id}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		k.Algorithm, k.PrivateKey, k.PublicKey = AlgorithmEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Algorithm, k.PublicKey = AlgorithmEdDSA, key
+	case *rsa.PrivateKey:
+		k.Algorithm, k.PrivateKey, k.PublicKey = AlgorithmRS256, key, key.Public()
+	case *rsa.PublicKey:
+		k.Algorithm, k.PublicKey = AlgorithmRS256, key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T for key %s", key, id)
+	}
+
+	return k, nil
+}
+
+// Keyring signs with its active key and verifies with any key it holds.
+// Keys are rotated by adding the new key next to the active one, making it
+// active once every verifier has it, and retiring the old key after the
+// last token it signed has expired.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyring(activeKeyID string, keys ...*Key) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]*Key)}
+
+	for _, key := range keys {
+		if _, ok := keyring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %s", key.ID)
+		}
+		keyring.keys[key.ID] = key
+	}
+
+	active, ok := keyring.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %s not found", activeKeyID)
+	}
+
+	if active.PrivateKey == nil {
+		return nil, fmt.Errorf("active key %s has no private key", activeKeyID)
+	}
+
+	keyring.active = active
+	return keyring, nil
+}
+
+// LoadKeyring reads every key of dir, <id>.pem holds a PKCS #8 private key
+// and <id>.pub.pem a PKIX public key, a key with both files is loaded from its
+// private key. The retired keys are not loaded.
+func LoadKeyring(dir, activeKeyID string, retiredKeyIDs []string) (*Keyring, error) {
+	retired := make(map[string]bool)
+	for _, id := range retiredKeyIDs {
+		retired[strings.TrimSpace(id)] = true
+	}
+
+	if retired[activeKeyID] {
+		return nil, fmt.Errorf("active key %s is retired", activeKeyID)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+privateKeyExt))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		names[filepath.Base(file)] = true
+	}
+
+	var keys []*Key
+	for _, file := range files {
+		name := filepath.Base(file)
+		id := strings.TrimSuffix(strings.TrimSuffix(name, publicKeyExt), privateKeyExt)
+		if retired[id] {
+			continue
+		}
+
+		// the public key of a private key is derived from it, only a key
+		// whose private key is gone is loaded from <id>.pub.pem
+		if strings.HasSuffix(name, publicKeyExt) && names[id+privateKeyExt] {
+			continue
+		}
+
+		key, err := readKey(id, file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(activeKeyID, keys...)
+}
+
+func readKey(id, file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key %s: %w", id, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", id)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse key %s: %w", id, err)
+	}
+
+	return NewKey(id, key)
+}
+
+// ActiveKey returns the key new tokens are signed with
+func (keyring *Keyring) ActiveKey() *Key {
+	return keyring.active
+}
+
+// Key returns the key that signed a token
+func (keyring *Keyring) Key(id string) (*Key, error) {
+	key, ok := keyring.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// Keys returns every key that can verify a token, sorted by id
+func (keyring *Keyring) Keys() []*Key {
+	keys := make([]*Key, 0, len(keyring.keys))
+	for _, key := range keyring.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
@@ -0,0 +1,190 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tokens "github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newEd25519Key(t *testing.T, id string) *tokens.Key {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key, err := tokens.NewKey(id, privateKey)
+	require.NoError(t, err)
+	return key
+}
+
+func newRSAKey(t *testing.T, id string) *tokens.Key {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := tokens.NewKey(id, privateKey)
+	require.NoError(t, err)
+	return key
+}
+
+func writePrivateKey(t *testing.T, dir string, key *tokens.Key) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	err = os.WriteFile(filepath.Join(dir, key.ID+".pem"), data, 0600)
+	require.NoError(t, err)
+}
+
+func writePublicKey(t *testing.T, dir string, key *tokens.Key) {
+	der, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	err = os.WriteFile(filepath.Join(dir, key.ID+".pub.pem"), data, 0600)
+	require.NoError(t, err)
+}
+
+func TestNewKeyring(t *testing.T) {
+	oldKey := newEd25519Key(t, "old")
+	newKey := newEd25519Key(t, "new")
+
+	keyring, err := tokens.NewKeyring("new", oldKey, newKey)
+	require.NoError(t, err)
+	require.Equal(t, newKey, keyring.ActiveKey())
+	require.Equal(t, []*tokens.Key{newKey, oldKey}, keyring.Keys())
+
+	key, err := keyring.Key("old")
+	require.NoError(t, err)
+	require.Equal(t, oldKey, key)
+
+	_, err = keyring.Key("unknown")
+	require.ErrorIs(t, err, tokens.ErrUnknownKey)
+
+	// unknown active key
+	_, err = tokens.NewKeyring("unknown", oldKey, newKey)
+	require.Error(t, err)
+
+	// duplicate key id
+	_, err = tokens.NewKeyring("old", oldKey, oldKey)
+	require.Error(t, err)
+
+	// active key without private key
+	publicKey, err := tokens.NewKey("public", oldKey.PublicKey)
+	require.NoError(t, err)
+	_, err = tokens.NewKeyring("public", publicKey)
+	require.Error(t, err)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	retiredKey := newEd25519Key(t, "2023-01")
+	oldKey := newEd25519Key(t, "2023-02")
+	activeKey := newEd25519Key(t, "2023-03")
+	rsaKey := newRSAKey(t, "rsa")
+
+	writePrivateKey(t, dir, retiredKey)
+	writePublicKey(t, dir, oldKey)
+	writePrivateKey(t, dir, activeKey)
+	writePublicKey(t, dir, activeKey)
+	writePrivateKey(t, dir, rsaKey)
+
+	keyring, err := tokens.LoadKeyring(dir, "2023-03", []string{"2023-01"})
+	require.NoError(t, err)
+	require.Equal(t, "2023-03", keyring.ActiveKey().ID)
+	require.Equal(t, tokens.AlgorithmEdDSA, keyring.ActiveKey().Algorithm)
+	require.NotNil(t, keyring.ActiveKey().PrivateKey)
+	require.Len(t, keyring.Keys(), 3)
+
+	key, err := keyring.Key("2023-02")
+	require.NoError(t, err)
+	require.Nil(t, key.PrivateKey)
+	require.Equal(t, oldKey.PublicKey, key.PublicKey)
+
+	key, err = keyring.Key("rsa")
+	require.NoError(t, err)
+	require.Equal(t, tokens.AlgorithmRS256, key.Algorithm)
+
+	_, err = keyring.Key("2023-01")
+	require.ErrorIs(t, err, tokens.ErrUnknownKey)
+
+	// the active key can't be retired
+	_, err = tokens.LoadKeyring(dir, "2023-03", []string{"2023-03"})
+	require.Error(t, err)
+
+	// the active key needs its private key
+	_, err = tokens.LoadKeyring(dir, "2023-02", nil)
+	require.Error(t, err)
+}
+
+func TestNewMaker(t *testing.T) {
+	dir := t.TempDir()
+	writePrivateKey(t, dir, newEd25519Key(t, "ed25519"))
+	writePrivateKey(t, dir, newRSAKey(t, "rsa"))
+
+	tests := []struct {
+		name   string
+		config util.Config
+		ok     bool
+	}{
+		{
+			name:   "default paseto",
+			config: util.Config{TokenSymmetricKey: util.RandomString(32)},
+			ok:     true,
+		},
+		{
+			name:   "jwt",
+			config: util.Config{TokenType: tokens.TypeJWT, TokenSymmetricKey: util.RandomString(32)},
+			ok:     true,
+		},
+		{
+			name:   "paseto public",
+			config: util.Config{TokenType: tokens.TypePasetoPublic, TokenKeyDir: dir, TokenActiveKeyID: "ed25519"},
+			ok:     true,
+		},
+		{
+			name:   "paseto public with RSA key",
+			config: util.Config{TokenType: tokens.TypePasetoPublic, TokenKeyDir: dir, TokenActiveKeyID: "rsa"},
+			ok:     false,
+		},
+		{
+			name:   "jwt public",
+			config: util.Config{TokenType: tokens.TypeJWTPublic, TokenKeyDir: dir, TokenActiveKeyID: "rsa"},
+			ok:     true,
+		},
+		{
+			name:   "missing key",
+			config: util.Config{TokenType: tokens.TypeJWTPublic, TokenKeyDir: dir, TokenActiveKeyID: "missing"},
+			ok:     false,
+		},
+		{
+			name:   "unknown type",
+			config: util.Config{TokenType: "unknown"},
+			ok:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maker, err := tokens.NewMaker(tt.config)
+			if !tt.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			token, _, err := maker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/aead/chacha20poly1305"
+	"github.com/google/uuid"
 
 	"github.com/o1egl/paseto"
 )
@@ -27,16 +28,35 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(email string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(email, duration)
+func (maker *PasetoMaker) CreateToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateScopedToken(email, role, sessionID, "", duration)
+}
+
+func (maker *PasetoMaker) CreateScopedToken(email string, role string, sessionID uuid.UUID, scope string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(email, role, sessionID, duration)
 	if err != nil {
 		return "", nil, err
 	}
+	payload.Scope = scope
 
-	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	token, err := maker.sign(payload)
 	return token, payload, err
 }
 
+func (maker *PasetoMaker) CreateRefreshToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewRefreshPayload(email, role, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *PasetoMaker) sign(payload *Payload) (string, error) {
+	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+}
+
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 	payload := &Payload{}
 
@@ -6,6 +6,7 @@ import (
 
 	tokens "github.com/claytten/golang-simplebank/internal/api/token"
 	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,12 +17,14 @@ func TestNewPasetoMaker(t *testing.T) {
 	require.NotEmpty(t, maker)
 
 	email := util.RandomEmail()
+	role := util.DepositorRole
+	sessionID := uuid.New()
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	pasetoToken, pasetoTokenPayload, err := maker.CreateToken(email, duration)
+	pasetoToken, pasetoTokenPayload, err := maker.CreateToken(email, role, sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, pasetoToken)
 	require.NotEmpty(t, pasetoTokenPayload)
@@ -31,7 +34,9 @@ func TestNewPasetoMaker(t *testing.T) {
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
+	require.Equal(t, sessionID, payload.SessionID)
 	require.Equal(t, email, payload.Email)
+	require.Equal(t, role, payload.Role)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -41,7 +46,7 @@ func TestExpiredPasetoMaker(t *testing.T) {
 	maker, err := tokens.NewPasetoMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	token, payload, err := maker.CreateToken(util.RandomEmail(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
@@ -51,3 +56,23 @@ func TestExpiredPasetoMaker(t *testing.T) {
 	require.EqualError(t, err, tokens.ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestScopedPasetoMaker(t *testing.T) {
+	maker, err := tokens.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateScopedToken(util.RandomEmail(), util.DepositorRole, uuid.New(), tokens.ScopeSensitive, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, tokens.ScopeSensitive, payload.Scope)
+
+	token, _, err = maker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Empty(t, payload.Scope)
+}
@@ -0,0 +1,157 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const pasetoPublicHeader = "v4.public."
+
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// PasetoPublicMaker is a PASETO v4.public token maker, tokens are signed with
+// the Ed25519 active key of the keyring and carry its id in the footer
+type PasetoPublicMaker struct {
+	keyring *Keyring
+}
+
+func NewPasetoPublicMaker(keyring *Keyring) (Maker, error) {
+	if keyring.ActiveKey().Algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("invalid key: PASETO v4.public needs an Ed25519 key")
+	}
+
+	return &PasetoPublicMaker{keyring: keyring}, nil
+}
+
+func (maker *PasetoPublicMaker) Keyring() *Keyring {
+	return maker.keyring
+}
+
+func (maker *PasetoPublicMaker) CreateToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateScopedToken(email, role, sessionID, "", duration)
+}
+
+func (maker *PasetoPublicMaker) CreateScopedToken(email string, role string, sessionID uuid.UUID, scope string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(email, role, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.Scope = scope
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *PasetoPublicMaker) CreateRefreshToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewRefreshPayload(email, role, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *PasetoPublicMaker) sign(payload *Payload) (string, error) {
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	key := maker.keyring.ActiveKey()
+	footer, err := json.Marshal(pasetoFooter{KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+
+	privateKey := key.PrivateKey.(ed25519.PrivateKey)
+	signature := ed25519.Sign(privateKey, preAuthEncode([]byte(pasetoPublicHeader), message, footer, nil))
+
+	token := pasetoPublicHeader +
+		base64.RawURLEncoding.EncodeToString(append(message, signature...)) + "." +
+		base64.RawURLEncoding.EncodeToString(footer)
+	return token, nil
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	if !strings.HasPrefix(token, pasetoPublicHeader) {
+		return nil, ErrInvalidToken
+	}
+
+	parts := strings.Split(strings.TrimPrefix(token, pasetoPublicHeader), ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(body) < ed25519.SignatureSize {
+		return nil, ErrInvalidToken
+	}
+
+	footer, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var decodedFooter pasetoFooter
+	if err := json.Unmarshal(footer, &decodedFooter); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := maker.keyring.Key(decodedFooter.KeyID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	publicKey, ok := key.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(publicKey, preAuthEncode([]byte(pasetoPublicHeader), message, footer, nil), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// preAuthEncode is the PAE function of the PASETO specification,
+// it binds the header, the footer and the message in what gets signed
+func preAuthEncode(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+
+	le64 := func(n int) {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(n)&^(1<<63))
+		buf.Write(b[:])
+	}
+
+	le64(len(pieces))
+	for _, piece := range pieces {
+		le64(len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
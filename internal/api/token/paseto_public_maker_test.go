@@ -0,0 +1,158 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	tokens "github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	keyring, err := tokens.NewKeyring("active", newEd25519Key(t, "active"))
+	require.NoError(t, err)
+
+	maker, err := tokens.NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+
+	email := util.RandomEmail()
+	role := util.DepositorRole
+	sessionID := uuid.New()
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, tokenPayload, err := maker.CreateToken(email, role, sessionID, duration)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "v4.public."))
+	require.NotEmpty(t, tokenPayload)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+
+	require.Equal(t, tokenPayload.ID, payload.ID)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, email, payload.Email)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicMaker(t *testing.T) {
+	keyring, err := tokens.NewKeyring("active", newEd25519Key(t, "active"))
+	require.NoError(t, err)
+
+	maker, err := tokens.NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, tokens.ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestTamperedPasetoPublicMaker(t *testing.T) {
+	keyring, err := tokens.NewKeyring("active", newEd25519Key(t, "active"))
+	require.NoError(t, err)
+
+	maker, err := tokens.NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// a token signed by another key with the same id
+	otherKeyring, err := tokens.NewKeyring("active", newEd25519Key(t, "active"))
+	require.NoError(t, err)
+	otherMaker, err := tokens.NewPasetoPublicMaker(otherKeyring)
+	require.NoError(t, err)
+
+	otherToken, _, err := otherMaker.CreateToken(util.RandomEmail(), util.AdminRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	for _, invalid := range []string{
+		"v2.local." + strings.TrimPrefix(token, "v4.public."),
+		token[:len(token)-10],
+		strings.Split(token, ".")[0] + "." + strings.Split(token, ".")[1] + "." + strings.Split(token, ".")[2],
+		otherToken,
+	} {
+		payload, err := maker.VerifyToken(invalid)
+		require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestRotatePasetoPublicMaker(t *testing.T) {
+	oldKey := newEd25519Key(t, "old")
+	newKey := newEd25519Key(t, "new")
+
+	oldKeyring, err := tokens.NewKeyring("old", oldKey)
+	require.NoError(t, err)
+	oldMaker, err := tokens.NewPasetoPublicMaker(oldKeyring)
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// after rotation the tokens signed by the old key are still valid
+	rotatedKeyring, err := tokens.NewKeyring("new", oldKey, newKey)
+	require.NoError(t, err)
+	rotatedMaker, err := tokens.NewPasetoPublicMaker(rotatedKeyring)
+	require.NoError(t, err)
+
+	_, err = rotatedMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, _, err := rotatedMaker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+	_, err = rotatedMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	// until the old key is retired
+	retiredKeyring, err := tokens.NewKeyring("new", newKey)
+	require.NoError(t, err)
+	retiredMaker, err := tokens.NewPasetoPublicMaker(retiredKeyring)
+	require.NoError(t, err)
+
+	_, err = retiredMaker.VerifyToken(oldToken)
+	require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+	_, err = retiredMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+}
+
+// test vector 4-S-2 of the PASETO specification, its payload expired in 2022
+// so a valid signature is reported as an expired token
+func TestPasetoPublicMakerVector(t *testing.T) {
+	publicKey, err := hex.DecodeString("1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+	privateKey, err := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774" +
+		"1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+
+	key, err := tokens.NewKey("zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN", ed25519.PrivateKey(privateKey))
+	require.NoError(t, err)
+	require.Equal(t, ed25519.PublicKey(publicKey), key.PublicKey)
+
+	keyring, err := tokens.NewKeyring(key.ID, key)
+	require.NoError(t, err)
+	maker, err := tokens.NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+
+	token := "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+		"v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw" +
+		".eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"
+
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, tokens.ErrExpiredToken.Error())
+
+	_, err = maker.VerifyToken(strings.Replace(token, "v3Jt8mx", "v3Jt8my", 1))
+	require.EqualError(t, err, tokens.ErrInvalidToken.Error())
+}
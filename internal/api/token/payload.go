@@ -7,19 +7,39 @@ import (
 	"github.com/google/uuid"
 )
 
+// ScopeSensitive is carried by the short-lived token issued after the user
+// entered the password again, sensitive actions only accept that token
+const ScopeSensitive = "sensitive"
+
+// token types, a refresh token is only accepted to renew an access token and
+// an access token never renews one
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 var (
 	ErrInvalidToken = errors.New("token is invalid")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
 	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Scope     string    `json:"scope,omitempty"`
+	TokenType string    `json:"token_type"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+
+	// set instead of the session when the request is authenticated with an API key
+	APIKeyID int64    `json:"api_key_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
 }
 
-func NewPayload(email string, duration time.Duration) (*Payload, error) {
+func NewPayload(email string, role string, sessionID uuid.UUID, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -27,13 +47,36 @@ func NewPayload(email string, duration time.Duration) (*Payload, error) {
 
 	payload := &Payload{
 		ID:        tokenID,
+		SessionID: sessionID,
 		Email:     email,
+		Role:      role,
+		TokenType: TokenTypeAccess,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
 	return payload, nil
 }
 
+// NewRefreshPayload creates the payload of a refresh token
+func NewRefreshPayload(email string, role string, sessionID uuid.UUID, duration time.Duration) (*Payload, error) {
+	payload, err := NewPayload(email, role, sessionID, duration)
+	if err != nil {
+		return nil, err
+	}
+	payload.TokenType = TokenTypeRefresh
+	return payload, nil
+}
+
+// IsRefresh reports whether the token is a refresh token
+func (payload *Payload) IsRefresh() bool {
+	return payload.TokenType == TokenTypeRefresh
+}
+
+// IsAPIKey reports whether the request is authenticated with an API key
+func (payload *Payload) IsAPIKey() bool {
+	return payload.APIKeyID != 0
+}
+
 // Valid checks if the token payload is valid or not
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
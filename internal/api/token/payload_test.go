@@ -0,0 +1,60 @@
+package token_test
+
+import (
+	"testing"
+	"time"
+
+	tokens "github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenType(t *testing.T) {
+	pasetoMaker, err := tokens.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	jwtMaker, err := tokens.NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	keyring, err := tokens.NewKeyring("2023-01", newEd25519Key(t, "2023-01"))
+	require.NoError(t, err)
+
+	pasetoPublicMaker, err := tokens.NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+
+	jwtPublicMaker, err := tokens.NewJWTPublicMaker(keyring)
+	require.NoError(t, err)
+
+	makers := map[string]tokens.Maker{
+		tokens.TypePaseto:       pasetoMaker,
+		tokens.TypeJWT:          jwtMaker,
+		tokens.TypePasetoPublic: pasetoPublicMaker,
+		tokens.TypeJWTPublic:    jwtPublicMaker,
+	}
+
+	for name, maker := range makers {
+		maker := maker
+
+		t.Run(name, func(t *testing.T) {
+			sessionID := uuid.New()
+
+			accessToken, _, err := maker.CreateToken(util.RandomEmail(), util.DepositorRole, sessionID, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(accessToken)
+			require.NoError(t, err)
+			require.Equal(t, tokens.TokenTypeAccess, payload.TokenType)
+			require.False(t, payload.IsRefresh())
+
+			// the refresh token of the same session is told apart from its access tokens
+			refreshToken, _, err := maker.CreateRefreshToken(util.RandomEmail(), util.DepositorRole, sessionID, time.Hour)
+			require.NoError(t, err)
+
+			payload, err = maker.VerifyToken(refreshToken)
+			require.NoError(t, err)
+			require.Equal(t, sessionID, payload.SessionID)
+			require.True(t, payload.IsRefresh())
+		})
+	}
+}
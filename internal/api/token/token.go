@@ -1,9 +1,54 @@
 package token
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/google/uuid"
+)
+
+// token types selected with TOKEN_TYPE
+const (
+	TypePaseto       = "paseto"
+	TypeJWT          = "jwt"
+	TypePasetoPublic = "paseto_public"
+	TypeJWTPublic    = "jwt_public"
+)
 
 type Maker interface {
-	CreateToken(email string, duration time.Duration) (string, *Payload, error)
+	// CreateToken creates a token for the given email and role that belongs to the login session sessionID
+	CreateToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error)
+
+	// CreateScopedToken creates a token like CreateToken that also carries scope
+	CreateScopedToken(email string, role string, sessionID uuid.UUID, scope string, duration time.Duration) (string, *Payload, error)
+
+	// CreateRefreshToken creates a token that only renews the access tokens of the session
+	CreateRefreshToken(email string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error)
 
 	VerifyToken(token string) (*Payload, error)
 }
+
+// NewMaker creates the token maker selected with TOKEN_TYPE, the public key
+// makers sign with the keys of TOKEN_KEY_DIR so verifiers don't need a secret
+func NewMaker(config util.Config) (Maker, error) {
+	switch config.TokenType {
+	case "", TypePaseto:
+		return NewPasetoMaker(config.TokenSymmetricKey)
+	case TypeJWT:
+		return NewJWTMaker(config.TokenSymmetricKey)
+	case TypePasetoPublic, TypeJWTPublic:
+	default:
+		return nil, fmt.Errorf("unsupported token type %s", config.TokenType)
+	}
+
+	keyring, err := LoadKeyring(config.TokenKeyDir, config.TokenActiveKeyID, config.TokenRetiredKeyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TokenType == TypePasetoPublic {
+		return NewPasetoPublicMaker(keyring)
+	}
+	return NewJWTPublicMaker(keyring)
+}
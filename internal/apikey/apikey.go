@@ -0,0 +1,151 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/google/uuid"
+)
+
+// keyPrefix marks our keys, so secret scanners and humans recognize them
+const keyPrefix = "sb"
+
+// lastUsedResolution limits the last-used writes to one per key and minute
+const lastUsedResolution = time.Minute
+
+var (
+	ErrInvalidKey = errors.New("api key is invalid")
+	ErrExpiredKey = errors.New("api key has expired")
+	ErrRevokedKey = errors.New("api key has been revoked")
+)
+
+// Key is an API key in the form sb_<prefix>_<secret>. The prefix identifies
+// the key and is stored as is, only the hash of the secret is stored.
+type Key struct {
+	Prefix string
+	Secret string
+}
+
+// Generate returns a new random key
+func Generate() (Key, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return Key{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+
+	key := Key{
+		Prefix: hex.EncodeToString(prefix),
+		Secret: base64.RawURLEncoding.EncodeToString(secret),
+	}
+	return key, nil
+}
+
+// Parse splits the key sent by a client
+func Parse(s string) (Key, error) {
+	parts := strings.SplitN(s, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return Key{}, ErrInvalidKey
+	}
+
+	return Key{Prefix: parts[1], Secret: parts[2]}, nil
+}
+
+func (key Key) String() string {
+	return fmt.Sprintf("%s_%s_%s", keyPrefix, key.Prefix, key.Secret)
+}
+
+// HashSecret hashes the secret for storage. The secret is 256 random bits,
+// so a fast hash is enough, unlike a password there is nothing to guess.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySecret checks the secret against its hash in constant time
+func VerifySecret(hashedSecret, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashedSecret), []byte(HashSecret(secret))) == 1
+}
+
+// Authenticate finds the key sent by a client and returns the payload of its
+// owner, carrying the key ID and scopes instead of a session
+func Authenticate(ctx context.Context, store db.Querier, s string) (*token.Payload, error) {
+	key, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, key.Prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, fmt.Errorf("cannot find api key: %w", err)
+	}
+
+	if !VerifySecret(apiKey.HashedSecret, key.Secret) {
+		return nil, ErrInvalidKey
+	}
+
+	if apiKey.RevokedAt.Valid {
+		return nil, ErrRevokedKey
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt.Valid && now.After(apiKey.ExpiresAt.Time) {
+		return nil, ErrExpiredKey
+	}
+
+	owner, err := store.GetUser(ctx, apiKey.Owner)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, fmt.Errorf("cannot find api key owner: %w", err)
+	}
+
+	// a failed write only makes last-used stale, the request goes on
+	err = store.TouchAPIKey(ctx, db.TouchAPIKeyParams{
+		ID:             apiKey.ID,
+		LastUsedAt:     sql.NullTime{Time: now, Valid: true},
+		LastUsedBefore: sql.NullTime{Time: now.Add(-lastUsedResolution), Valid: true},
+	})
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Int64("api_key_id", apiKey.ID).Msg("cannot update api key last use")
+	}
+
+	return NewPayload(apiKey, owner), nil
+}
+
+// NewPayload returns the payload of a request made with the key
+func NewPayload(apiKey db.ApiKeys, owner db.Users) *token.Payload {
+	payload := &token.Payload{
+		ID:       uuid.New(),
+		Email:    owner.Email,
+		Role:     owner.Role,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+		IssuedAt: apiKey.CreatedAt,
+	}
+
+	if apiKey.ExpiresAt.Valid {
+		payload.ExpiredAt = apiKey.ExpiresAt.Time
+	}
+	return payload
+}
@@ -0,0 +1,217 @@
+package apikey_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/apikey"
+	"github.com/claytten/golang-simplebank/internal/authz"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateParse(t *testing.T) {
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+	require.Len(t, key.Prefix, 12)
+	require.NotEmpty(t, key.Secret)
+
+	parsed, err := apikey.Parse(key.String())
+	require.NoError(t, err)
+	require.Equal(t, key, parsed)
+
+	other, err := apikey.Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, key, other)
+
+	for _, s := range []string{"", "sb", "sb_prefix", "sb__secret", "xx_prefix_secret"} {
+		_, err = apikey.Parse(s)
+		require.ErrorIs(t, err, apikey.ErrInvalidKey)
+	}
+}
+
+func TestVerifySecret(t *testing.T) {
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	hashedSecret := apikey.HashSecret(key.Secret)
+	require.NotEqual(t, key.Secret, hashedSecret)
+	require.True(t, apikey.VerifySecret(hashedSecret, key.Secret))
+	require.False(t, apikey.VerifySecret(hashedSecret, util.RandomString(43)))
+}
+
+func randomAPIKey(t *testing.T, owner db.Users) (apikey.Key, db.ApiKeys) {
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKeys{
+		ID:           util.RandomInt(1, 1000),
+		Prefix:       key.Prefix,
+		HashedSecret: apikey.HashSecret(key.Secret),
+		Name:         util.RandomOwner(),
+		Owner:        owner.Username,
+		Scopes:       []string{authz.ScopeAccountsRead},
+		ExpiresAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		CreatedAt:    time.Now(),
+	}
+	return key, apiKey
+}
+
+func TestAuthenticate(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	key, apiKey := randomAPIKey(t, user)
+
+	tests := []struct {
+		name       string
+		key        string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name: "OK",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: OK when last-used cannot be written
+		{
+			name: "OK touch failed",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: malformed key
+		{
+			name: "malformed",
+			key:  "not-a-key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, apikey.ErrInvalidKey)
+			},
+		},
+
+		// TODO: unknown prefix
+		{
+			name: "unknown",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(db.ApiKeys{}, db.ErrRecordNotFound)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, apikey.ErrInvalidKey)
+			},
+		},
+
+		// TODO: owner deleted, the store wraps the error
+		{
+			name: "deleted owner",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.Users{}, fmt.Errorf("cannot get user: %w", db.ErrRecordNotFound))
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, apikey.ErrInvalidKey)
+			},
+		},
+
+		// TODO: wrong secret
+		{
+			name: "wrong secret",
+			key:  apikey.Key{Prefix: key.Prefix, Secret: util.RandomString(43)}.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, apikey.ErrInvalidKey)
+			},
+		},
+
+		// TODO: revoked key
+		{
+			name: "revoked",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(revoked, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, apikey.ErrRevokedKey)
+			},
+		},
+
+		// TODO: expired key
+		{
+			name: "expired",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(expired, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, apikey.ErrExpiredKey)
+			},
+		},
+
+		// TODO: database down
+		{
+			name: "internal error",
+			key:  key.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(db.ApiKeys{}, sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.False(t, errors.Is(err, apikey.ErrInvalidKey))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			payload, err := apikey.Authenticate(context.Background(), store, tt.key)
+			tt.checkError(t, err)
+			if err != nil {
+				return
+			}
+
+			require.True(t, payload.IsAPIKey())
+			require.Equal(t, apiKey.ID, payload.APIKeyID)
+			require.Equal(t, apiKey.Scopes, payload.Scopes)
+			require.Equal(t, user.Email, payload.Email)
+			require.Equal(t, user.Role, payload.Role)
+		})
+	}
+}
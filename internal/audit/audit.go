@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+)
+
+const defaultPageSize = 500
+
+// Result is what a verification walked through
+type Result struct {
+	Events   int64
+	LastID   int64
+	LastHash string
+}
+
+// BrokenChainError points at the first event the chain doesn't account for
+type BrokenChainError struct {
+	EventID int64
+	Reason  string
+}
+
+func (err *BrokenChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at event %d: %s", err.EventID, err.Reason)
+}
+
+// Verify recomputes the hash chain of every audit event from the first one.
+// An edited event or one removed from the middle of the log breaks the chain,
+// events removed from the end can only be noticed by comparing LastHash with
+// a hash saved by an earlier verification.
+func Verify(ctx context.Context, q db.Querier, pageSize int32) (Result, error) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	var result Result
+	for {
+		events, err := q.ListAuditEvents(ctx, db.ListAuditEventsParams{
+			ID:    result.LastID,
+			Limit: pageSize,
+		})
+		if err != nil {
+			return result, fmt.Errorf("cannot list audit events: %w", err)
+		}
+
+		for _, event := range events {
+			if event.PrevHash != result.LastHash {
+				return result, &BrokenChainError{
+					EventID: event.ID,
+					Reason:  "previous hash doesn't match, an event before it was removed or changed",
+				}
+			}
+
+			if db.HashAuditEvent(event) != event.Hash {
+				return result, &BrokenChainError{
+					EventID: event.ID,
+					Reason:  "hash doesn't match, the event was changed",
+				}
+			}
+
+			result.Events++
+			result.LastID = event.ID
+			result.LastHash = event.Hash
+		}
+
+		if len(events) < int(pageSize) {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package audit_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/audit"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// randomChain links n events the way the store records them
+func randomChain(n int) []db.AuditEvents {
+	events := make([]db.AuditEvents, n)
+	prevHash := ""
+	for i := range events {
+		event := db.AuditEvents{
+			ID:        int64(i + 1),
+			Actor:     util.RandomOwner(),
+			Action:    "account.update",
+			Target:    "account:1",
+			ClientIp:  "127.0.0.1",
+			UserAgent: "test",
+			Before:    json.RawMessage(`{"balance":0}`),
+			After:     json.RawMessage(`{"balance":100}`),
+			PrevHash:  prevHash,
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		}
+		event.Hash = db.HashAuditEvent(event)
+		prevHash = event.Hash
+		events[i] = event
+	}
+	return events
+}
+
+// listPages serves events like ListAuditEvents does
+func listPages(events []db.AuditEvents) func(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvents, error) {
+	return func(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvents, error) {
+		page := []db.AuditEvents{}
+		for _, event := range events {
+			if event.ID > arg.ID && len(page) < int(arg.Limit) {
+				page = append(page, event)
+			}
+		}
+		return page, nil
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result audit.Result, err error)
+	}{
+		// TODO: intact chain over several pages
+		{
+			name: "intact",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(4).DoAndReturn(listPages(randomChain(7)))
+			},
+			check: func(t *testing.T, result audit.Result, err error) {
+				require.NoError(t, err)
+				require.EqualValues(t, 7, result.Events)
+				require.EqualValues(t, 7, result.LastID)
+				require.NotEmpty(t, result.LastHash)
+			},
+		},
+
+		// TODO: empty log
+		{
+			name: "empty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(listPages(nil))
+			},
+			check: func(t *testing.T, result audit.Result, err error) {
+				require.NoError(t, err)
+				require.Zero(t, result.Events)
+			},
+		},
+
+		// TODO: edited event
+		{
+			name: "edited",
+			buildStubs: func(store *mockdb.MockStore) {
+				events := randomChain(5)
+				events[2].After = json.RawMessage(`{"balance":1000000}`)
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(listPages(events))
+			},
+			check: func(t *testing.T, result audit.Result, err error) {
+				var brokenErr *audit.BrokenChainError
+				require.ErrorAs(t, err, &brokenErr)
+				require.EqualValues(t, 3, brokenErr.EventID)
+				require.EqualValues(t, 2, result.Events)
+			},
+		},
+
+		// TODO: edited event with a recomputed hash
+		{
+			name: "rehashed",
+			buildStubs: func(store *mockdb.MockStore) {
+				events := randomChain(5)
+				events[2].Actor = "someone_else"
+				events[2].Hash = db.HashAuditEvent(events[2])
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(listPages(events))
+			},
+			check: func(t *testing.T, result audit.Result, err error) {
+				var brokenErr *audit.BrokenChainError
+				require.ErrorAs(t, err, &brokenErr)
+				require.EqualValues(t, 4, brokenErr.EventID)
+			},
+		},
+
+		// TODO: removed event
+		{
+			name: "removed",
+			buildStubs: func(store *mockdb.MockStore) {
+				events := randomChain(5)
+				events = append(events[:1], events[2:]...)
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(listPages(events))
+			},
+			check: func(t *testing.T, result audit.Result, err error) {
+				var brokenErr *audit.BrokenChainError
+				require.ErrorAs(t, err, &brokenErr)
+				require.EqualValues(t, 3, brokenErr.EventID)
+			},
+		},
+
+		// TODO: database error
+		{
+			name: "internal error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			check: func(t *testing.T, result audit.Result, err error) {
+				require.True(t, errors.Is(err, sql.ErrConnDone))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			result, err := audit.Verify(context.Background(), store, 2)
+			tt.check(t, result, err)
+		})
+	}
+}
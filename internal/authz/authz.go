@@ -0,0 +1,27 @@
+package authz
+
+// Permissions maps an operation, a gRPC full method name or a gin route,
+// to the roles allowed to perform it. Operations that are not listed are
+// open to every role.
+type Permissions map[string][]string
+
+// IsRestricted reports whether the operation is limited to some roles
+func (p Permissions) IsRestricted(operation string) bool {
+	_, ok := p[operation]
+	return ok
+}
+
+// IsAllowed reports whether the role may perform the operation
+func (p Permissions) IsAllowed(operation, role string) bool {
+	roles, ok := p[operation]
+	if !ok {
+		return true
+	}
+
+	for _, allowed := range roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
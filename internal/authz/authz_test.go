@@ -0,0 +1,45 @@
+package authz_test
+
+import (
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/authz"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissions(t *testing.T) {
+	permissions := authz.Permissions{
+		"/pb.Simplebank/ListUsers": {util.AdminRole},
+	}
+
+	require.True(t, permissions.IsRestricted("/pb.Simplebank/ListUsers"))
+	require.True(t, permissions.IsAllowed("/pb.Simplebank/ListUsers", util.AdminRole))
+	require.False(t, permissions.IsAllowed("/pb.Simplebank/ListUsers", util.DepositorRole))
+	require.False(t, permissions.IsAllowed("/pb.Simplebank/ListUsers", ""))
+
+	require.False(t, permissions.IsRestricted("/pb.Simplebank/GetUser"))
+	require.True(t, permissions.IsAllowed("/pb.Simplebank/GetUser", util.DepositorRole))
+}
+
+func TestScopes(t *testing.T) {
+	scopes := authz.Scopes{
+		"/pb.Simplebank/GetAccount":        {authz.ScopeAccountsRead},
+		"/pb.Simplebank/TransferTxAccount": {authz.ScopeTransfersWrite},
+	}
+
+	require.True(t, scopes.IsGranted("/pb.Simplebank/GetAccount", []string{authz.ScopeAccountsRead}))
+	require.True(t, scopes.IsGranted("/pb.Simplebank/TransferTxAccount", []string{authz.ScopeAccountsRead, authz.ScopeTransfersWrite}))
+	require.False(t, scopes.IsGranted("/pb.Simplebank/TransferTxAccount", []string{authz.ScopeAccountsRead}))
+	require.False(t, scopes.IsGranted("/pb.Simplebank/GetAccount", nil))
+
+	// an operation without scopes is closed to API keys
+	require.False(t, scopes.IsGranted("/pb.Simplebank/UpdatePassword", []string{authz.ScopeAccountsRead}))
+
+	require.True(t, authz.IsSupportScope(authz.ScopeAdminRead))
+	require.False(t, authz.IsSupportScope("accounts:delete"))
+
+	require.True(t, authz.MovesMoney(authz.ScopeTransfersWrite))
+	require.True(t, authz.MovesMoney(authz.ScopeAccountsWrite))
+	require.False(t, authz.MovesMoney(authz.ScopeAccountsRead))
+}
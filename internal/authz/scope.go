@@ -0,0 +1,50 @@
+package authz
+
+// API key scopes, an API key only performs the operations one of its scopes grants
+const (
+	ScopeUsersRead      = "users:read"
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeWebhooksRead   = "webhooks:read"
+	ScopeWebhooksWrite  = "webhooks:write"
+	ScopeAdminRead      = "admin:read"
+	ScopeAdminWrite     = "admin:write"
+)
+
+// IsSupportScope reports whether an API key may be issued with the scope
+func IsSupportScope(scope string) bool {
+	switch scope {
+	case ScopeUsersRead, ScopeAccountsRead, ScopeAccountsWrite,
+		ScopeTransfersWrite, ScopeWebhooksRead, ScopeWebhooksWrite,
+		ScopeAdminRead, ScopeAdminWrite:
+		return true
+	}
+	return false
+}
+
+// MovesMoney reports whether the scope changes a balance, such a key is only
+// issued by its owner and never by an admin on their behalf
+func MovesMoney(scope string) bool {
+	switch scope {
+	case ScopeAccountsWrite, ScopeTransfersWrite:
+		return true
+	}
+	return false
+}
+
+// Scopes maps an operation to the API key scopes granting it. Unlike
+// Permissions, an operation that is not listed is closed to every API key.
+type Scopes map[string][]string
+
+// IsGranted reports whether an API key holding the granted scopes may perform the operation
+func (s Scopes) IsGranted(operation string, granted []string) bool {
+	for _, scope := range s[operation] {
+		for _, g := range granted {
+			if g == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
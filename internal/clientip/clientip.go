@@ -0,0 +1,98 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Resolver finds the address of a client behind the proxies in front of the server.
+// The x-forwarded-for header is only read on connections from a trusted proxy,
+// anybody else could send it to pass for another client.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver trusts the proxies in the given CIDRs or IPs, such as 127.0.0.1/32
+// for the HTTP gateway calling the gRPC server. No proxy is trusted without any.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	resolver := &Resolver{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			resolver.trusted = append(resolver.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+	return resolver, nil
+}
+
+// Resolve returns the client address of a connection from peer, with the values of
+// the x-forwarded-for header it sent. When peer is a trusted proxy, the client is the
+// right-most hop of the header that is not a trusted proxy, every hop on its left
+// was written by the client itself. The port is dropped.
+func (resolver *Resolver) Resolve(peer string, forwardedFor []string) string {
+	client := host(peer)
+	if !resolver.Trusted(client) {
+		return client
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = host(strings.TrimSpace(hop)); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		client = hops[i]
+		if !resolver.Trusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+// Trusted tells whether ip is the address of a trusted proxy
+func (resolver *Resolver) Trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if resolver == nil || parsed == nil {
+		return false
+	}
+
+	for _, network := range resolver.trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// host drops the port of an address, an address without one is returned as it is
+func host(address string) string {
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		return h
+	}
+	return address
+}
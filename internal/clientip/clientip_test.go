@@ -0,0 +1,93 @@
+package clientip_test
+
+import (
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/clientip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResolver(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"127.0.0.1", " 10.0.0.0/8", "::1", ""})
+	require.NoError(t, err)
+
+	require.True(t, resolver.Trusted("127.0.0.1"))
+	require.True(t, resolver.Trusted("10.1.2.3"))
+	require.True(t, resolver.Trusted("::1"))
+	require.False(t, resolver.Trusted("127.0.0.2"))
+	require.False(t, resolver.Trusted("not an ip"))
+
+	_, err = clientip.NewResolver([]string{"localhost"})
+	require.Error(t, err)
+
+	_, err = clientip.NewResolver([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"127.0.0.1/32", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		peer         string
+		forwardedFor []string
+		clientIP     string
+	}{
+		// TODO: direct client without the header
+		{
+			name:     "DirectClient",
+			peer:     "203.0.113.7:51000",
+			clientIP: "203.0.113.7",
+		},
+		// TODO: direct client forging the header
+		{
+			name:         "ForgedByDirectClient",
+			peer:         "203.0.113.7:51000",
+			forwardedFor: []string{"198.51.100.1"},
+			clientIP:     "203.0.113.7",
+		},
+		// TODO: client behind the gateway
+		{
+			name:         "Gateway",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"203.0.113.7"},
+			clientIP:     "203.0.113.7",
+		},
+		// TODO: client forging the header through the gateway
+		{
+			name:         "ForgedThroughGateway",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.7"},
+			clientIP:     "203.0.113.7",
+		},
+		// TODO: client behind a trusted proxy and the gateway
+		{
+			name:         "TrustedProxies",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.7", "10.0.0.5"},
+			clientIP:     "203.0.113.7",
+		},
+		// TODO: gateway called without the header
+		{
+			name:     "GatewayWithoutHeader",
+			peer:     "127.0.0.1:40000",
+			clientIP: "127.0.0.1",
+		},
+		// TODO: only trusted proxies in the header
+		{
+			name:         "OnlyTrustedHops",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"10.0.0.6, 10.0.0.5"},
+			clientIP:     "10.0.0.6",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.clientIP, resolver.Resolve(tc.peer, tc.forwardedFor))
+		})
+	}
+}
@@ -11,10 +11,10 @@ SELECT COUNT(*) FROM accounts WHERE owner = $1;
 SELECT * FROM accounts WHERE owner = $1 ORDER BY id LIMIT $2 OFFSET $3;
 
 -- name: UpdateAccount :one
-UPDATE accounts SET balance = $2, updated_at = $3 WHERE id = $1 RETURNING *;
+UPDATE accounts SET balance = $2, updated_at = $3 WHERE id = $1 AND is_frozen = false RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1;
+-- name: DeleteAccount :one
+DELETE FROM accounts WHERE id = $1 AND is_frozen = false RETURNING *;
 
 -- name: DeleteAllAccount :exec
 TRUNCATE TABLE accounts CASCADE;
@@ -22,6 +22,11 @@ TRUNCATE TABLE accounts CASCADE;
 -- name: AddAccountBalance :one
 UPDATE accounts
 SET balance = balance + sqlc.arg(amount)
-WHERE id = sqlc.arg(id)
+WHERE id = sqlc.arg(id) AND is_frozen = false
 RETURNING *;
 
+-- name: FreezeAccount :one
+UPDATE accounts SET is_frozen = true, updated_at = $2 WHERE id = $1 RETURNING *;
+
+-- name: NotifyAccountActivity :exec
+SELECT pg_notify('account_activity', sqlc.arg(account_id)::bigint::text);
@@ -0,0 +1,19 @@
+-- name: CreateUserDevice :one
+INSERT INTO user_devices (
+  username,
+  user_agent,
+  client_ip
+) VALUES (
+  $1, $2, $3
+) ON CONFLICT (username, user_agent) DO NOTHING
+RETURNING *;
+
+-- name: TouchUserDevice :one
+UPDATE user_devices
+SET client_ip = $3, last_seen_at = now()
+WHERE username = $1 AND user_agent = $2
+RETURNING *;
+
+-- name: CountUserDevices :one
+SELECT count(*) FROM user_devices
+WHERE username = $1;
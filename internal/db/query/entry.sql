@@ -5,4 +5,23 @@ INSERT INTO entries (account_id, amount) VALUES ($1, $2) RETURNING *;
 SELECT * FROM entries WHERE id = $1 LIMIT 1;
 
 -- name: ListsEntries :many
-SELECT * FROM entries WHERE account_id = $1 ORDER BY id LIMIT $2 OFFSET $3;
\ No newline at end of file
+SELECT * FROM entries WHERE account_id = $1 ORDER BY id LIMIT $2 OFFSET $3;
+
+-- name: ListEntriesAfter :many
+-- the entries of the first transactions after the cursor that are older than every
+-- transaction still running, a transaction committing later has a txid from the
+-- snapshot's xmin on, so the cursor never moves past it
+SELECT * FROM entries
+WHERE account_id = ANY(sqlc.arg(account_ids)::bigint[]) AND txid IN (
+  SELECT DISTINCT txid FROM entries
+  WHERE account_id = ANY(sqlc.arg(account_ids)::bigint[])
+    AND txid > sqlc.arg(after_txid)
+    AND txid < txid_snapshot_xmin(txid_current_snapshot())
+  ORDER BY txid
+  LIMIT sqlc.arg(limit_transactions)
+)
+ORDER BY txid, id;
+
+-- name: GetEntryCursor :one
+-- the cursor of the entries every transaction still running comes after
+SELECT (txid_snapshot_xmin(txid_current_snapshot()) - 1)::bigint;
@@ -0,0 +1,38 @@
+-- name: GetNotificationSettings :one
+SELECT * FROM notification_settings
+WHERE username = $1 LIMIT 1;
+
+-- name: UpsertNotificationSettings :one
+INSERT INTO notification_settings (
+  username,
+  locale,
+  phone_number,
+  push_token,
+  large_debit_threshold
+) VALUES (
+  $1, $2, $3, $4, $5
+) ON CONFLICT (username) DO UPDATE SET
+  locale = EXCLUDED.locale,
+  phone_number = EXCLUDED.phone_number,
+  push_token = EXCLUDED.push_token,
+  large_debit_threshold = EXCLUDED.large_debit_threshold,
+  updated_at = now()
+RETURNING *;
+
+-- name: ListNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE username = $1
+ORDER BY event, channel;
+
+-- name: UpsertNotificationPreference :one
+INSERT INTO notification_preferences (
+  username,
+  event,
+  channel,
+  enabled
+) VALUES (
+  $1, $2, $3, $4
+) ON CONFLICT (username, event, channel) DO UPDATE SET
+  enabled = EXCLUDED.enabled,
+  updated_at = now()
+RETURNING *;
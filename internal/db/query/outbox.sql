@@ -0,0 +1,42 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  topic,
+  payload,
+  trace_context,
+  request_id
+) VALUES (
+  $1, $2, COALESCE(sqlc.arg(trace_context)::json, '{}'), sqlc.arg(request_id)
+) RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+-- leases the oldest pending events until leased_until, other relays skip them
+-- while they are published outside of any transaction
+WITH pending AS (
+  SELECT id FROM outbox
+  WHERE published_at IS NULL AND available_at <= now()
+  ORDER BY id
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE SKIP LOCKED
+), claimed AS (
+  UPDATE outbox
+  SET available_at = sqlc.arg(leased_until)
+  FROM pending
+  WHERE outbox.id = pending.id
+  RETURNING outbox.*
+)
+SELECT * FROM claimed
+ORDER BY id;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now(), last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = sqlc.arg(last_error), available_at = sqlc.arg(available_at)
+WHERE id = sqlc.arg(id);
+
+-- name: DeletePublishedOutboxEvents :exec
+DELETE FROM outbox
+WHERE published_at < $1;
@@ -0,0 +1,22 @@
+-- name: CreatePasswordHistory :one
+INSERT INTO password_history (
+  username,
+  hashed_password
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: ListPasswordHistory :many
+SELECT * FROM password_history
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2;
+
+-- name: TrimPasswordHistory :exec
+DELETE FROM password_history
+WHERE username = sqlc.arg(username) AND id NOT IN (
+  SELECT id FROM password_history
+  WHERE username = sqlc.arg(username)
+  ORDER BY id DESC
+  LIMIT sqlc.arg(keep)
+);
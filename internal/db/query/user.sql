@@ -27,3 +27,15 @@ SET
 WHERE
   username = sqlc.arg(username)
 RETURNING *;
+
+-- name: ListUsers :many
+SELECT * FROM users ORDER BY username LIMIT $1 OFFSET $2;
+
+-- name: GetTotalPageListsUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: RehashUserPassword :one
+UPDATE users
+SET hashed_password = sqlc.arg(new_hashed_password)
+WHERE username = sqlc.arg(username) AND hashed_password = sqlc.arg(old_hashed_password)
+RETURNING *;
@@ -13,8 +13,8 @@ import (
 const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
 SET balance = balance + $1
-WHERE id = $2
-RETURNING id, owner, balance, currency, created_at, updated_at
+WHERE id = $2 AND is_frozen = false
+RETURNING id, owner, balance, currency, created_at, updated_at, is_frozen
 `
 
 type AddAccountBalanceParams struct {
@@ -32,12 +32,13 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
 const createAccount = `-- name: CreateAccount :one
-INSERT INTO accounts (owner, balance, currency) VALUES ($1, $2, $3) RETURNING id, owner, balance, currency, created_at, updated_at
+INSERT INTO accounts (owner, balance, currency) VALUES ($1, $2, $3) RETURNING id, owner, balance, currency, created_at, updated_at, is_frozen
 `
 
 type CreateAccountParams struct {
@@ -56,17 +57,28 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
-const deleteAccount = `-- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1
+const deleteAccount = `-- name: DeleteAccount :one
+DELETE FROM accounts WHERE id = $1 AND is_frozen = false RETURNING id, owner, balance, currency, created_at, updated_at, is_frozen
 `
 
-func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteAccount, id)
-	return err
+func (q *Queries) DeleteAccount(ctx context.Context, id int64) (Accounts, error) {
+	row := q.db.QueryRowContext(ctx, deleteAccount, id)
+	var i Accounts
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsFrozen,
+	)
+	return i, err
 }
 
 const deleteAllAccount = `-- name: DeleteAllAccount :exec
@@ -78,8 +90,32 @@ func (q *Queries) DeleteAllAccount(ctx context.Context) error {
 	return err
 }
 
+const freezeAccount = `-- name: FreezeAccount :one
+UPDATE accounts SET is_frozen = true, updated_at = $2 WHERE id = $1 RETURNING id, owner, balance, currency, created_at, updated_at, is_frozen
+`
+
+type FreezeAccountParams struct {
+	ID        int64     `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) FreezeAccount(ctx context.Context, arg FreezeAccountParams) (Accounts, error) {
+	row := q.db.QueryRowContext(ctx, freezeAccount, arg.ID, arg.UpdatedAt)
+	var i Accounts
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at, updated_at FROM accounts WHERE id = $1 LIMIT 1
+SELECT id, owner, balance, currency, created_at, updated_at, is_frozen FROM accounts WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetAccount(ctx context.Context, id int64) (Accounts, error) {
@@ -92,6 +128,7 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Accounts, error) {
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
@@ -108,7 +145,7 @@ func (q *Queries) GetTotalPageListsAccounts(ctx context.Context, owner string) (
 }
 
 const listsAccounts = `-- name: ListsAccounts :many
-SELECT id, owner, balance, currency, created_at, updated_at FROM accounts WHERE owner = $1 ORDER BY id LIMIT $2 OFFSET $3
+SELECT id, owner, balance, currency, created_at, updated_at, is_frozen FROM accounts WHERE owner = $1 ORDER BY id LIMIT $2 OFFSET $3
 `
 
 type ListsAccountsParams struct {
@@ -133,6 +170,7 @@ func (q *Queries) ListsAccounts(ctx context.Context, arg ListsAccountsParams) ([
 			&i.Currency,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsFrozen,
 		); err != nil {
 			return nil, err
 		}
@@ -147,8 +185,17 @@ func (q *Queries) ListsAccounts(ctx context.Context, arg ListsAccountsParams) ([
 	return items, nil
 }
 
+const notifyAccountActivity = `-- name: NotifyAccountActivity :exec
+SELECT pg_notify('account_activity', $1::bigint::text)
+`
+
+func (q *Queries) NotifyAccountActivity(ctx context.Context, accountID int64) error {
+	_, err := q.db.ExecContext(ctx, notifyAccountActivity, accountID)
+	return err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
-UPDATE accounts SET balance = $2, updated_at = $3 WHERE id = $1 RETURNING id, owner, balance, currency, created_at, updated_at
+UPDATE accounts SET balance = $2, updated_at = $3 WHERE id = $1 AND is_frozen = false RETURNING id, owner, balance, currency, created_at, updated_at, is_frozen
 `
 
 type UpdateAccountParams struct {
@@ -167,6 +214,7 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
@@ -31,6 +31,7 @@ func CreateRandomAccount(t *testing.T) db.Accounts {
 	require.Equal(t, arg.Currency, account.Currency)
 
 	require.NotZero(t, account.ID)
+	require.False(t, account.IsFrozen)
 	require.NotZero(t, account.CreatedAt)
 	require.NotZero(t, account.UpdatedAt)
 
@@ -78,10 +79,52 @@ func TestUpdateAccount(t *testing.T) {
 	require.WithinDuration(t, arg.UpdatedAt, account2.UpdatedAt, time.Second)
 }
 
+func TestFreezeAccount(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+
+	arg := db.FreezeAccountParams{
+		ID:        account1.ID,
+		UpdatedAt: time.Now(),
+	}
+
+	account2, err := testQueries.FreezeAccount(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, account2)
+
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, account1.Balance, account2.Balance)
+	require.True(t, account2.IsFrozen)
+	require.WithinDuration(t, arg.UpdatedAt, account2.UpdatedAt, time.Second)
+
+	// the balance of a frozen account never changes
+	_, err = testQueries.UpdateAccount(context.Background(), db.UpdateAccountParams{
+		ID:        account1.ID,
+		Balance:   util.RandomMoney(),
+		UpdatedAt: time.Now(),
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	_, err = testQueries.AddAccountBalance(context.Background(), db.AddAccountBalanceParams{
+		ID:     account1.ID,
+		Amount: util.RandomMoney(),
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	// nor is a frozen account deleted
+	_, err = testQueries.DeleteAccount(context.Background(), account1.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	account3, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.True(t, account3.IsFrozen)
+}
+
 func TestDeleteAccount(t *testing.T) {
 	account1 := CreateRandomAccount(t)
-	err := testQueries.DeleteAccount(context.Background(), account1.ID)
+	deleted, err := testQueries.DeleteAccount(context.Background(), account1.ID)
 	require.NoError(t, err)
+	require.Equal(t, account1, deleted)
 
 	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
 	require.Error(t, err)
@@ -0,0 +1,119 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomAPIKey(t *testing.T, owner db.Users) db.ApiKeys {
+	arg := db.CreateAPIKeyParams{
+		Prefix:       util.RandomString(12),
+		HashedSecret: util.RandomString(64),
+		Name:         util.RandomOwner(),
+		Owner:        owner.Username,
+		Scopes:       []string{"accounts:read", "transfers:write"},
+		ExpiresAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, apiKey)
+
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.HashedSecret, apiKey.HashedSecret)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Owner, apiKey.Owner)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, apiKey.ExpiresAt.Time, time.Second)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	CreateRandomAPIKey(t, CreateRandomUser(t))
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	apiKey1 := CreateRandomAPIKey(t, CreateRandomUser(t))
+	apiKey2, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey1.Prefix)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey1.ID, apiKey2.ID)
+	require.Equal(t, apiKey1.HashedSecret, apiKey2.HashedSecret)
+	require.Equal(t, apiKey1.Scopes, apiKey2.Scopes)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	owner := CreateRandomUser(t)
+	for i := 0; i < 3; i++ {
+		CreateRandomAPIKey(t, owner)
+	}
+	CreateRandomAPIKey(t, CreateRandomUser(t))
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), owner.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+
+	for _, apiKey := range apiKeys {
+		require.Equal(t, owner.Username, apiKey.Owner)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	owner := CreateRandomUser(t)
+	apiKey1 := CreateRandomAPIKey(t, owner)
+
+	// only the owner revokes the key
+	_, err := testQueries.RevokeAPIKey(context.Background(), db.RevokeAPIKeyParams{
+		ID:    apiKey1.ID,
+		Owner: CreateRandomUser(t).Username,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	apiKey2, err := testQueries.RevokeAPIKey(context.Background(), db.RevokeAPIKeyParams{
+		ID:    apiKey1.ID,
+		Owner: owner.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, apiKey2.RevokedAt.Valid)
+
+	// a revoked key stays revoked
+	_, err = testQueries.RevokeAPIKey(context.Background(), db.RevokeAPIKeyParams{
+		ID:    apiKey1.ID,
+		Owner: owner.Username,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	apiKey1 := CreateRandomAPIKey(t, CreateRandomUser(t))
+	usedAt := time.Now()
+
+	err := testQueries.TouchAPIKey(context.Background(), db.TouchAPIKeyParams{
+		ID:             apiKey1.ID,
+		LastUsedAt:     sql.NullTime{Time: usedAt, Valid: true},
+		LastUsedBefore: sql.NullTime{Time: usedAt.Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	// used again within the minute, the first use is kept
+	err = testQueries.TouchAPIKey(context.Background(), db.TouchAPIKeyParams{
+		ID:             apiKey1.ID,
+		LastUsedAt:     sql.NullTime{Time: usedAt.Add(time.Second), Valid: true},
+		LastUsedBefore: sql.NullTime{Time: usedAt.Add(time.Second - time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	apiKey2, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey1.Prefix)
+	require.NoError(t, err)
+	require.WithinDuration(t, usedAt, apiKey2.LastUsedAt.Time, time.Millisecond)
+}
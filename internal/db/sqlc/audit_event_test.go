@@ -0,0 +1,112 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditTx(t *testing.T) {
+	store := db.NewStore(testDB)
+	user := CreateRandomUser(t)
+	audit := db.AuditContext{
+		Actor:     user.Username,
+		ClientIP:  "127.0.0.1",
+		UserAgent: "test",
+	}
+
+	var account db.Accounts
+	err := store.AuditTx(context.Background(), func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		account, err = q.CreateAccount(context.Background(), db.CreateAccountParams{
+			Owner:    user.Username,
+			Currency: util.RandomCurrency(),
+		})
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "account.create",
+			Target:       fmt.Sprintf("account:%d", account.ID),
+			After:        account,
+		}, err
+	})
+	require.NoError(t, err)
+
+	first, err := testQueries.GetLastAuditEvent(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, user.Username, first.Actor)
+	require.Equal(t, "account.create", first.Action)
+	require.JSONEq(t, "null", string(first.Before))
+	// the stored event hashes to what was recorded
+	require.Equal(t, first.Hash, db.HashAuditEvent(first))
+
+	err = store.AuditTx(context.Background(), func(q db.Querier) (db.AuditEventParams, error) {
+		_, err := q.DeleteAccount(context.Background(), account.ID)
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "account.delete",
+			Target:       fmt.Sprintf("account:%d", account.ID),
+			Before:       account,
+		}, err
+	})
+	require.NoError(t, err)
+
+	second, err := testQueries.GetLastAuditEvent(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, first.Hash, second.PrevHash)
+	require.Equal(t, second.Hash, db.HashAuditEvent(second))
+
+	// a failed change records nothing
+	err = store.AuditTx(context.Background(), func(q db.Querier) (db.AuditEventParams, error) {
+		_, err := q.GetAccount(context.Background(), account.ID)
+		return db.AuditEventParams{AuditContext: audit, Action: "account.update"}, err
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	last, err := testQueries.GetLastAuditEvent(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, second.ID, last.ID)
+}
+
+func TestAuditTxConcurrent(t *testing.T) {
+	store := db.NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- store.AuditTx(context.Background(), func(q db.Querier) (db.AuditEventParams, error) {
+				return db.AuditEventParams{
+					AuditContext: db.AuditContext{Actor: user.Username},
+					Action:       "test",
+					Target:       "user:" + user.Username,
+				}, nil
+			})
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// every event links to a different previous one
+	last, err := testQueries.GetLastAuditEvent(context.Background())
+	require.NoError(t, err)
+
+	events, err := testQueries.ListAuditEvents(context.Background(), db.ListAuditEventsParams{
+		ID:    last.ID - int64(n),
+		Limit: int32(n),
+	})
+	require.NoError(t, err)
+
+	prevHashes := make(map[string]bool)
+	for _, event := range events {
+		require.False(t, prevHashes[event.PrevHash])
+		prevHashes[event.PrevHash] = true
+	}
+}
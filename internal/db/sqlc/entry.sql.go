@@ -7,10 +7,12 @@ package db
 
 import (
 	"context"
+
+	"github.com/lib/pq"
 )
 
 const createEntry = `-- name: CreateEntry :one
-INSERT INTO entries (account_id, amount) VALUES ($1, $2) RETURNING id, account_id, amount, created_at, updated_at
+INSERT INTO entries (account_id, amount) VALUES ($1, $2) RETURNING id, account_id, amount, created_at, updated_at, txid
 `
 
 type CreateEntryParams struct {
@@ -27,12 +29,13 @@ func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entri
 		&i.Amount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Txid,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at, updated_at FROM entries WHERE id = $1 LIMIT 1
+SELECT id, account_id, amount, created_at, updated_at, txid FROM entries WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entries, error) {
@@ -44,12 +47,77 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entries, error) {
 		&i.Amount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Txid,
 	)
 	return i, err
 }
 
+const getEntryCursor = `-- name: GetEntryCursor :one
+SELECT (txid_snapshot_xmin(txid_current_snapshot()) - 1)::bigint
+`
+
+// the cursor of the entries every transaction still running comes after
+func (q *Queries) GetEntryCursor(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getEntryCursor)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const listEntriesAfter = `-- name: ListEntriesAfter :many
+SELECT id, account_id, amount, created_at, updated_at, txid FROM entries
+WHERE account_id = ANY($1::bigint[]) AND txid IN (
+  SELECT DISTINCT txid FROM entries
+  WHERE account_id = ANY($1::bigint[])
+    AND txid > $2
+    AND txid < txid_snapshot_xmin(txid_current_snapshot())
+  ORDER BY txid
+  LIMIT $3
+)
+ORDER BY txid, id
+`
+
+type ListEntriesAfterParams struct {
+	AccountIds        []int64 `json:"account_ids"`
+	AfterTxid         int64   `json:"after_txid"`
+	LimitTransactions int32   `json:"limit_transactions"`
+}
+
+// the entries of the first transactions after the cursor that are older than every
+// transaction still running, a transaction committing later has a txid from the
+// snapshot's xmin on, so the cursor never moves past it
+func (q *Queries) ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entries, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesAfter, pq.Array(arg.AccountIds), arg.AfterTxid, arg.LimitTransactions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entries{}
+	for rows.Next() {
+		var i Entries
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Txid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listsEntries = `-- name: ListsEntries :many
-SELECT id, account_id, amount, created_at, updated_at FROM entries WHERE account_id = $1 ORDER BY id LIMIT $2 OFFSET $3
+SELECT id, account_id, amount, created_at, updated_at, txid FROM entries WHERE account_id = $1 ORDER BY id LIMIT $2 OFFSET $3
 `
 
 type ListsEntriesParams struct {
@@ -73,6 +141,7 @@ func (q *Queries) ListsEntries(ctx context.Context, arg ListsEntriesParams) ([]E
 			&i.Amount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Txid,
 		); err != nil {
 			return nil, err
 		}
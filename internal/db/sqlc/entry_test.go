@@ -68,3 +68,55 @@ func TestListsEntries(t *testing.T) {
 		require.Equal(t, lastEntry.AccountID, entry.AccountID)
 	}
 }
+
+func TestListEntriesAfter(t *testing.T) {
+	ctx := context.Background()
+	account := CreateRandomAccount(t)
+
+	cursor, err := testQueries.GetEntryCursor(ctx)
+	require.NoError(t, err)
+
+	// every entry is created in a transaction of its own
+	entries := make([]db.Entries, 5)
+	for i := range entries {
+		entry, err := testQueries.CreateEntry(ctx, db.CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    util.RandomInt(1, 10),
+		})
+		require.NoError(t, err)
+		require.Greater(t, entry.Txid, cursor)
+		entries[i] = entry
+	}
+
+	after, err := testQueries.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+		AccountIds:        []int64{account.ID},
+		AfterTxid:         cursor,
+		LimitTransactions: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	require.Equal(t, entries[0].ID, after[0].ID)
+	require.Equal(t, entries[1].ID, after[1].ID)
+
+	after, err = testQueries.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+		AccountIds:        []int64{account.ID},
+		AfterTxid:         after[1].Txid,
+		LimitTransactions: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, after, 3)
+	require.Equal(t, entries[2].ID, after[0].ID)
+	require.Equal(t, entries[4].ID, after[2].ID)
+
+	// a stream starting now
+	cursor, err = testQueries.GetEntryCursor(ctx)
+	require.NoError(t, err)
+
+	after, err = testQueries.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+		AccountIds:        []int64{account.ID},
+		AfterTxid:         cursor,
+		LimitTransactions: 10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, after)
+}
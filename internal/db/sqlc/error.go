@@ -0,0 +1,27 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// names of the Postgres error codes the handlers tell apart
+const (
+	ForeignKeyViolation = "foreign_key_violation"
+	UniqueViolation     = "unique_violation"
+)
+
+// ErrRecordNotFound is returned by the queries of a single row that found none
+var ErrRecordNotFound = sql.ErrNoRows
+
+// ErrorCode returns the name of the Postgres error code of err, such as
+// UniqueViolation, empty when err doesn't come from Postgres
+func ErrorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Name()
+	}
+	return ""
+}
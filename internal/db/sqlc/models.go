@@ -5,6 +5,8 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +19,35 @@ type Accounts struct {
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	IsFrozen  bool      `json:"is_frozen"`
+}
+
+type ApiKeys struct {
+	ID           int64        `json:"id"`
+	Prefix       string       `json:"prefix"`
+	HashedSecret string       `json:"hashed_secret"`
+	Name         string       `json:"name"`
+	Owner        string       `json:"owner"`
+	Scopes       []string     `json:"scopes"`
+	ExpiresAt    sql.NullTime `json:"expires_at"`
+	LastUsedAt   sql.NullTime `json:"last_used_at"`
+	RevokedAt    sql.NullTime `json:"revoked_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type AuditEvents struct {
+	ID        int64  `json:"id"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	// json instead of jsonb keeps the hashed text as it was written
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+	CreatedAt time.Time       `json:"created_at"`
 }
 
 type Entries struct {
@@ -26,6 +57,45 @@ type Entries struct {
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// the transaction that created the entry, zero before the activity streams
+	Txid int64 `json:"txid"`
+}
+
+type NotificationPreferences struct {
+	Username  string    `json:"username"`
+	Event     string    `json:"event"`
+	Channel   string    `json:"channel"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NotificationSettings struct {
+	Username            string    `json:"username"`
+	Locale              string    `json:"locale"`
+	PhoneNumber         string    `json:"phone_number"`
+	PushToken           string    `json:"push_token"`
+	LargeDebitThreshold int64     `json:"large_debit_threshold"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type Outbox struct {
+	ID           int64           `json:"id"`
+	Topic        string          `json:"topic"`
+	Payload      json.RawMessage `json:"payload"`
+	Attempts     int32           `json:"attempts"`
+	LastError    sql.NullString  `json:"last_error"`
+	AvailableAt  time.Time       `json:"available_at"`
+	PublishedAt  sql.NullTime    `json:"published_at"`
+	CreatedAt    time.Time       `json:"created_at"`
+	TraceContext json.RawMessage `json:"trace_context"`
+	RequestID    string          `json:"request_id"`
+}
+
+type PasswordHistory struct {
+	ID             int64     `json:"id"`
+	Username       string    `json:"username"`
+	HashedPassword string    `json:"hashed_password"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type Sessions struct {
@@ -49,6 +119,23 @@ type Transfers struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type UserDevices struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	UserAgent   string    `json:"user_agent"`
+	ClientIp    string    `json:"client_ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+type UserIdentities struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Users struct {
 	Username          string    `json:"username"`
 	HashedPassword    string    `json:"hashed_password"`
@@ -57,4 +144,29 @@ type Users struct {
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
+	Role              string    `json:"role"`
+}
+
+type WebhookDeliveries struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	ResponseStatus sql.NullInt32   `json:"response_status"`
+	LastError      sql.NullString  `json:"last_error"`
+	NextAttemptAt  sql.NullTime    `json:"next_attempt_at"`
+	DeliveredAt    sql.NullTime    `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type WebhookSubscriptions struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret"`
+	CreatedAt  time.Time `json:"created_at"`
 }
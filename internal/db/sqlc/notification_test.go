@@ -0,0 +1,115 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertNotificationSettings(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	_, err := testQueries.GetNotificationSettings(context.Background(), user.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	arg := db.UpsertNotificationSettingsParams{
+		Username:            user.Username,
+		Locale:              "id",
+		PhoneNumber:         "+628123456789",
+		LargeDebitThreshold: 5000,
+	}
+	settings, err := testQueries.UpsertNotificationSettings(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, settings.Username)
+	require.Equal(t, arg.Locale, settings.Locale)
+	require.Equal(t, arg.PhoneNumber, settings.PhoneNumber)
+	require.Empty(t, settings.PushToken)
+	require.Equal(t, arg.LargeDebitThreshold, settings.LargeDebitThreshold)
+
+	arg.Locale = "en"
+	arg.PushToken = "device-token"
+	updated, err := testQueries.UpsertNotificationSettings(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, "en", updated.Locale)
+	require.Equal(t, "device-token", updated.PushToken)
+
+	getSettings, err := testQueries.GetNotificationSettings(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, updated, getSettings)
+}
+
+func TestUpsertNotificationPreference(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	preferences, err := testQueries.ListNotificationPreferences(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, preferences)
+
+	for _, enabled := range []bool{false, true, false} {
+		preference, err := testQueries.UpsertNotificationPreference(context.Background(), db.UpsertNotificationPreferenceParams{
+			Username: user.Username,
+			Event:    "large_debit",
+			Channel:  "sms",
+			Enabled:  enabled,
+		})
+		require.NoError(t, err)
+		require.Equal(t, enabled, preference.Enabled)
+	}
+
+	_, err = testQueries.UpsertNotificationPreference(context.Background(), db.UpsertNotificationPreferenceParams{
+		Username: user.Username,
+		Event:    "large_debit",
+		Channel:  "email",
+		Enabled:  true,
+	})
+	require.NoError(t, err)
+
+	preferences, err = testQueries.ListNotificationPreferences(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, preferences, 2)
+	require.Equal(t, "email", preferences[0].Channel)
+	require.True(t, preferences[0].Enabled)
+	require.Equal(t, "sms", preferences[1].Channel)
+	require.False(t, preferences[1].Enabled)
+}
+
+func TestCreateUserDevice(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	arg := db.CreateUserDeviceParams{
+		Username:  user.Username,
+		UserAgent: "curl/8.0",
+		ClientIp:  "10.0.0.1",
+	}
+	device, err := testQueries.CreateUserDevice(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, device.ID)
+	require.Equal(t, arg.UserAgent, device.UserAgent)
+	require.Equal(t, arg.ClientIp, device.ClientIp)
+	require.Equal(t, device.FirstSeenAt, device.LastSeenAt)
+
+	// a known device isn't created again
+	_, err = testQueries.CreateUserDevice(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	touched, err := testQueries.TouchUserDevice(context.Background(), db.TouchUserDeviceParams{
+		Username:  user.Username,
+		UserAgent: arg.UserAgent,
+		ClientIp:  "10.0.0.2",
+	})
+	require.NoError(t, err)
+	require.Equal(t, device.ID, touched.ID)
+	require.Equal(t, "10.0.0.2", touched.ClientIp)
+	require.Equal(t, device.FirstSeenAt, touched.FirstSeenAt)
+
+	arg.UserAgent = "Mozilla/5.0"
+	_, err = testQueries.CreateUserDevice(context.Background(), arg)
+	require.NoError(t, err)
+
+	devices, err := testQueries.CountUserDevices(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), devices)
+}
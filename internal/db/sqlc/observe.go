@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// QueryObserver is called before a query runs with the sqlc name of the query,
+// or "raw" for a query written by hand. The returned done is called once the
+// query ran, err is always nil for a query returning a single row since its
+// error is only known when the row is scanned.
+type QueryObserver func(ctx context.Context, name string) (done func(err error))
+
+// observedDBTX tells the observers about the queries sent through it. A query
+// returning rows is done once its first row is ready, not once the rows are read.
+type observedDBTX struct {
+	db        DBTX
+	observers []QueryObserver
+}
+
+func newObservedDBTX(db DBTX, observers []QueryObserver) DBTX {
+	return &observedDBTX{db: db, observers: observers}
+}
+
+// txDBTX observes the queries of a transaction when the store is observed
+func (store *SQLStore) txDBTX(tx *sql.Tx) DBTX {
+	if len(store.observers) == 0 {
+		return tx
+	}
+	return newObservedDBTX(tx, store.observers)
+}
+
+func (o *observedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	done := o.start(ctx, query)
+	result, err := o.db.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+func (o *observedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return o.db.PrepareContext(ctx, query)
+}
+
+func (o *observedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	done := o.start(ctx, query)
+	rows, err := o.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (o *observedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	done := o.start(ctx, query)
+	row := o.db.QueryRowContext(ctx, query, args...)
+	done(nil)
+	return row
+}
+
+func (o *observedDBTX) start(ctx context.Context, query string) func(err error) {
+	name := queryName(query)
+	dones := make([]func(err error), len(o.observers))
+	for i, observe := range o.observers {
+		dones[i] = observe(ctx, name)
+	}
+
+	return func(err error) {
+		for _, done := range dones {
+			done(err)
+		}
+	}
+}
+
+// queryName reads the name sqlc puts in the first line of a query
+func queryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "raw"
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(query, prefix))
+	if len(fields) == 0 {
+		return "raw"
+	}
+	return fields[0]
+}
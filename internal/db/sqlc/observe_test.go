@@ -0,0 +1,43 @@
+package db_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservedStore(t *testing.T) {
+	var mu sync.Mutex
+	observed := make(map[string]int)
+	store := db.NewObservedStore(testDB, func(ctx context.Context, name string) func(err error) {
+		return func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			observed[name]++
+		}
+	})
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	_, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+
+	// the queries of a transaction are observed too
+	_, err = store.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, observed["GetAccount"])
+	require.Equal(t, 1, observed["CreateTransfer"])
+	require.Equal(t, 2, observed["CreateEntry"])
+	require.Equal(t, 2, observed["AddAccountBalance"])
+}
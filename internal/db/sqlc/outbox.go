@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/tracing"
+)
+
+// WriteOutboxEvent writes an event with the trace context and the request id of ctx, the
+// task of the event continues the trace and the logs of the request that wrote it even
+// when it is relayed much later
+func WriteOutboxEvent(ctx context.Context, q Querier, arg CreateOutboxEventParams) (Outbox, error) {
+	arg.RequestID = logging.RequestID(ctx)
+	if carrier := tracing.Inject(ctx); len(carrier) > 0 {
+		traceContext, err := json.Marshal(carrier)
+		if err != nil {
+			return Outbox{}, err
+		}
+		arg.TraceContext = traceContext
+	}
+	return q.CreateOutboxEvent(ctx, arg)
+}
@@ -0,0 +1,219 @@
+package db_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// drainOutbox publishes every pending event so a test only sees its own
+func drainOutbox(t *testing.T, store db.Store) {
+	for {
+		result, err := store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+			Limit:   100,
+			Publish: func(ctx context.Context, event db.Outbox) error { return nil },
+		})
+		require.NoError(t, err)
+		if result.Published == 0 {
+			return
+		}
+	}
+}
+
+func TestCreateUserTxOutbox(t *testing.T) {
+	store := db.NewStore(testDB)
+	drainOutbox(t, store)
+
+	hashedPassword, err := util.HashingPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		Events: []db.CreateOutboxEventParams{
+			{Topic: "test:verify_email", Payload: json.RawMessage(`{"email":"user@email.com"}`)},
+		},
+	}
+
+	_, err = store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// a rolled back user leaves no event behind
+	_, err = store.CreateUserTx(context.Background(), arg)
+	require.Error(t, err)
+
+	var published []db.Outbox
+	result, err := store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+		Limit: 100,
+		Publish: func(ctx context.Context, event db.Outbox) error {
+			published = append(published, event)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Published)
+	require.Len(t, published, 1)
+	require.Equal(t, "test:verify_email", published[0].Topic)
+	require.JSONEq(t, `{"email":"user@email.com"}`, string(published[0].Payload))
+}
+
+func TestRelayOutboxTxRetry(t *testing.T) {
+	store := db.NewStore(testDB)
+	drainOutbox(t, store)
+
+	event, err := testQueries.CreateOutboxEvent(context.Background(), db.CreateOutboxEventParams{
+		Topic:   "test:retry",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	failing := db.RelayOutboxTxParams{
+		Limit:      100,
+		Publish:    func(ctx context.Context, event db.Outbox) error { return errors.New("sink is down") },
+		RetryDelay: func(attempts int32) time.Duration { return 0 },
+	}
+	result, err := store.RelayOutboxTx(context.Background(), failing)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Failed)
+
+	// the event is published by a later relay
+	var published []db.Outbox
+	result, err = store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+		Limit: 100,
+		Publish: func(ctx context.Context, event db.Outbox) error {
+			published = append(published, event)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Published)
+	require.Equal(t, event.ID, published[0].ID)
+	require.Equal(t, int32(1), published[0].Attempts)
+	require.Equal(t, "sink is down", published[0].LastError.String)
+
+	// a failed event waits for its retry delay
+	_, err = testQueries.CreateOutboxEvent(context.Background(), db.CreateOutboxEventParams{
+		Topic:   "test:retry",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	failing.RetryDelay = func(attempts int32) time.Duration { return time.Hour }
+	_, err = store.RelayOutboxTx(context.Background(), failing)
+	require.NoError(t, err)
+
+	result, err = store.RelayOutboxTx(context.Background(), failing)
+	require.NoError(t, err)
+	require.Zero(t, result.Published+result.Failed)
+}
+
+func TestRelayOutboxTxLease(t *testing.T) {
+	store := db.NewStore(testDB)
+	drainOutbox(t, store)
+
+	event, err := testQueries.CreateOutboxEvent(context.Background(), db.CreateOutboxEventParams{
+		Topic:   "test:lease",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	result, err := store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+		Limit:          100,
+		Lease:          time.Minute,
+		PublishTimeout: 10 * time.Millisecond,
+		Publish: func(ctx context.Context, event db.Outbox) error {
+			// the claim is committed, another relay skips the leased event without waiting
+			result, err := store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+				Limit:   100,
+				Lease:   time.Minute,
+				Publish: func(ctx context.Context, event db.Outbox) error { return nil },
+			})
+			require.NoError(t, err)
+			require.Zero(t, result.Published+result.Failed)
+
+			// a sink slower than the publish timeout fails the event
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		RetryDelay: func(attempts int32) time.Duration { return 0 },
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Failed)
+
+	var published []db.Outbox
+	result, err = store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+		Limit: 100,
+		Lease: time.Minute,
+		Publish: func(ctx context.Context, event db.Outbox) error {
+			published = append(published, event)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Published)
+	require.Equal(t, event.ID, published[0].ID)
+	require.Equal(t, context.DeadlineExceeded.Error(), published[0].LastError.String)
+}
+
+func TestWriteOutboxEventTraceContext(t *testing.T) {
+	// without a trace the event has an empty trace context
+	event, err := db.WriteOutboxEvent(context.Background(), testQueries, db.CreateOutboxEventParams{
+		Topic:   "test:untraced",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(event.TraceContext))
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	event, err = db.WriteOutboxEvent(ctx, testQueries, db.CreateOutboxEventParams{
+		Topic:   "test:traced",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	var carrier map[string]string
+	require.NoError(t, json.Unmarshal(event.TraceContext, &carrier))
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", carrier["traceparent"])
+}
+
+func TestWriteOutboxEventRequestID(t *testing.T) {
+	event, err := db.WriteOutboxEvent(context.Background(), testQueries, db.CreateOutboxEventParams{
+		Topic:   "test:background",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+	require.Empty(t, event.RequestID)
+
+	ctx := logging.WithRequestID(context.Background(), "request-1")
+	event, err = db.WriteOutboxEvent(ctx, testQueries, db.CreateOutboxEventParams{
+		Topic:   "test:request",
+		Payload: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "request-1", event.RequestID)
+}
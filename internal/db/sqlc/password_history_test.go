@@ -0,0 +1,84 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func updatePassword(t *testing.T, store db.Store, username string, historySize int32) (db.Users, []string) {
+	hashedPassword, err := util.HashingPassword(util.RandomString(12))
+	require.NoError(t, err)
+
+	var hashedPasswords []string
+	result, err := store.UpdatePasswordTx(context.Background(), db.UpdatePasswordTxParams{
+		Username:       username,
+		HashedPassword: hashedPassword,
+		HistorySize:    historySize,
+		BeforeUpdate: func(previous []string) error {
+			hashedPasswords = previous
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	return result.User, hashedPasswords
+}
+
+func TestUpdatePasswordTx(t *testing.T) {
+	store := db.NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	// the first change only sees the current password
+	updated, hashedPasswords := updatePassword(t, store, user.Username, 2)
+	require.Equal(t, []string{user.HashedPassword}, hashedPasswords)
+	require.True(t, updated.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	second, hashedPasswords := updatePassword(t, store, user.Username, 2)
+	require.Equal(t, []string{updated.HashedPassword, user.HashedPassword}, hashedPasswords)
+
+	// the history keeps the two newest previous passwords
+	_, hashedPasswords = updatePassword(t, store, user.Username, 2)
+	require.Equal(t, []string{second.HashedPassword, updated.HashedPassword, user.HashedPassword}, hashedPasswords)
+
+	history, err := testQueries.ListPasswordHistory(context.Background(), db.ListPasswordHistoryParams{
+		Username: user.Username,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, second.HashedPassword, history[0].HashedPassword)
+	require.Equal(t, updated.HashedPassword, history[1].HashedPassword)
+}
+
+func TestUpdatePasswordTxRejected(t *testing.T) {
+	store := db.NewStore(testDB)
+	user := CreateRandomUser(t)
+	errReused := errors.New("reused password")
+
+	_, err := store.UpdatePasswordTx(context.Background(), db.UpdatePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: "rejected",
+		HistorySize:    5,
+		BeforeUpdate: func(hashedPasswords []string) error {
+			return errReused
+		},
+	})
+	require.ErrorIs(t, err, errReused)
+
+	// neither the password nor the history changed
+	user2, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, user2.HashedPassword)
+
+	history, err := testQueries.ListPasswordHistory(context.Background(), db.ListPasswordHistoryParams{
+		Username: user.Username,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
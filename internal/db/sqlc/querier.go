@@ -6,36 +6,89 @@ package db
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Accounts, error)
+	BlockSession(ctx context.Context, id uuid.UUID) (Sessions, error)
+	BlockUserSessions(ctx context.Context, email string) ([]Sessions, error)
+	// leases the oldest pending events until leased_until, other relays skip them
+	// while they are published outside of any transaction
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	CountUserDevices(ctx context.Context, username string) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKeys, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Accounts, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvents, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entries, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreatePasswordHistory(ctx context.Context, arg CreatePasswordHistoryParams) (PasswordHistory, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Sessions, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfers, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (Users, error)
-	DeleteAccount(ctx context.Context, id int64) error
+	CreateUserDevice(ctx context.Context, arg CreateUserDeviceParams) (UserDevices, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentities, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDeliveries, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscriptions, error)
+	DeleteAccount(ctx context.Context, id int64) (Accounts, error)
 	DeleteAllAccount(ctx context.Context) error
+	DeletePublishedOutboxEvents(ctx context.Context, publishedAt sql.NullTime) error
+	DeleteWebhookSubscription(ctx context.Context, arg DeleteWebhookSubscriptionParams) (WebhookSubscriptions, error)
+	FreezeAccount(ctx context.Context, arg FreezeAccountParams) (Accounts, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKeys, error)
 	GetAccount(ctx context.Context, id int64) (Accounts, error)
 	GetEntry(ctx context.Context, id int64) (Entries, error)
+	// the cursor of the entries every transaction still running comes after
+	GetEntryCursor(ctx context.Context) (int64, error)
+	GetLastAuditEvent(ctx context.Context) (AuditEvents, error)
 	GetListsTransfers(ctx context.Context, arg GetListsTransfersParams) ([]Transfers, error)
+	GetNotificationSettings(ctx context.Context, username string) (NotificationSettings, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Sessions, error)
 	GetTotalPageListsAccounts(ctx context.Context, owner string) (int64, error)
 	GetTotalPageListsTransfers(ctx context.Context) (int64, error)
 	GetTotalPageListsTransfersSpesific(ctx context.Context, fromAccountID int64) (int64, error)
+	GetTotalPageListsUsers(ctx context.Context) (int64, error)
 	GetTransferByFromAccountId(ctx context.Context, fromAccountID int64) (Transfers, error)
 	GetTransferById(ctx context.Context, id int64) (Transfers, error)
 	GetTransferByToAccountId(ctx context.Context, toAccountID int64) (Transfers, error)
 	GetUser(ctx context.Context, username string) (Users, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentities, error)
 	GetUserUsingEmail(ctx context.Context, email string) (Users, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDeliveries, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscriptions, error)
+	ListAPIKeys(ctx context.Context, owner string) ([]ApiKeys, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvents, error)
+	// the entries of the first transactions after the cursor that are older than every
+	// transaction still running, a transaction committing later has a txid from the
+	// snapshot's xmin on, so the cursor never moves past it
+	ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entries, error)
+	ListNotificationPreferences(ctx context.Context, username string) ([]NotificationPreferences, error)
+	ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]PasswordHistory, error)
+	ListUsers(ctx context.Context, arg ListUsersParams) ([]Users, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDeliveries, error)
+	ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscriptions, error)
+	ListWebhookSubscriptionsForEvent(ctx context.Context, arg ListWebhookSubscriptionsForEventParams) ([]WebhookSubscriptions, error)
 	ListsAccounts(ctx context.Context, arg ListsAccountsParams) ([]Accounts, error)
 	ListsEntries(ctx context.Context, arg ListsEntriesParams) ([]Entries, error)
 	ListsTransfers(ctx context.Context, arg ListsTransfersParams) ([]Transfers, error)
+	LockAuditChain(ctx context.Context) error
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) (WebhookDeliveries, error)
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) (WebhookDeliveries, error)
+	NotifyAccountActivity(ctx context.Context, accountID int64) error
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (Users, error)
+	ReplayWebhookDelivery(ctx context.Context, id int64) (WebhookDeliveries, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKeys, error)
+	TouchAPIKey(ctx context.Context, arg TouchAPIKeyParams) error
+	TouchUserDevice(ctx context.Context, arg TouchUserDeviceParams) (UserDevices, error)
+	TrimPasswordHistory(ctx context.Context, arg TrimPasswordHistoryParams) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Accounts, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (Users, error)
+	UpsertNotificationPreference(ctx context.Context, arg UpsertNotificationPreferenceParams) (NotificationPreferences, error)
+	UpsertNotificationSettings(ctx context.Context, arg UpsertNotificationSettingsParams) (NotificationSettings, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -12,6 +12,68 @@ import (
 	"github.com/google/uuid"
 )
 
+const blockSession = `-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+RETURNING id, email, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
+`
+
+func (q *Queries) BlockSession(ctx context.Context, id uuid.UUID) (Sessions, error) {
+	row := q.db.QueryRowContext(ctx, blockSession, id)
+	var i Sessions
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.RefreshToken,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.IsBlocked,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const blockUserSessions = `-- name: BlockUserSessions :many
+UPDATE sessions
+SET is_blocked = true
+WHERE email = $1 AND is_blocked = false
+RETURNING id, email, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
+`
+
+func (q *Queries) BlockUserSessions(ctx context.Context, email string) ([]Sessions, error) {
+	rows, err := q.db.QueryContext(ctx, blockUserSessions, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Sessions{}
+	for rows.Next() {
+		var i Sessions
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.RefreshToken,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.IsBlocked,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
   id,
@@ -10,11 +10,17 @@ type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
 	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	LinkUserIdentityTx(ctx context.Context, arg LinkUserIdentityTxParams) (LinkUserIdentityTxResult, error)
+	UpdatePasswordTx(ctx context.Context, arg UpdatePasswordTxParams) (UpdatePasswordTxResult, error)
+	AuditTx(ctx context.Context, fn func(q Querier) (AuditEventParams, error)) error
+	RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (RelayOutboxTxResult, error)
+	DispatchWebhookTx(ctx context.Context, arg DispatchWebhookTxParams) (DispatchWebhookTxResult, error)
 }
 
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db        *sql.DB
+	observers []QueryObserver
 }
 
 // NewStore creates a new store
@@ -25,6 +31,16 @@ func NewStore(db *sql.DB) Store {
 	}
 }
 
+// NewObservedStore creates a new store telling the observers about every query,
+// including the queries of the transactions
+func NewObservedStore(db *sql.DB, observers ...QueryObserver) Store {
+	return &SQLStore{
+		db:        db,
+		Queries:   New(newObservedDBTX(db, observers)),
+		observers: observers,
+	}
+}
+
 // execTx or rollback database transaction
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 	tx, err := store.db.BeginTx(ctx, nil)
@@ -34,13 +50,13 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	}
 
 	// creating new query
-	q := New(tx)
+	q := New(store.txDBTX(tx))
 	// checking query
 	err = fn(q)
 
 	if err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx error: %v, rb err: %v", err, rbErr)
+			return fmt.Errorf("tx error: %w, rb err: %v", err, rbErr)
 		}
 		return err
 	}
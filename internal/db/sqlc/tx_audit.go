@@ -0,0 +1,260 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditContext tells who made a change and from where
+type AuditContext struct {
+	Actor     string
+	ClientIP  string
+	UserAgent string
+}
+
+// AuditEventParams describes one change, Before and After are snapshots
+// encoded as JSON, nil when there is nothing before or after the change
+type AuditEventParams struct {
+	AuditContext
+	Action string
+	Target string
+	Before interface{}
+	After  interface{}
+}
+
+// AuditTx runs fn in a transaction and records the event it returns in the same transaction,
+// nothing is changed when fn fails
+func (store *SQLStore) AuditTx(ctx context.Context, fn func(q Querier) (AuditEventParams, error)) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		event, err := fn(q)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, event)
+		return err
+	})
+}
+
+// recordAuditEvent appends an event to the hash chain. Events are recorded at
+// the end of a transaction, the chain stays locked until the commit.
+func recordAuditEvent(ctx context.Context, q *Queries, arg AuditEventParams) (AuditEvents, error) {
+	before, err := json.Marshal(arg.Before)
+	if err != nil {
+		return AuditEvents{}, fmt.Errorf("cannot encode audit snapshot: %w", err)
+	}
+
+	after, err := json.Marshal(arg.After)
+	if err != nil {
+		return AuditEvents{}, fmt.Errorf("cannot encode audit snapshot: %w", err)
+	}
+
+	// concurrent transactions would link their events to the same previous one
+	if err = q.LockAuditChain(ctx); err != nil {
+		return AuditEvents{}, err
+	}
+
+	var prevHash string
+	last, err := q.GetLastAuditEvent(ctx)
+	switch {
+	case err == nil:
+		prevHash = last.Hash
+	case err != sql.ErrNoRows:
+		return AuditEvents{}, err
+	}
+
+	event := AuditEvents{
+		Actor:     arg.Actor,
+		Action:    arg.Action,
+		Target:    arg.Target,
+		ClientIp:  arg.ClientIP,
+		UserAgent: arg.UserAgent,
+		Before:    before,
+		After:     after,
+		PrevHash:  prevHash,
+		// postgres keeps microseconds
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	return q.CreateAuditEvent(ctx, CreateAuditEventParams{
+		Actor:     event.Actor,
+		Action:    event.Action,
+		Target:    event.Target,
+		ClientIp:  event.ClientIp,
+		UserAgent: event.UserAgent,
+		Before:    event.Before,
+		After:     event.After,
+		PrevHash:  event.PrevHash,
+		Hash:      HashAuditEvent(event),
+		CreatedAt: event.CreatedAt,
+	})
+}
+
+// HashAuditEvent returns the SHA-256 of every field of an event but its id and hash,
+// the previous hash links it to the event before
+func HashAuditEvent(event AuditEvents) string {
+	// a JSON array keeps the fields apart whatever they contain
+	data, _ := json.Marshal([]string{
+		event.PrevHash,
+		event.Actor,
+		event.Action,
+		event.Target,
+		event.ClientIp,
+		event.UserAgent,
+		string(event.Before),
+		string(event.After),
+		event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditUser is the snapshot of a user without the password hash
+type AuditUser struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+func NewAuditUser(user Users) AuditUser {
+	return AuditUser{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		PasswordChangedAt: user.PasswordChangedAt,
+	}
+}
+
+// AuditSession is the snapshot of a session without the refresh token
+type AuditSession struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewAuditSession(session Sessions) AuditSession {
+	return AuditSession{
+		ID:        session.ID.String(),
+		Email:     session.Email,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// AuditAPIKey is the snapshot of an API key without the hashed secret
+type AuditAPIKey struct {
+	ID        int64      `json:"id"`
+	Prefix    string     `json:"prefix"`
+	Name      string     `json:"name"`
+	Owner     string     `json:"owner"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+func NewAuditAPIKey(apiKey ApiKeys) AuditAPIKey {
+	return AuditAPIKey{
+		ID:        apiKey.ID,
+		Prefix:    apiKey.Prefix,
+		Name:      apiKey.Name,
+		Owner:     apiKey.Owner,
+		Scopes:    apiKey.Scopes,
+		ExpiresAt: auditTime(apiKey.ExpiresAt),
+		RevokedAt: auditTime(apiKey.RevokedAt),
+	}
+}
+
+func auditTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// AuditWebhookSubscription is the snapshot of a webhook subscription without its secret
+type AuditWebhookSubscription struct {
+	ID         int64    `json:"id"`
+	Owner      string   `json:"owner"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func NewAuditWebhookSubscription(subscription WebhookSubscriptions) AuditWebhookSubscription {
+	return AuditWebhookSubscription{
+		ID:         subscription.ID,
+		Owner:      subscription.Owner,
+		Url:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+	}
+}
+
+// AuditWebhookDelivery is the snapshot of a webhook delivery without its payload
+type AuditWebhookDelivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID int64  `json:"subscription_id"`
+	EventID        string `json:"event_id"`
+	EventType      string `json:"event_type"`
+	Status         string `json:"status"`
+	Attempts       int32  `json:"attempts"`
+}
+
+func NewAuditWebhookDelivery(delivery WebhookDeliveries) AuditWebhookDelivery {
+	return AuditWebhookDelivery{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+	}
+}
+
+// AuditNotificationSettings is the snapshot of notification settings telling only
+// whether a push token is set
+type AuditNotificationSettings struct {
+	Username            string                        `json:"username"`
+	Locale              string                        `json:"locale"`
+	PhoneNumber         string                        `json:"phone_number"`
+	HasPushToken        bool                          `json:"has_push_token"`
+	LargeDebitThreshold int64                         `json:"large_debit_threshold"`
+	Preferences         []AuditNotificationPreference `json:"preferences"`
+}
+
+type AuditNotificationPreference struct {
+	Event   string `json:"event"`
+	Channel string `json:"channel"`
+	Enabled bool   `json:"enabled"`
+}
+
+func NewAuditNotificationSettings(settings NotificationSettings, preferences []NotificationPreferences) AuditNotificationSettings {
+	res := AuditNotificationSettings{
+		Username:            settings.Username,
+		Locale:              settings.Locale,
+		PhoneNumber:         settings.PhoneNumber,
+		HasPushToken:        settings.PushToken != "",
+		LargeDebitThreshold: settings.LargeDebitThreshold,
+		Preferences:         []AuditNotificationPreference{},
+	}
+	for _, preference := range preferences {
+		res.Preferences = append(res.Preferences, AuditNotificationPreference{
+			Event:   preference.Event,
+			Channel: preference.Channel,
+			Enabled: preference.Enabled,
+		})
+	}
+	return res
+}
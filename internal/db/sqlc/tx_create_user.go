@@ -4,7 +4,9 @@ import "context"
 
 type CreateUserTxParams struct {
 	CreateUserParams
-	AfterCreate func(user Users) error
+	// published by the outbox relay once the user is committed, such as the verification email
+	Events []CreateOutboxEventParams
+	Audit  AuditContext
 }
 
 type CreateUserTxResult struct {
@@ -22,7 +24,19 @@ func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams)
 			return err
 		}
 
-		return arg.AfterCreate(result.User)
+		for _, event := range arg.Events {
+			if _, err = WriteOutboxEvent(ctx, q, event); err != nil {
+				return err
+			}
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "user.create",
+			Target:       "user:" + result.User.Username,
+			After:        NewAuditUser(result.User),
+		})
+		return err
 	})
 
 	return result, err
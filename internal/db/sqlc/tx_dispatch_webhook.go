@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// DispatchWebhookTxParams is one event of an owner, delivered to every
+// subscription of the owner to its type
+type DispatchWebhookTxParams struct {
+	Owner     string
+	EventID   string
+	EventType string
+	Payload   json.RawMessage
+	// NewTask creates the outbox event of the task making a new delivery
+	NewTask func(delivery WebhookDeliveries) (CreateOutboxEventParams, error)
+}
+
+type DispatchWebhookTxResult struct {
+	Deliveries []WebhookDeliveries
+}
+
+// DispatchWebhookTx creates the deliveries of an event with the tasks making them,
+// an event dispatched again only creates the deliveries it is missing
+func (store *SQLStore) DispatchWebhookTx(ctx context.Context, arg DispatchWebhookTxParams) (DispatchWebhookTxResult, error) {
+	var result DispatchWebhookTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		subscriptions, err := q.ListWebhookSubscriptionsForEvent(ctx, ListWebhookSubscriptionsForEventParams{
+			Owner:     arg.Owner,
+			EventType: arg.EventType,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, subscription := range subscriptions {
+			delivery, err := q.CreateWebhookDelivery(ctx, CreateWebhookDeliveryParams{
+				SubscriptionID: subscription.ID,
+				EventID:        arg.EventID,
+				EventType:      arg.EventType,
+				Payload:        arg.Payload,
+			})
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			task, err := arg.NewTask(delivery)
+			if err != nil {
+				return err
+			}
+
+			if _, err = WriteOutboxEvent(ctx, q, task); err != nil {
+				return err
+			}
+			result.Deliveries = append(result.Deliveries, delivery)
+		}
+		return nil
+	})
+
+	return result, err
+}
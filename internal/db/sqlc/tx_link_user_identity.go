@@ -0,0 +1,62 @@
+package db
+
+import "context"
+
+type LinkUserIdentityTxParams struct {
+	Identity CreateUserIdentityParams
+	// creates the user first when the identity belongs to a new user
+	NewUser *CreateUserParams
+	Audit   AuditContext
+}
+
+type LinkUserIdentityTxResult struct {
+	User     Users
+	Identity UserIdentities
+}
+
+// LinkUserIdentityTx links an external identity to an existing user, or to a new user created with it
+func (store *SQLStore) LinkUserIdentityTx(ctx context.Context, arg LinkUserIdentityTxParams) (LinkUserIdentityTxResult, error) {
+	var result LinkUserIdentityTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		if arg.NewUser != nil {
+			result.User, err = q.CreateUser(ctx, *arg.NewUser)
+		} else {
+			result.User, err = q.GetUser(ctx, arg.Identity.Username)
+		}
+		if err != nil {
+			return err
+		}
+
+		identity := arg.Identity
+		identity.Username = result.User.Username
+		result.Identity, err = q.CreateUserIdentity(ctx, identity)
+		if err != nil {
+			return err
+		}
+
+		if arg.NewUser != nil {
+			_, err = recordAuditEvent(ctx, q, AuditEventParams{
+				AuditContext: arg.Audit,
+				Action:       "user.create",
+				Target:       "user:" + result.User.Username,
+				After:        NewAuditUser(result.User),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "user_identity.link",
+			Target:       "user:" + result.User.Username,
+			After:        result.Identity,
+		})
+		return err
+	})
+
+	return result, err
+}
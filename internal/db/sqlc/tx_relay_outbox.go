@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type RelayOutboxTxParams struct {
+	// how many pending events are claimed at once
+	Limit int32
+	// Lease is how long the claimed events are hidden from other relays, an event
+	// whose relay stops before marking it is published again once its lease expires
+	Lease time.Duration
+	// PublishTimeout bounds every call to Publish, zero leaves it unbounded
+	PublishTimeout time.Duration
+	// Publish hands an event to its sink, an error leaves the event pending
+	Publish func(ctx context.Context, event Outbox) error
+	// RetryDelay is how long a failed event waits after its attempts so far
+	RetryDelay func(attempts int32) time.Duration
+}
+
+type RelayOutboxTxResult struct {
+	Published int
+	Failed    int
+}
+
+// relayOutcome is the result of publishing one claimed event
+type relayOutcome struct {
+	event Outbox
+	err   error
+}
+
+// RelayOutboxTx publishes the pending outbox events, oldest first. The events are
+// leased and committed before they are published, so no transaction stays open while
+// a sink is slow, and the outcomes are marked in a second short transaction. An event
+// may be published again when its outcome cannot be marked.
+func (store *SQLStore) RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (RelayOutboxTxResult, error) {
+	var result RelayOutboxTxResult
+
+	events, err := store.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+		BatchSize:   arg.Limit,
+		LeasedUntil: time.Now().Add(arg.Lease),
+	})
+	if err != nil || len(events) == 0 {
+		return result, err
+	}
+
+	outcomes := make([]relayOutcome, 0, len(events))
+	for _, event := range events {
+		outcomes = append(outcomes, relayOutcome{event: event, err: publishOutboxEvent(ctx, arg, event)})
+	}
+
+	err = store.execTx(ctx, func(q *Queries) error {
+		for _, outcome := range outcomes {
+			if outcome.err == nil {
+				if err := q.MarkOutboxEventPublished(ctx, outcome.event.ID); err != nil {
+					return err
+				}
+				continue
+			}
+
+			err := q.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+				ID: outcome.event.ID,
+				LastError: sql.NullString{
+					String: outcome.err.Error(),
+					Valid:  true,
+				},
+				AvailableAt: time.Now().Add(arg.RetryDelay(outcome.event.Attempts + 1)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, outcome := range outcomes {
+		if outcome.err == nil {
+			result.Published++
+		} else {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// publishOutboxEvent publishes one event within the publish timeout
+func publishOutboxEvent(ctx context.Context, arg RelayOutboxTxParams, event Outbox) error {
+	if arg.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, arg.PublishTimeout)
+		defer cancel()
+	}
+	return arg.Publish(ctx, event)
+}
@@ -1,12 +1,30 @@
 package db
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FrozenAccountError is returned by TransferTx when an account of the transfer is frozen,
+// the balance of a frozen account is never changed even if it was frozen after being checked
+type FrozenAccountError struct {
+	AccountID int64
+}
+
+func (err *FrozenAccountError) Error() string {
+	return fmt.Sprintf("account %d is frozen", err.AccountID)
+}
 
 // transfertxparams contains the input parameters of the transfer transaction
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// who made the transfer, recorded in the audit log
+	Audit AuditContext `json:"-"`
+	// Events creates the outbox events of the transfer, such as the webhooks of both owners
+	Events func(result TransferTxResult) ([]CreateOutboxEventParams, error) `json:"-"`
 }
 
 // transferTxResult contains result of the transfer transaction
@@ -24,7 +42,11 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
+		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+		})
 		if err != nil {
 			return err
 		}
@@ -53,23 +75,59 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 			//money going in from first account to second account
 			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
 		}
+		if err != nil {
+			return err
+		}
+
+		// delivered to the listeners once the transfer commits
+		for _, accountID := range []int64{arg.FromAccountID, arg.ToAccountID} {
+			if err = q.NotifyAccountActivity(ctx, accountID); err != nil {
+				return err
+			}
+		}
 
+		if arg.Events != nil {
+			events, err := arg.Events(result)
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				if _, err = WriteOutboxEvent(ctx, q, event); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "transfer.create",
+			Target:       fmt.Sprintf("transfer:%d", result.Transfer.ID),
+			After:        result,
+		})
 		return err
 	})
 	return result, err
 }
 
 func addMoney(ctx context.Context, q *Queries, accountID1, amount1 int64, accountID2, amount2 int64) (account1 Accounts, account2 Accounts, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1,
-	})
+	account1, err = addUnfrozenBalance(ctx, q, accountID1, amount1)
 	if err != nil {
 		return
 	}
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
-	})
+	account2, err = addUnfrozenBalance(ctx, q, accountID2, amount2)
 	return
 }
+
+// addUnfrozenBalance only finds no row for a frozen account,
+// the transfer and its entries reference the account
+func addUnfrozenBalance(ctx context.Context, q *Queries, accountID, amount int64) (Accounts, error) {
+	account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     accountID,
+		Amount: amount,
+	})
+	if errors.Is(err, ErrRecordNotFound) {
+		return Accounts{}, &FrozenAccountError{AccountID: accountID}
+	}
+	return account, err
+}
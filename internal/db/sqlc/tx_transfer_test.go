@@ -3,6 +3,7 @@ package db_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
 	"github.com/claytten/golang-simplebank/internal/util"
@@ -160,4 +161,86 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account2.Balance, updateAccount2.Balance)
 }
 
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := db.NewStore(testDB)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	// frozen after the service checked it, the transaction still refuses it
+	_, err := testQueries.FreezeAccount(context.Background(), db.FreezeAccountParams{
+		ID:        account2.ID,
+		UpdatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        util.RandomInt(1, 10),
+	})
+	var frozen *db.FrozenAccountError
+	require.ErrorAs(t, err, &frozen)
+	require.Equal(t, account2.ID, frozen.AccountID)
+
+	// nothing of the transfer is kept
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+}
+
+func TestTransferTxEntriesCommitOrder(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewStore(testDB)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	account3 := CreateRandomAccount(t)
+	accountIDs := []int64{account1.ID, account2.ID, account3.ID}
+
+	cursor, err := testQueries.GetEntryCursor(ctx)
+	require.NoError(t, err)
+
+	// a transaction still in flight created its entry
+	tx, err := testDB.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	entry, err := db.New(tx).CreateEntry(ctx, db.CreateEntryParams{
+		AccountID: account1.ID,
+		Amount:    10,
+	})
+	require.NoError(t, err)
+
+	// a later transfer doesn't wait for it
+	transfer, err := store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account3.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// but is only listed after it, a stream would move its cursor past the entry in flight
+	after, err := testQueries.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+		AccountIds:        accountIDs,
+		AfterTxid:         cursor,
+		LimitTransactions: 10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, after)
+
+	require.NoError(t, tx.Commit())
+
+	after, err = testQueries.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+		AccountIds:        accountIDs,
+		AfterTxid:         cursor,
+		LimitTransactions: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, after, 3)
+	require.Equal(t, entry.ID, after[0].ID)
+	require.Equal(t, transfer.FromEntry.ID, after[1].ID)
+	require.Equal(t, transfer.ToEntry.ID, after[2].ID)
+	require.Less(t, after[0].Txid, after[1].Txid)
+	require.Equal(t, after[1].Txid, after[2].Txid)
+}
+
 /** end testing normally **/
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type UpdatePasswordTxParams struct {
+	Username       string
+	HashedPassword string
+	// how many previous passwords are kept in the history, zero keeps none
+	HistorySize int32
+	// BeforeUpdate gets the current hash followed by the kept previous ones, newest first,
+	// the password is not changed when it returns an error
+	BeforeUpdate func(hashedPasswords []string) error
+	Audit        AuditContext
+}
+
+type UpdatePasswordTxResult struct {
+	User Users
+	// the sessions logged in with the old password, they are blocked
+	Sessions []Sessions
+}
+
+// UpdatePasswordTx changes the password of a user, moves the old one into the password history
+// and blocks every session of the user
+func (store *SQLStore) UpdatePasswordTx(ctx context.Context, arg UpdatePasswordTxParams) (UpdatePasswordTxResult, error) {
+	var result UpdatePasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		hashedPasswords := []string{user.HashedPassword}
+		if arg.HistorySize > 0 {
+			history, err := q.ListPasswordHistory(ctx, ListPasswordHistoryParams{
+				Username: user.Username,
+				Limit:    arg.HistorySize,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, previous := range history {
+				hashedPasswords = append(hashedPasswords, previous.HashedPassword)
+			}
+		}
+
+		if arg.BeforeUpdate != nil {
+			if err = arg.BeforeUpdate(hashedPasswords); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username: user.Username,
+			HashedPassword: sql.NullString{
+				String: arg.HashedPassword,
+				Valid:  true,
+			},
+			PasswordChangedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.HistorySize > 0 {
+			_, err = q.CreatePasswordHistory(ctx, CreatePasswordHistoryParams{
+				Username:       user.Username,
+				HashedPassword: user.HashedPassword,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = q.TrimPasswordHistory(ctx, TrimPasswordHistoryParams{
+				Username: user.Username,
+				Keep:     arg.HistorySize,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Sessions, err = q.BlockUserSessions(ctx, result.User.Email)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "user.update_password",
+			Target:       "user:" + user.Username,
+			Before:       NewAuditUser(user),
+			After:        NewAuditUser(result.User),
+		})
+		if err != nil {
+			return err
+		}
+
+		blocked := make([]AuditSession, len(result.Sessions))
+		for i, session := range result.Sessions {
+			blocked[i] = NewAuditSession(session)
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "session.block_all",
+			Target:       "user:" + user.Username,
+			After:        blocked,
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -19,7 +19,7 @@ INSERT INTO users (
   email
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, updated_at
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, updated_at, role
 `
 
 type CreateUserParams struct {
@@ -45,12 +45,24 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (Users,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
+const getTotalPageListsUsers = `-- name: GetTotalPageListsUsers :one
+SELECT COUNT(*) FROM users
+`
+
+func (q *Queries) GetTotalPageListsUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getTotalPageListsUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at, updated_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, updated_at, role FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -65,12 +77,13 @@ func (q *Queries) GetUser(ctx context.Context, username string) (Users, error) {
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getUserUsingEmail = `-- name: GetUserUsingEmail :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at, updated_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, updated_at, role FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -85,10 +98,52 @@ func (q *Queries) GetUserUsingEmail(ctx context.Context, email string) (Users, e
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
+const listUsers = `-- name: ListUsers :many
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, updated_at, role FROM users ORDER BY username LIMIT $1 OFFSET $2
+`
+
+type ListUsersParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]Users, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Users{}
+	for rows.Next() {
+		var i Users
+		if err := rows.Scan(
+			&i.Username,
+			&i.HashedPassword,
+			&i.FullName,
+			&i.Email,
+			&i.PasswordChangedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET
@@ -99,7 +154,7 @@ SET
   updated_at = $5
 WHERE
   username = $6
-RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, updated_at
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, updated_at, role
 `
 
 type UpdateUserParams struct {
@@ -129,6 +184,36 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (Users,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :one
+UPDATE users
+SET hashed_password = $1
+WHERE username = $2 AND hashed_password = $3
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, updated_at, role
+`
+
+type RehashUserPasswordParams struct {
+	NewHashedPassword string `json:"new_hashed_password"`
+	Username          string `json:"username"`
+	OldHashedPassword string `json:"old_hashed_password"`
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (Users, error) {
+	row := q.db.QueryRowContext(ctx, rehashUserPassword, arg.NewHashedPassword, arg.Username, arg.OldHashedPassword)
+	var i Users
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
@@ -0,0 +1,85 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkUserIdentityTxExistingUser(t *testing.T) {
+	store := db.NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	arg := db.LinkUserIdentityTxParams{
+		Identity: db.CreateUserIdentityParams{
+			Issuer:   "https://idp.example.com",
+			Subject:  util.RandomString(12),
+			Username: user.Username,
+			Email:    user.Email,
+		},
+	}
+
+	result, err := store.LinkUserIdentityTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, arg.Identity.Subject, result.Identity.Subject)
+	require.NotZero(t, result.Identity.CreatedAt)
+
+	identity, err := testQueries.GetUserIdentity(context.Background(), db.GetUserIdentityParams{
+		Issuer:  arg.Identity.Issuer,
+		Subject: arg.Identity.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, identity.Username)
+
+	// a subject is linked once
+	_, err = store.LinkUserIdentityTx(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestLinkUserIdentityTxNewUser(t *testing.T) {
+	store := db.NewStore(testDB)
+
+	hashedPassword, err := util.HashingPassword(util.RandomString(32))
+	require.NoError(t, err)
+
+	newUser := db.CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+
+	result, err := store.LinkUserIdentityTx(context.Background(), db.LinkUserIdentityTxParams{
+		Identity: db.CreateUserIdentityParams{
+			Issuer:  "https://idp.example.com",
+			Subject: util.RandomString(12),
+			Email:   newUser.Email,
+		},
+		NewUser: &newUser,
+	})
+	require.NoError(t, err)
+	require.Equal(t, newUser.Username, result.User.Username)
+	require.Equal(t, newUser.Username, result.Identity.Username)
+
+	// the user is rolled back with a failed link
+	another := newUser
+	another.Username = util.RandomOwner()
+	another.Email = util.RandomEmail()
+	_, err = store.LinkUserIdentityTx(context.Background(), db.LinkUserIdentityTxParams{
+		Identity: db.CreateUserIdentityParams{
+			Issuer:  result.Identity.Issuer,
+			Subject: result.Identity.Subject,
+			Email:   another.Email,
+		},
+		NewUser: &another,
+	})
+	require.Error(t, err)
+
+	_, err = testQueries.GetUser(context.Background(), another.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
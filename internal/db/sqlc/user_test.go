@@ -33,6 +33,7 @@ func CreateRandomUser(t *testing.T) db.Users {
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
 	require.True(t, user.PasswordChangedAt.IsZero())
+	require.Equal(t, util.DepositorRole, user.Role)
 
 	require.NotZero(t, user.CreatedAt)
 	require.NotZero(t, user.UpdatedAt)
@@ -186,4 +187,25 @@ func TestUpdateUserOnlyPassword(t *testing.T) {
 	require.WithinDuration(t, oldUser.UpdatedAt, user2.UpdatedAt, time.Second)
 }
 
+func TestListUsers(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		CreateRandomUser(t)
+	}
+
+	users, err := testQueries.ListUsers(context.Background(), db.ListUsersParams{
+		Limit:  5,
+		Offset: 0,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, users, 5)
+	for _, v := range users {
+		require.NotEmpty(t, v)
+	}
+
+	total, err := testQueries.GetTotalPageListsUsers(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, total, int64(5))
+}
+
 /** end testing normally **/
@@ -0,0 +1,161 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomWebhookSubscription(t *testing.T, owner db.Users, eventTypes ...string) db.WebhookSubscriptions {
+	arg := db.CreateWebhookSubscriptionParams{
+		Owner:      owner.Username,
+		Url:        "https://example.com/" + util.RandomString(8),
+		EventTypes: eventTypes,
+		Secret:     util.RandomString(32),
+	}
+
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, subscription.ID)
+	require.Equal(t, arg.Owner, subscription.Owner)
+	require.Equal(t, arg.Url, subscription.Url)
+	require.Equal(t, arg.EventTypes, subscription.EventTypes)
+	require.Equal(t, arg.Secret, subscription.Secret)
+	require.NotZero(t, subscription.CreatedAt)
+
+	return subscription
+}
+
+func TestDeleteWebhookSubscription(t *testing.T) {
+	user := CreateRandomUser(t)
+	other := CreateRandomUser(t)
+	subscription := CreateRandomWebhookSubscription(t, user, "account.created")
+
+	// only the owner deletes a subscription
+	_, err := testQueries.DeleteWebhookSubscription(context.Background(), db.DeleteWebhookSubscriptionParams{
+		ID:    subscription.ID,
+		Owner: other.Username,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleted, err := testQueries.DeleteWebhookSubscription(context.Background(), db.DeleteWebhookSubscriptionParams{
+		ID:    subscription.ID,
+		Owner: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, subscription.ID, deleted.ID)
+
+	subscriptions, err := testQueries.ListWebhookSubscriptions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, subscriptions)
+}
+
+func TestDispatchWebhookTx(t *testing.T) {
+	store := db.NewStore(testDB)
+	drainOutbox(t, store)
+
+	user := CreateRandomUser(t)
+	subscribed := CreateRandomWebhookSubscription(t, user, "transfer.received", "account.created")
+	CreateRandomWebhookSubscription(t, user, "account.created")
+	CreateRandomWebhookSubscription(t, CreateRandomUser(t), "transfer.received")
+
+	arg := db.DispatchWebhookTxParams{
+		Owner:     user.Username,
+		EventID:   util.RandomString(16),
+		EventType: "transfer.received",
+		Payload:   json.RawMessage(`{"type":"transfer.received"}`),
+		NewTask: func(delivery db.WebhookDeliveries) (db.CreateOutboxEventParams, error) {
+			return db.CreateOutboxEventParams{
+				Topic:   "test:deliver_webhook",
+				Payload: json.RawMessage(fmt.Sprintf(`{"delivery_id":%d}`, delivery.ID)),
+			}, nil
+		},
+	}
+
+	result, err := store.DispatchWebhookTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, result.Deliveries, 1)
+
+	delivery := result.Deliveries[0]
+	require.Equal(t, subscribed.ID, delivery.SubscriptionID)
+	require.Equal(t, arg.EventID, delivery.EventID)
+	require.Equal(t, "pending", delivery.Status)
+	require.Zero(t, delivery.Attempts)
+	require.JSONEq(t, string(arg.Payload), string(delivery.Payload))
+
+	// an event published twice by the relay is delivered once
+	result, err = store.DispatchWebhookTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, result.Deliveries)
+
+	var published []db.Outbox
+	_, err = store.RelayOutboxTx(context.Background(), db.RelayOutboxTxParams{
+		Limit: 100,
+		Publish: func(ctx context.Context, event db.Outbox) error {
+			published = append(published, event)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, published, 1)
+	require.Equal(t, "test:deliver_webhook", published[0].Topic)
+	require.JSONEq(t, fmt.Sprintf(`{"delivery_id":%d}`, delivery.ID), string(published[0].Payload))
+}
+
+func TestWebhookDeliveryStatus(t *testing.T) {
+	subscription := CreateRandomWebhookSubscription(t, CreateRandomUser(t), "account.updated")
+
+	delivery, err := testQueries.CreateWebhookDelivery(context.Background(), db.CreateWebhookDeliveryParams{
+		SubscriptionID: subscription.ID,
+		EventID:        util.RandomString(16),
+		EventType:      "account.updated",
+		Payload:        json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	nextAttemptAt := time.Now().Add(time.Minute)
+	failed, err := testQueries.MarkWebhookDeliveryFailed(context.Background(), db.MarkWebhookDeliveryFailedParams{
+		Status:         "dead",
+		ResponseStatus: sql.NullInt32{Int32: 500, Valid: true},
+		LastError:      sql.NullString{String: "receiver responded with status 500", Valid: true},
+		NextAttemptAt:  sql.NullTime{Time: nextAttemptAt, Valid: true},
+		ID:             delivery.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "dead", failed.Status)
+	require.Equal(t, int32(1), failed.Attempts)
+	require.Equal(t, int32(500), failed.ResponseStatus.Int32)
+	require.WithinDuration(t, nextAttemptAt, failed.NextAttemptAt.Time, time.Second)
+
+	replayed, err := testQueries.ReplayWebhookDelivery(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", replayed.Status)
+	require.Zero(t, replayed.Attempts)
+	require.False(t, replayed.LastError.Valid)
+
+	succeeded, err := testQueries.MarkWebhookDeliverySucceeded(context.Background(), db.MarkWebhookDeliverySucceededParams{
+		ResponseStatus: sql.NullInt32{Int32: 204, Valid: true},
+		ID:             delivery.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "succeeded", succeeded.Status)
+	require.Equal(t, int32(1), succeeded.Attempts)
+	require.True(t, succeeded.DeliveredAt.Valid)
+	require.False(t, succeeded.NextAttemptAt.Valid)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          5,
+		Offset:         0,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, succeeded.ID, deliveries[0].ID)
+}
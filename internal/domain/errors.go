@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Kind tells what went wrong, it decides the gRPC code and the HTTP status
+// an error is answered with
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalidArgument
+	KindNotFound
+	KindAlreadyExists
+	KindUnauthenticated
+	KindPermissionDenied
+	KindFailedPrecondition
+	KindResourceExhausted
+	KindUnavailable
+)
+
+var httpStatuses = map[Kind]int{
+	KindInternal:           http.StatusInternalServerError,
+	KindInvalidArgument:    http.StatusBadRequest,
+	KindNotFound:           http.StatusNotFound,
+	KindAlreadyExists:      http.StatusConflict,
+	KindUnauthenticated:    http.StatusUnauthorized,
+	KindPermissionDenied:   http.StatusForbidden,
+	KindFailedPrecondition: http.StatusUnprocessableEntity,
+	KindResourceExhausted:  http.StatusTooManyRequests,
+	KindUnavailable:        http.StatusServiceUnavailable,
+}
+
+// HTTPStatus is the status the REST API answers an error of the kind with
+func (kind Kind) HTTPStatus() int {
+	if status, ok := httpStatuses[kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// FieldViolation tells why a field of the request is invalid
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error is an error the caller is told about. Reason names it in UPPER_SNAKE_CASE
+// for clients to match on and never changes, Message is shown to the caller unless
+// the error is internal. The cause is only logged.
+type Error struct {
+	Kind       Kind
+	Reason     string
+	Message    string
+	Metadata   map[string]string
+	Violations []FieldViolation
+	// RetryAfter is how long a throttled caller has to wait, zero when it isn't throttled
+	RetryAfter time.Duration
+	cause      error
+}
+
+func New(kind Kind, reason, message string) *Error {
+	return &Error{Kind: kind, Reason: reason, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches the errors with the same reason, whatever their cause and metadata
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Reason == e.Reason
+}
+
+// Wrap returns a copy of the error caused by err
+func (e *Error) Wrap(err error) *Error {
+	wrapped := e.clone()
+	wrapped.cause = err
+	return wrapped
+}
+
+// WithMetadata returns a copy of the error telling the caller more, such as the id
+// of the resource not found
+func (e *Error) WithMetadata(key, value string) *Error {
+	withMetadata := e.clone()
+	withMetadata.Metadata = make(map[string]string, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		withMetadata.Metadata[k] = v
+	}
+	withMetadata.Metadata[key] = value
+	return withMetadata
+}
+
+// WithMessage returns a copy of the error with a message more precise than the default one
+func (e *Error) WithMessage(message string) *Error {
+	withMessage := e.clone()
+	withMessage.Message = message
+	return withMessage
+}
+
+// WithViolations returns a copy of the error telling which fields of the request are invalid
+func (e *Error) WithViolations(violations ...FieldViolation) *Error {
+	withViolations := e.clone()
+	withViolations.Violations = violations
+	return withViolations
+}
+
+// WithRetryAfter returns a copy of the error telling the caller how long to wait before trying again
+func (e *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	withRetryAfter := e.clone()
+	withRetryAfter.RetryAfter = retryAfter
+	return withRetryAfter
+}
+
+func (e *Error) clone() *Error {
+	clone := *e
+	return &clone
+}
+
+// From returns the domain error err is or wraps, an internal error caused by err otherwise
+func From(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// Internal is an internal error caused by err, message is only logged
+func Internal(message string, err error) *Error {
+	return ErrInternal.WithMessage(message).Wrap(err)
+}
+
+// InvalidArgument is the error of a request with invalid fields
+func InvalidArgument(violations ...FieldViolation) *Error {
+	return ErrInvalidArgument.WithViolations(violations...)
+}
+
+// errors shared by the gRPC and the REST API
+var (
+	ErrInternal        = New(KindInternal, "INTERNAL", "internal error")
+	ErrInvalidArgument = New(KindInvalidArgument, "INVALID_ARGUMENT", "invalid parameters")
+	ErrUnavailable     = New(KindUnavailable, "UNAVAILABLE", "service unavailable")
+	ErrRateLimited     = New(KindResourceExhausted, "RATE_LIMIT_EXCEEDED", "rate limit exceeded, retry later")
+
+	ErrUnauthenticated    = New(KindUnauthenticated, "UNAUTHENTICATED", "unauthenticated")
+	ErrInvalidCredentials = New(KindUnauthenticated, "INVALID_CREDENTIALS", "incorrect email or password")
+	ErrTooManyLogins      = New(KindResourceExhausted, "TOO_MANY_LOGIN_ATTEMPTS", "too many failed login attempts, try again later")
+	ErrSessionInvalid     = New(KindUnauthenticated, "SESSION_INVALID", "session is not valid")
+	ErrSessionNotFound    = New(KindNotFound, "SESSION_NOT_FOUND", "session not found")
+	ErrPermissionDenied   = New(KindPermissionDenied, "PERMISSION_DENIED", "permission denied")
+	ErrElevationRequired  = New(KindPermissionDenied, "ELEVATION_REQUIRED", "elevated token required, call Reauthenticate first")
+	ErrIncorrectPassword  = New(KindUnauthenticated, "INCORRECT_PASSWORD", "password doesn't match")
+	ErrPasswordReused     = New(KindInvalidArgument, "PASSWORD_REUSED", "password was used recently, choose another one")
+
+	ErrUserNotFound      = New(KindNotFound, "USER_NOT_FOUND", "user not found")
+	ErrUserAlreadyExists = New(KindAlreadyExists, "USER_ALREADY_EXISTS", "username or email is already in use")
+	ErrNotOwnUser        = New(KindPermissionDenied, "NOT_OWN_USER", "request is not made for the authenticated user")
+
+	ErrAccountNotFound      = New(KindNotFound, "ACCOUNT_NOT_FOUND", "account not found")
+	ErrAccountAlreadyExists = New(KindAlreadyExists, "ACCOUNT_ALREADY_EXISTS", "user already has an account in this currency")
+	ErrAccountNotOwned      = New(KindPermissionDenied, "ACCOUNT_NOT_OWNED", "account doesn't belong to authenticated user")
+	ErrAccountFrozen        = New(KindFailedPrecondition, "ACCOUNT_FROZEN", "account is frozen")
+	ErrCurrencyMismatch     = New(KindInvalidArgument, "CURRENCY_MISMATCH", "account currency doesn't match the transfer currency")
+
+	ErrAPIKeyNotFound              = New(KindNotFound, "API_KEY_NOT_FOUND", "api key not found or already revoked")
+	ErrWebhookSubscriptionNotFound = New(KindNotFound, "WEBHOOK_SUBSCRIPTION_NOT_FOUND", "webhook subscription not found")
+	ErrWebhookDeliveryNotFound     = New(KindNotFound, "WEBHOOK_DELIVERY_NOT_FOUND", "webhook delivery not found")
+	ErrWebhookDeliveryNotFinished  = New(KindFailedPrecondition, "WEBHOOK_DELIVERY_NOT_FINISHED", "only a succeeded or dead delivery is replayed")
+)
@@ -0,0 +1,98 @@
+package domain_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	testCases := []struct {
+		name  string
+		err   error
+		check func(t *testing.T, err error)
+	}{
+		// TODO: sentinel
+		{
+			name: "Sentinel",
+			err:  domain.ErrAccountNotFound,
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotFound)
+				require.NotErrorIs(t, err, domain.ErrUserNotFound)
+				require.Equal(t, "account not found", err.Error())
+			},
+		},
+		// TODO: cause kept for the log
+		{
+			name: "Wrap",
+			err:  fmt.Errorf("cannot transfer: %w", domain.ErrAccountNotFound.Wrap(sql.ErrNoRows)),
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotFound)
+				require.ErrorIs(t, err, sql.ErrNoRows)
+				require.Contains(t, err.Error(), sql.ErrNoRows.Error())
+			},
+		},
+		// TODO: metadata and message
+		{
+			name: "WithMetadata",
+			err:  domain.ErrAccountNotFound.WithMetadata("account_id", "7").WithMessage("account 7 not found"),
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotFound)
+
+				domainErr := domain.From(err)
+				require.Equal(t, "ACCOUNT_NOT_FOUND", domainErr.Reason)
+				require.Equal(t, "account 7 not found", domainErr.Message)
+				require.Equal(t, map[string]string{"account_id": "7"}, domainErr.Metadata)
+
+				// the sentinel is left as it is
+				require.Empty(t, domain.ErrAccountNotFound.Metadata)
+				require.Equal(t, "account not found", domain.ErrAccountNotFound.Message)
+			},
+		},
+		// TODO: field violations
+		{
+			name: "InvalidArgument",
+			err:  domain.InvalidArgument(domain.FieldViolation{Field: "amount", Description: "must be greater than 0"}),
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidArgument)
+
+				domainErr := domain.From(err)
+				require.Equal(t, domain.KindInvalidArgument, domainErr.Kind)
+				require.Len(t, domainErr.Violations, 1)
+				require.Empty(t, domain.ErrInvalidArgument.Violations)
+			},
+		},
+		// TODO: error that is not a domain error
+		{
+			name: "Internal",
+			err:  sql.ErrConnDone,
+			check: func(t *testing.T, err error) {
+				domainErr := domain.From(err)
+				require.Equal(t, domain.KindInternal, domainErr.Kind)
+				require.ErrorIs(t, domainErr, domain.ErrInternal)
+				require.ErrorIs(t, domainErr, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, tc.err)
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	require.Equal(t, http.StatusNotFound, domain.ErrAccountNotFound.Kind.HTTPStatus())
+	require.Equal(t, http.StatusUnauthorized, domain.ErrInvalidCredentials.Kind.HTTPStatus())
+	require.Equal(t, http.StatusConflict, domain.ErrUserAlreadyExists.Kind.HTTPStatus())
+	require.Equal(t, http.StatusUnprocessableEntity, domain.ErrAccountFrozen.Kind.HTTPStatus())
+	require.Equal(t, http.StatusTooManyRequests, domain.ErrTooManyLogins.Kind.HTTPStatus())
+	require.Equal(t, http.StatusInternalServerError, domain.Kind(-1).HTTPStatus())
+}
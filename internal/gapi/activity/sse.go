@@ -0,0 +1,191 @@
+package gapiActivity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Path serves WatchAccountActivity as server-sent events
+const Path = "/api/v1/account/activity/events"
+
+// streamPath is the gateway route of WatchAccountActivity
+const streamPath = "/api/v1/account/activity"
+
+// keepAliveInterval keeps proxies from closing an idle stream
+var keepAliveInterval = 15 * time.Second
+
+// Handler bridges the newline-delimited JSON the gateway streams WatchAccountActivity
+// with to server-sent events. Every activity is an "activity" event with its cursor as
+// id, an EventSource reconnecting with Last-Event-ID resumes after it.
+func Handler(gateway http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+			if _, err := strconv.ParseInt(lastEventID, 10, 64); err != nil {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			query.Set("after_entry_id", lastEventID)
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+
+		req := r.Clone(ctx)
+		req.URL.Path = streamPath
+		req.URL.RawPath = ""
+		req.URL.RawQuery = query.Encode()
+
+		writer := &eventWriter{ResponseWriter: w, flusher: flusher}
+		done := make(chan struct{})
+		go func() {
+			writer.keepAlive(ctx)
+			close(done)
+		}()
+
+		gateway.ServeHTTP(writer, req)
+
+		// w can't be written once the handler returned
+		cancel()
+		<-done
+	})
+}
+
+// eventWriter turns every line the gateway writes into an event. Errors
+// returned before the stream starts are written as they are.
+type eventWriter struct {
+	http.ResponseWriter
+	flusher http.Flusher
+
+	mu          sync.Mutex
+	wroteHeader bool
+	status      int
+	buf         []byte
+}
+
+func (w *eventWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(statusCode)
+}
+
+func (w *eventWriter) writeHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+
+	if statusCode == http.StatusOK {
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Del("Content-Length")
+		header.Del("Transfer-Encoding")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeHeader(http.StatusOK)
+	if w.status != http.StatusOK {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := w.buf[:i]
+		w.buf = w.buf[i+1:]
+		if err := w.writeEvent(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *eventWriter) writeEvent(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+
+	var message struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(line, &message); err != nil {
+		return fmt.Errorf("cannot decode stream message: %w", err)
+	}
+
+	var event bytes.Buffer
+	if message.Error != nil {
+		fmt.Fprintf(&event, "event: error\ndata: %s\n\n", message.Error)
+	} else {
+		// int64 fields are JSON strings, a zero cursor is left out
+		var activity struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(message.Result, &activity)
+
+		if activity.Cursor != "" {
+			fmt.Fprintf(&event, "id: %s\n", activity.Cursor)
+		}
+		fmt.Fprintf(&event, "event: activity\ndata: %s\n\n", message.Result)
+	}
+
+	_, err := w.ResponseWriter.Write(event.Bytes())
+	return err
+}
+
+func (w *eventWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeHeader(http.StatusOK)
+	w.flusher.Flush()
+}
+
+// keepAlive writes a comment while the stream is idle
+func (w *eventWriter) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.wroteHeader && w.status == http.StatusOK {
+				_, _ = w.ResponseWriter.Write([]byte(": keep-alive\n\n"))
+				w.flusher.Flush()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
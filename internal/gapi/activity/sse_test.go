@@ -0,0 +1,127 @@
+package gapiActivity
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		lastEventID   string
+		gateway       func(t *testing.T) http.HandlerFunc
+		checkResponse func(t *testing.T, res *http.Response, body string)
+	}{
+		// TODO: every message of the gateway is an event with its cursor as id
+		{
+			name: "OK",
+			gateway: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					require.Equal(t, streamPath, r.URL.Path)
+					require.Equal(t, "1", r.URL.Query().Get("account_ids"))
+					require.Empty(t, r.URL.Query().Get("after_entry_id"))
+
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"result":{"account":{"id":"1"},"cursor":"41"}}`))
+					w.Write([]byte("\n"))
+					w.(http.Flusher).Flush()
+					// a message may arrive in pieces
+					w.Write([]byte(`{"result":{"account":{"id":"1"},"entry":{"id":"42"},`))
+					w.Write([]byte(`"cursor":"42"}}` + "\n"))
+					w.Write([]byte(`{"error":{"code":13,"message":"cannot list account activity"}}` + "\n"))
+				}
+			},
+			checkResponse: func(t *testing.T, res *http.Response, body string) {
+				require.Equal(t, http.StatusOK, res.StatusCode)
+				require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+				require.Equal(t, "id: 41\nevent: activity\ndata: {\"account\":{\"id\":\"1\"},\"cursor\":\"41\"}\n\n"+
+					"id: 42\nevent: activity\ndata: {\"account\":{\"id\":\"1\"},\"entry\":{\"id\":\"42\"},\"cursor\":\"42\"}\n\n"+
+					"event: error\ndata: {\"code\":13,\"message\":\"cannot list account activity\"}\n\n", body)
+			},
+		},
+		// TODO: a reconnecting EventSource resumes after the last event
+		{
+			name:        "Resume",
+			lastEventID: "42",
+			gateway: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					require.Equal(t, "42", r.URL.Query().Get("after_entry_id"))
+					w.Write([]byte(`{"result":{"cursor":"43"}}` + "\n"))
+				}
+			},
+			checkResponse: func(t *testing.T, res *http.Response, body string) {
+				require.Equal(t, http.StatusOK, res.StatusCode)
+				require.Equal(t, "id: 43\nevent: activity\ndata: {\"cursor\":\"43\"}\n\n", body)
+			},
+		},
+		// TODO: an error before the stream starts keeps its status
+		{
+			name: "Unauthenticated",
+			gateway: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"code":16,"message":"missing authorization header"}`))
+				}
+			},
+			checkResponse: func(t *testing.T, res *http.Response, body string) {
+				require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+				require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+				require.JSONEq(t, `{"code":16,"message":"missing authorization header"}`, body)
+			},
+		},
+		// TODO: a cursor that isn't an entry id
+		{
+			name:        "InvalidLastEventID",
+			lastEventID: "abc",
+			gateway: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					t.Fatal("gateway called with an invalid cursor")
+				}
+			},
+			checkResponse: func(t *testing.T, res *http.Response, body string) {
+				require.Equal(t, http.StatusBadRequest, res.StatusCode)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(Handler(tt.gateway(t)))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+Path+"?account_ids=1", nil)
+			require.NoError(t, err)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			tt.checkResponse(t, res, string(body))
+		})
+	}
+}
+
+func TestHandlerKeepAlive(t *testing.T) {
+	interval := keepAliveInterval
+	keepAliveInterval = 10 * time.Millisecond
+	defer func() { keepAliveInterval = interval }()
+
+	gateway := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"cursor":"1"}}` + "\n"))
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	recorder := httptest.NewRecorder()
+	Handler(gateway).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Contains(t, recorder.Body.String(), ": keep-alive\n\n")
+}
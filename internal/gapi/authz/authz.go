@@ -0,0 +1,178 @@
+package gapiAuthz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/authz"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/pb"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// publicServices only describe the API, evans needs them before login,
+// and the health of the server probed by the orchestrator
+var publicServices = map[string]bool{
+	"grpc.reflection.v1alpha.ServerReflection": true,
+	"grpc.health.v1.Health":                    true,
+}
+
+// Authenticator enforces the auth policy declared on every RPC with the (pb.auth) option.
+// An RPC without the option, including one that is added later, requires an access token.
+type Authenticator struct {
+	server      *gapi.Server
+	public      map[string]bool
+	elevated    map[string]bool
+	permissions authz.Permissions
+	scopes      authz.Scopes
+}
+
+func NewAuthenticator(server *gapi.Server, services ...protoreflect.ServiceDescriptor) *Authenticator {
+	authenticator := &Authenticator{
+		server:      server,
+		public:      make(map[string]bool),
+		elevated:    make(map[string]bool),
+		permissions: make(authz.Permissions),
+		scopes:      make(authz.Scopes),
+	}
+
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			fullMethod := fmt.Sprintf("/%s/%s", service.FullName(), method.Name())
+
+			policy, ok := proto.GetExtension(method.Options(), pb.E_Auth).(*pb.AuthPolicy)
+			if !ok || policy == nil {
+				continue
+			}
+
+			if policy.GetPublic() {
+				authenticator.public[fullMethod] = true
+			}
+
+			if policy.GetElevated() {
+				authenticator.elevated[fullMethod] = true
+			}
+
+			if len(policy.GetRoles()) > 0 {
+				authenticator.permissions[fullMethod] = policy.GetRoles()
+			}
+
+			if len(policy.GetScopes()) > 0 {
+				authenticator.scopes[fullMethod] = policy.GetScopes()
+			}
+		}
+	}
+
+	return authenticator
+}
+
+// Unary authenticates unary RPCs and puts the caller in the handler context
+func (a *Authenticator) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream authenticates streaming RPCs and puts the caller in the stream context
+func (a *Authenticator) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (a *Authenticator) isPublic(fullMethod string) bool {
+	service := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")[0]
+	return a.public[fullMethod] || publicServices[service]
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	if a.isPublic(fullMethod) {
+		return ctx, nil
+	}
+
+	authPayload, err := gapiConverter.AuthorizeUser(ctx, a.server)
+	if err != nil {
+		// an internal error is left to the sanitizer, which logs its cause
+		if domainErr := domain.From(err); domainErr.Kind != domain.KindInternal {
+			return nil, gapiError.Status(domainErr).Err()
+		}
+		return nil, err
+	}
+
+	if !a.permissions.IsAllowed(fullMethod, authPayload.Role) {
+		return nil, gapiError.Status(domain.ErrPermissionDenied.
+			WithMessage(fmt.Sprintf("role %s is not allowed to call %s", authPayload.Role, fullMethod))).Err()
+	}
+
+	// the owner granted the scope when creating the key,
+	// so a granted API key stands in for the elevated token
+	if authPayload.IsAPIKey() {
+		if !a.scopes.IsGranted(fullMethod, authPayload.Scopes) {
+			return nil, gapiError.Status(domain.ErrPermissionDenied.
+				WithMessage(fmt.Sprintf("api key has no scope granting %s", fullMethod))).Err()
+		}
+	} else if a.elevated[fullMethod] && authPayload.Scope != token.ScopeSensitive {
+		return nil, gapiError.Status(domain.ErrElevationRequired).Err()
+	}
+
+	user, err := a.server.DB.GetUserUsingEmail(ctx, authPayload.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, gapiError.Status(domain.ErrUnauthenticated.WithMessage("authenticated user no longer exists")).Err()
+		}
+		return nil, domain.Internal("cannot find authenticated user", err)
+	}
+
+	authUser := &gapi.AuthUser{
+		Payload: authPayload,
+		User:    user,
+	}
+
+	// every log line of the request names the caller from now on
+	logging.AddFields(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str("username", user.Username)
+	})
+	return gapi.ContextWithAuthUser(ctx, authUser), nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,449 @@
+package gapiAuthz_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/apikey"
+	"github.com/claytten/golang-simplebank/internal/authz"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiAuthz "github.com/claytten/golang-simplebank/internal/gapi/authz"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/pb"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, user db.Users) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(user.Email, user.Role, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	md := metadata.MD{
+		"authorization": []string{fmt.Sprintf("bearer %s", accessToken)},
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func newContextWithElevatedToken(t *testing.T, tokenMaker token.Maker, user db.Users) context.Context {
+	elevatedToken, _, err := tokenMaker.CreateScopedToken(user.Email, user.Role, uuid.New(), token.ScopeSensitive, time.Minute)
+	require.NoError(t, err)
+
+	md := metadata.MD{
+		"authorization": []string{fmt.Sprintf("bearer %s", elevatedToken)},
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func newContextWithAPIKey(key apikey.Key) context.Context {
+	md := metadata.MD{
+		"authorization": []string{fmt.Sprintf("ApiKey %s", key)},
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func randomAPIKey(t *testing.T, owner db.Users, scopes ...string) (apikey.Key, db.ApiKeys) {
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKeys{
+		ID:           util.RandomInt(1, 1000),
+		Prefix:       key.Prefix,
+		HashedSecret: apikey.HashSecret(key.Secret),
+		Name:         util.RandomOwner(),
+		Owner:        owner.Username,
+		Scopes:       scopes,
+		CreatedAt:    time.Now(),
+	}
+	return key, apiKey
+}
+
+func newTestAuthenticator(t *testing.T, store db.Store) (*gapiAuthz.Authenticator, token.Maker) {
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	server := &gapi.Server{
+		DB:         store,
+		Token:      tokenMaker,
+		Revocation: revocation.NewMemoryCache(),
+	}
+	service := pb.File_service_simplebank_proto.Services().ByName("Simplebank")
+	return gapiAuthz.NewAuthenticator(server, service), tokenMaker
+}
+
+func TestUnaryAuthenticator(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	admin, _ := util.RandomUser(t)
+	admin.Role = util.AdminRole
+	key, apiKey := randomAPIKey(t, user, authz.ScopeTransfersWrite)
+
+	tests := []struct {
+		name          string
+		method        string
+		buildCtx      func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, ctx context.Context, err error)
+	}{
+		// TODO: public RPC without token
+		{
+			name:       "OK public",
+			method:     "/pb.Simplebank/LoginUser",
+			buildCtx:   func(t *testing.T, tokenMaker token.Maker) context.Context { return context.Background() },
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				_, err = gapi.AuthUserFromContext(ctx)
+				require.ErrorIs(t, err, domain.ErrUnauthenticated)
+			},
+		},
+
+		// TODO: RPC without option puts the caller in the context
+		{
+			name:   "OK authenticated",
+			method: "/pb.Simplebank/GetUser",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				authUser, err := gapi.AuthUserFromContext(ctx)
+				require.NoError(t, err)
+				require.Equal(t, user, authUser.User)
+				require.Equal(t, user.Email, authUser.Payload.Email)
+			},
+		},
+
+		// TODO: RPC without option requires a token
+		{
+			name:       "Unauthenticated missing token",
+			method:     "/pb.Simplebank/GetAccount",
+			buildCtx:   func(t *testing.T, tokenMaker token.Maker) context.Context { return context.Background() },
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+
+		// TODO: RPC unknown to the proto requires a token
+		{
+			name:       "Unauthenticated unknown RPC",
+			method:     "/pb.Simplebank/NotDeclaredYet",
+			buildCtx:   func(t *testing.T, tokenMaker token.Maker) context.Context { return context.Background() },
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+
+		// TODO: refresh token of a logged out session used as a bearer
+		{
+			name:   "Unauthenticated refresh token",
+			method: "/pb.Simplebank/GetUser",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				refreshToken, _, err := tokenMaker.CreateRefreshToken(user.Email, user.Role, uuid.New(), time.Hour)
+				require.NoError(t, err)
+				md := metadata.MD{
+					"authorization": []string{fmt.Sprintf("bearer %s", refreshToken)},
+				}
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+
+		// TODO: user of the token is gone
+		{
+			name:   "Unauthenticated user not found",
+			method: "/pb.Simplebank/GetUser",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(db.Users{}, sql.ErrNoRows).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+
+		// TODO: admin calling an admin RPC
+		{
+			name:   "OK admin",
+			method: "/pb.Simplebank/ListUsers",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, admin)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(admin.Email)).Return(admin, nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: depositor calling an admin RPC
+		{
+			name:   "PermissionDenied depositor",
+			method: "/pb.Simplebank/FreezeAccount",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+
+		// TODO: elevated token calling a sensitive RPC
+		{
+			name:   "OK elevated",
+			method: "/pb.Simplebank/TransferTxAccount",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithElevatedToken(t, tokenMaker, user)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				authUser, err := gapi.AuthUserFromContext(ctx)
+				require.NoError(t, err)
+				require.Equal(t, token.ScopeSensitive, authUser.Payload.Scope)
+			},
+		},
+
+		// TODO: API key with the scope calling a sensitive RPC
+		{
+			name:   "OK api key",
+			method: "/pb.Simplebank/TransferTxAccount",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAPIKey(key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				authUser, err := gapi.AuthUserFromContext(ctx)
+				require.NoError(t, err)
+				require.Equal(t, user, authUser.User)
+				require.Equal(t, apiKey.ID, authUser.Payload.APIKeyID)
+			},
+		},
+
+		// TODO: API key that cannot be checked
+		{
+			name:   "Internal api key lookup",
+			method: "/pb.Simplebank/GetAccount",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAPIKey(key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Return(db.ApiKeys{}, sql.ErrConnDone).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.ErrorIs(t, err, domain.ErrInternal)
+				sanitized := status.Convert(gapiError.Sanitize(err))
+				require.Equal(t, codes.Internal, sanitized.Code())
+				require.NotContains(t, sanitized.Message(), sql.ErrConnDone.Error())
+			},
+		},
+
+		// TODO: API key without the scope
+		{
+			name:   "PermissionDenied api key scope",
+			method: "/pb.Simplebank/GetAccount",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAPIKey(key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+
+		// TODO: API key calling an RPC without scopes
+		{
+			name:   "PermissionDenied api key creating a key",
+			method: "/pb.Simplebank/CreateAPIKey",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAPIKey(key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+
+		// TODO: revoked API key
+		{
+			name:   "Unauthenticated api key revoked",
+			method: "/pb.Simplebank/TransferTxAccount",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAPIKey(key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Return(revoked, nil).Times(1)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+
+		// TODO: access token calling a sensitive RPC
+		{
+			name:   "PermissionDenied not elevated",
+			method: "/pb.Simplebank/UpdatePassword",
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			authenticator, tokenMaker := newTestAuthenticator(t, store)
+
+			handlerCtx := context.Background()
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				handlerCtx = ctx
+				return nil, nil
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			_, err := authenticator.Unary()(tt.buildCtx(t, tokenMaker), nil, info, handler)
+			tt.checkResponse(t, handlerCtx, err)
+		})
+	}
+}
+
+// unavailableCache is a revocation cache whose store is down
+type unavailableCache struct{}
+
+func (unavailableCache) Revoke(ctx context.Context, sessionID uuid.UUID, ttl time.Duration) error {
+	return errors.New("dial tcp 10.0.0.5:6379: connect: connection refused")
+}
+
+func (unavailableCache) IsRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	return false, errors.New("dial tcp 10.0.0.5:6379: connect: connection refused")
+}
+
+func TestUnaryAuthenticatorRevocationDown(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	server := &gapi.Server{
+		DB:         mockdb.NewMockStore(controller),
+		Token:      tokenMaker,
+		Revocation: unavailableCache{},
+	}
+	service := pb.File_service_simplebank_proto.Services().ByName("Simplebank")
+	authenticator := gapiAuthz.NewAuthenticator(server, service)
+
+	user, _ := util.RandomUser(t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.Simplebank/GetUser"}
+	_, err = authenticator.Unary()(newContextWithBearerToken(t, tokenMaker, user), nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+
+	// an outage is not a rejected token, and its cause stays in the logs
+	require.ErrorIs(t, err, domain.ErrInternal)
+	sanitized := status.Convert(gapiError.Sanitize(err))
+	require.Equal(t, codes.Internal, sanitized.Code())
+	require.NotContains(t, sanitized.Message(), "10.0.0.5")
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamAuthenticator(t *testing.T) {
+	user, _ := util.RandomUser(t)
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Return(user, nil).Times(1)
+
+	authenticator, tokenMaker := newTestAuthenticator(t, store)
+
+	var authUser *gapi.AuthUser
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		var err error
+		authUser, err = gapi.AuthUserFromContext(stream.Context())
+		return err
+	}
+
+	// server reflection stays public
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", IsServerStream: true}
+	err := authenticator.Stream()(nil, &testServerStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	info = &grpc.StreamServerInfo{FullMethod: "/pb.Simplebank/NotDeclaredYet", IsServerStream: true}
+	err = authenticator.Stream()(nil, &testServerStream{ctx: context.Background()}, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream := &testServerStream{ctx: newContextWithBearerToken(t, tokenMaker, user)}
+	err = authenticator.Stream()(nil, stream, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, user, authUser.User)
+}
@@ -0,0 +1,30 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+)
+
+type authUserKey struct{}
+
+// AuthUser is the caller resolved from the access token by the authentication interceptor
+type AuthUser struct {
+	Payload *token.Payload
+	User    db.Users
+}
+
+func ContextWithAuthUser(ctx context.Context, authUser *AuthUser) context.Context {
+	return context.WithValue(ctx, authUserKey{}, authUser)
+}
+
+// AuthUserFromContext returns the caller of an RPC that is not public
+func AuthUserFromContext(ctx context.Context) (*AuthUser, error) {
+	authUser, ok := ctx.Value(authUserKey{}).(*AuthUser)
+	if !ok {
+		return nil, domain.ErrUnauthenticated.WithMessage("missing authenticated user")
+	}
+	return authUser, nil
+}
@@ -0,0 +1,29 @@
+package gapiConverter
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+)
+
+// AuditContext describes the caller of an RPC for the audit log
+func AuditContext(ctx context.Context, server *gapi.Server, actor string) db.AuditContext {
+	mtdt := ExtractMetadata(ctx, server)
+	return db.AuditContext{
+		Actor:     actor,
+		ClientIP:  mtdt.ClientIP,
+		UserAgent: mtdt.UserAgent,
+	}
+}
+
+// AuthUserAuditContext is the AuditContext of an authenticated caller,
+// a request made with an API key is told apart from its owner
+func AuthUserAuditContext(ctx context.Context, server *gapi.Server, authUser *gapi.AuthUser) db.AuditContext {
+	actor := authUser.User.Username
+	if authUser.Payload.IsAPIKey() {
+		actor = fmt.Sprintf("%s/api_key:%d", actor, authUser.Payload.APIKeyID)
+	}
+	return AuditContext(ctx, server, actor)
+}
@@ -2,10 +2,12 @@ package gapiConverter
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"strings"
 
 	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/apikey"
+	"github.com/claytten/golang-simplebank/internal/domain"
 	"github.com/claytten/golang-simplebank/internal/gapi"
 	"google.golang.org/grpc/metadata"
 )
@@ -13,34 +15,66 @@ import (
 const (
 	authorizationHeader = "authorization"
 	authorizationBearer = "bearer"
+	authorizationAPIKey = "apikey"
 )
 
+// AuthorizeUser verifies the bearer access token or the API key of the request for the authentication
+// interceptor. A rejected credential is unauthenticated with a fixed message, a failure to check it is internal.
 func AuthorizeUser(ctx context.Context, server *gapi.Server) (*token.Payload, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("missing metadata")
+		return nil, domain.ErrUnauthenticated.WithMessage("authorization header is not provided")
 	}
 
 	values := md.Get(authorizationHeader)
 	if len(values) == 0 {
-		return nil, fmt.Errorf("missing authorization header")
+		return nil, domain.ErrUnauthenticated.WithMessage("authorization header is not provided")
 	}
 
 	authHeader := values[0]
 	fields := strings.Fields(authHeader)
 	if len(fields) < 2 {
-		return nil, fmt.Errorf("invalid authorization header format")
+		return nil, domain.ErrUnauthenticated.WithMessage("invalid authorization header format")
 	}
 
 	authType := strings.ToLower(fields[0])
-	if authType != authorizationBearer {
-		return nil, fmt.Errorf("unsupported authorization type: %s", authType)
+	switch authType {
+	case authorizationBearer:
+	case authorizationAPIKey:
+		payload, err := apikey.Authenticate(ctx, server.DB, fields[1])
+		if err != nil {
+			if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrExpiredKey) || errors.Is(err, apikey.ErrRevokedKey) {
+				return nil, domain.ErrUnauthenticated.WithMessage(err.Error()).Wrap(err)
+			}
+			return nil, domain.Internal("cannot check api key", err)
+		}
+		return payload, nil
+	default:
+		return nil, domain.ErrUnauthenticated.WithMessage("unsupported authorization type")
 	}
 
 	accessToken := fields[1]
 	payload, err := server.Token.VerifyToken(accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid access token: %s", err)
+		if errors.Is(err, token.ErrExpiredToken) {
+			return nil, domain.ErrUnauthenticated.WithMessage(token.ErrExpiredToken.Error()).Wrap(err)
+		}
+		return nil, domain.ErrUnauthenticated.WithMessage(token.ErrInvalidToken.Error()).Wrap(err)
+	}
+
+	// a refresh token only renews the access token of its session
+	if payload.IsRefresh() {
+		return nil, domain.ErrUnauthenticated.WithMessage(token.ErrInvalidToken.Error())
+	}
+
+	// the token is still valid, but its session may have been blocked since
+	revoked, err := server.Revocation.IsRevoked(ctx, payload.SessionID)
+	if err != nil {
+		return nil, domain.Internal("cannot check access token revocation", err)
+	}
+
+	if revoked {
+		return nil, domain.ErrSessionInvalid.WithMessage(token.ErrRevokedToken.Error())
 	}
 
 	return payload, nil
@@ -1,15 +1,10 @@
 package gapiConverter
 
 import (
-	"context"
-	"database/sql"
-
+	"github.com/claytten/golang-simplebank/internal/api/token"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
-	"github.com/claytten/golang-simplebank/internal/gapi"
-	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/notification"
 	"github.com/claytten/golang-simplebank/pb"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -21,6 +16,7 @@ func ConvertUser(user db.Users) *pb.User {
 		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
 		CreatedAt:         timestamppb.New(user.CreatedAt),
 		UpdatedAt:         timestamppb.New(user.UpdatedAt),
+		Role:              user.Role,
 	}
 }
 
@@ -32,6 +28,7 @@ func ConvertAccount(account db.Accounts) *pb.Account {
 		Currency:  account.Currency,
 		CreatedAt: timestamppb.New(account.CreatedAt),
 		UpdatedAt: timestamppb.New(account.UpdatedAt),
+		IsFrozen:  account.IsFrozen,
 	}
 }
 
@@ -56,58 +53,111 @@ func ConvertEntry(entry db.Entries) *pb.Entries {
 	}
 }
 
-func ConvertTransferTx(transfer db.TransferTxResult) *pb.TransferTxAccountResponse {
-	return &pb.TransferTxAccountResponse{
-		Transfer:    ConvertTransfer(transfer.Transfer),
-		FromAccount: ConvertAccount(transfer.FromAccount),
-		ToAccount:   ConvertAccount(transfer.ToAccount),
-		FromEntry:   ConvertEntry(transfer.FromEntry),
-		ToEntry:     ConvertEntry(transfer.ToEntry),
+func ConvertAPIKey(apiKey db.ApiKeys) *pb.ApiKey {
+	res := &pb.ApiKey{
+		Id:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Owner:     apiKey.Owner,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: timestamppb.New(apiKey.CreatedAt),
 	}
-}
 
-func CheckOwnUser(username, oldPassword, email string, server *gapi.Server, ctx context.Context) (string, error) {
-	//finding user by email
-	userHeader, err := server.DB.GetUserUsingEmail(ctx, email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", status.Error(codes.NotFound, "User Not Found and Not Authorized")
-		}
+	if apiKey.ExpiresAt.Valid {
+		res.ExpiresAt = timestamppb.New(apiKey.ExpiresAt.Time)
+	}
 
-		return "", status.Error(codes.Internal, "account doesn't belong to authenticated user")
+	if apiKey.LastUsedAt.Valid {
+		res.LastUsedAt = timestamppb.New(apiKey.LastUsedAt.Time)
 	}
 
-	// checking if username is provided at header
-	if username != userHeader.Username {
-		return "", status.Error(codes.PermissionDenied, "User Status Permission Denied")
+	if apiKey.RevokedAt.Valid {
+		res.RevokedAt = timestamppb.New(apiKey.RevokedAt.Time)
 	}
+	return res
+}
+
+func ConvertWebhookSubscription(subscription db.WebhookSubscriptions) *pb.WebhookSubscription {
+	return &pb.WebhookSubscription{
+		Id:         subscription.ID,
+		Owner:      subscription.Owner,
+		Url:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  timestamppb.New(subscription.CreatedAt),
+	}
+}
 
-	// checking if user typing old password and new password is same
-	err = util.ComparePassword(userHeader.HashedPassword, oldPassword)
-	if err != nil {
-		return "", status.Error(codes.Internal, "new password / old password doesn't match")
+func ConvertWebhookDelivery(delivery db.WebhookDeliveries) *pb.WebhookDelivery {
+	res := &pb.WebhookDelivery{
+		Id:             delivery.ID,
+		SubscriptionId: delivery.SubscriptionID,
+		EventId:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        string(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus.Int32,
+		LastError:      delivery.LastError.String,
+		CreatedAt:      timestamppb.New(delivery.CreatedAt),
 	}
 
-	return userHeader.Username, nil
+	if delivery.NextAttemptAt.Valid {
+		res.NextAttemptAt = timestamppb.New(delivery.NextAttemptAt.Time)
+	}
+
+	if delivery.DeliveredAt.Valid {
+		res.DeliveredAt = timestamppb.New(delivery.DeliveredAt.Time)
+	}
+	return res
 }
 
-func ValidateAccount(ctx context.Context, db db.Store, from_account_id, to_account_id int64, currency string) error {
-	fromAccount, err := db.GetAccount(ctx, from_account_id)
-	if err != nil {
-		return status.Error(codes.Internal, err.Error())
+// ConvertNotificationSettings lists every event with every channel so
+// the channels that were never changed show as enabled
+func ConvertNotificationSettings(settings db.NotificationSettings, preferences []db.NotificationPreferences) *pb.NotificationSettings {
+	res := &pb.NotificationSettings{
+		Locale:              settings.Locale,
+		PhoneNumber:         settings.PhoneNumber,
+		PushToken:           settings.PushToken,
+		LargeDebitThreshold: settings.LargeDebitThreshold,
 	}
 
-	toAccount, err := db.GetAccount(ctx, from_account_id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return status.Error(codes.NotFound, err.Error())
+	enabled := notification.NewPreferences(preferences)
+	for _, event := range notification.Events {
+		for _, channel := range notification.Channels {
+			res.Preferences = append(res.Preferences, &pb.NotificationPreference{
+				Event:   event,
+				Channel: channel,
+				Enabled: enabled.Enabled(event, channel),
+			})
 		}
+	}
+	return res
+}
 
-		return status.Error(codes.Internal, err.Error())
+func ConvertJWKS(jwks token.JWKS) *pb.GetJWKSResponse {
+	keys := make([]*pb.JSONWebKey, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		keys = append(keys, &pb.JSONWebKey{
+			Kty: key.KeyType,
+			Kid: key.KeyID,
+			Use: key.Use,
+			Alg: key.Algorithm,
+			Crv: key.Curve,
+			X:   key.X,
+			N:   key.N,
+			E:   key.E,
+		})
 	}
 
-	if fromAccount.Currency != currency || toAccount.Currency != currency {
-		return status.Error(codes.Internal, "From/To Account Mismatch Currency")
+	return &pb.GetJWKSResponse{Keys: keys}
+}
+
+func ConvertTransferTx(transfer db.TransferTxResult) *pb.TransferTxAccountResponse {
+	return &pb.TransferTxAccountResponse{
+		Transfer:    ConvertTransfer(transfer.Transfer),
+		FromAccount: ConvertAccount(transfer.FromAccount),
+		ToAccount:   ConvertAccount(transfer.ToAccount),
+		FromEntry:   ConvertEntry(transfer.FromEntry),
+		ToEntry:     ConvertEntry(transfer.ToEntry),
 	}
-	return nil
 }
@@ -22,6 +22,7 @@ type Metadata struct {
 func ExtractMetadata(ctx context.Context, server *gapi.Server) *Metadata {
 	mtdt := &Metadata{}
 
+	var forwardedFor []string
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
 			mtdt.UserAgent = userAgents[0]
@@ -31,13 +32,13 @@ func ExtractMetadata(ctx context.Context, server *gapi.Server) *Metadata {
 			mtdt.UserAgent = userAgents[0]
 		}
 
-		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
-			mtdt.ClientIP = clientIPs[0]
-		}
+		forwardedFor = md.Get(xForwardedForHeader)
 	}
 
+	// x-forwarded-for is only read on the connections of the HTTP gateway
+	// or a trusted proxy, a direct client is known by its peer address
 	if p, ok := peer.FromContext(ctx); ok {
-		mtdt.ClientIP = p.Addr.String()
+		mtdt.ClientIP = server.ClientIP.Resolve(p.Addr.String(), forwardedFor)
 	}
 
 	return mtdt
@@ -0,0 +1,26 @@
+package gapiConverter
+
+import (
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/claytten/golang-simplebank/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ClientInfo is the device of the caller of an RPC a session is created for
+func (mtdt *Metadata) ClientInfo() service.ClientInfo {
+	return service.ClientInfo{
+		UserAgent: mtdt.UserAgent,
+		ClientIP:  mtdt.ClientIP,
+	}
+}
+
+func ConvertLoginResult(result service.LoginResult) *pb.LoginUserResponse {
+	return &pb.LoginUserResponse{
+		SessionId:             result.Session.ID.String(),
+		AccessToken:           result.AccessToken,
+		AccessTokenExpiresAt:  timestamppb.New(result.AccessPayload.ExpiredAt),
+		RefreshToken:          result.RefreshToken,
+		RefreshTokenExpiresAt: timestamppb.New(result.RefreshPayload.ExpiredAt),
+		User:                  ConvertUser(result.User),
+	}
+}
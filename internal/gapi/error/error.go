@@ -1,9 +1,20 @@
 package gapiError
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/claytten/golang-simplebank/internal/domain"
+
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func FieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
@@ -24,3 +35,210 @@ func InvalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) er
 
 	return statusDetails.Err()
 }
+
+// ResourceExhaustedError tells the client why it is throttled and how long to wait before trying again
+func ResourceExhaustedError(err *domain.Error, retryAfter time.Duration) error {
+	return Status(err.WithRetryAfter(retryAfter)).Err()
+}
+
+// WithRequestInfo adds the request id to the details of an error, so a client
+// reporting the error points at the log lines of the request
+func WithRequestInfo(err error, requestID string) error {
+	statusErr := status.Convert(err)
+	requestInfo := &errdetails.RequestInfo{RequestId: requestID}
+
+	statusDetails, detailsErr := statusErr.WithDetails(requestInfo)
+	if detailsErr != nil {
+		return statusErr.Err()
+	}
+
+	return statusDetails.Err()
+}
+
+// ErrorDomain is the domain of the ErrorInfo of every error
+const ErrorDomain = "simplebank"
+
+var kindCodes = map[domain.Kind]codes.Code{
+	domain.KindInternal:           codes.Internal,
+	domain.KindInvalidArgument:    codes.InvalidArgument,
+	domain.KindNotFound:           codes.NotFound,
+	domain.KindAlreadyExists:      codes.AlreadyExists,
+	domain.KindUnauthenticated:    codes.Unauthenticated,
+	domain.KindPermissionDenied:   codes.PermissionDenied,
+	domain.KindFailedPrecondition: codes.FailedPrecondition,
+	domain.KindResourceExhausted:  codes.ResourceExhausted,
+	domain.KindUnavailable:        codes.Unavailable,
+}
+
+// Status is the status of a domain error, its reason is in an ErrorInfo and its
+// field violations in a BadRequest. An internal error only tells it is one.
+func Status(err *domain.Error) *status.Status {
+	code := Code(err)
+	message := err.Message
+	if err.Kind == domain.KindInternal {
+		message = domain.ErrInternal.Message
+	}
+
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{
+			Reason:   err.Reason,
+			Domain:   ErrorDomain,
+			Metadata: err.Metadata,
+		},
+	}
+	if len(err.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range err.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	if err.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(err.RetryAfter)})
+	}
+
+	statusErr := status.New(code, message)
+	statusDetails, detailsErr := statusErr.WithDetails(details...)
+	if detailsErr != nil {
+		return statusErr
+	}
+	return statusDetails
+}
+
+// Code is the code an error is answered with, a domain error included
+func Code(err error) codes.Code {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		if code, ok := kindCodes[domainErr.Kind]; ok {
+			return code
+		}
+		return codes.Internal
+	}
+	return status.Code(err)
+}
+
+// internalMessages answer the errors whose message may come from the database or
+// another dependency, the caller only learns what kind of error it was
+var internalMessages = map[codes.Code]string{
+	codes.Internal:           "internal error",
+	codes.NotFound:           "resource not found",
+	codes.AlreadyExists:      "resource already exists",
+	codes.FailedPrecondition: "request cannot be processed in the current state",
+	codes.InvalidArgument:    "invalid parameters",
+	codes.Unauthenticated:    "unauthenticated",
+	codes.PermissionDenied:   "permission denied",
+	codes.Unavailable:        "service unavailable",
+}
+
+// internalErrorPrefixes start the messages of the database/sql and lib/pq errors
+var internalErrorPrefixes = []string{"sql: ", "pq: "}
+
+// Sanitize maps an error to the status returned to the caller, every path of the API
+// answers through it. A domain error is mapped by Status. Another error gets the
+// reason of its code, and a generic message when it is internal or carries a
+// database error. The other details, such as the field violations, are kept.
+func Sanitize(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return Status(domainErr).Err()
+	}
+
+	statusErr := status.Convert(err)
+	code := statusErr.Code()
+	switch code {
+	case codes.Unknown, codes.DataLoss:
+		code = codes.Internal
+	}
+
+	sanitized := statusErr.Proto()
+	sanitized.Code = int32(code)
+	if leaksInternals(statusErr) {
+		message, ok := internalMessages[code]
+		if !ok {
+			message = strings.ToLower(code.String())
+		}
+		sanitized.Message = message
+	}
+	statusErr = status.FromProto(sanitized)
+
+	if hasErrorInfo(statusErr) {
+		return statusErr.Err()
+	}
+
+	statusDetails, detailsErr := statusErr.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason(code),
+		Domain: ErrorDomain,
+	})
+	if detailsErr != nil {
+		return statusErr.Err()
+	}
+	return statusDetails.Err()
+}
+
+func leaksInternals(statusErr *status.Status) bool {
+	switch statusErr.Code() {
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		return true
+	}
+
+	message := statusErr.Message()
+	for _, prefix := range internalErrorPrefixes {
+		if strings.Contains(message, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasErrorInfo(statusErr *status.Status) bool {
+	for _, detail := range statusErr.Details() {
+		if _, ok := detail.(*errdetails.ErrorInfo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reason names a code like the domain errors, such as NOT_FOUND
+func reason(code codes.Code) string {
+	var b strings.Builder
+	for i, c := range code.String() {
+		if i > 0 && unicode.IsUpper(c) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(c))
+	}
+	return b.String()
+}
+
+// GrpcSanitizer sanitizes the errors returned to the caller, it runs outside
+// the logger so the log keeps the original error
+func GrpcSanitizer(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	result, err := handler(ctx, req)
+	if err != nil {
+		return nil, Sanitize(err)
+	}
+	return result, nil
+}
+
+// GrpcStreamSanitizer is GrpcSanitizer for streaming RPCs
+func GrpcStreamSanitizer(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return Sanitize(handler(srv, stream))
+}
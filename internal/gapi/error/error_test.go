@@ -0,0 +1,170 @@
+package gapiError_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/domain"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSanitize(t *testing.T) {
+	testCases := []struct {
+		name  string
+		err   error
+		check func(t *testing.T, err error)
+	}{
+		// TODO: internal error
+		{
+			name: "Internal",
+			err:  status.Errorf(codes.Internal, "Failed to create user : %s", `pq: duplicate key value violates unique constraint "users_pkey"`),
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+				require.Equal(t, "internal error", status.Convert(err).Message())
+				requireReason(t, err, "INTERNAL")
+			},
+		},
+		// TODO: database error
+		{
+			name: "NotFound",
+			err:  status.Error(codes.NotFound, sql.ErrNoRows.Error()),
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+				require.Equal(t, "resource not found", status.Convert(err).Message())
+				requireReason(t, err, "NOT_FOUND")
+			},
+		},
+		// TODO: error without status
+		{
+			name: "Plain",
+			err:  fmt.Errorf("cannot get account: %w", sql.ErrConnDone),
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+				require.Equal(t, "internal error", status.Convert(err).Message())
+				requireReason(t, err, "INTERNAL")
+			},
+		},
+		// TODO: error meant for the caller
+		{
+			name: "InvalidArgument",
+			err: gapiError.InvalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+				gapiError.FieldViolation("amount", errors.New("must be greater than 0")),
+			}),
+			check: func(t *testing.T, err error) {
+				statusErr := status.Convert(err)
+				require.Equal(t, codes.InvalidArgument, statusErr.Code())
+				require.Equal(t, "invalid parameters", statusErr.Message())
+				require.Len(t, statusErr.Details(), 2)
+				requireReason(t, err, "INVALID_ARGUMENT")
+			},
+		},
+		// TODO: details kept
+		{
+			name: "Details",
+			err:  gapiError.WithRequestInfo(status.Error(codes.Internal, "sql: database is closed"), "request-1"),
+			check: func(t *testing.T, err error) {
+				statusErr := status.Convert(err)
+				require.Equal(t, "internal error", statusErr.Message())
+				require.Len(t, statusErr.Details(), 2)
+			},
+		},
+		// TODO: domain error
+		{
+			name: "Domain",
+			err:  fmt.Errorf("cannot login: %w", domain.ErrInvalidCredentials),
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Equal(t, domain.ErrInvalidCredentials.Message, status.Convert(err).Message())
+				requireReason(t, err, "INVALID_CREDENTIALS")
+			},
+		},
+		// TODO: internal domain error
+		{
+			name: "DomainInternal",
+			err:  domain.Internal("cannot find account", sql.ErrConnDone),
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+				require.Equal(t, "internal error", status.Convert(err).Message())
+				requireReason(t, err, "INTERNAL")
+			},
+		},
+		// TODO: no error
+		{
+			name: "Nil",
+			err:  nil,
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, gapiError.Sanitize(tc.err))
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	err := domain.ErrAccountNotFound.WithMetadata("account_id", "7")
+	statusErr := gapiError.Status(err)
+	require.Equal(t, codes.NotFound, statusErr.Code())
+	require.Equal(t, "account not found", statusErr.Message())
+
+	info := requireReason(t, statusErr.Err(), "ACCOUNT_NOT_FOUND")
+	require.Equal(t, gapiError.ErrorDomain, info.GetDomain())
+	require.Equal(t, map[string]string{"account_id": "7"}, info.GetMetadata())
+
+	statusErr = gapiError.Status(domain.InvalidArgument(domain.FieldViolation{Field: "amount", Description: "must be greater than 0"}))
+	require.Equal(t, codes.InvalidArgument, statusErr.Code())
+	require.Len(t, statusErr.Details(), 2)
+
+	badRequest, ok := statusErr.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Equal(t, "amount", badRequest.GetFieldViolations()[0].GetField())
+}
+
+func TestCode(t *testing.T) {
+	require.Equal(t, codes.FailedPrecondition, gapiError.Code(domain.ErrAccountFrozen))
+	require.Equal(t, codes.Internal, gapiError.Code(domain.Internal("cannot transfer", sql.ErrConnDone)))
+	require.Equal(t, codes.NotFound, gapiError.Code(status.Error(codes.NotFound, "not found")))
+	require.Equal(t, codes.OK, gapiError.Code(nil))
+}
+
+// requireReason checks the reason of the ErrorInfo of err
+func requireReason(t *testing.T, err error, reason string) *errdetails.ErrorInfo {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			require.Equal(t, reason, info.GetReason())
+			return info
+		}
+	}
+	require.FailNow(t, "missing error info")
+	return nil
+}
+
+func TestGrpcSanitizer(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.Simplebank/GetAccount"}
+
+	_, err := gapiError.GrpcSanitizer(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "pq: relation \"accounts\" does not exist")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.NotContains(t, status.Convert(err).Message(), "accounts")
+
+	res, err := gapiError.GrpcSanitizer(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "account", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "account", res)
+}
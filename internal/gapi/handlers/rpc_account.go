@@ -2,17 +2,15 @@ package gapiHandler
 
 import (
 	"context"
-	"database/sql"
-	"time"
 
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
 	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
 	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
 	gapiValidate "github.com/claytten/golang-simplebank/internal/gapi/validate"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/claytten/golang-simplebank/pb"
-	"github.com/lib/pq"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (s *gapiHandlerSetup) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
@@ -21,31 +19,23 @@ func (s *gapiHandlerSetup) CreateAccount(ctx context.Context, req *pb.CreateAcco
 		return nil, gapiError.InvalidArgumentError(err)
 	}
 
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 
-	username, err := gapiConverter.CheckOwnUser(req.GetUsername(), req.GetOldPassword(), authPayload.Email, s.server, ctx)
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
 		return nil, err
 	}
 
-	arg := db.CreateAccountParams{
-		Owner:    username,
+	account, err := s.server.Accounts.Create(ctx, service.CreateAccountParams{
+		Owner:    authUser.User.Username,
 		Currency: req.GetCurrency(),
-		Balance:  0,
-	}
-
-	account, err := s.server.DB.CreateAccount(ctx, arg)
+		Audit:    gapiConverter.AuthUserAuditContext(ctx, s.server, authUser),
+	})
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				return nil, status.Error(codes.PermissionDenied, err.Error())
-			}
-		}
-		return nil, status.Error(codes.Internal, "Cannot Create Account")
+		return nil, err
 	}
 
 	return &pb.CreateAccountResponse{
@@ -53,18 +43,15 @@ func (s *gapiHandlerSetup) CreateAccount(ctx context.Context, req *pb.CreateAcco
 	}, nil
 }
 func (s *gapiHandlerSetup) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
-	_, err := gapiConverter.AuthorizeUser(ctx, s.server)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 
-	account, err := s.server.DB.GetAccount(ctx, req.GetId())
+	// only admins can look at other users' accounts, through AdminGetAccount
+	account, err := s.server.Accounts.GetOwned(ctx, authUser.User.Username, req.GetId())
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Error(codes.NotFound, err.Error())
-		}
-
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
 	res := &pb.GetAccountResponse{
@@ -78,25 +65,24 @@ func (s *gapiHandlerSetup) UpdateAccount(ctx context.Context, req *pb.UpdateAcco
 		return nil, gapiError.InvalidArgumentError(err)
 	}
 
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 
-	_, err = gapiConverter.CheckOwnUser(req.GetUsername(), req.GetOldPassword(), authPayload.Email, s.server, ctx)
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
 		return nil, err
 	}
 
-	args := db.UpdateAccountParams{
-		ID:        req.GetId(),
-		Balance:   req.Balance,
-		UpdatedAt: time.Now(),
-	}
-
-	account, err := s.server.DB.UpdateAccount(ctx, args)
+	account, err := s.server.Accounts.Update(ctx, service.UpdateAccountParams{
+		Owner:   authUser.User.Username,
+		ID:      req.GetId(),
+		Balance: req.GetBalance(),
+		Audit:   gapiConverter.AuthUserAuditContext(ctx, s.server, authUser),
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, "cannot update balance account")
+		return nil, err
 	}
 
 	res := &pb.UpdateAccountResponse{
@@ -106,18 +92,23 @@ func (s *gapiHandlerSetup) UpdateAccount(ctx context.Context, req *pb.UpdateAcco
 	return res, nil
 }
 func (s *gapiHandlerSetup) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 
-	_, err = gapiConverter.CheckOwnUser(req.GetUsername(), req.GetOldPassword(), authPayload.Email, s.server, ctx)
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
 		return nil, err
 	}
-	err = s.server.DB.DeleteAccount(ctx, req.GetId())
+
+	err = s.server.Accounts.Delete(ctx, service.DeleteAccountParams{
+		Owner: authUser.User.Username,
+		ID:    req.GetId(),
+		Audit: gapiConverter.AuthUserAuditContext(ctx, s.server, authUser),
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, "cannot delete account")
+		return nil, err
 	}
 
 	res := &pb.DeleteAccountResponse{
@@ -131,33 +122,209 @@ func (s *gapiHandlerSetup) TransferTxAccount(ctx context.Context, req *pb.Transf
 		return nil, gapiError.InvalidArgumentError(err)
 	}
 
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
-	}
-
-	_, err = gapiConverter.CheckOwnUser(req.GetUsername(), req.GetOldPassword(), authPayload.Email, s.server, ctx)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = gapiConverter.ValidateAccount(ctx, s.server.DB, req.GetFromAccountID(), req.GetToAccountID(), req.Currency)
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
 		return nil, err
 	}
 
-	arg := db.TransferTxParams{
+	result, err := s.server.Transfers.Transfer(ctx, service.TransferParams{
+		Owner:         authUser.User.Username,
 		FromAccountID: req.GetFromAccountID(),
 		ToAccountID:   req.GetToAccountID(),
 		Amount:        req.GetAmount(),
-	}
-
-	result, err := s.server.DB.TransferTx(ctx, arg)
+		Currency:      req.GetCurrency(),
+		Audit:         gapiConverter.AuthUserAuditContext(ctx, s.server, authUser),
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
 	res := gapiConverter.ConvertTransferTx(result)
 
 	return res, nil
 }
+
+// activityBatchSize limits the transactions whose entries are read at once,
+// a stream resuming far behind reads several batches
+const activityBatchSize = 100
+
+func (s *gapiHandlerSetup) WatchAccountActivity(req *pb.WatchAccountActivityRequest, stream pb.Simplebank_WatchAccountActivityServer) error {
+	if violations := gapiValidate.ValidateWatchAccountActivityRequest(req); violations != nil {
+		return gapiError.InvalidArgumentError(violations)
+	}
+
+	ctx := stream.Context()
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	accountIDs, err := s.watchedAccountIDs(ctx, authUser, req.GetAccountIds())
+	if err != nil {
+		return err
+	}
+
+	// subscribed before reading, activity committed meanwhile wakes the stream up again
+	sub := s.server.Activity.Subscribe(accountIDs)
+	defer sub.Close()
+
+	cursor := req.GetAfterEntryId()
+	if req.AfterEntryId == nil {
+		cursor, err = s.server.DB.GetEntryCursor(ctx)
+		if err != nil {
+			return domain.Internal("cannot find entry cursor", err)
+		}
+
+		// the current balances are where a new stream starts from
+		_, err = s.sendAccountActivity(ctx, stream, accountIDs, cursor, accountIDs)
+	} else {
+		cursor, err = s.sendAccountActivity(ctx, stream, accountIDs, cursor, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Done():
+			// the client resumes after cursor on another server
+			return domain.ErrUnavailable.WithMessage("server is shutting down")
+		case <-sub.Wake():
+			cursor, err = s.sendAccountActivity(ctx, stream, accountIDs, cursor, sub.Pending())
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendAccountActivity sends the entries of the transactions after cursor, then the
+// notified accounts that changed without an entry. The cursor only moves past a
+// transaction with its last entry, a client resuming in the middle of a transaction
+// gets all of its entries again. It returns the cursor of the last entry sent.
+func (s *gapiHandlerSetup) sendAccountActivity(
+	ctx context.Context,
+	stream pb.Simplebank_WatchAccountActivityServer,
+	accountIDs []int64,
+	cursor int64,
+	notified []int64,
+) (int64, error) {
+	accounts := make(map[int64]db.Accounts)
+	getAccount := func(id int64) (db.Accounts, error) {
+		if account, ok := accounts[id]; ok {
+			return account, nil
+		}
+
+		account, err := s.server.Accounts.Get(ctx, id)
+		if err != nil {
+			return db.Accounts{}, err
+		}
+		accounts[id] = account
+		return account, nil
+	}
+
+	for {
+		entries, err := s.server.DB.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+			AccountIds:        accountIDs,
+			AfterTxid:         cursor,
+			LimitTransactions: activityBatchSize,
+		})
+		if err != nil {
+			return cursor, domain.Internal("cannot list account activity", err)
+		}
+
+		transactions := 0
+		for i, entry := range entries {
+			account, err := getAccount(entry.AccountID)
+			if err != nil {
+				return cursor, err
+			}
+
+			if i == len(entries)-1 || entries[i+1].Txid != entry.Txid {
+				cursor = entry.Txid
+				transactions++
+			}
+
+			err = stream.Send(&pb.AccountActivity{
+				Account: gapiConverter.ConvertAccount(account),
+				Entry:   gapiConverter.ConvertEntry(entry),
+				Cursor:  cursor,
+			})
+			if err != nil {
+				return cursor, err
+			}
+		}
+
+		if transactions < activityBatchSize {
+			break
+		}
+	}
+
+	for _, id := range notified {
+		if _, sent := accounts[id]; sent {
+			continue
+		}
+
+		account, err := getAccount(id)
+		if err != nil {
+			return cursor, err
+		}
+
+		err = stream.Send(&pb.AccountActivity{
+			Account: gapiConverter.ConvertAccount(account),
+			Cursor:  cursor,
+		})
+		if err != nil {
+			return cursor, err
+		}
+	}
+	return cursor, nil
+}
+
+// watchedAccountIDs returns the requested accounts once they all belong to the caller,
+// every account of the caller when none is requested
+func (s *gapiHandlerSetup) watchedAccountIDs(ctx context.Context, authUser *gapi.AuthUser, requested []int64) ([]int64, error) {
+	if len(requested) == 0 {
+		accounts, err := s.server.DB.ListsAccounts(ctx, db.ListsAccountsParams{
+			Owner:  authUser.User.Username,
+			Limit:  gapiValidate.MaxWatchedAccounts,
+			Offset: 0,
+		})
+		if err != nil {
+			return nil, domain.Internal("cannot list accounts", err)
+		}
+
+		if len(accounts) == 0 {
+			return nil, domain.ErrAccountNotFound.WithMessage("no account to watch")
+		}
+
+		accountIDs := make([]int64, 0, len(accounts))
+		for _, account := range accounts {
+			accountIDs = append(accountIDs, account.ID)
+		}
+		return accountIDs, nil
+	}
+
+	accountIDs := make([]int64, 0, len(requested))
+	seen := make(map[int64]bool)
+	for _, id := range requested {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		account, err := s.server.Accounts.GetOwned(ctx, authUser.User.Username, id)
+		if err != nil {
+			return nil, err
+		}
+		accountIDs = append(accountIDs, account.ID)
+	}
+	return accountIDs, nil
+}
@@ -0,0 +1,75 @@
+package gapiHandler
+
+import (
+	"context"
+	"math"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	gapiValidate "github.com/claytten/golang-simplebank/internal/gapi/validate"
+	"github.com/claytten/golang-simplebank/pb"
+)
+
+// admin RPCs are limited to the admin role by their (pb.auth) option
+
+func (s *gapiHandlerSetup) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if violations := gapiValidate.ValidateListUsersRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	args := db.ListUsersParams{
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+
+	users, err := s.server.DB.ListUsers(ctx, args)
+	if err != nil {
+		return nil, domain.Internal("cannot list users", err)
+	}
+
+	allUsers, err := s.server.DB.GetTotalPageListsUsers(ctx)
+	if err != nil {
+		return nil, domain.Internal("cannot count users", err)
+	}
+
+	res := &pb.ListUsersResponse{
+		TotalPage:   int64(math.Ceil(float64(allUsers) / float64(args.Limit))),
+		CurrentPage: req.GetPageId(),
+	}
+	for _, user := range users {
+		res.Users = append(res.Users, gapiConverter.ConvertUser(user))
+	}
+
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) FreezeAccount(ctx context.Context, req *pb.FreezeAccountRequest) (*pb.FreezeAccountResponse, error) {
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.server.Accounts.Freeze(ctx, req.GetId(), gapiConverter.AuthUserAuditContext(ctx, s.server, authUser))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.FreezeAccountResponse{
+		Account: gapiConverter.ConvertAccount(account),
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) AdminGetAccount(ctx context.Context, req *pb.AdminGetAccountRequest) (*pb.AdminGetAccountResponse, error) {
+	account, err := s.server.Accounts.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.AdminGetAccountResponse{
+		Account: gapiConverter.ConvertAccount(account),
+	}
+	return res, nil
+}
@@ -0,0 +1,183 @@
+package gapiHandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/claytten/golang-simplebank/internal/apikey"
+	"github.com/claytten/golang-simplebank/internal/authz"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	gapiValidate "github.com/claytten/golang-simplebank/internal/gapi/validate"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/pb"
+)
+
+func (s *gapiHandlerSetup) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	if violations := gapiValidate.ValidateCreateAPIKeyRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.apiKeyOwner(ctx, authUser, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// the role check still applies to a key, an admin scope would be dead weight
+	for _, scope := range req.GetScopes() {
+		if strings.HasPrefix(scope, "admin:") && owner.Role != util.AdminRole {
+			return nil, domain.InvalidArgument(domain.FieldViolation{
+				Field:       "scopes",
+				Description: fmt.Sprintf("scope %s needs an admin owner", scope),
+			})
+		}
+
+		// an admin cannot spend the money of another user through a key they hold
+		if authz.MovesMoney(scope) && owner.Username != authUser.User.Username {
+			return nil, domain.ErrPermissionDenied.WithMessage(
+				fmt.Sprintf("only the owner creates an api key with scope %s", scope))
+		}
+	}
+
+	key, err := apikey.Generate()
+	if err != nil {
+		return nil, domain.Internal("cannot generate api key", err)
+	}
+
+	arg := db.CreateAPIKeyParams{
+		Prefix:       key.Prefix,
+		HashedSecret: apikey.HashSecret(key.Secret),
+		Name:         req.GetName(),
+		Owner:        owner.Username,
+		Scopes:       req.GetScopes(),
+	}
+	if req.ExpiresAt != nil {
+		arg.ExpiresAt = sql.NullTime{Time: req.GetExpiresAt().AsTime(), Valid: true}
+	}
+
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	var apiKey db.ApiKeys
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		apiKey, err = q.CreateAPIKey(ctx, arg)
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "api_key.create",
+			Target:       fmt.Sprintf("api_key:%d", apiKey.ID),
+			After:        db.NewAuditAPIKey(apiKey),
+		}, err
+	})
+	if err != nil {
+		return nil, domain.Internal("cannot create api key", err)
+	}
+
+	res := &pb.CreateAPIKeyResponse{
+		ApiKey: gapiConverter.ConvertAPIKey(apiKey),
+		Key:    key.String(),
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	if violations := gapiValidate.ValidateListAPIKeysRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.apiKeyOwner(ctx, authUser, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := s.server.DB.ListAPIKeys(ctx, owner.Username)
+	if err != nil {
+		return nil, domain.Internal("cannot list api keys", err)
+	}
+
+	res := &pb.ListAPIKeysResponse{}
+	for _, apiKey := range apiKeys {
+		res.ApiKeys = append(res.ApiKeys, gapiConverter.ConvertAPIKey(apiKey))
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	if violations := gapiValidate.ValidateRevokeAPIKeyRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.apiKeyOwner(ctx, authUser, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	var apiKey db.ApiKeys
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		apiKey, err = q.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+			ID:    req.GetId(),
+			Owner: owner.Username,
+		})
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "api_key.revoke",
+			Target:       fmt.Sprintf("api_key:%d", apiKey.ID),
+			After:        db.NewAuditAPIKey(apiKey),
+		}, err
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, domain.ErrAPIKeyNotFound.Wrap(err)
+		}
+		return nil, domain.Internal("cannot revoke api key", err)
+	}
+
+	res := &pb.RevokeAPIKeyResponse{
+		ApiKey: gapiConverter.ConvertAPIKey(apiKey),
+	}
+	return res, nil
+}
+
+// apiKeyOwner returns the user whose keys are managed, the caller itself unless
+// an admin manages the keys of another user, such as the user a service runs as
+func (s *gapiHandlerSetup) apiKeyOwner(ctx context.Context, authUser *gapi.AuthUser, owner *string) (db.Users, error) {
+	if owner == nil || *owner == authUser.User.Username {
+		return authUser.User, nil
+	}
+
+	if authUser.User.Role != util.AdminRole {
+		return db.Users{}, domain.ErrPermissionDenied.WithMessage("only an admin manages the api keys of another user")
+	}
+
+	user, err := s.server.DB.GetUser(ctx, *owner)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Users{}, domain.ErrUserNotFound.WithMessage("owner not found").Wrap(err)
+		}
+		return db.Users{}, domain.Internal("cannot find owner", err)
+	}
+	return user, nil
+}
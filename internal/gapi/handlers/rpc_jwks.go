@@ -0,0 +1,16 @@
+package gapiHandler
+
+import (
+	"context"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	"github.com/claytten/golang-simplebank/pb"
+)
+
+// GetJWKS publishes the keys verifying the access tokens,
+// it is empty while the tokens are signed with a symmetric key
+func (s *gapiHandlerSetup) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	jwks := token.PublicJWKS(s.server.Token)
+	return gapiConverter.ConvertJWKS(jwks), nil
+}
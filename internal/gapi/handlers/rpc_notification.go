@@ -0,0 +1,130 @@
+package gapiHandler
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	gapiValidate "github.com/claytten/golang-simplebank/internal/gapi/validate"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/claytten/golang-simplebank/pb"
+)
+
+func (s *gapiHandlerSetup) GetNotificationSettings(ctx context.Context, req *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error) {
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, preferences, err := getNotificationSettings(ctx, s.server.DB, authUser.User.Username)
+	if err != nil {
+		return nil, domain.Internal("cannot get notification settings", err)
+	}
+
+	res := &pb.GetNotificationSettingsResponse{
+		Settings: gapiConverter.ConvertNotificationSettings(settings, preferences),
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) UpdateNotificationSettings(ctx context.Context, req *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error) {
+	if violations := gapiValidate.ValidateUpdateNotificationSettingsRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	username := authUser.User.Username
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	var settings db.NotificationSettings
+	var preferences []db.NotificationPreferences
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		before, beforePreferences, err := getNotificationSettings(ctx, q, username)
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		arg := db.UpsertNotificationSettingsParams{
+			Username:            username,
+			Locale:              before.Locale,
+			PhoneNumber:         before.PhoneNumber,
+			PushToken:           before.PushToken,
+			LargeDebitThreshold: before.LargeDebitThreshold,
+		}
+		if req.Locale != nil {
+			arg.Locale = req.GetLocale()
+		}
+		if req.PhoneNumber != nil {
+			arg.PhoneNumber = req.GetPhoneNumber()
+		}
+		if req.PushToken != nil {
+			arg.PushToken = req.GetPushToken()
+		}
+		if req.LargeDebitThreshold != nil {
+			arg.LargeDebitThreshold = req.GetLargeDebitThreshold()
+		}
+
+		settings, err = q.UpsertNotificationSettings(ctx, arg)
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		for _, preference := range req.GetPreferences() {
+			_, err = q.UpsertNotificationPreference(ctx, db.UpsertNotificationPreferenceParams{
+				Username: username,
+				Event:    preference.GetEvent(),
+				Channel:  preference.GetChannel(),
+				Enabled:  preference.GetEnabled(),
+			})
+			if err != nil {
+				return db.AuditEventParams{}, err
+			}
+		}
+
+		preferences, err = q.ListNotificationPreferences(ctx, username)
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "notification_settings.update",
+			Target:       "user:" + username,
+			Before:       db.NewAuditNotificationSettings(before, beforePreferences),
+			After:        db.NewAuditNotificationSettings(settings, preferences),
+		}, nil
+	})
+	if err != nil {
+		return nil, domain.Internal("cannot update notification settings", err)
+	}
+
+	res := &pb.UpdateNotificationSettingsResponse{
+		Settings: gapiConverter.ConvertNotificationSettings(settings, preferences),
+	}
+	return res, nil
+}
+
+// getNotificationSettings returns the default settings of a user who never changed them
+func getNotificationSettings(ctx context.Context, q db.Querier, username string) (db.NotificationSettings, []db.NotificationPreferences, error) {
+	settings, err := q.GetNotificationSettings(ctx, username)
+	if err != nil {
+		if !errors.Is(err, db.ErrRecordNotFound) {
+			return db.NotificationSettings{}, nil, err
+		}
+		settings = notification.DefaultSettings(username)
+	}
+
+	preferences, err := q.ListNotificationPreferences(ctx, username)
+	if err != nil {
+		return db.NotificationSettings{}, nil, err
+	}
+	return settings, preferences, nil
+}
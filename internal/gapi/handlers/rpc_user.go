@@ -2,152 +2,75 @@ package gapiHandler
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
+	"errors"
 	"time"
 
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
 	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
 	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
 	gapiValidate "github.com/claytten/golang-simplebank/internal/gapi/validate"
-	"github.com/claytten/golang-simplebank/internal/util"
-	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/claytten/golang-simplebank/pb"
-	"github.com/hibiken/asynq"
-	"github.com/lib/pq"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (s *gapiHandlerSetup) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
 	//validating request
-	if err := gapiValidate.ValidateCreateUserRequest(req); err != nil {
+	if err := gapiValidate.ValidateCreateUserRequest(req, s.server.PasswordPolicy); err != nil {
 		return nil, gapiError.InvalidArgumentError(err)
 	}
 
-	hashedPassword, err := util.HashingPassword(req.GetPassword())
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to hashed password %s", err.Error())
-	}
-
-	arg := db.CreateUserTxParams{
-		CreateUserParams: db.CreateUserParams{
-			Username:       req.GetUsername(),
-			Email:          req.GetEmail(),
-			FullName:       req.GetFullName(),
-			HashedPassword: hashedPassword,
-		},
-		AfterCreate: func(user db.Users) error {
-			// send verification email
-			// TODO: db transaction while creating user for blocking process
-			taskPayload := &worker.PayloadSendVerifyEmail{
-				Email: user.Email,
-			}
-			opts := []asynq.Option{
-				asynq.MaxRetry(10),
-				asynq.ProcessIn(10 * time.Second),
-				asynq.Queue(worker.QueueCritical),
-			}
-			return s.server.TaskDistrbutor.DistributeTaskVerifyEmail(ctx, taskPayload, opts...)
-		},
-	}
-	userTx, err := s.server.DB.CreateUserTx(ctx, arg)
-
+	user, err := s.server.Users.Create(ctx, service.CreateUserParams{
+		Username: req.GetUsername(),
+		Email:    req.GetEmail(),
+		FullName: req.GetFullName(),
+		Password: req.GetPassword(),
+		// users sign up themselves
+		Audit: gapiConverter.AuditContext(ctx, s.server, req.GetUsername()),
+	})
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				return nil, status.Errorf(codes.AlreadyExists, "User cannot use. Please try again.")
-			}
-		}
-		return nil, status.Errorf(codes.Internal, "Failed to create user : %s", err.Error())
+		return nil, err
 	}
 
 	res := &pb.CreateUserResponse{
-		User: gapiConverter.ConvertUser(userTx.User),
+		User: gapiConverter.ConvertUser(user),
 	}
 	return res, nil
 }
 
 func (s *gapiHandlerSetup) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
-	violations := gapiValidate.ValidateLoginUserRequest(req)
+	violations := gapiValidate.ValidateLoginUserRequest(req, s.server.PasswordPolicy)
 	if violations != nil {
 		return nil, gapiError.InvalidArgumentError(violations)
 	}
-	user, err := s.server.DB.GetUserUsingEmail(ctx, req.GetEmail())
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Error(codes.NotFound, "User Not Found")
-		}
-		return nil, status.Error(codes.Internal, "Cannot Login!. username/email or password is not match.")
-	}
-
-	err = util.ComparePassword(user.HashedPassword, req.Password)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "Cannot Login!. username/email or password is not match.")
-	}
-
-	accessToken, accessPayload, err := s.server.Token.CreateToken(user.Email, s.server.Config.AccessTokenDuration)
-	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
-	}
 
-	refreshToken, refreshPayload, err := s.server.Token.CreateToken(user.Email, s.server.Config.RefreshTokenDuration)
-	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
-	}
-
-	extractMetadata := gapiConverter.ExtractMetadata(ctx, s.server)
-	session, err := s.server.DB.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		Email:        user.Email,
-		RefreshToken: refreshToken,
-		UserAgent:    extractMetadata.UserAgent,
-		ClientIp:     extractMetadata.ClientIP,
-		IsBlocked:    false,
-		ExpiresAt:    refreshPayload.ExpiredAt,
-		CreatedAt:    time.Now(),
+	result, err := s.server.Users.Login(ctx, service.LoginParams{
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+		Client:   gapiConverter.ExtractMetadata(ctx, s.server).ClientInfo(),
 	})
-
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
-	res := &pb.LoginUserResponse{
-		SessionId:             session.ID.String(),
-		AccessToken:           accessToken,
-		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiredAt),
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiredAt),
-		User:                  gapiConverter.ConvertUser(user),
-	}
-	return res, nil
+	return gapiConverter.ConvertLoginResult(result), nil
 }
 
 func (s *gapiHandlerSetup) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
-	}
-
-	userHeader, err := s.server.DB.GetUserUsingEmail(ctx, authPayload.Email)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "account doesn't belong to authenticated user")
-	}
-
-	if userHeader.Username != req.GetUsername() {
-		ff := fmt.Sprintf("%s %s", userHeader.Username, req.GetUsername())
-		return nil, status.Error(codes.Unauthenticated, ff)
+		return nil, err
 	}
 
-	user, err := s.server.DB.GetUser(ctx, req.GetUsername())
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "User Not Found")
+		return nil, err
 	}
 
 	return &pb.GetUserResponse{
-		User: gapiConverter.ConvertUser(user),
+		User: gapiConverter.ConvertUser(authUser.User),
 	}, nil
 }
 
@@ -157,39 +80,24 @@ func (s *gapiHandlerSetup) UpdateProfile(ctx context.Context, req *pb.UpdateProf
 		return nil, gapiError.InvalidArgumentError(violations)
 	}
 
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 
-	username, err := gapiConverter.CheckOwnUser(req.GetUsername(), req.GetOldPassword(), authPayload.Email, s.server, ctx)
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.server.DB.GetUser(ctx, username)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "User Not Found")
-	}
-
-	updatedUser, err := s.server.DB.UpdateUser(ctx, db.UpdateUserParams{
-		Username: user.Username,
-		FullName: sql.NullString{
-			String: req.GetFullName(),
-			Valid:  true,
-		},
-		Email: sql.NullString{
-			String: req.GetEmail(),
-			Valid:  true,
-		},
-		UpdatedAt: time.Now(),
+	updatedUser, err := s.server.Users.UpdateProfile(ctx, service.UpdateProfileParams{
+		User:     authUser.User,
+		FullName: req.GetFullName(),
+		Email:    req.GetEmail(),
+		Audit:    gapiConverter.AuthUserAuditContext(ctx, s.server, authUser),
 	})
-
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Error(codes.NotFound, "User Not Found")
-		}
-		return nil, status.Error(codes.Internal, "User Cannot Updated")
+		return nil, err
 	}
 
 	res := &pb.UpdateProfileResponse{
@@ -200,50 +108,32 @@ func (s *gapiHandlerSetup) UpdateProfile(ctx context.Context, req *pb.UpdateProf
 }
 
 func (s *gapiHandlerSetup) UpdatePassword(ctx context.Context, req *pb.UpdatePasswordRequest) (*pb.UpdatePasswordResponse, error) {
-	violations := gapiValidate.ValidateUpdatePasswordRequest(req)
+	violations := gapiValidate.ValidateUpdatePasswordRequest(req, s.server.PasswordPolicy)
 	if violations != nil {
 		return nil, gapiError.InvalidArgumentError(violations)
 	}
 
-	authPayload, err := gapiConverter.AuthorizeUser(ctx, s.server)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
-	}
-
-	username, err := gapiConverter.CheckOwnUser(req.GetUsername(), req.GetOldPassword(), authPayload.Email, s.server, ctx)
+	authUser, err := gapi.AuthUserFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.server.DB.GetUser(ctx, username)
+	err = service.CheckOwnUser(authUser.User, req.GetUsername())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "User Not Found")
+		return nil, err
 	}
 
-	newPassword, err := util.HashingPassword(req.GetPassword())
-	if err != nil {
-		return nil, status.Error(codes.Internal, "Password can't use. Please Try Another Password")
-	}
-
-	updatedUser, err := s.server.DB.UpdateUser(ctx, db.UpdateUserParams{
-		Username: user.Username,
-		HashedPassword: sql.NullString{
-			String: newPassword,
-			Valid:  true,
-		},
-		PasswordChangedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		UpdatedAt: time.Now(),
+	user, err := s.server.Users.UpdatePassword(ctx, service.UpdatePasswordParams{
+		Username: authUser.User.Username,
+		Password: req.GetPassword(),
+		Audit:    gapiConverter.AuthUserAuditContext(ctx, s.server, authUser),
 	})
-
 	if err != nil {
-		return nil, status.Error(codes.Internal, "User Cannot Updated")
+		return nil, err
 	}
 
 	res := &pb.UpdatePasswordResponse{
-		User: gapiConverter.ConvertUser(updatedUser),
+		User: gapiConverter.ConvertUser(user),
 	}
 
 	return res, nil
@@ -252,46 +142,53 @@ func (s *gapiHandlerSetup) UpdatePassword(ctx context.Context, req *pb.UpdatePas
 func (s *gapiHandlerSetup) RenewToken(ctx context.Context, req *pb.RenewTokenRequest) (*pb.RenewTokenResponse, error) {
 	refreshPayload, err := s.server.Token.VerifyToken(req.RefreshToken)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, domain.ErrSessionInvalid.WithMessage(err.Error()).Wrap(err)
+	}
+
+	// only a refresh token renews the access token of its session
+	if !refreshPayload.IsRefresh() {
+		return nil, domain.ErrSessionInvalid.WithMessage("not a refresh token")
 	}
 
-	session, err := s.server.DB.GetSession(ctx, refreshPayload.ID)
+	session, err := s.server.DB.GetSession(ctx, refreshPayload.SessionID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Error(codes.NotFound, err.Error())
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, domain.ErrSessionNotFound.Wrap(err)
 		}
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, domain.Internal("cannot find session", err)
 	}
 
 	// session checking
 	// check block sessin
 	if session.IsBlocked {
-		return nil, status.Error(codes.Unauthenticated, "blocked session")
+		return nil, domain.ErrSessionInvalid.WithMessage("blocked session")
 	}
 
 	// check email is matching
 	if session.Email != refreshPayload.Email {
-		return nil, status.Error(codes.Unauthenticated, "incorrect session user")
+		return nil, domain.ErrSessionInvalid.WithMessage("incorrect session user")
 	}
 
 	// check refreshToken is matching
 	if session.RefreshToken != req.RefreshToken {
-		return nil, status.Error(codes.Unauthenticated, "mismatched session token")
+		return nil, domain.ErrSessionInvalid.WithMessage("mismatched session token")
 	}
 
 	// check expired token
 	if time.Now().After(session.ExpiresAt) {
-		return nil, status.Error(codes.Unauthenticated, "expired session")
+		return nil, domain.ErrSessionInvalid.WithMessage("expired session")
 	}
 
 	// create new token
 	accessToken, accessTokenPayload, err := s.server.Token.CreateToken(
 		refreshPayload.Email,
+		refreshPayload.Role,
+		session.ID,
 		s.server.Config.AccessTokenDuration,
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, domain.Internal("cannot create access token", err)
 	}
 
 	response := &pb.RenewTokenResponse{
@@ -301,3 +198,69 @@ func (s *gapiHandlerSetup) RenewToken(ctx context.Context, req *pb.RenewTokenReq
 
 	return response, nil
 }
+
+// Reauthenticate checks the password again and issues a short-lived token for the same session,
+// sensitive RPCs accept that token instead of asking for the password on every request
+func (s *gapiHandlerSetup) Reauthenticate(ctx context.Context, req *pb.ReauthenticateRequest) (*pb.ReauthenticateResponse, error) {
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := gapiValidate.ValidateReauthenticateRequest(req, s.server.PasswordPolicy)
+	if violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	elevatedToken, elevatedPayload, err := s.server.Users.Reauthenticate(ctx, service.ReauthenticateParams{
+		User:      authUser.User,
+		SessionID: authUser.Payload.SessionID,
+		Password:  req.GetPassword(),
+		Client:    gapiConverter.ExtractMetadata(ctx, s.server).ClientInfo(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ReauthenticateResponse{
+		ElevatedToken:          elevatedToken,
+		ElevatedTokenExpiresAt: timestamppb.New(elevatedPayload.ExpiredAt),
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) LogoutUser(ctx context.Context, req *pb.LogoutUserRequest) (*pb.LogoutUserResponse, error) {
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	var session db.Sessions
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		session, err = q.BlockSession(ctx, authUser.Payload.SessionID)
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "session.block",
+			Target:       "session:" + authUser.Payload.SessionID.String(),
+			After:        db.NewAuditSession(session),
+		}, err
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, domain.ErrSessionNotFound.Wrap(err)
+		}
+		return nil, domain.Internal("cannot block session", err)
+	}
+
+	if err = s.server.Users.RevokeSessions(ctx, session); err != nil {
+		return nil, err
+	}
+
+	res := &pb.LogoutUserResponse{
+		Message: "Logout Successfully",
+	}
+	return res, nil
+}
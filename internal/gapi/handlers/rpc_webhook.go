@@ -0,0 +1,237 @@
+package gapiHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	gapiValidate "github.com/claytten/golang-simplebank/internal/gapi/validate"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/claytten/golang-simplebank/pb"
+)
+
+func (s *gapiHandlerSetup) CreateWebhookSubscription(ctx context.Context, req *pb.CreateWebhookSubscriptionRequest) (*pb.CreateWebhookSubscriptionResponse, error) {
+	if violations := gapiValidate.ValidateCreateWebhookSubscriptionRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return nil, domain.Internal("cannot generate webhook secret", err)
+	}
+
+	arg := db.CreateWebhookSubscriptionParams{
+		Owner:      authUser.User.Username,
+		Url:        req.GetUrl(),
+		EventTypes: req.GetEventTypes(),
+		Secret:     secret,
+	}
+
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	var subscription db.WebhookSubscriptions
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		subscription, err = q.CreateWebhookSubscription(ctx, arg)
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "webhook_subscription.create",
+			Target:       fmt.Sprintf("webhook_subscription:%d", subscription.ID),
+			After:        db.NewAuditWebhookSubscription(subscription),
+		}, err
+	})
+	if err != nil {
+		return nil, domain.Internal("cannot create webhook subscription", err)
+	}
+
+	res := &pb.CreateWebhookSubscriptionResponse{
+		Subscription: gapiConverter.ConvertWebhookSubscription(subscription),
+		Secret:       secret,
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) ListWebhookSubscriptions(ctx context.Context, req *pb.ListWebhookSubscriptionsRequest) (*pb.ListWebhookSubscriptionsResponse, error) {
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.server.DB.ListWebhookSubscriptions(ctx, authUser.User.Username)
+	if err != nil {
+		return nil, domain.Internal("cannot list webhook subscriptions", err)
+	}
+
+	res := &pb.ListWebhookSubscriptionsResponse{}
+	for _, subscription := range subscriptions {
+		res.Subscriptions = append(res.Subscriptions, gapiConverter.ConvertWebhookSubscription(subscription))
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) DeleteWebhookSubscription(ctx context.Context, req *pb.DeleteWebhookSubscriptionRequest) (*pb.DeleteWebhookSubscriptionResponse, error) {
+	if violations := gapiValidate.ValidateDeleteWebhookSubscriptionRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	var subscription db.WebhookSubscriptions
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		subscription, err = q.DeleteWebhookSubscription(ctx, db.DeleteWebhookSubscriptionParams{
+			ID:    req.GetId(),
+			Owner: authUser.User.Username,
+		})
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "webhook_subscription.delete",
+			Target:       fmt.Sprintf("webhook_subscription:%d", subscription.ID),
+			Before:       db.NewAuditWebhookSubscription(subscription),
+		}, err
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, domain.ErrWebhookSubscriptionNotFound.Wrap(err)
+		}
+		return nil, domain.Internal("cannot delete webhook subscription", err)
+	}
+
+	res := &pb.DeleteWebhookSubscriptionResponse{
+		Subscription: gapiConverter.ConvertWebhookSubscription(subscription),
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	if violations := gapiValidate.ValidateListWebhookDeliveriesRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.ownWebhookSubscription(ctx, authUser, req.GetSubscriptionId())
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.server.DB.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          req.GetPageSize(),
+		Offset:         (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, domain.Internal("cannot list webhook deliveries", err)
+	}
+
+	res := &pb.ListWebhookDeliveriesResponse{
+		CurrentPage: req.GetPageId(),
+	}
+	for _, delivery := range deliveries {
+		res.Deliveries = append(res.Deliveries, gapiConverter.ConvertWebhookDelivery(delivery))
+	}
+	return res, nil
+}
+
+func (s *gapiHandlerSetup) ReplayWebhookDelivery(ctx context.Context, req *pb.ReplayWebhookDeliveryRequest) (*pb.ReplayWebhookDeliveryResponse, error) {
+	if violations := gapiValidate.ValidateReplayWebhookDeliveryRequest(req); violations != nil {
+		return nil, gapiError.InvalidArgumentError(violations)
+	}
+
+	authUser, err := gapi.AuthUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.server.DB.GetWebhookDelivery(ctx, req.GetId())
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, domain.ErrWebhookDeliveryNotFound.Wrap(err)
+		}
+		return nil, domain.Internal("cannot find webhook delivery", err)
+	}
+
+	if _, err = s.ownWebhookSubscription(ctx, authUser, delivery.SubscriptionID); err != nil {
+		if errors.Is(err, domain.ErrWebhookSubscriptionNotFound) {
+			return nil, domain.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	// a delivery that is still retried would be made twice
+	if delivery.Status != webhook.StatusSucceeded && delivery.Status != webhook.StatusDead {
+		return nil, domain.ErrWebhookDeliveryNotFinished.WithMetadata("status", delivery.Status)
+	}
+
+	task, err := worker.NewTaskEvent(worker.TaskDeliverWebhook, &worker.PayloadDeliverWebhook{DeliveryID: delivery.ID})
+	if err != nil {
+		return nil, domain.Internal("cannot create webhook delivery task", err)
+	}
+
+	audit := gapiConverter.AuthUserAuditContext(ctx, s.server, authUser)
+
+	before := delivery
+	err = s.server.DB.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		delivery, err = q.ReplayWebhookDelivery(ctx, before.ID)
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if _, err = db.WriteOutboxEvent(ctx, q, task); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "webhook_delivery.replay",
+			Target:       fmt.Sprintf("webhook_delivery:%d", delivery.ID),
+			Before:       db.NewAuditWebhookDelivery(before),
+			After:        db.NewAuditWebhookDelivery(delivery),
+		}, nil
+	})
+	if err != nil {
+		return nil, domain.Internal("cannot replay webhook delivery", err)
+	}
+
+	res := &pb.ReplayWebhookDeliveryResponse{
+		Delivery: gapiConverter.ConvertWebhookDelivery(delivery),
+	}
+	return res, nil
+}
+
+// ownWebhookSubscription returns a subscription of the caller, the subscriptions
+// of other users are not found
+func (s *gapiHandlerSetup) ownWebhookSubscription(ctx context.Context, authUser *gapi.AuthUser, id int64) (db.WebhookSubscriptions, error) {
+	subscription, err := s.server.DB.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.WebhookSubscriptions{}, domain.ErrWebhookSubscriptionNotFound.Wrap(err)
+		}
+		return db.WebhookSubscriptions{}, domain.Internal("cannot find webhook subscription", err)
+	}
+
+	if subscription.Owner != authUser.User.Username {
+		return db.WebhookSubscriptions{}, domain.ErrWebhookSubscriptionNotFound
+	}
+	return subscription, nil
+}
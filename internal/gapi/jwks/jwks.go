@@ -0,0 +1,47 @@
+package gapiJWKS
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+)
+
+// Path is where other services look for the keys verifying our tokens
+const Path = "/.well-known/jwks.json"
+
+// Handler serves the JWKS of the token maker. The keys only change when the
+// server restarts with a rotated keyring, so consumers may cache them for
+// maxAge and revalidate with the ETag afterwards.
+func Handler(maker token.Maker, maxAge time.Duration) (http.Handler, error) {
+	body, err := json.Marshal(token.PublicJWKS(maker))
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal JWKS: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}), nil
+}
@@ -0,0 +1,159 @@
+package gapiJWKS_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	gapiJWKS "github.com/claytten/golang-simplebank/internal/gapi/jwks"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyringMaker(t *testing.T) token.Maker {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key, err := token.NewKey("2023-04", privateKey)
+	require.NoError(t, err)
+
+	keyring, err := token.NewKeyring(key.ID, key)
+	require.NoError(t, err)
+
+	maker, err := token.NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+	return maker
+}
+
+func TestHandler(t *testing.T) {
+	maker := newKeyringMaker(t)
+
+	handler, err := gapiJWKS.Handler(maker, time.Hour)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		method        string
+		setupHeader   func(header http.Header, etag string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		// TODO: 200 OK
+		{
+			name:        "200 OK",
+			method:      http.MethodGet,
+			setupHeader: func(header http.Header, etag string) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "public, max-age=3600", recorder.Header().Get("Cache-Control"))
+				require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+				require.NotEmpty(t, recorder.Header().Get("ETag"))
+
+				var jwks token.JWKS
+				err := json.Unmarshal(recorder.Body.Bytes(), &jwks)
+				require.NoError(t, err)
+				require.Len(t, jwks.Keys, 1)
+				require.Equal(t, "2023-04", jwks.Keys[0].KeyID)
+				require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+			},
+		},
+
+		// TODO: 304 cached by the consumer
+		{
+			name:   "304 not modified",
+			method: http.MethodGet,
+			setupHeader: func(header http.Header, etag string) {
+				header.Set("If-None-Match", etag)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotModified, recorder.Code)
+				require.Empty(t, recorder.Body.Bytes())
+			},
+		},
+
+		// TODO: 200 stale ETag
+		{
+			name:   "200 stale etag",
+			method: http.MethodGet,
+			setupHeader: func(header http.Header, etag string) {
+				header.Set("If-None-Match", `"stale"`)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotEmpty(t, recorder.Body.Bytes())
+			},
+		},
+
+		// TODO: 405 method not allowed
+		{
+			name:        "405 method not allowed",
+			method:      http.MethodPost,
+			setupHeader: func(header http.Header, etag string) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the ETag of the document
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, gapiJWKS.Path, nil))
+			etag := recorder.Header().Get("ETag")
+
+			request := httptest.NewRequest(tt.method, gapiJWKS.Path, nil)
+			tt.setupHeader(request.Header, etag)
+
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			tt.checkResponse(t, recorder)
+		})
+	}
+}
+
+// a consumer finds the key of an issued token by its key ID
+func TestHandlerKeyID(t *testing.T) {
+	maker := newKeyringMaker(t)
+
+	handler, err := gapiJWKS.Handler(maker, time.Hour)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, gapiJWKS.Path, nil))
+
+	var jwks token.JWKS
+	err = json.Unmarshal(recorder.Body.Bytes(), &jwks)
+	require.NoError(t, err)
+	require.Len(t, jwks.Keys, 1)
+
+	accessToken, _, err := maker.CreateToken(util.RandomEmail(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// v4.public.<payload>.<footer>
+	parts := strings.Split(accessToken, ".")
+	require.Len(t, parts, 4)
+
+	footer, err := base64.RawURLEncoding.DecodeString(parts[3])
+	require.NoError(t, err)
+
+	var gotFooter struct {
+		KeyID string `json:"kid"`
+	}
+	err = json.Unmarshal(footer, &gotFooter)
+	require.NoError(t, err)
+	require.Equal(t, jwks.Keys[0].KeyID, gotFooter.KeyID)
+
+	x, err := base64.RawURLEncoding.DecodeString(jwks.Keys[0].X)
+	require.NoError(t, err)
+
+	key, err := maker.(token.KeyringMaker).Keyring().Key(gotFooter.KeyID)
+	require.NoError(t, err)
+	require.Equal(t, ed25519.PublicKey(x), key.PublicKey)
+}
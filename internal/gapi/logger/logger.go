@@ -5,12 +5,108 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/metrics"
+	"github.com/claytten/golang-simplebank/internal/redact"
+	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
+// GrpcRequestID gives every unary RPC the request id sent by the caller or a new one,
+// and a logger with the request id and the method, the authenticator adds the user.
+// The id is returned in the response header and the details of an error.
+func GrpcRequestID(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	ctx, requestID := grpcRequestContext(ctx, info.FullMethod)
+
+	result, err := handler(ctx, req)
+	if err != nil {
+		return nil, gapiError.WithRequestInfo(err, requestID)
+	}
+	return result, nil
+}
+
+// GrpcStreamRequestID is GrpcRequestID for streaming RPCs
+func GrpcStreamRequestID(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, requestID := grpcRequestContext(stream.Context(), info.FullMethod)
+
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	if err != nil {
+		return gapiError.WithRequestInfo(err, requestID)
+	}
+	return nil
+}
+
+func grpcRequestContext(ctx context.Context, method string) (context.Context, string) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(logging.MetadataRequestID); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	requestID = logging.RequestIDOrNew(requestID)
+
+	grpc.SetHeader(ctx, metadata.Pairs(logging.MetadataRequestID, requestID))
+
+	ctx = logging.WithRequestID(ctx, requestID)
+	ctx = logging.WithLogger(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str("request_id", requestID).Str("method", method)
+	})
+	return ctx, requestID
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// GrpcClientRequestID passes the request id of the gateway request on to the gRPC server
+func GrpcClientRequestID(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+// GrpcClientStreamRequestID is GrpcClientRequestID for streaming RPCs
+func GrpcClientStreamRequestID(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+func outgoingRequestID(ctx context.Context) context.Context {
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		return metadata.AppendToOutgoingContext(ctx, logging.MetadataRequestID, requestID)
+	}
+	return ctx
+}
+
 func GrpcLogger(
 	ctx context.Context,
 	req interface{},
@@ -21,18 +117,19 @@ func GrpcLogger(
 	result, err := handler(ctx, req)
 	duration := time.Since(startTime)
 
-	statusCode := codes.Unknown
-	if status, ok := status.FromError(err); ok {
-		statusCode = status.Code()
-	}
+	statusCode := gapiError.Code(err)
+	metrics.ObserveGRPCRequest(info.FullMethod, statusCode, duration)
 
-	logger := log.Info()
+	logger := logging.Ctx(ctx).Info()
 	if err != nil {
-		logger = log.Error().Err(err)
+		// the request helps to tell why it failed, its sensitive fields are masked
+		logger = logging.Ctx(ctx).Error().Err(err)
+		if message, ok := req.(proto.Message); ok {
+			logger = logger.RawJSON("request", redact.MessageJSON(message))
+		}
 	}
 
 	logger.Str("protocol", "gRPC").
-		Str("method", info.FullMethod).
 		Int("status_code", int(statusCode)).
 		Str("status_text", statusCode.String()).
 		Dur("duration", duration).
@@ -40,10 +137,36 @@ func GrpcLogger(
 	return result, err
 }
 
+// GrpcStreamLogger logs a streaming RPC once it ends
+func GrpcStreamLogger(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	startTime := time.Now()
+	err := handler(srv, stream)
+	duration := time.Since(startTime)
+
+	statusCode := gapiError.Code(err)
+	logger := logging.Ctx(stream.Context()).Info()
+	if err != nil {
+		logger = logging.Ctx(stream.Context()).Error().Err(err)
+	}
+
+	logger.Str("protocol", "gRPC").
+		Int("status_code", int(statusCode)).
+		Str("status_text", statusCode.String()).
+		Dur("duration", duration).
+		Msg("received_grpc_stream")
+	return err
+}
+
+// ResponseRecorder records the status code of a response, not its body,
+// the body may carry tokens or personal data
 type ResponseRecorder struct {
 	http.ResponseWriter
 	StatusCode int
-	Body       []byte
 }
 
 func (rec *ResponseRecorder) WriteHeader(statusCode int) {
@@ -51,29 +174,45 @@ func (rec *ResponseRecorder) WriteHeader(statusCode int) {
 	rec.ResponseWriter.WriteHeader(statusCode)
 }
 
-func (rec *ResponseRecorder) Write(body []byte) (int, error) {
-	rec.Body = body
-	return rec.ResponseWriter.Write(body)
+// Flush lets streamed responses through, such as WatchAccountActivity
+func (rec *ResponseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
 
+// HttpLogger gives every request the X-Request-ID sent by the caller or a new one,
+// returns it in the response header and logs the request with it. Only the path
+// is logged, the query may carry codes and tokens.
 func HttpLogger(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		startTime := time.Now()
+
+		requestID := logging.RequestIDOrNew(req.Header.Get(logging.HeaderRequestID))
+		res.Header().Set(logging.HeaderRequestID, requestID)
+
+		ctx := logging.WithRequestID(req.Context(), requestID)
+		ctx = logging.WithLogger(ctx, func(c zerolog.Context) zerolog.Context {
+			return c.Str("request_id", requestID)
+		})
+		req = req.WithContext(ctx)
+
 		rec := &ResponseRecorder{
 			ResponseWriter: res,
 			StatusCode:     http.StatusOK,
 		}
 		handler.ServeHTTP(rec, req)
 		duration := time.Since(startTime)
+		metrics.ObserveHTTPRequest(req.Method, rec.StatusCode, duration)
 
-		logger := log.Info()
+		logger := logging.Ctx(ctx).Info()
 		if rec.StatusCode != http.StatusOK {
-			logger = log.Error()
+			logger = logging.Ctx(ctx).Error()
 		}
 
 		logger.Str("protocol", "HTTP").
 			Str("method", req.Method).
-			Str("path", req.RequestURI).
+			Str("path", req.URL.Path).
 			Int("status_code", rec.StatusCode).
 			Str("status_text", http.StatusText(rec.StatusCode)).
 			Dur("duration", duration).
@@ -0,0 +1,165 @@
+package gapiLogger_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gapiLogger "github.com/claytten/golang-simplebank/internal/gapi/logger"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/redact"
+	"github.com/claytten/golang-simplebank/pb"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/pb.Simplebank/GetAccount"}
+
+func requestInfo(t *testing.T, err error) *errdetails.RequestInfo {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RequestInfo); ok {
+			return info
+		}
+	}
+	t.Fatal("error has no request info")
+	return nil
+}
+
+func TestGrpcRequestID(t *testing.T) {
+	testCases := []struct {
+		name       string
+		md         metadata.MD
+		handlerErr error
+		check      func(t *testing.T, requestID string, err error)
+	}{
+		// TODO: id sent by the gateway
+		{
+			name: "Forwarded",
+			md:   metadata.Pairs(logging.MetadataRequestID, "request-1"),
+			check: func(t *testing.T, requestID string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "request-1", requestID)
+			},
+		},
+		// TODO: new id
+		{
+			name: "New",
+			md:   metadata.MD{},
+			check: func(t *testing.T, requestID string, err error) {
+				require.NoError(t, err)
+				require.Len(t, requestID, 36)
+			},
+		},
+		// TODO: id in the error details
+		{
+			name:       "ErrorDetails",
+			md:         metadata.Pairs(logging.MetadataRequestID, "request-1"),
+			handlerErr: status.Error(codes.NotFound, "account not found"),
+			check: func(t *testing.T, requestID string, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+				require.Equal(t, "account not found", status.Convert(err).Message())
+				require.Equal(t, "request-1", requestInfo(t, err).GetRequestId())
+			},
+		},
+		// TODO: error without status
+		{
+			name:       "PlainError",
+			md:         metadata.MD{},
+			handlerErr: errors.New("boom"),
+			check: func(t *testing.T, requestID string, err error) {
+				require.Equal(t, codes.Unknown, status.Code(err))
+				require.Equal(t, requestID, requestInfo(t, err).GetRequestId())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+
+			var requestID string
+			_, err := gapiLogger.GrpcRequestID(ctx, nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+				requestID = logging.RequestID(ctx)
+				return nil, tc.handlerErr
+			})
+			tc.check(t, requestID, err)
+		})
+	}
+}
+
+func TestGrpcClientRequestID(t *testing.T) {
+	ctx := logging.WithRequestID(context.Background(), "request-1")
+
+	err := gapiLogger.GrpcClientRequestID(ctx, unaryInfo.FullMethod, nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, ok := metadata.FromOutgoingContext(ctx)
+			require.True(t, ok)
+			require.Equal(t, []string{"request-1"}, md.Get(logging.MetadataRequestID))
+			return nil
+		},
+	)
+	require.NoError(t, err)
+}
+
+func TestGrpcLoggerRedactsRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	ctx := logger.WithContext(context.Background())
+	req := &pb.LoginUserRequest{Email: "alice@email.com", Password: "secret123"}
+
+	_, err := gapiLogger.GrpcLogger(ctx, req, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "incorrect password")
+	})
+	require.Error(t, err)
+
+	require.Contains(t, buf.String(), `"request":{`)
+	require.Contains(t, buf.String(), redact.Mask)
+	require.NotContains(t, buf.String(), "alice@email.com")
+	require.NotContains(t, buf.String(), "secret123")
+}
+
+func TestHttpLogger(t *testing.T) {
+	var requestID string
+	handler := gapiLogger.HttpLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = logging.RequestID(r.Context())
+	}))
+
+	// the id sent by the caller is kept
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil)
+	req.Header.Set(logging.HeaderRequestID, "request-1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	require.Equal(t, "request-1", requestID)
+	require.Equal(t, "request-1", recorder.Header().Get(logging.HeaderRequestID))
+
+	// a new id is returned otherwise
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil))
+
+	require.Len(t, requestID, 36)
+	require.Equal(t, requestID, recorder.Header().Get(logging.HeaderRequestID))
+}
+
+func TestHttpLoggerOmitsQuery(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	handler := gapiLogger.HttpLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/callback?code=secret-code&state=abc", nil)
+	req = req.WithContext(logger.WithContext(req.Context()))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Contains(t, buf.String(), `"path":"/oauth/callback"`)
+	require.NotContains(t, buf.String(), "secret-code")
+}
@@ -0,0 +1,316 @@
+package gapiOIDC
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiConverter "github.com/claytten/golang-simplebank/internal/gapi/converter"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/oidc"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	LoginPath    = "/api/v1/oidc/login"
+	CallbackPath = "/api/v1/oidc/callback"
+
+	// flowCookie carries the state, nonce and PKCE verifier from login to callback
+	flowCookie = "simplebank_oidc"
+	flowMaxAge = 10 * time.Minute
+)
+
+var marshaler = protojson.MarshalOptions{UseProtoNames: true}
+
+// flow is what the callback needs to check it answers our own login
+type flow struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// Handler signs users in with an external identity provider. The external
+// subject is linked to a users row, on first login to the user with the same
+// verified email or to a new user, and the login ends with a normal session.
+type Handler struct {
+	server   *gapi.Server
+	provider *oidc.Provider
+	secure   bool
+}
+
+func NewHandler(server *gapi.Server, provider *oidc.Provider) *Handler {
+	return &Handler{
+		server:   server,
+		provider: provider,
+		secure:   strings.HasPrefix(server.Config.OIDCRedirectURL, "https://"),
+	}
+}
+
+// Register adds the login and callback routes to the gateway mux
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(LoginPath, h.login)
+	mux.HandleFunc(CallbackPath, h.callback)
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, status.Error(codes.Unimplemented, "method not allowed"))
+		return
+	}
+
+	f, err := newFlow()
+	if err != nil {
+		writeError(w, domain.Internal("cannot start login", err))
+		return
+	}
+
+	authURL, err := h.provider.AuthCodeURL(r.Context(), f.State, f.Nonce, oidc.CodeChallenge(f.CodeVerifier))
+	if err != nil {
+		logging.Ctx(r.Context()).Error().Err(err).Msg("cannot reach identity provider")
+		writeError(w, domain.ErrUnavailable.WithMessage("identity provider is unavailable").Wrap(err))
+		return
+	}
+
+	value, err := json.Marshal(f)
+	if err != nil {
+		writeError(w, domain.Internal("cannot start login", err))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flowCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     CallbackPath,
+		MaxAge:   int(flowMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *Handler) callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, status.Error(codes.Unimplemented, "method not allowed"))
+		return
+	}
+
+	// the flow is single use
+	http.SetCookie(w, &http.Cookie{Name: flowCookie, Path: CallbackPath, MaxAge: -1, HttpOnly: true, Secure: h.secure})
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		writeError(w, domain.ErrUnauthenticated.WithMessage("identity provider denied the login: "+errCode))
+		return
+	}
+
+	f, err := readFlow(r)
+	if err != nil || query.Get("state") == "" || query.Get("state") != f.State {
+		writeError(w, status.Error(codes.InvalidArgument, "login state mismatch, start the login again"))
+		return
+	}
+
+	rawIDToken, err := h.provider.Exchange(r.Context(), query.Get("code"), f.CodeVerifier)
+	if err != nil {
+		logging.Ctx(r.Context()).Error().Err(err).Msg("cannot exchange authorization code")
+		writeError(w, domain.ErrUnauthenticated.WithMessage("cannot exchange authorization code").Wrap(err))
+		return
+	}
+
+	claims, err := h.provider.Verify(r.Context(), rawIDToken, f.Nonce)
+	if err != nil {
+		writeError(w, domain.ErrUnauthenticated.WithMessage("invalid id token: "+err.Error()).Wrap(err))
+		return
+	}
+
+	mtdt := &gapiConverter.Metadata{
+		UserAgent: r.UserAgent(),
+		ClientIP:  h.server.ClientIP.Resolve(r.RemoteAddr, r.Header.Values("X-Forwarded-For")),
+	}
+
+	user, err := h.linkUser(r.Context(), claims, mtdt)
+	if err != nil {
+		logging.Ctx(r.Context()).Error().Err(err).Msg("cannot log in with identity provider")
+		writeError(w, err)
+		return
+	}
+
+	result, err := h.server.Users.CreateSession(r.Context(), user, mtdt.ClientInfo())
+	if err != nil {
+		logging.Ctx(r.Context()).Error().Err(err).Msg("cannot log in with identity provider")
+		writeError(w, err)
+		return
+	}
+
+	data, err := marshaler.Marshal(gapiConverter.ConvertLoginResult(result))
+	if err != nil {
+		writeError(w, domain.Internal("cannot encode response", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+// linkUser returns the user of the external identity, linking it on first login
+func (h *Handler) linkUser(ctx context.Context, claims *oidc.Claims, mtdt *gapiConverter.Metadata) (db.Users, error) {
+	identity, err := h.server.DB.GetUserIdentity(ctx, db.GetUserIdentityParams{
+		Issuer:  h.provider.Issuer(),
+		Subject: claims.Subject,
+	})
+	if err == nil {
+		user, err := h.server.DB.GetUser(ctx, identity.Username)
+		if err != nil {
+			return db.Users{}, domain.Internal("cannot find linked user", err)
+		}
+		return user, nil
+	}
+
+	if !errors.Is(err, db.ErrRecordNotFound) {
+		return db.Users{}, domain.Internal("cannot find identity", err)
+	}
+
+	// an unverified email would let anyone claim the account of its owner
+	if claims.Email == "" || !claims.EmailVerified {
+		return db.Users{}, domain.ErrPermissionDenied.WithMessage("identity provider did not verify the email")
+	}
+
+	arg := db.LinkUserIdentityTxParams{
+		Identity: db.CreateUserIdentityParams{
+			Issuer:  h.provider.Issuer(),
+			Subject: claims.Subject,
+			Email:   claims.Email,
+		},
+	}
+
+	user, err := h.server.DB.GetUserUsingEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		arg.Identity.Username = user.Username
+	case errors.Is(err, db.ErrRecordNotFound):
+		newUser, err := newUserParams(claims)
+		if err != nil {
+			return db.Users{}, domain.Internal("cannot create user", err)
+		}
+		arg.NewUser = &newUser
+	default:
+		return db.Users{}, domain.Internal("cannot find user", err)
+	}
+
+	arg.Audit = db.AuditContext{
+		Actor:     arg.Identity.Username,
+		ClientIP:  mtdt.ClientIP,
+		UserAgent: mtdt.UserAgent,
+	}
+	if arg.NewUser != nil {
+		arg.Audit.Actor = arg.NewUser.Username
+	}
+
+	result, err := h.server.DB.LinkUserIdentityTx(ctx, arg)
+	if err != nil {
+		return db.Users{}, domain.Internal("cannot link identity", err)
+	}
+	return result.User, nil
+}
+
+// newUserParams creates a user who only signs in with the identity provider,
+// the random password is never shown so it cannot be used
+func newUserParams(claims *oidc.Claims) (db.CreateUserParams, error) {
+	password, err := oidc.RandomValue()
+	if err != nil {
+		return db.CreateUserParams{}, err
+	}
+
+	hashedPassword, err := util.HashingPassword(password)
+	if err != nil {
+		return db.CreateUserParams{}, err
+	}
+
+	local := strings.ToLower(strings.SplitN(claims.Email, "@", 2)[0])
+	username := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, local)
+	if len(username) > 90 {
+		username = username[:90]
+	}
+	// the local part is often taken, by another domain or another user
+	username = fmt.Sprintf("%s_%s", username, strings.ToLower(util.RandomString(6)))
+
+	fullName := claims.Name
+	if fullName == "" {
+		fullName = local
+	}
+
+	return db.CreateUserParams{
+		Username:       username,
+		HashedPassword: hashedPassword,
+		FullName:       fullName,
+		Email:          claims.Email,
+	}, nil
+}
+
+func newFlow() (*flow, error) {
+	var f flow
+	for _, v := range []*string{&f.State, &f.Nonce, &f.CodeVerifier} {
+		value, err := oidc.RandomValue()
+		if err != nil {
+			return nil, err
+		}
+		*v = value
+	}
+	return &f, nil
+}
+
+func readFlow(r *http.Request) (*flow, error) {
+	cookie, err := r.Cookie(flowCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var f flow
+	if err := json.Unmarshal(value, &f); err != nil {
+		return nil, err
+	}
+
+	if f.State == "" || f.Nonce == "" || f.CodeVerifier == "" {
+		return nil, errors.New("incomplete login flow")
+	}
+	return &f, nil
+}
+
+// writeError answers like the gateway does for a gRPC status, sanitized like the RPCs
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(gapiError.Sanitize(err))
+
+	data, merr := marshaler.Marshal(st.Proto())
+	if merr != nil {
+		http.Error(w, st.Message(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+	w.Write(data)
+}
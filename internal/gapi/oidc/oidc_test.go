@@ -0,0 +1,292 @@
+package gapiOIDC_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiOIDC "github.com/claytten/golang-simplebank/internal/gapi/oidc"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/oidc"
+	"github.com/claytten/golang-simplebank/internal/oidc/oidctest"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// newGateway runs the OIDC routes against the fake identity provider
+func newGateway(t *testing.T, store db.Store, idp *oidctest.Provider) (*httptest.Server, token.Maker) {
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	gateway := httptest.NewServer(mux)
+	t.Cleanup(gateway.Close)
+
+	server := &gapi.Server{
+		DB:    store,
+		Token: tokenMaker,
+		Config: util.Config{
+			AccessTokenDuration:  time.Minute,
+			RefreshTokenDuration: time.Hour,
+			OIDCRedirectURL:      gateway.URL + gapiOIDC.CallbackPath,
+		},
+	}
+	loginGuard := loginguard.NewGuard(loginguard.NewMemoryStore(), loginguard.Policy{}, loginguard.Policy{})
+	server.Users = service.NewUserService(server.Config, store, tokenMaker, revocation.NewMemoryCache(), loginGuard)
+
+	provider := oidc.NewProvider(idp.Config(server.Config.OIDCRedirectURL), nil)
+	gapiOIDC.NewHandler(server, provider).Register(mux)
+	return gateway, tokenMaker
+}
+
+func newBrowser(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &http.Client{Jar: jar}
+}
+
+func expectCreateSession(store *mockdb.MockStore, user db.Users) {
+	store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+			event, err := fn(store)
+			if err != nil {
+				return err
+			}
+			if event.Action != "session.create" || event.Actor != user.Username {
+				return sql.ErrConnDone
+			}
+			return nil
+		})
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.CreateSessionParams) (db.Sessions, error) {
+			if arg.Email != user.Email {
+				return db.Sessions{}, sql.ErrConnDone
+			}
+			return db.Sessions{ID: arg.ID, Email: arg.Email, RefreshToken: arg.RefreshToken, ExpiresAt: arg.ExpiresAt}, nil
+		})
+	// the browser is a device the user logged in from before
+	store.EXPECT().
+		CreateUserDevice(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.UserDevices{}, sql.ErrNoRows)
+	store.EXPECT().
+		TouchUserDevice(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.TouchUserDeviceParams) (db.UserDevices, error) {
+			if arg.Username != user.Username {
+				return db.UserDevices{}, sql.ErrConnDone
+			}
+			return db.UserDevices{Username: arg.Username, UserAgent: arg.UserAgent, ClientIp: arg.ClientIp}, nil
+		})
+	store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(0)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	subject := util.RandomString(12)
+
+	tests := []struct {
+		name          string
+		idpUser       oidctest.User
+		buildStubs    func(store *mockdb.MockStore, issuer string)
+		checkResponse func(t *testing.T, resp *http.Response, tokenMaker token.Maker)
+	}{
+		// TODO: 200 linked identity
+		{
+			name:    "200 linked identity",
+			idpUser: oidctest.User{Subject: subject, Email: "another@example.com", EmailVerified: true},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					GetUserIdentity(gomock.Any(), gomock.Eq(db.GetUserIdentityParams{Issuer: issuer, Subject: subject})).
+					Times(1).
+					Return(db.UserIdentities{Issuer: issuer, Subject: subject, Username: user.Username}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().LinkUserIdentityTx(gomock.Any(), gomock.Any()).Times(0)
+				expectCreateSession(store, user)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				requireBodyMatchLogin(t, resp.Body, tokenMaker, user)
+			},
+		},
+
+		// TODO: 200 first login of an existing user
+		{
+			name:    "200 link existing user",
+			idpUser: oidctest.User{Subject: subject, Email: user.Email, EmailVerified: true},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().GetUserIdentity(gomock.Any(), gomock.Any()).Times(1).Return(db.UserIdentities{}, sql.ErrNoRows)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					LinkUserIdentityTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.LinkUserIdentityTxParams) (db.LinkUserIdentityTxResult, error) {
+						identity := db.CreateUserIdentityParams{
+							Issuer:   issuer,
+							Subject:  subject,
+							Username: user.Username,
+							Email:    user.Email,
+						}
+						if arg.Identity != identity || arg.NewUser != nil || arg.Audit.Actor != user.Username {
+							return db.LinkUserIdentityTxResult{}, sql.ErrConnDone
+						}
+						return db.LinkUserIdentityTxResult{User: user}, nil
+					})
+				expectCreateSession(store, user)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				requireBodyMatchLogin(t, resp.Body, tokenMaker, user)
+			},
+		},
+
+		// TODO: 200 first login of a new user
+		{
+			name:    "200 new user",
+			idpUser: oidctest.User{Subject: subject, Email: "Jane.Doe@example.com", EmailVerified: true, Name: "Jane Doe"},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				newUser := db.Users{Username: "jane_doe_abcdef", Email: "Jane.Doe@example.com", FullName: "Jane Doe", Role: util.DepositorRole}
+
+				store.EXPECT().GetUserIdentity(gomock.Any(), gomock.Any()).Times(1).Return(db.UserIdentities{}, sql.ErrNoRows)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(newUser.Email)).Times(1).Return(db.Users{}, sql.ErrNoRows)
+				store.EXPECT().
+					LinkUserIdentityTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.LinkUserIdentityTxParams) (db.LinkUserIdentityTxResult, error) {
+						if arg.NewUser == nil || arg.NewUser.Email != newUser.Email || arg.NewUser.FullName != newUser.FullName ||
+							arg.Audit.Actor != arg.NewUser.Username {
+							return db.LinkUserIdentityTxResult{}, sql.ErrConnDone
+						}
+
+						if err := util.ValidateUsername(arg.NewUser.Username); err != nil {
+							return db.LinkUserIdentityTxResult{}, err
+						}
+						return db.LinkUserIdentityTxResult{User: newUser}, nil
+					})
+				expectCreateSession(store, newUser)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+
+		// TODO: 403 unverified email
+		{
+			name:    "403 unverified email",
+			idpUser: oidctest.User{Subject: subject, Email: user.Email, EmailVerified: false},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().GetUserIdentity(gomock.Any(), gomock.Any()).Times(1).Return(db.UserIdentities{}, sql.ErrNoRows)
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().LinkUserIdentityTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusForbidden, resp.StatusCode)
+			},
+		},
+
+		// TODO: 500 identity lookup failed
+		{
+			name:    "500 internal error",
+			idpUser: oidctest.User{Subject: subject, Email: user.Email, EmailVerified: true},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().GetUserIdentity(gomock.Any(), gomock.Any()).Times(1).Return(db.UserIdentities{}, sql.ErrConnDone)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			idp := oidctest.NewProvider(t)
+			idp.SetUser(tt.idpUser)
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store, idp.Issuer())
+
+			gateway, tokenMaker := newGateway(t, store, idp)
+
+			// the browser follows the redirects through the provider and back
+			resp, err := newBrowser(t).Get(gateway.URL + gapiOIDC.LoginPath)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			tt.checkResponse(t, resp, tokenMaker)
+		})
+	}
+}
+
+func TestOIDCCallbackStateMismatch(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	idp := oidctest.NewProvider(t)
+	store := mockdb.NewMockStore(controller)
+	gateway, _ := newGateway(t, store, idp)
+
+	// a callback the browser did not start, such as a login CSRF
+	resp, err := newBrowser(t).Get(gateway.URL + gapiOIDC.CallbackPath + "?code=stolen&state=forged")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// the login of the browser does not match another state either
+	browser := newBrowser(t)
+	browser.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err = browser.Get(gateway.URL + gapiOIDC.LoginPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp, err = browser.Get(gateway.URL + gapiOIDC.CallbackPath + "?code=stolen&state=forged")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func requireBodyMatchLogin(t *testing.T, body io.Reader, tokenMaker token.Maker, user db.Users) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotData struct {
+		SessionID   string `json:"session_id"`
+		AccessToken string `json:"access_token"`
+		User        struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+	err = json.Unmarshal(data, &gotData)
+	require.NoError(t, err)
+	require.NotEmpty(t, gotData.SessionID)
+	require.Equal(t, user.Username, gotData.User.Username)
+
+	payload, err := tokenMaker.VerifyToken(gotData.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, payload.Email)
+	require.Equal(t, gotData.SessionID, payload.SessionID.String())
+}
@@ -0,0 +1,245 @@
+package gapiRateLimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/clientip"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/ratelimit"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// the rate limit of a request is returned in the response metadata,
+// the gateway turns it into the RateLimit-* headers
+const (
+	metadataLimit      = "ratelimit-limit"
+	metadataRemaining  = "ratelimit-remaining"
+	metadataReset      = "ratelimit-reset"
+	metadataRetryAfter = "retry-after"
+
+	xForwardedForHeader = "x-forwarded-for"
+)
+
+var httpHeaders = map[string]string{
+	metadataLimit:      "RateLimit-Limit",
+	metadataRemaining:  "RateLimit-Remaining",
+	metadataReset:      "RateLimit-Reset",
+	metadataRetryAfter: "Retry-After",
+}
+
+// RateLimiter limits the RPCs of every authenticated user, every API key and
+// every anonymous client IP, with the limit of the method. Every client IP is
+// limited before authentication too, by ipLimiter.
+type RateLimiter struct {
+	limiter   *ratelimit.Limiter
+	ipLimiter *ratelimit.Limiter
+	clientIP  *clientip.Resolver
+}
+
+// NewRateLimiter limits the callers with limiter and the client IPs with ipLimiter,
+// the client IP is resolved through the trusted proxies of resolver
+func NewRateLimiter(limiter, ipLimiter *ratelimit.Limiter, resolver *clientip.Resolver) *RateLimiter {
+	return &RateLimiter{limiter: limiter, ipLimiter: ipLimiter, clientIP: resolver}
+}
+
+// UnaryIP limits the unary RPCs of every client IP. It runs before the authenticator,
+// a client sending invalid tokens is limited without checking each of them.
+func (r *RateLimiter) UnaryIP() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if result := r.allowIP(ctx, info.FullMethod); !result.Allowed {
+			return nil, exhaustedError(result)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamIP limits the streams opened by every client IP, before the authenticator
+func (r *RateLimiter) StreamIP() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if result := r.allowIP(stream.Context(), info.FullMethod); !result.Allowed {
+			return exhaustedError(result)
+		}
+		return handler(srv, stream)
+	}
+}
+
+// Unary limits unary RPCs, it runs after the authenticator to know the caller
+func (r *RateLimiter) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		result, ok := r.allow(ctx, info.FullMethod)
+		if ok {
+			grpc.SetHeader(ctx, resultMetadata(result))
+		}
+
+		if !result.Allowed {
+			return nil, exhaustedError(result)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream limits the streams opened, not the messages of a stream
+func (r *RateLimiter) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		result, ok := r.allow(stream.Context(), info.FullMethod)
+		if ok {
+			stream.SetHeader(resultMetadata(result))
+		}
+
+		if !result.Allowed {
+			return exhaustedError(result)
+		}
+		return handler(srv, stream)
+	}
+}
+
+// allow takes a token for the caller of ctx. The request is let through when the
+// limit cannot be checked, a Redis outage doesn't stop the API. The result is only
+// returned in the response when the method is limited.
+func (r *RateLimiter) allow(ctx context.Context, method string) (ratelimit.Result, bool) {
+	result, err := r.limiter.Allow(ctx, r.clientKey(ctx), method)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msg("cannot check rate limit")
+		return ratelimit.Result{Allowed: true}, false
+	}
+	return result, result.Limit > 0
+}
+
+// allowIP takes a token for the client IP of ctx, the request is let through
+// when the limit cannot be checked
+func (r *RateLimiter) allowIP(ctx context.Context, method string) ratelimit.Result {
+	result, err := r.ipLimiter.Allow(ctx, ratelimit.ConnectionKey(r.resolveClientIP(ctx)), method)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msg("cannot check client IP rate limit")
+		return ratelimit.Result{Allowed: true}
+	}
+	return result
+}
+
+// clientKey names the caller of ctx: the API key or the user it is authenticated
+// as, the client IP otherwise
+func (r *RateLimiter) clientKey(ctx context.Context) string {
+	if authUser, err := gapi.AuthUserFromContext(ctx); err == nil {
+		if authUser.Payload.IsAPIKey() {
+			return ratelimit.APIKeyKey(authUser.Payload.APIKeyID)
+		}
+		return ratelimit.UserKey(authUser.User.Username)
+	}
+	return ratelimit.IPKey(r.resolveClientIP(ctx))
+}
+
+// resolveClientIP is the peer address of a direct gRPC client. The x-forwarded-for
+// header is only read when the peer is the gateway or another trusted proxy,
+// anybody else could send it to be limited as another client.
+func (r *RateLimiter) resolveClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	return r.clientIP.Resolve(p.Addr.String(), md.Get(xForwardedForHeader))
+}
+
+func resultMetadata(result ratelimit.Result) metadata.MD {
+	md := metadata.Pairs(
+		metadataLimit, strconv.Itoa(result.Limit),
+		metadataRemaining, strconv.Itoa(result.Remaining),
+		metadataReset, ceilSeconds(result.Reset),
+	)
+	if !result.Allowed {
+		md.Set(metadataRetryAfter, ceilSeconds(result.RetryAfter))
+	}
+	return md
+}
+
+func exhaustedError(result ratelimit.Result) error {
+	return gapiError.ResourceExhaustedError(domain.ErrRateLimited, result.RetryAfter)
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// OutgoingHeaderMatcher returns the rate limit of an RPC as the RateLimit-* headers,
+// the rest of the metadata is returned as the gateway does by default
+func OutgoingHeaderMatcher(key string) (string, bool) {
+	if header, ok := httpHeaders[key]; ok {
+		return header, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// HTTP limits the gateway routes served without calling an RPC, by client IP
+// and with the limit of the path
+func (r *RateLimiter) HTTP(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		clientIP := r.clientIP.Resolve(req.RemoteAddr, req.Header.Values(xForwardedForHeader))
+		result, err := r.limiter.Allow(ctx, ratelimit.IPKey(clientIP), req.URL.Path)
+		if err != nil {
+			logging.Ctx(ctx).Error().Err(err).Msg("cannot check rate limit")
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		if result.Limit > 0 {
+			for key, values := range resultMetadata(result) {
+				w.Header().Set(httpHeaders[key], values[0])
+			}
+		}
+
+		if !result.Allowed {
+			writeExhausted(w, result)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// writeExhausted answers like the gateway does for the error of a limited RPC
+func writeExhausted(w http.ResponseWriter, result ratelimit.Result) {
+	st := status.Convert(exhaustedError(result))
+
+	data, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		http.Error(w, st.Message(), http.StatusTooManyRequests)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write(data)
+}
@@ -0,0 +1,248 @@
+package gapiRateLimit_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/clientip"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiRateLimit "github.com/claytten/golang-simplebank/internal/gapi/ratelimit"
+	"github.com/claytten/golang-simplebank/internal/ratelimit"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/pb.Simplebank/GetAccount"}
+
+// newRateLimiter lets 2 requests of a caller and 3 of a client IP through every minute,
+// the gateway calls from 127.0.0.1
+func newRateLimiter(t *testing.T) *gapiRateLimit.RateLimiter {
+	resolver, err := clientip.NewResolver([]string{"127.0.0.1/32"})
+	require.NoError(t, err)
+
+	store := ratelimit.NewMemoryStore()
+	limits := ratelimit.Limits{
+		Default: ratelimit.Limit{Requests: 2, Per: time.Minute},
+	}
+	ipLimits := ratelimit.Limits{
+		Default: ratelimit.Limit{Requests: 3, Per: time.Minute},
+	}
+	return gapiRateLimit.NewRateLimiter(ratelimit.NewLimiter(store, limits), ratelimit.NewLimiter(store, ipLimits), resolver)
+}
+
+func call(rateLimiter *gapiRateLimit.RateLimiter, ctx context.Context) error {
+	_, err := rateLimiter.Unary()(ctx, nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	return err
+}
+
+func anonymousContext(clientIP string, md metadata.MD) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(clientIP), Port: 51000},
+	})
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func userContext(username string, apiKeyID int64) context.Context {
+	return gapi.ContextWithAuthUser(anonymousContext("10.0.0.1", metadata.MD{}), &gapi.AuthUser{
+		Payload: &token.Payload{APIKeyID: apiKeyID},
+		User:    db.Users{Username: username},
+	})
+}
+
+func TestUnary(t *testing.T) {
+	testCases := []struct {
+		name  string
+		ctxs  []context.Context
+		check func(t *testing.T, err error)
+	}{
+		// TODO: within the limit
+		{
+			name: "OK",
+			ctxs: []context.Context{
+				anonymousContext("10.0.0.1", metadata.MD{}),
+				anonymousContext("10.0.0.1", metadata.MD{}),
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		// TODO: over the limit
+		{
+			name: "Exhausted",
+			ctxs: []context.Context{
+				anonymousContext("10.0.0.1", metadata.MD{}),
+				anonymousContext("10.0.0.1", metadata.MD{}),
+				anonymousContext("10.0.0.1", metadata.MD{}),
+			},
+			check: func(t *testing.T, err error) {
+				statusErr := status.Convert(err)
+				require.Equal(t, codes.ResourceExhausted, statusErr.Code())
+				require.Len(t, statusErr.Details(), 2)
+
+				errorInfo, ok := statusErr.Details()[0].(*errdetails.ErrorInfo)
+				require.True(t, ok)
+				require.Equal(t, domain.ErrRateLimited.Reason, errorInfo.GetReason())
+
+				retryInfo, ok := statusErr.Details()[1].(*errdetails.RetryInfo)
+				require.True(t, ok)
+				require.InDelta(t, 30*time.Second, retryInfo.GetRetryDelay().AsDuration(), float64(time.Second))
+			},
+		},
+		// TODO: gateway clients told apart by the address the gateway appended
+		{
+			name: "Gateway",
+			ctxs: []context.Context{
+				anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "203.0.113.7")),
+				anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "203.0.113.7")),
+				anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "203.0.113.8")),
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		// TODO: forged x-forwarded-for
+		{
+			name: "ForgedForwardedFor",
+			ctxs: []context.Context{
+				anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "198.51.100.1, 203.0.113.7")),
+				anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "198.51.100.2, 203.0.113.7")),
+				anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "198.51.100.3, 203.0.113.7")),
+			},
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.ResourceExhausted, status.Code(err))
+			},
+		},
+		// TODO: x-forwarded-for sent by a direct client is ignored
+		{
+			name: "UntrustedForwardedFor",
+			ctxs: []context.Context{
+				anonymousContext("203.0.113.7", metadata.Pairs("x-forwarded-for", "198.51.100.1")),
+				anonymousContext("203.0.113.7", metadata.Pairs("x-forwarded-for", "198.51.100.2")),
+				anonymousContext("203.0.113.7", metadata.Pairs("x-forwarded-for", "198.51.100.3")),
+			},
+			check: func(t *testing.T, err error) {
+				require.Equal(t, codes.ResourceExhausted, status.Code(err))
+			},
+		},
+		// TODO: users of one client IP
+		{
+			name: "Users",
+			ctxs: []context.Context{
+				userContext("alice", 0),
+				userContext("alice", 0),
+				userContext("bob", 0),
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		// TODO: API key of a user
+		{
+			name: "APIKey",
+			ctxs: []context.Context{
+				userContext("alice", 0),
+				userContext("alice", 0),
+				userContext("alice", 1),
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			rateLimiter := newRateLimiter(t)
+
+			var err error
+			for _, ctx := range tc.ctxs {
+				err = call(rateLimiter, ctx)
+			}
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestUnaryIP(t *testing.T) {
+	rateLimiter := newRateLimiter(t)
+	callIP := func(ctx context.Context) error {
+		_, err := rateLimiter.UnaryIP()(ctx, nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	// every request of the client IP counts, whoever it claims to be
+	for i := 0; i < 3; i++ {
+		require.NoError(t, callIP(anonymousContext("203.0.113.7", metadata.Pairs("authorization", "bearer invalid"))))
+	}
+
+	err := callIP(anonymousContext("203.0.113.7", metadata.MD{}))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// another client behind the gateway has its own bucket
+	require.NoError(t, callIP(anonymousContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "203.0.113.8"))))
+}
+
+func TestOutgoingHeaderMatcher(t *testing.T) {
+	header, ok := gapiRateLimit.OutgoingHeaderMatcher("ratelimit-remaining")
+	require.True(t, ok)
+	require.Equal(t, "RateLimit-Remaining", header)
+
+	header, ok = gapiRateLimit.OutgoingHeaderMatcher("retry-after")
+	require.True(t, ok)
+	require.Equal(t, "Retry-After", header)
+
+	header, ok = gapiRateLimit.OutgoingHeaderMatcher("x-request-id")
+	require.True(t, ok)
+	require.Equal(t, "Grpc-Metadata-x-request-id", header)
+}
+
+func TestHTTP(t *testing.T) {
+	handler := newRateLimiter(t).HTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var requests int
+	serve := func() *httptest.ResponseRecorder {
+		requests++
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		req.RemoteAddr = "10.0.0.1:51000"
+		// ignored, the client isn't a trusted proxy
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", requests))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve()
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+	require.Empty(t, recorder.Header().Get("Retry-After"))
+
+	serve()
+	recorder = serve()
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+	require.Contains(t, recorder.Body.String(), "rate limit exceeded")
+}
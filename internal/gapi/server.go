@@ -3,31 +3,61 @@ package gapi
 import (
 	"fmt"
 
+	"github.com/claytten/golang-simplebank/internal/activity"
 	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/clientip"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/passwordpolicy"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/service"
 	"github.com/claytten/golang-simplebank/internal/util"
-	"github.com/claytten/golang-simplebank/internal/worker"
 )
 
 type Server struct {
 	DB             db.Store
 	Config         util.Config
 	Token          token.Maker
-	TaskDistrbutor worker.TaskDistributor
+	Revocation     revocation.Cache
+	PasswordPolicy passwordpolicy.Policy
+	// finds the client address of the requests coming through the gateway or a proxy
+	ClientIP *clientip.Resolver
+	// wakes up the streams watching account activity
+	Activity *activity.Hub
+
+	Accounts  *service.AccountService
+	Transfers *service.TransferService
+	Users     *service.UserService
 }
 
-func SetupServer(config util.Config, store db.Store, taskDistrbutor worker.TaskDistributor) (*Server, error) {
-	// can change to JWT or Paseto maker for token
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func SetupServer(
+	config util.Config,
+	store db.Store,
+	revocationCache revocation.Cache,
+	loginGuard *loginguard.Guard,
+) (*Server, error) {
+	// the token maker is selected with TOKEN_TYPE
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	clientIPResolver, err := clientip.NewResolver(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse trusted proxies: %w", err)
+	}
+
 	server := &Server{
 		DB:             store,
 		Config:         config,
 		Token:          tokenMaker,
-		TaskDistrbutor: taskDistrbutor,
+		Revocation:     revocationCache,
+		PasswordPolicy: passwordpolicy.NewPolicy(config),
+		ClientIP:       clientIPResolver,
+		Activity:       activity.NewHub(),
+		Accounts:       service.NewAccountService(store),
+		Transfers:      service.NewTransferService(store),
+		Users:          service.NewUserService(config, store, tokenMaker, revocationCache, loginGuard),
 	}
 
 	return server, nil
@@ -0,0 +1,68 @@
+package gapiValidate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/authz"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func ValidateCreateAPIKeyRequest(req *pb.CreateAPIKeyRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := util.ValidateString(req.GetName(), 3, 100); err != nil {
+		violations = append(violations, gapiError.FieldViolation("name", err))
+	}
+
+	if len(req.GetScopes()) == 0 {
+		violations = append(violations, gapiError.FieldViolation("scopes", fmt.Errorf("must grant at least one scope")))
+	}
+
+	for _, scope := range req.GetScopes() {
+		if !authz.IsSupportScope(scope) {
+			violations = append(violations, gapiError.FieldViolation("scopes", fmt.Errorf("scope %s not supported", scope)))
+		}
+	}
+
+	if req.ExpiresAt != nil {
+		if err := req.GetExpiresAt().CheckValid(); err != nil {
+			violations = append(violations, gapiError.FieldViolation("expires_at", err))
+		} else if req.GetExpiresAt().AsTime().Before(time.Now()) {
+			violations = append(violations, gapiError.FieldViolation("expires_at", fmt.Errorf("must be in the future")))
+		}
+	}
+
+	if req.Owner != nil {
+		if err := util.ValidateUsername(req.GetOwner()); err != nil {
+			violations = append(violations, gapiError.FieldViolation("owner", err))
+		}
+	}
+
+	return violations
+}
+
+func ValidateListAPIKeysRequest(req *pb.ListAPIKeysRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.Owner != nil {
+		if err := util.ValidateUsername(req.GetOwner()); err != nil {
+			violations = append(violations, gapiError.FieldViolation("owner", err))
+		}
+	}
+
+	return violations
+}
+
+func ValidateRevokeAPIKeyRequest(req *pb.RevokeAPIKeyRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetId() < 1 {
+		violations = append(violations, gapiError.FieldViolation("id", fmt.Errorf("must be at least 1")))
+	}
+
+	if req.Owner != nil {
+		if err := util.ValidateUsername(req.GetOwner()); err != nil {
+			violations = append(violations, gapiError.FieldViolation("owner", err))
+		}
+	}
+
+	return violations
+}
@@ -9,20 +9,16 @@ import (
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 )
 
-func ValidateAuthorizeAccountRequest(username, password string) (violations []*errdetails.BadRequest_FieldViolation) {
+func ValidateAuthorizeAccountRequest(username string) (violations []*errdetails.BadRequest_FieldViolation) {
 	if err := util.ValidateUsername(username); err != nil {
 		violations = append(violations, gapiError.FieldViolation("account_id", err))
 	}
 
-	if err := util.ValidatePassword(password); err != nil {
-		violations = append(violations, gapiError.FieldViolation("password", err))
-	}
-
 	return violations
 }
 
 func ValidateCreateAccountRequest(req *pb.CreateAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := ValidateAuthorizeAccountRequest(req.GetUsername(), req.GetOldPassword()); err != nil {
+	if err := ValidateAuthorizeAccountRequest(req.GetUsername()); err != nil {
 		violations = append(violations, err...)
 	}
 
@@ -35,7 +31,7 @@ func ValidateCreateAccountRequest(req *pb.CreateAccountRequest) (violations []*e
 }
 
 func ValidateUpdateAccountRequest(req *pb.UpdateAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := ValidateAuthorizeAccountRequest(req.GetUsername(), req.GetOldPassword()); err != nil {
+	if err := ValidateAuthorizeAccountRequest(req.GetUsername()); err != nil {
 		violations = append(violations, err...)
 	}
 
@@ -47,7 +43,7 @@ func ValidateUpdateAccountRequest(req *pb.UpdateAccountRequest) (violations []*e
 }
 
 func ValidateTransactionAccountRequest(req *pb.TransferTxAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := ValidateAuthorizeAccountRequest(req.GetUsername(), req.GetOldPassword()); err != nil {
+	if err := ValidateAuthorizeAccountRequest(req.GetUsername()); err != nil {
 		violations = append(violations, err...)
 	}
 
@@ -62,3 +58,25 @@ func ValidateTransactionAccountRequest(req *pb.TransferTxAccountRequest) (violat
 
 	return violations
 }
+
+// MaxWatchedAccounts limits the accounts one activity stream watches
+const MaxWatchedAccounts = 100
+
+func ValidateWatchAccountActivityRequest(req *pb.WatchAccountActivityRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if len(req.GetAccountIds()) > MaxWatchedAccounts {
+		violations = append(violations, gapiError.FieldViolation("account_ids", fmt.Errorf("must contain at most %d accounts", MaxWatchedAccounts)))
+	}
+
+	for _, id := range req.GetAccountIds() {
+		if id < 1 {
+			violations = append(violations, gapiError.FieldViolation("account_ids", fmt.Errorf("must be at least 1")))
+			break
+		}
+	}
+
+	if req.AfterEntryId != nil && req.GetAfterEntryId() < 0 {
+		violations = append(violations, gapiError.FieldViolation("after_entry_id", fmt.Errorf("must be at least 0")))
+	}
+
+	return violations
+}
@@ -0,0 +1,21 @@
+package gapiValidate
+
+import (
+	"fmt"
+
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func ValidateListUsersRequest(req *pb.ListUsersRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetPageId() < 1 {
+		violations = append(violations, gapiError.FieldViolation("page_id", fmt.Errorf("must be at least 1")))
+	}
+
+	if req.GetPageSize() < 5 || req.GetPageSize() > 10 {
+		violations = append(violations, gapiError.FieldViolation("page_size", fmt.Errorf("must be from 5-10")))
+	}
+
+	return violations
+}
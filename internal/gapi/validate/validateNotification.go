@@ -0,0 +1,46 @@
+package gapiValidate
+
+import (
+	"fmt"
+	"regexp"
+
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/claytten/golang-simplebank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// MaxPushTokenLength is longer than the device tokens of the push providers
+const MaxPushTokenLength = 4096
+
+var isValidPhoneNumber = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`).MatchString
+
+func ValidateUpdateNotificationSettingsRequest(req *pb.UpdateNotificationSettingsRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.Locale != nil && !notification.IsSupportLocale(req.GetLocale()) {
+		violations = append(violations, gapiError.FieldViolation("locale", fmt.Errorf("locale %s not supported", req.GetLocale())))
+	}
+
+	if req.GetPhoneNumber() != "" && !isValidPhoneNumber(req.GetPhoneNumber()) {
+		violations = append(violations, gapiError.FieldViolation("phone_number", fmt.Errorf("must be an E.164 number such as +628123456789")))
+	}
+
+	if len(req.GetPushToken()) > MaxPushTokenLength {
+		violations = append(violations, gapiError.FieldViolation("push_token", fmt.Errorf("must be at most %d characters", MaxPushTokenLength)))
+	}
+
+	if req.LargeDebitThreshold != nil && req.GetLargeDebitThreshold() < 1 {
+		violations = append(violations, gapiError.FieldViolation("large_debit_threshold", fmt.Errorf("must be at least 1")))
+	}
+
+	for _, preference := range req.GetPreferences() {
+		if !notification.IsSupportEvent(preference.GetEvent()) {
+			violations = append(violations, gapiError.FieldViolation("preferences", fmt.Errorf("event %s not supported", preference.GetEvent())))
+		}
+
+		if !notification.IsSupportChannel(preference.GetChannel()) {
+			violations = append(violations, gapiError.FieldViolation("preferences", fmt.Errorf("channel %s not supported", preference.GetChannel())))
+		}
+	}
+
+	return violations
+}
@@ -2,17 +2,18 @@ package gapiValidate
 
 import (
 	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/passwordpolicy"
 	"github.com/claytten/golang-simplebank/internal/util"
 	"github.com/claytten/golang-simplebank/pb"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 )
 
-func ValidateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func ValidateCreateUserRequest(req *pb.CreateUserRequest, policy passwordpolicy.Policy) (violations []*errdetails.BadRequest_FieldViolation) {
 	if err := util.ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, gapiError.FieldViolation("username", err))
 	}
 
-	if err := util.ValidatePassword(req.GetPassword()); err != nil {
+	if err := policy.Validate(req.GetPassword()); err != nil {
 		violations = append(violations, gapiError.FieldViolation("password", err))
 	}
 
@@ -27,12 +28,20 @@ func ValidateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdeta
 	return violations
 }
 
-func ValidateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func ValidateLoginUserRequest(req *pb.LoginUserRequest, policy passwordpolicy.Policy) (violations []*errdetails.BadRequest_FieldViolation) {
 	if err := util.ValidateEmail(req.GetEmail()); err != nil {
 		violations = append(violations, gapiError.FieldViolation("username", err))
 	}
 
-	if err := util.ValidatePassword(req.GetPassword()); err != nil {
+	if err := policy.ValidateLogin(req.GetPassword()); err != nil {
+		violations = append(violations, gapiError.FieldViolation("password", err))
+	}
+
+	return violations
+}
+
+func ValidateReauthenticateRequest(req *pb.ReauthenticateRequest, policy passwordpolicy.Policy) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := policy.ValidateLogin(req.GetPassword()); err != nil {
 		violations = append(violations, gapiError.FieldViolation("password", err))
 	}
 
@@ -48,19 +57,11 @@ func ValidateUpdateProfileRequest(req *pb.UpdateProfileRequest) (violations []*e
 		violations = append(violations, gapiError.FieldViolation("email", err))
 	}
 
-	if err := util.ValidatePassword(req.GetOldPassword()); err != nil {
-		violations = append(violations, gapiError.FieldViolation("old password", err))
-	}
-
 	return violations
 }
 
-func ValidateUpdatePasswordRequest(req *pb.UpdatePasswordRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := util.ValidatePassword(req.GetOldPassword()); err != nil {
-		violations = append(violations, gapiError.FieldViolation("old_password", err))
-	}
-
-	if err := util.ValidatePassword(req.GetPassword()); err != nil {
+func ValidateUpdatePasswordRequest(req *pb.UpdatePasswordRequest, policy passwordpolicy.Policy) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := policy.Validate(req.GetPassword()); err != nil {
 		violations = append(violations, gapiError.FieldViolation("new_password", err))
 	}
 
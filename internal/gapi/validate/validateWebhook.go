@@ -0,0 +1,60 @@
+package gapiValidate
+
+import (
+	"fmt"
+
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+	"github.com/claytten/golang-simplebank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func ValidateCreateWebhookSubscriptionRequest(req *pb.CreateWebhookSubscriptionRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := webhook.ValidateURL(req.GetUrl()); err != nil {
+		violations = append(violations, gapiError.FieldViolation("url", err))
+	}
+
+	if len(req.GetEventTypes()) == 0 {
+		violations = append(violations, gapiError.FieldViolation("event_types", fmt.Errorf("must subscribe to at least one event type")))
+	}
+
+	for _, eventType := range req.GetEventTypes() {
+		if !webhook.IsSupportEventType(eventType) {
+			violations = append(violations, gapiError.FieldViolation("event_types", fmt.Errorf("event type %s not supported", eventType)))
+		}
+	}
+
+	return violations
+}
+
+func ValidateDeleteWebhookSubscriptionRequest(req *pb.DeleteWebhookSubscriptionRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetId() < 1 {
+		violations = append(violations, gapiError.FieldViolation("id", fmt.Errorf("must be at least 1")))
+	}
+
+	return violations
+}
+
+func ValidateListWebhookDeliveriesRequest(req *pb.ListWebhookDeliveriesRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetSubscriptionId() < 1 {
+		violations = append(violations, gapiError.FieldViolation("subscription_id", fmt.Errorf("must be at least 1")))
+	}
+
+	if req.GetPageId() < 1 {
+		violations = append(violations, gapiError.FieldViolation("page_id", fmt.Errorf("must be at least 1")))
+	}
+
+	if req.GetPageSize() < 5 || req.GetPageSize() > 50 {
+		violations = append(violations, gapiError.FieldViolation("page_size", fmt.Errorf("must be from 5-50")))
+	}
+
+	return violations
+}
+
+func ValidateReplayWebhookDeliveryRequest(req *pb.ReplayWebhookDeliveryRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetId() < 1 {
+		violations = append(violations, gapiError.FieldViolation("id", fmt.Errorf("must be at least 1")))
+	}
+
+	return violations
+}
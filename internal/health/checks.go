@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Postgres checks the app can reach the database
+func Postgres(conn *sql.DB) Check {
+	return Check{
+		Name: "postgres",
+		Check: func(ctx context.Context) error {
+			return conn.PingContext(ctx)
+		},
+	}
+}
+
+// Redis checks the app can reach the Redis the tasks are queued in
+func Redis(client redis.UniversalClient) Check {
+	return Check{
+		Name: "redis",
+		Check: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
+
+// MigrationVersion checks the database schema is at least the version the app
+// migrated it to and no migration failed halfway. A newer version is accepted,
+// another instance may already run a newer release.
+func MigrationVersion(conn *sql.DB, version uint) Check {
+	return Check{
+		Name: "migration",
+		Check: func(ctx context.Context) error {
+			var current uint
+			var dirty bool
+			err := conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+			if err != nil {
+				return fmt.Errorf("cannot get migration version: %w", err)
+			}
+
+			if dirty {
+				return fmt.Errorf("migration %d is dirty", current)
+			}
+			if current < version {
+				return fmt.Errorf("migration version %d is older than %d", current, version)
+			}
+			return nil
+		},
+	}
+}
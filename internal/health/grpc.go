@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCServer implements the standard grpc.health.v1 service. The empty service
+// name reports the liveness of the server, the name of a registered service
+// reports whether the app is ready to serve it.
+type GRPCServer struct {
+	healthpb.UnimplementedHealthServer
+	health        *Health
+	services      map[string]bool
+	watchInterval time.Duration
+}
+
+// NewGRPCServer returns the health service of the services, a watcher is
+// sent the new status within watchInterval of a change
+func NewGRPCServer(health *Health, watchInterval time.Duration, services ...string) *GRPCServer {
+	server := &GRPCServer{
+		health:        health,
+		services:      make(map[string]bool, len(services)),
+		watchInterval: watchInterval,
+	}
+	for _, service := range services {
+		server.services[service] = true
+	}
+	return server
+}
+
+// Register adds the health service to the gRPC server
+func (s *GRPCServer) Register(grpcServer *grpc.Server) {
+	healthpb.RegisterHealthServer(grpcServer, s)
+}
+
+func (s *GRPCServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus := s.status(ctx, req.GetService())
+	if servingStatus == healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// Watch sends the status whenever it changes. The stream ends once the app shuts
+// down, a graceful stop would otherwise wait for the watchers forever.
+func (s *GRPCServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	var last healthpb.HealthCheckResponse_ServingStatus = -1
+	send := func(servingStatus healthpb.HealthCheckResponse_ServingStatus) error {
+		if servingStatus == last {
+			return nil
+		}
+		last = servingStatus
+		return stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus})
+	}
+
+	for {
+		if err := send(s.status(ctx, req.GetService())); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.health.ShuttingDown():
+			if err := send(healthpb.HealthCheckResponse_NOT_SERVING); err != nil {
+				return err
+			}
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *GRPCServer) status(ctx context.Context, service string) healthpb.HealthCheckResponse_ServingStatus {
+	if service != "" && !s.services[service] {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+	}
+
+	if s.health.IsShuttingDown() {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	if service != "" && !s.health.Ready(ctx).Ready {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
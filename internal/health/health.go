@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Check tells whether a dependency the app needs to serve requests is available
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health tells whether the app is alive and ready to serve. A live app may not be
+// ready while a dependency is down, and it is never ready once it shuts down.
+type Health struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown chan struct{}
+	shutdownOnce sync.Once
+}
+
+// NewHealth returns the health of the app, every check must pass within
+// timeout for the app to be ready
+func NewHealth(timeout time.Duration, checks ...Check) *Health {
+	return &Health{
+		checks:       checks,
+		timeout:      timeout,
+		shuttingDown: make(chan struct{}),
+	}
+}
+
+// Shutdown makes the app not ready for good, so no new traffic is sent to
+// it while the requests in flight are drained
+func (h *Health) Shutdown() {
+	h.shutdownOnce.Do(func() {
+		close(h.shuttingDown)
+	})
+}
+
+// ShuttingDown is closed once the app shuts down
+func (h *Health) ShuttingDown() <-chan struct{} {
+	return h.shuttingDown
+}
+
+func (h *Health) IsShuttingDown() bool {
+	select {
+	case <-h.shuttingDown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Report is the result of the readiness checks
+type Report struct {
+	Ready        bool
+	ShuttingDown bool
+	// Checks has the error of every failed check, nil for a passed one
+	Checks map[string]error
+}
+
+// Ready runs the checks concurrently
+func (h *Health) Ready(ctx context.Context) Report {
+	if h.IsShuttingDown() {
+		return Report{ShuttingDown: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	errs := make([]error, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check.Check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Ready:  true,
+		Checks: make(map[string]error, len(h.checks)),
+	}
+	for i, check := range h.checks {
+		report.Checks[check.Name] = errs[i]
+		if errs[i] != nil {
+			report.Ready = false
+			log.Warn().Err(errs[i]).Str("check", check.Name).Msg("app is not ready")
+		}
+	}
+	return report
+}
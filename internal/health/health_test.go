@@ -0,0 +1,256 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/claytten/golang-simplebank/internal/health"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const service = "simplebank.Simplebank"
+
+func passing(name string) health.Check {
+	return health.Check{
+		Name:  name,
+		Check: func(ctx context.Context) error { return nil },
+	}
+}
+
+func failing(name string) health.Check {
+	return health.Check{
+		Name:  name,
+		Check: func(ctx context.Context) error { return errors.New("connection refused") },
+	}
+}
+
+func probe(t *testing.T, appHealth *health.Health, method, path string) (int, map[string]interface{}) {
+	mux := http.NewServeMux()
+	appHealth.Register(mux)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+
+	var body map[string]interface{}
+	if recorder.Code != http.StatusMethodNotAllowed {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	}
+	return recorder.Code, body
+}
+
+func TestHTTP(t *testing.T) {
+	testCases := []struct {
+		name          string
+		checks        []health.Check
+		shutdown      bool
+		method        string
+		path          string
+		checkResponse func(t *testing.T, code int, body map[string]interface{})
+	}{
+		// TODO: live
+		{
+			name:   "Live",
+			checks: []health.Check{failing("postgres")},
+			method: http.MethodGet,
+			path:   health.LivenessPath,
+			checkResponse: func(t *testing.T, code int, body map[string]interface{}) {
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, "ok", body["status"])
+			},
+		},
+		// TODO: ready
+		{
+			name:   "Ready",
+			checks: []health.Check{passing("postgres"), passing("redis")},
+			method: http.MethodGet,
+			path:   health.ReadinessPath,
+			checkResponse: func(t *testing.T, code int, body map[string]interface{}) {
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, "ready", body["status"])
+				require.Equal(t, map[string]interface{}{"postgres": "ok", "redis": "ok"}, body["checks"])
+			},
+		},
+		// TODO: a check failed
+		{
+			name:   "NotReady",
+			checks: []health.Check{passing("postgres"), failing("redis")},
+			method: http.MethodGet,
+			path:   health.ReadinessPath,
+			checkResponse: func(t *testing.T, code int, body map[string]interface{}) {
+				require.Equal(t, http.StatusServiceUnavailable, code)
+				require.Equal(t, "not ready", body["status"])
+				require.Equal(t, map[string]interface{}{"postgres": "ok", "redis": "failed"}, body["checks"])
+			},
+		},
+		// TODO: shutting down
+		{
+			name:     "ShuttingDown",
+			checks:   []health.Check{passing("postgres")},
+			shutdown: true,
+			method:   http.MethodGet,
+			path:     health.ReadinessPath,
+			checkResponse: func(t *testing.T, code int, body map[string]interface{}) {
+				require.Equal(t, http.StatusServiceUnavailable, code)
+				require.Equal(t, "shutting down", body["status"])
+			},
+		},
+		// TODO: still live while shutting down
+		{
+			name:     "LiveWhileShuttingDown",
+			checks:   []health.Check{passing("postgres")},
+			shutdown: true,
+			method:   http.MethodGet,
+			path:     health.LivenessPath,
+			checkResponse: func(t *testing.T, code int, body map[string]interface{}) {
+				require.Equal(t, http.StatusOK, code)
+			},
+		},
+		// TODO: method not allowed
+		{
+			name:   "MethodNotAllowed",
+			checks: []health.Check{passing("postgres")},
+			method: http.MethodPost,
+			path:   health.ReadinessPath,
+			checkResponse: func(t *testing.T, code int, body map[string]interface{}) {
+				require.Equal(t, http.StatusMethodNotAllowed, code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			appHealth := health.NewHealth(time.Second, tc.checks...)
+			if tc.shutdown {
+				appHealth.Shutdown()
+			}
+
+			code, body := probe(t, appHealth, tc.method, tc.path)
+			tc.checkResponse(t, code, body)
+		})
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	slow := health.Check{
+		Name: "postgres",
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	appHealth := health.NewHealth(10*time.Millisecond, slow, passing("redis"))
+
+	report := appHealth.Ready(context.Background())
+	require.False(t, report.Ready)
+	require.ErrorIs(t, report.Checks["postgres"], context.DeadlineExceeded)
+	require.NoError(t, report.Checks["redis"])
+}
+
+func TestRedis(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer client.Close()
+
+	check := health.Redis(client)
+	require.Equal(t, "redis", check.Name)
+	require.NoError(t, check.Check(context.Background()))
+
+	redisServer.Close()
+	require.Error(t, check.Check(context.Background()))
+}
+
+func newHealthClient(t *testing.T, appHealth *health.Health) healthpb.HealthClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	health.NewGRPCServer(appHealth, 10*time.Millisecond, service).Register(grpcServer)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestGRPCCheck(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	check := health.Check{
+		Name: "postgres",
+		Check: func(ctx context.Context) error {
+			if !ready.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	}
+	appHealth := health.NewHealth(time.Second, check)
+	client := newHealthClient(t, appHealth)
+	ctx := context.Background()
+
+	checkStatus := func(service string, expected healthpb.HealthCheckResponse_ServingStatus) {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		require.Equal(t, expected, res.GetStatus())
+	}
+
+	checkStatus("", healthpb.HealthCheckResponse_SERVING)
+	checkStatus(service, healthpb.HealthCheckResponse_SERVING)
+
+	// the server is still alive while a dependency is down
+	ready.Store(false)
+	checkStatus("", healthpb.HealthCheckResponse_SERVING)
+	checkStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	ready.Store(true)
+	appHealth.Shutdown()
+	checkStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	checkStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown.Service"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCWatch(t *testing.T) {
+	appHealth := health.NewHealth(time.Second, passing("postgres"))
+	client := newHealthClient(t, appHealth)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+
+	// the watcher is told before the stream ends, so it doesn't hold the shutdown
+	appHealth.Shutdown()
+
+	res, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
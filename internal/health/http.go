@@ -0,0 +1,80 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// paths the orchestrator probes on the HTTP gateway
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Register adds the liveness and readiness probes to the mux
+func (h *Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc(LivenessPath, h.serveLiveness)
+	mux.HandleFunc(ReadinessPath, h.serveReadiness)
+}
+
+// serveLiveness only tells the process serves requests, a dependency being
+// down is no reason to restart the app
+func (h *Health) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r) {
+		return
+	}
+	writeResponse(w, http.StatusOK, response{Status: "ok"})
+}
+
+// serveReadiness tells whether the app should receive traffic, the errors of the
+// failed checks are logged and not exposed
+func (h *Health) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r) {
+		return
+	}
+
+	report := h.Ready(r.Context())
+	if report.ShuttingDown {
+		writeResponse(w, http.StatusServiceUnavailable, response{Status: "shutting down"})
+		return
+	}
+
+	res := response{
+		Status: "ready",
+		Checks: make(map[string]string, len(report.Checks)),
+	}
+	for name, err := range report.Checks {
+		res.Checks[name] = "ok"
+		if err != nil {
+			res.Checks[name] = "failed"
+		}
+	}
+
+	if !report.Ready {
+		res.Status = "not ready"
+		writeResponse(w, http.StatusServiceUnavailable, res)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, code int, res response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,137 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+)
+
+// Group runs the services of the app until one of them fails or ctx is done,
+// then shuts every service down within the shutdown timeout
+type Group struct {
+	group           *errgroup.Group
+	ctx             context.Context
+	shutdownTimeout time.Duration
+}
+
+// NewGroup returns the group with the context its services run with,
+// the context is done once a service fails or ctx is done
+func NewGroup(ctx context.Context, shutdownTimeout time.Duration) (*Group, context.Context) {
+	group, ctx := errgroup.WithContext(ctx)
+	return &Group{
+		group:           group,
+		ctx:             ctx,
+		shutdownTimeout: shutdownTimeout,
+	}, ctx
+}
+
+// Go runs serve, an error stops the whole group. Once the group stops, shutdown is
+// called with a context expiring after the shutdown timeout, a service stopping
+// with the group context has no shutdown.
+func (g *Group) Go(name string, serve func() error, shutdown func(ctx context.Context) error) {
+	g.group.Go(func() error {
+		log.Info().Msgf("start %s", name)
+		if err := serve(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+
+	if shutdown == nil {
+		return
+	}
+
+	g.group.Go(func() error {
+		<-g.ctx.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), g.shutdownTimeout)
+		defer cancel()
+
+		log.Info().Msgf("shutdown %s", name)
+		if err := shutdown(ctx); err != nil {
+			return fmt.Errorf("cannot shutdown %s: %w", name, err)
+		}
+
+		log.Info().Msgf("%s is stopped", name)
+		return nil
+	})
+}
+
+// Wait waits for every service to stop and returns the first error
+func (g *Group) Wait() error {
+	return g.group.Wait()
+}
+
+// ServeGRPC serves gRPC on address until the server is stopped
+func ServeGRPC(server *grpc.Server, address string) func() error {
+	return func() error {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Msgf("gRPC server listens at %s", listener.Addr().String())
+		return server.Serve(listener)
+	}
+}
+
+// StopGRPC drains the calls in flight, the calls still running at the deadline are cancelled
+func StopGRPC(server *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return StopWithin(ctx, server.GracefulStop, server.Stop)
+	}
+}
+
+// ServeHTTP serves HTTP on the address of the server until it is shut down
+func ServeHTTP(server *http.Server) func() error {
+	return func() error {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Msgf("HTTP server listens at %s", listener.Addr().String())
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// ShutdownHTTP drains the requests in flight, the connections still active at the deadline are closed
+func ShutdownHTTP(server *http.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			return err
+		}
+		return nil
+	}
+}
+
+// StopWithin runs a blocking stop until it returns or ctx is done, then
+// force runs unless it is nil and ctx.Err is returned
+func StopWithin(ctx context.Context, stop func(), force func()) error {
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		if force != nil {
+			force()
+		}
+		return ctx.Err()
+	}
+}
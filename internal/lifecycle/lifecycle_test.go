@@ -0,0 +1,206 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/lifecycle"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	testgrpc "google.golang.org/grpc/interop/grpc_testing"
+)
+
+// slowService answers a call once release is closed or the call is cancelled
+type slowService struct {
+	testgrpc.UnimplementedTestServiceServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (service *slowService) UnaryCall(ctx context.Context, req *testgrpc.SimpleRequest) (*testgrpc.SimpleResponse, error) {
+	close(service.started)
+	select {
+	case <-service.release:
+		return &testgrpc.SimpleResponse{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func startGRPCCall(t *testing.T, address string) <-chan error {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := testgrpc.NewTestServiceClient(conn).UnaryCall(context.Background(), &testgrpc.SimpleRequest{}, grpc.WaitForReady(true))
+		result <- err
+	}()
+	return result
+}
+
+func startHTTPRequest(t *testing.T, address string) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		var res *http.Response
+		var err error
+		// the server may not listen yet
+		for i := 0; i < 100; i++ {
+			res, err = http.Get("http://" + address)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			result <- err
+			return
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err == nil && string(body) != "done" {
+			err = errors.New("request wasn't finished")
+		}
+		result <- err
+	}()
+	return result
+}
+
+func waitStarted(t *testing.T, started <-chan struct{}) {
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call didn't start")
+	}
+}
+
+func TestGroupDrainsInFlightCalls(t *testing.T) {
+	service := &slowService{started: make(chan struct{}), release: make(chan struct{})}
+	grpcServer := grpc.NewServer()
+	testgrpc.RegisterTestServiceServer(grpcServer, service)
+	grpcAddress := freeAddress(t)
+
+	httpStarted := make(chan struct{})
+	httpServer := &http.Server{
+		Addr: freeAddress(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(httpStarted)
+			<-service.release
+			w.Write([]byte("done"))
+		}),
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	group, _ := lifecycle.NewGroup(ctx, 5*time.Second)
+	group.Go("gRPC server", lifecycle.ServeGRPC(grpcServer, grpcAddress), lifecycle.StopGRPC(grpcServer))
+	group.Go("HTTP server", lifecycle.ServeHTTP(httpServer), lifecycle.ShutdownHTTP(httpServer))
+
+	grpcResult := startGRPCCall(t, grpcAddress)
+	httpResult := startHTTPRequest(t, httpServer.Addr)
+	waitStarted(t, service.started)
+	waitStarted(t, httpStarted)
+
+	// the app is told to stop while both calls are in flight
+	stop()
+	time.Sleep(50 * time.Millisecond)
+	close(service.release)
+
+	require.NoError(t, group.Wait())
+	require.NoError(t, <-grpcResult)
+	require.NoError(t, <-httpResult)
+
+	// nothing is served anymore
+	_, err := http.Get("http://" + httpServer.Addr)
+	require.Error(t, err)
+}
+
+func TestGroupShutdownTimeout(t *testing.T) {
+	// the call only ends once it is cancelled
+	service := &slowService{started: make(chan struct{}), release: make(chan struct{})}
+	grpcServer := grpc.NewServer()
+	testgrpc.RegisterTestServiceServer(grpcServer, service)
+	grpcAddress := freeAddress(t)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	group, _ := lifecycle.NewGroup(ctx, 100*time.Millisecond)
+	group.Go("gRPC server", lifecycle.ServeGRPC(grpcServer, grpcAddress), lifecycle.StopGRPC(grpcServer))
+
+	grpcResult := startGRPCCall(t, grpcAddress)
+	waitStarted(t, service.started)
+
+	start := time.Now()
+	stop()
+
+	err := group.Wait()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "cannot shutdown gRPC server")
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Error(t, <-grpcResult)
+}
+
+func TestGroupServiceFailed(t *testing.T) {
+	group, ctx := lifecycle.NewGroup(context.Background(), time.Second)
+
+	stopped := make(chan struct{})
+	group.Go("healthy service",
+		func() error {
+			<-stopped
+			return nil
+		},
+		func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		},
+	)
+	group.Go("broken service",
+		func() error {
+			return errors.New("cannot listen")
+		},
+		nil,
+	)
+
+	err := group.Wait()
+	require.EqualError(t, err, "broken service: cannot listen")
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("healthy service wasn't shut down")
+	}
+}
+
+func TestStopWithin(t *testing.T) {
+	err := lifecycle.StopWithin(context.Background(), func() {}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	forced := false
+	err = lifecycle.StopWithin(ctx, func() { <-release }, func() { forced = true })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, forced)
+}
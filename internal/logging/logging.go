@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderRequestID is the HTTP header a request id is accepted from and returned in,
+// MetadataRequestID is the gRPC metadata key carrying it
+const (
+	HeaderRequestID   = "X-Request-ID"
+	MetadataRequestID = "x-request-id"
+)
+
+// maxRequestIDLength keeps an id chosen by the caller out of the logs when it is too long
+const maxRequestIDLength = 128
+
+// RequestIDOrNew returns the id sent by the caller when it is safe to log,
+// a new id otherwise
+func RequestIDOrNew(id string) string {
+	if isValidRequestID(id) {
+		return id
+	}
+	return uuid.NewString()
+}
+
+func isValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the request ctx belongs to, empty outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx returns the logger of ctx, the global logger when ctx has none
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger != zerolog.Ctx(context.Background()) {
+		return logger
+	}
+	return &log.Logger
+}
+
+// WithLogger attaches a logger to ctx with the fields of the logger of ctx, the
+// fields added by fields, and the trace and span ids when ctx is traced
+func WithLogger(ctx context.Context, fields func(c zerolog.Context) zerolog.Context) context.Context {
+	c := fields(Ctx(ctx).With())
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		c = c.Str("trace_id", spanContext.TraceID().String()).
+			Str("span_id", spanContext.SpanID().String())
+	}
+
+	logger := c.Logger()
+	return logger.WithContext(ctx)
+}
+
+// AddFields adds fields to the logger attached to ctx, every holder of the
+// logger logs them from then on. Nothing is added without an attached logger.
+func AddFields(ctx context.Context, fields func(c zerolog.Context) zerolog.Context) {
+	logger := zerolog.Ctx(ctx)
+	if logger == zerolog.Ctx(context.Background()) {
+		return
+	}
+	logger.UpdateContext(fields)
+}
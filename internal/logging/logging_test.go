@@ -0,0 +1,123 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestIDOrNew(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    string
+		check func(t *testing.T, requestID string)
+	}{
+		// TODO: id sent by the caller
+		{
+			name: "Caller",
+			id:   "0f8b1c2e-4a5d-4c3b-9e1f-2a3b4c5d6e7f",
+			check: func(t *testing.T, requestID string) {
+				require.Equal(t, "0f8b1c2e-4a5d-4c3b-9e1f-2a3b4c5d6e7f", requestID)
+			},
+		},
+		// TODO: no id
+		{
+			name: "Empty",
+			id:   "",
+			check: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+		// TODO: id unsafe to log
+		{
+			name: "Unsafe",
+			id:   "abc\n{\"level\":\"error\"}",
+			check: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+				require.NotContains(t, requestID, "level")
+			},
+		},
+		// TODO: id too long
+		{
+			name: "TooLong",
+			id:   strings.Repeat("a", 129),
+			check: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, logging.RequestIDOrNew(tc.id))
+		})
+	}
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &fields))
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := zerolog.New(&buf)
+	ctx := base.WithContext(context.Background())
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	ctx = logging.WithRequestID(ctx, "request-1")
+	ctx = logging.WithLogger(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str("request_id", logging.RequestID(ctx))
+	})
+	logging.Ctx(ctx).Info().Msg("before login")
+
+	// the handlers holding the logger log the user once it is added
+	logging.AddFields(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str("username", "alice")
+	})
+	logging.Ctx(ctx).Info().Msg("after login")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+
+	require.Equal(t, "request-1", lines[0]["request_id"])
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", lines[0]["trace_id"])
+	require.Equal(t, "00f067aa0ba902b7", lines[0]["span_id"])
+	require.NotContains(t, lines[0], "username")
+
+	require.Equal(t, "request-1", lines[1]["request_id"])
+	require.Equal(t, "alice", lines[1]["username"])
+}
+
+func TestCtxWithoutLogger(t *testing.T) {
+	ctx := context.Background()
+	require.NotNil(t, logging.Ctx(ctx))
+	require.Empty(t, logging.RequestID(ctx))
+
+	// nothing to add to, the global logger is left alone
+	logging.AddFields(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str("username", "alice")
+	})
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type FileConfig struct {
+	// File the logs are written to, the rotated files are kept next to it
+	// named after it with the time of the rotation
+	File string
+	// MaxSizeMB the file grows to before it is rotated
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept, 0 keeps them all
+	MaxBackups int
+	// MaxAge of the rotated files kept, rounded up to days, 0 keeps them all
+	MaxAge time.Duration
+	// Compress the rotated files with gzip
+	Compress bool
+	// Daily rotates the file on the first write of every day as well
+	Daily bool
+}
+
+// RotatingFile writes the logs to a file rotated by size and by day,
+// the rotated files are compressed and removed in the background
+type RotatingFile struct {
+	mu     sync.Mutex
+	file   *lumberjack.Logger
+	daily  bool
+	day    string
+	now    func() time.Time
+	opened bool
+}
+
+func NewRotatingFile(config FileConfig) *RotatingFile {
+	return &RotatingFile{
+		file: &lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSizeMB,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     days(config.MaxAge),
+			Compress:   config.Compress,
+			LocalTime:  true,
+		},
+		daily: config.Daily,
+		now:   time.Now,
+	}
+}
+
+func days(age time.Duration) int {
+	if age <= 0 {
+		return 0
+	}
+	return int((age + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.daily {
+		if err := f.rotateDaily(); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(p)
+}
+
+// rotateDaily rotates the file when the day changed since the last write,
+// or since the file was last written by a previous run
+func (f *RotatingFile) rotateDaily() error {
+	today := f.now().Format("20060102")
+
+	if !f.opened {
+		f.opened = true
+		f.day = today
+
+		info, err := os.Stat(f.file.Filename)
+		if err != nil || info.ModTime().Format("20060102") == today {
+			return nil
+		}
+		return f.file.Rotate()
+	}
+
+	if f.day == today {
+		return nil
+	}
+	f.day = today
+	return f.file.Rotate()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
@@ -0,0 +1,145 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func logFiles(t *testing.T, dir string) (current string, backups []string) {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		if entry.Name() == "myapp.log" {
+			current = entry.Name()
+			continue
+		}
+		backups = append(backups, entry.Name())
+	}
+	return current, backups
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	file := NewRotatingFile(FileConfig{
+		File:      filepath.Join(dir, "myapp.log"),
+		MaxSizeMB: 1,
+	})
+	defer file.Close()
+
+	line := append(bytes.Repeat([]byte("a"), 1023), '\n')
+	for i := 0; i < 1025; i++ {
+		_, err := file.Write(line)
+		require.NoError(t, err)
+	}
+
+	current, backups := logFiles(t, dir)
+	require.NotEmpty(t, current)
+	require.Len(t, backups, 1)
+	require.True(t, strings.HasPrefix(backups[0], "myapp-"))
+}
+
+func TestRotatingFileDaily(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	file := NewRotatingFile(FileConfig{
+		File:      filepath.Join(dir, "myapp.log"),
+		MaxSizeMB: 100,
+		Daily:     true,
+	})
+	file.now = func() time.Time { return now }
+	defer file.Close()
+
+	_, err := file.Write([]byte("first day\n"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("first day\n"))
+	require.NoError(t, err)
+
+	_, backups := logFiles(t, dir)
+	require.Empty(t, backups)
+
+	now = now.Add(24 * time.Hour)
+	_, err = file.Write([]byte("second day\n"))
+	require.NoError(t, err)
+
+	_, backups = logFiles(t, dir)
+	require.Len(t, backups, 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, "myapp.log"))
+	require.NoError(t, err)
+	require.Equal(t, "second day\n", string(content))
+}
+
+func TestRotatingFileDailyRestart(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "myapp.log")
+
+	// written by the previous run the day before
+	require.NoError(t, os.WriteFile(name, []byte("yesterday\n"), 0644))
+	yesterday := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(name, yesterday, yesterday))
+
+	file := NewRotatingFile(FileConfig{File: name, MaxSizeMB: 100, Daily: true})
+	defer file.Close()
+
+	_, err := file.Write([]byte("today\n"))
+	require.NoError(t, err)
+
+	_, backups := logFiles(t, dir)
+	require.Len(t, backups, 1)
+
+	content, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, "today\n", string(content))
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	file := NewRotatingFile(FileConfig{
+		File:       filepath.Join(dir, "myapp.log"),
+		MaxSizeMB:  100,
+		MaxBackups: 2,
+		Compress:   true,
+		Daily:      true,
+	})
+	file.now = func() time.Time { return now }
+	defer file.Close()
+
+	for i := 0; i < 4; i++ {
+		_, err := file.Write([]byte("log\n"))
+		require.NoError(t, err)
+		now = now.Add(24 * time.Hour)
+		// the backup names have millisecond precision
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// old files are compressed and removed in the background
+	require.Eventually(t, func() bool {
+		_, backups := logFiles(t, dir)
+		if len(backups) != 2 {
+			return false
+		}
+		for _, backup := range backups {
+			if !strings.HasSuffix(backup, ".log.gz") {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDays(t *testing.T) {
+	require.Equal(t, 0, days(0))
+	require.Equal(t, 1, days(time.Hour))
+	require.Equal(t, 1, days(24*time.Hour))
+	require.Equal(t, 30, days(720*time.Hour))
+}
@@ -0,0 +1,207 @@
+package loginguard
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	emailKeyPrefix = "email:"
+	ipKeyPrefix    = "ip:"
+)
+
+// Attempts is what a Store remembers about the failed logins of one key
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps the failed login attempts of an email or a client IP.
+type Store interface {
+	// Get returns the failures recorded for key, zero when there is none.
+	Get(ctx context.Context, key string) (Attempts, error)
+
+	// Reserve counts an attempt at the given time as a failure and keeps the key
+	// for ttl after it. It returns the attempts of key right before and right after
+	// the reservation in one atomic step, so concurrent attempts each see the ones
+	// reserved before them.
+	Reserve(ctx context.Context, key string, at time.Time, ttl time.Duration) (before Attempts, after Attempts, err error)
+
+	// Refund takes back the attempt reserved at the given time. The last failure
+	// goes back to before unless a later attempt was reserved in the meantime.
+	Refund(ctx context.Context, key string, at time.Time, before Attempts) error
+
+	// Reset forgets every failure of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy decides how long a key has to wait after its failures.
+// The first MaxAttempts failures are free, every further failure doubles
+// the wait starting from BaseDelay, and once LockoutAttempts failures are
+// reached the key is locked for LockoutDuration, no wait is ever longer.
+// Failures are forgotten Window after the last one. A zero MaxAttempts
+// disables the policy.
+type Policy struct {
+	MaxAttempts     int
+	LockoutAttempts int
+	BaseDelay       time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// delay returns how long to wait after the given number of failures
+// and whether that wait is a lockout
+func (policy Policy) delay(failures int) (time.Duration, bool) {
+	if policy.MaxAttempts <= 0 || failures < policy.MaxAttempts {
+		return 0, false
+	}
+
+	if policy.LockoutAttempts > 0 && failures >= policy.LockoutAttempts {
+		return policy.LockoutDuration, true
+	}
+
+	delay := policy.BaseDelay
+	for i := policy.MaxAttempts; i < failures && delay < policy.LockoutDuration; i++ {
+		delay *= 2
+	}
+
+	if delay > policy.LockoutDuration {
+		delay = policy.LockoutDuration
+	}
+	return delay, false
+}
+
+// ttl is how long the failures of a key have to be kept
+func (policy Policy) ttl() time.Duration {
+	if policy.LockoutDuration > policy.Window {
+		return policy.LockoutDuration
+	}
+	return policy.Window
+}
+
+// retryAfter returns how long the key still has to wait at now
+func (policy Policy) retryAfter(attempts Attempts, now time.Time) (time.Duration, bool) {
+	delay, locked := policy.delay(attempts.Failures)
+	wait := attempts.LastFailure.Add(delay).Sub(now)
+	if wait <= 0 {
+		return 0, false
+	}
+	return wait, locked
+}
+
+// Result tells what a failed login changed
+type Result struct {
+	// RetryAfter is how long the client has to wait before the next attempt
+	RetryAfter time.Duration
+	// Locked is only true on the failure that took the email from unlocked
+	// to locked, so the owner is notified once per lockout
+	Locked bool
+}
+
+// Guard throttles failed logins per email and per client IP.
+type Guard struct {
+	store Store
+	email Policy
+	ip    Policy
+}
+
+func NewGuard(store Store, email, ip Policy) *Guard {
+	return &Guard{store: store, email: email, ip: ip}
+}
+
+// Attempt is a login reserved by Begin, it has to be ended by Fail, Succeed or Cancel
+type Attempt struct {
+	email       string
+	clientIP    string
+	at          time.Time
+	emailBefore Attempts
+	emailAfter  Attempts
+	ipBefore    Attempts
+	ipAfter     Attempts
+}
+
+// Begin reserves a login attempt for the email and the client IP before the
+// password is checked, the reservation counts as a failure until the attempt
+// ends. When the email or the client IP has to wait, counting the attempts still
+// in progress, the reservation is refunded and the wait is returned instead, so
+// guesses sent in parallel are throttled like sequential ones.
+func (guard *Guard) Begin(ctx context.Context, email, clientIP string) (*Attempt, time.Duration, error) {
+	attempt := &Attempt{email: email, clientIP: clientIP, at: time.Now()}
+
+	var err error
+	attempt.emailBefore, attempt.emailAfter, err = guard.store.Reserve(ctx, emailKey(email), attempt.at, guard.email.ttl())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	attempt.ipBefore, attempt.ipAfter, err = guard.store.Reserve(ctx, ipKey(clientIP), attempt.at, guard.ip.ttl())
+	if err != nil {
+		refundErr := guard.store.Refund(ctx, emailKey(email), attempt.at, attempt.emailBefore)
+		if refundErr != nil {
+			return nil, 0, refundErr
+		}
+		return nil, 0, err
+	}
+
+	emailWait, _ := guard.email.retryAfter(attempt.emailBefore, attempt.at)
+	ipWait, _ := guard.ip.retryAfter(attempt.ipBefore, attempt.at)
+	if wait := maxDuration(emailWait, ipWait); wait > 0 {
+		return nil, wait, guard.Cancel(ctx, attempt)
+	}
+	return attempt, 0, nil
+}
+
+// Fail keeps the reservation of the attempt as a failed login. Begin refuses an
+// email that is locked, so the email is newly locked when its reservation reached
+// the lockout, which also catches a lockout that expired and was reached again.
+func (guard *Guard) Fail(attempt *Attempt) Result {
+	now := time.Now()
+
+	emailWait, locked := guard.email.retryAfter(attempt.emailAfter, now)
+	ipWait, _ := guard.ip.retryAfter(attempt.ipAfter, now)
+	_, wasLocked := guard.email.retryAfter(attempt.emailBefore, attempt.at)
+
+	result := Result{
+		RetryAfter: maxDuration(emailWait, ipWait),
+		Locked:     locked && !wasLocked,
+	}
+	return result
+}
+
+// Succeed forgets the failures of the email and refunds the reservation of the
+// client IP. The earlier failures of the client IP are kept, otherwise one valid
+// account would reset the IP between guesses.
+func (guard *Guard) Succeed(ctx context.Context, attempt *Attempt) error {
+	if err := guard.store.Reset(ctx, emailKey(attempt.email)); err != nil {
+		return err
+	}
+	return guard.store.Refund(ctx, ipKey(attempt.clientIP), attempt.at, attempt.ipBefore)
+}
+
+// Cancel refunds the attempt when it ended before the password was checked.
+func (guard *Guard) Cancel(ctx context.Context, attempt *Attempt) error {
+	if err := guard.store.Refund(ctx, emailKey(attempt.email), attempt.at, attempt.emailBefore); err != nil {
+		return err
+	}
+	return guard.store.Refund(ctx, ipKey(attempt.clientIP), attempt.at, attempt.ipBefore)
+}
+
+func emailKey(email string) string {
+	return emailKeyPrefix + email
+}
+
+// ipKey drops the port, a direct gRPC client gets a new one on every connection
+func ipKey(clientIP string) string {
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	return ipKeyPrefix + clientIP
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
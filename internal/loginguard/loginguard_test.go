@@ -0,0 +1,226 @@
+package loginguard_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+var emailPolicy = loginguard.Policy{
+	MaxAttempts:     2,
+	LockoutAttempts: 4,
+	BaseDelay:       20 * time.Millisecond,
+	LockoutDuration: time.Minute,
+	Window:          time.Minute,
+}
+
+var ipPolicy = loginguard.Policy{
+	MaxAttempts:     10,
+	LockoutAttempts: 20,
+	BaseDelay:       20 * time.Millisecond,
+	LockoutDuration: time.Minute,
+	Window:          time.Minute,
+}
+
+func newRedisStore(t *testing.T) loginguard.Store {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return loginguard.NewRedisStore(client)
+}
+
+func TestMemoryGuard(t *testing.T) {
+	testGuard(t, loginguard.NewMemoryStore())
+}
+
+func TestRedisGuard(t *testing.T) {
+	testGuard(t, newRedisStore(t))
+}
+
+// check returns how long the email or the client IP has to wait, an attempt that
+// may go on is cancelled right away
+func check(t *testing.T, guard *loginguard.Guard, email, clientIP string) time.Duration {
+	attempt, wait, err := guard.Begin(context.Background(), email, clientIP)
+	require.NoError(t, err)
+	if attempt != nil {
+		require.Zero(t, wait)
+		require.NoError(t, guard.Cancel(context.Background(), attempt))
+	}
+	return wait
+}
+
+// fail makes an attempt that may go on and fails it
+func fail(t *testing.T, guard *loginguard.Guard, email, clientIP string) loginguard.Result {
+	attempt, wait, err := guard.Begin(context.Background(), email, clientIP)
+	require.NoError(t, err)
+	require.Zero(t, wait)
+	require.NotNil(t, attempt)
+	return guard.Fail(attempt)
+}
+
+func testGuard(t *testing.T, store loginguard.Store) {
+	ctx := context.Background()
+	guard := loginguard.NewGuard(store, emailPolicy, ipPolicy)
+	email := util.RandomEmail()
+	clientIP := "10.0.0.1:51000"
+
+	// a cancelled attempt is not a failure
+	for i := 0; i < emailPolicy.LockoutAttempts; i++ {
+		require.Zero(t, check(t, guard, email, clientIP))
+	}
+
+	// the first failure is free
+	result := fail(t, guard, email, clientIP)
+	require.Zero(t, result.RetryAfter)
+	require.False(t, result.Locked)
+
+	// then the wait doubles with every failure
+	result = fail(t, guard, email, clientIP)
+	require.InDelta(t, emailPolicy.BaseDelay, result.RetryAfter, float64(10*time.Millisecond))
+
+	// a throttled attempt is refunded and doesn't make the wait longer
+	require.InDelta(t, emailPolicy.BaseDelay, check(t, guard, email, clientIP), float64(10*time.Millisecond))
+	require.InDelta(t, emailPolicy.BaseDelay, check(t, guard, email, clientIP), float64(10*time.Millisecond))
+
+	// another port of the same client shares the wait
+	require.Positive(t, check(t, guard, email, "10.0.0.1:52000"))
+
+	// another email from the same client is not throttled yet
+	require.Zero(t, check(t, guard, util.RandomEmail(), clientIP))
+
+	// the wait passes
+	time.Sleep(2 * emailPolicy.BaseDelay)
+	result = fail(t, guard, email, clientIP)
+	require.InDelta(t, 2*emailPolicy.BaseDelay, result.RetryAfter, float64(10*time.Millisecond))
+	require.False(t, result.Locked)
+
+	// the email is locked once and refuses further attempts
+	time.Sleep(3 * emailPolicy.BaseDelay)
+	result = fail(t, guard, email, clientIP)
+	require.True(t, result.Locked)
+	require.InDelta(t, emailPolicy.LockoutDuration, result.RetryAfter, float64(time.Second))
+
+	require.InDelta(t, emailPolicy.LockoutDuration, check(t, guard, email, "10.0.0.2:51000"), float64(time.Second))
+
+	// a successful login forgets the email and doesn't count for the client
+	otherEmail := util.RandomEmail()
+	fail(t, guard, otherEmail, clientIP)
+
+	attempt, wait, err := guard.Begin(ctx, otherEmail, clientIP)
+	require.NoError(t, err)
+	require.Zero(t, wait)
+	require.NoError(t, guard.Succeed(ctx, attempt))
+
+	require.Zero(t, fail(t, guard, otherEmail, "10.0.0.3").RetryAfter)
+}
+
+func TestMemoryGuardConcurrentAttempts(t *testing.T) {
+	testGuardConcurrentAttempts(t, loginguard.NewMemoryStore())
+}
+
+func TestRedisGuardConcurrentAttempts(t *testing.T) {
+	testGuardConcurrentAttempts(t, newRedisStore(t))
+}
+
+func testGuardConcurrentAttempts(t *testing.T, store loginguard.Store) {
+	ctx := context.Background()
+	policy := emailPolicy
+	policy.BaseDelay = time.Second
+	guard := loginguard.NewGuard(store, policy, ipPolicy)
+	email := util.RandomEmail()
+
+	// guesses sent at once from many clients, no more than the free attempts go on.
+	// An attempt refused for the reservations still in progress may be one of them
+	n := 20
+	attempts := make(chan *loginguard.Attempt, n)
+	errs := make(chan error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			attempt, _, err := guard.Begin(ctx, email, fmt.Sprintf("10.0.0.%d", i))
+			errs <- err
+			if attempt != nil {
+				attempts <- attempt
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(attempts)
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var started int
+	for attempt := range attempts {
+		guard.Fail(attempt)
+		started++
+	}
+	require.Positive(t, started)
+	require.LessOrEqual(t, started, policy.MaxAttempts)
+
+	// the refused attempts are refunded, only the failures count
+	for i := started; i < policy.MaxAttempts; i++ {
+		fail(t, guard, email, "10.0.1.1")
+	}
+	require.Positive(t, check(t, guard, email, "10.0.1.1"))
+}
+
+func TestGuardLockedAgain(t *testing.T) {
+	policy := loginguard.Policy{
+		MaxAttempts:     1,
+		LockoutAttempts: 2,
+		BaseDelay:       time.Millisecond,
+		LockoutDuration: 20 * time.Millisecond,
+		Window:          time.Minute,
+	}
+	guard := loginguard.NewGuard(loginguard.NewMemoryStore(), policy, loginguard.Policy{})
+	email := util.RandomEmail()
+	clientIP := "10.0.0.1"
+
+	result := fail(t, guard, email, clientIP)
+	require.False(t, result.Locked)
+
+	time.Sleep(5 * time.Millisecond)
+	result = fail(t, guard, email, clientIP)
+	require.True(t, result.Locked)
+
+	// the lockout expires within the window, the next failure locks the email again
+	time.Sleep(2 * policy.LockoutDuration)
+	result = fail(t, guard, email, clientIP)
+	require.True(t, result.Locked)
+}
+
+func TestGuardIP(t *testing.T) {
+	guard := loginguard.NewGuard(loginguard.NewMemoryStore(), emailPolicy, ipPolicy)
+	clientIP := "10.0.0.1"
+
+	// guessing many emails from the same client
+	for i := 0; i < ipPolicy.MaxAttempts; i++ {
+		result := fail(t, guard, util.RandomEmail(), clientIP)
+		require.False(t, result.Locked)
+	}
+
+	require.Positive(t, check(t, guard, util.RandomEmail(), clientIP))
+}
+
+func TestGuardDisabled(t *testing.T) {
+	guard := loginguard.NewGuard(loginguard.NewMemoryStore(), loginguard.Policy{}, loginguard.Policy{})
+	email := util.RandomEmail()
+
+	for i := 0; i < 10; i++ {
+		result := fail(t, guard, email, "10.0.0.1")
+		require.Zero(t, result.RetryAfter)
+	}
+}
@@ -0,0 +1,95 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	attempts  Attempts
+	expiresAt time.Time
+}
+
+// MemoryStore keeps failed logins in process. It is only correct when a
+// single instance of the server is running.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (store *MemoryStore) Get(ctx context.Context, key string) (Attempts, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return Attempts{}, nil
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(store.entries, key)
+		return Attempts{}, nil
+	}
+	return entry.attempts, nil
+}
+
+func (store *MemoryStore) Reserve(ctx context.Context, key string, at time.Time, ttl time.Duration) (Attempts, Attempts, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	store.purge(now)
+
+	entry := store.entries[key]
+	before := entry.attempts
+	entry.attempts.Failures++
+	entry.attempts.LastFailure = at
+	entry.expiresAt = now.Add(ttl)
+	store.entries[key] = entry
+
+	return before, entry.attempts, nil
+}
+
+func (store *MemoryStore) Refund(ctx context.Context, key string, at time.Time, before Attempts) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry.attempts.Failures--
+	if entry.attempts.Failures <= 0 {
+		delete(store.entries, key)
+		return nil
+	}
+
+	if entry.attempts.LastFailure.Equal(at) {
+		entry.attempts.LastFailure = before.LastFailure
+	}
+	store.entries[key] = entry
+	return nil
+}
+
+func (store *MemoryStore) Reset(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.entries, key)
+	return nil
+}
+
+// purge removes the entries whose ttl has passed, must be called with mu held
+func (store *MemoryStore) purge(now time.Time) {
+	for key, entry := range store.entries {
+		if now.After(entry.expiresAt) {
+			delete(store.entries, key)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package loginguard
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	redisKeyPrefix        = "login_attempts:"
+	redisFieldFailures    = "failures"
+	redisFieldLastFailure = "last_failure"
+)
+
+// RedisStore keeps failed logins in Redis so every server instance
+// sharing the same Redis throttles the same clients.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) Store {
+	return &RedisStore{client: client}
+}
+
+func (store *RedisStore) Get(ctx context.Context, key string) (Attempts, error) {
+	values, err := store.client.HGetAll(ctx, redisKeyPrefix+key).Result()
+	if err != nil {
+		return Attempts{}, err
+	}
+	return parseAttempts(values)
+}
+
+func (store *RedisStore) Reserve(ctx context.Context, key string, at time.Time, ttl time.Duration) (Attempts, Attempts, error) {
+	redisKey := redisKeyPrefix + key
+
+	var values *redis.StringStringMapCmd
+	var failures *redis.IntCmd
+	_, err := store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.HGetAll(ctx, redisKey)
+		failures = pipe.HIncrBy(ctx, redisKey, redisFieldFailures, 1)
+		pipe.HSet(ctx, redisKey, redisFieldLastFailure, at.UnixNano())
+		pipe.PExpire(ctx, redisKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return Attempts{}, Attempts{}, err
+	}
+
+	before, err := parseAttempts(values.Val())
+	if err != nil {
+		return Attempts{}, Attempts{}, err
+	}
+
+	after := Attempts{
+		Failures:    int(failures.Val()),
+		LastFailure: at,
+	}
+	return before, after, nil
+}
+
+// refundScript takes back one reserved failure, the key is removed with its last
+// failure and the last failure is only restored while it is still the refunded one
+var refundScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local failures = redis.call("HINCRBY", KEYS[1], "` + redisFieldFailures + `", -1)
+if failures <= 0 then
+	redis.call("DEL", KEYS[1])
+	return 0
+end
+if redis.call("HGET", KEYS[1], "` + redisFieldLastFailure + `") == ARGV[1] then
+	redis.call("HSET", KEYS[1], "` + redisFieldLastFailure + `", ARGV[2])
+end
+return failures
+`)
+
+func (store *RedisStore) Refund(ctx context.Context, key string, at time.Time, before Attempts) error {
+	args := []interface{}{
+		strconv.FormatInt(at.UnixNano(), 10),
+		strconv.FormatInt(before.LastFailure.UnixNano(), 10),
+	}
+	return refundScript.Run(ctx, store.client, []string{redisKeyPrefix + key}, args...).Err()
+}
+
+func (store *RedisStore) Reset(ctx context.Context, key string) error {
+	return store.client.Del(ctx, redisKeyPrefix+key).Err()
+}
+
+func parseAttempts(values map[string]string) (Attempts, error) {
+	var attempts Attempts
+	if len(values) == 0 {
+		return attempts, nil
+	}
+
+	failures, err := strconv.Atoi(values[redisFieldFailures])
+	if err != nil {
+		return attempts, err
+	}
+
+	lastFailure, err := strconv.ParseInt(values[redisFieldLastFailure], 10, 64)
+	if err != nil {
+		return attempts, err
+	}
+
+	attempts.Failures = failures
+	attempts.LastFailure = time.Unix(0, lastFailure)
+	return attempts, nil
+}
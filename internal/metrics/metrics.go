@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+)
+
+// Path is where Prometheus scrapes the metrics on the HTTP gateway
+const Path = "/metrics"
+
+// namespace prefixes every metric of the app
+const namespace = "simplebank"
+
+var (
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Unary RPCs handled, by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the unary RPCs, by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served by the gateway, by method and status code.",
+	}, []string{"method", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests served by the gateway, by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of the database queries, by sqlc query name.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query"})
+
+	tasksProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "task",
+		Name:      "processed_total",
+		Help:      "Tasks processed by the worker, by task type and outcome.",
+	}, []string{"task", "outcome"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "task",
+		Name:      "duration_seconds",
+		Help:      "Processing time of the tasks, by task type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"task"})
+
+	transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Transfers made, by currency.",
+	}, []string{"currency"})
+
+	transferVolume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_volume_total",
+		Help:      "Amount transferred, by currency.",
+	}, []string{"currency"})
+)
+
+// Handler serves the metrics of the app with the Go runtime and process metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveGRPCRequest records a handled unary RPC
+func ObserveGRPCRequest(method string, code codes.Code, duration time.Duration) {
+	grpcRequests.WithLabelValues(method, code.String()).Inc()
+	grpcDuration.WithLabelValues(method, code.String()).Observe(duration.Seconds())
+}
+
+// ObserveHTTPRequest records a served HTTP request. The path isn't a label, the
+// gateway paths embed ids and the RPC metrics already tell the requests apart.
+func ObserveHTTPRequest(method string, statusCode int, duration time.Duration) {
+	code := strconv.Itoa(statusCode)
+	httpRequests.WithLabelValues(method, code).Inc()
+	httpDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+// ObserveQuery records the latency of a database query once done is called
+func ObserveQuery(ctx context.Context, name string) (done func(err error)) {
+	startTime := time.Now()
+	return func(err error) {
+		queryDuration.WithLabelValues(name).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// task outcomes
+const (
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+)
+
+// ObserveTask records a processed task, a failed task may be retried later
+func ObserveTask(taskType string, duration time.Duration, err error) {
+	outcome := TaskSucceeded
+	if err != nil {
+		outcome = TaskFailed
+	}
+	tasksProcessed.WithLabelValues(taskType, outcome).Inc()
+	taskDuration.WithLabelValues(taskType).Observe(duration.Seconds())
+}
+
+// ObserveTransfer records a committed transfer
+func ObserveTransfer(currency string, amount int64) {
+	transfers.WithLabelValues(currency).Inc()
+	transferVolume.WithLabelValues(currency).Add(float64(amount))
+}
+
+// RegisterDB exposes the stats of the connection pool
+func RegisterDB(conn *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(conn, name))
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestObserveGRPCRequest(t *testing.T) {
+	method := "/pb.Simplebank/GetAccount"
+	before := testutil.ToFloat64(grpcRequests.WithLabelValues(method, "NotFound"))
+
+	ObserveGRPCRequest(method, codes.NotFound, 5*time.Millisecond)
+	ObserveGRPCRequest(method, codes.OK, 5*time.Millisecond)
+
+	require.Equal(t, before+1, testutil.ToFloat64(grpcRequests.WithLabelValues(method, "NotFound")))
+	require.Equal(t, 2, testutil.CollectAndCount(grpcDuration, "simplebank_grpc_request_duration_seconds"))
+}
+
+func TestObserveTask(t *testing.T) {
+	ObserveTask("task:test", time.Millisecond, nil)
+	ObserveTask("task:test", time.Millisecond, errors.New("cannot send email"))
+	ObserveTask("task:test", time.Millisecond, errors.New("cannot send email"))
+
+	require.Equal(t, float64(1), testutil.ToFloat64(tasksProcessed.WithLabelValues("task:test", TaskSucceeded)))
+	require.Equal(t, float64(2), testutil.ToFloat64(tasksProcessed.WithLabelValues("task:test", TaskFailed)))
+}
+
+func TestObserveTransfer(t *testing.T) {
+	ObserveTransfer("EUR", 100)
+	ObserveTransfer("EUR", 250)
+	ObserveTransfer("CAD", 10)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(transfers.WithLabelValues("EUR")))
+	require.Equal(t, float64(350), testutil.ToFloat64(transferVolume.WithLabelValues("EUR")))
+	require.Equal(t, float64(10), testutil.ToFloat64(transferVolume.WithLabelValues("CAD")))
+}
+
+// the queue info needs Redis commands miniredis doesn't support,
+// so only the unavailable Redis is tested
+func TestQueueCollectorRedisDown(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	inspector := asynq.NewInspector(asynq.RedisClientOpt{Addr: redisServer.Addr()})
+	defer inspector.Close()
+	redisServer.Close()
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(&queueCollector{inspector: inspector}))
+
+	// a scrape still succeeds while Redis is down
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Empty(t, families)
+}
+
+func TestHandler(t *testing.T) {
+	ObserveHTTPRequest(http.MethodGet, http.StatusOK, time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `simplebank_http_requests_total{code="200",method="GET"}`)
+	require.Contains(t, string(body), "go_goroutines")
+}
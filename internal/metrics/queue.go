@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	queueTasks = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "task_queue", "tasks"),
+		"Tasks in the queue, by queue and state.",
+		[]string{"queue", "state"}, nil,
+	)
+
+	queueLatency = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "task_queue", "latency_seconds"),
+		"Time the oldest pending task of the queue has waited.",
+		[]string{"queue"}, nil,
+	)
+)
+
+// queueCollector reads the depth of the task queues from Redis on every scrape
+type queueCollector struct {
+	inspector *asynq.Inspector
+}
+
+// RegisterQueues exposes the depth of the task queues
+func RegisterQueues(inspector *asynq.Inspector) error {
+	return prometheus.Register(&queueCollector{inspector: inspector})
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueTasks
+	ch <- queueLatency
+}
+
+// Collect skips the queues it cannot read, a scrape shouldn't fail while Redis is down
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	queues, err := c.inspector.Queues()
+	if err != nil {
+		log.Error().Err(err).Msg("cannot list task queues")
+		return
+	}
+
+	for _, queue := range queues {
+		info, err := c.inspector.GetQueueInfo(queue)
+		if err != nil {
+			log.Error().Err(err).Str("queue", queue).Msg("cannot get task queue info")
+			continue
+		}
+
+		states := map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+			"completed": info.Completed,
+		}
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(queueTasks, prometheus.GaugeValue, float64(count), queue, state)
+		}
+		ch <- prometheus.MustNewConstMetric(queueLatency, prometheus.GaugeValue, info.Latency.Seconds(), queue)
+	}
+}
@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSender appends every message as a JSON line to <dir>/<channel>.log, it sends
+// nothing so alerts can be read locally without an email, SMS or push provider
+type FileSender struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create notification dir: %w", err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+func (sender *FileSender) SendEmail(ctx context.Context, to, subject, body string) error {
+	return sender.write(SentMessage{Channel: ChannelEmail, To: to, Subject: subject, Body: body})
+}
+
+func (sender *FileSender) SendSMS(ctx context.Context, to, body string) error {
+	return sender.write(SentMessage{Channel: ChannelSMS, To: to, Body: body})
+}
+
+func (sender *FileSender) SendPush(ctx context.Context, token, title, body string) error {
+	return sender.write(SentMessage{Channel: ChannelPush, To: token, Subject: title, Body: body})
+}
+
+func (sender *FileSender) write(message SentMessage) error {
+	message.SentAt = time.Now()
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(sender.dir, message.Channel+".log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
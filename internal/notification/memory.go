@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SentMessage is a message kept by the file and memory senders,
+// Subject is empty for an SMS and is the title of a push
+type SentMessage struct {
+	Channel string    `json:"channel"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject,omitempty"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// MemorySender keeps every message in memory, it is meant for tests
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []SentMessage
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (sender *MemorySender) SendEmail(ctx context.Context, to, subject, body string) error {
+	sender.add(SentMessage{Channel: ChannelEmail, To: to, Subject: subject, Body: body})
+	return nil
+}
+
+func (sender *MemorySender) SendSMS(ctx context.Context, to, body string) error {
+	sender.add(SentMessage{Channel: ChannelSMS, To: to, Body: body})
+	return nil
+}
+
+func (sender *MemorySender) SendPush(ctx context.Context, token, title, body string) error {
+	sender.add(SentMessage{Channel: ChannelPush, To: token, Subject: title, Body: body})
+	return nil
+}
+
+func (sender *MemorySender) add(message SentMessage) {
+	message.SentAt = time.Now()
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	sender.messages = append(sender.messages, message)
+}
+
+// Messages returns the messages sent so far
+func (sender *MemorySender) Messages() []SentMessage {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	return append([]SentMessage(nil), sender.messages...)
+}
@@ -0,0 +1,213 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+)
+
+// events a user is alerted of
+const (
+	EventTransferReceived = "transfer_received"
+	EventLargeDebit       = "large_debit"
+	EventNewDevice        = "new_device"
+)
+
+// channels an alert is sent through
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// Events and Channels list what a user has preferences for
+var (
+	Events   = []string{EventTransferReceived, EventLargeDebit, EventNewDevice}
+	Channels = []string{ChannelEmail, ChannelSMS, ChannelPush}
+)
+
+// DefaultLocale is used for the users whose locale has no templates
+const DefaultLocale = "en"
+
+// DefaultLargeDebitThreshold is the smallest debit alerted of until the user changes it
+const DefaultLargeDebitThreshold = 1000
+
+func IsSupportEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func IsSupportChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSettings are the settings of a user who never changed them
+func DefaultSettings(username string) db.NotificationSettings {
+	return db.NotificationSettings{
+		Username:            username,
+		Locale:              DefaultLocale,
+		LargeDebitThreshold: DefaultLargeDebitThreshold,
+	}
+}
+
+// Preferences tells which channels a user enabled per event, a channel
+// the user has no preference for is enabled
+type Preferences map[string]map[string]bool
+
+func NewPreferences(preferences []db.NotificationPreferences) Preferences {
+	p := make(Preferences)
+	for _, preference := range preferences {
+		if p[preference.Event] == nil {
+			p[preference.Event] = make(map[string]bool)
+		}
+		p[preference.Event][preference.Channel] = preference.Enabled
+	}
+	return p
+}
+
+func (p Preferences) Enabled(event, channel string) bool {
+	enabled, ok := p[event][channel]
+	return !ok || enabled
+}
+
+// Recipient is who an alert is sent to, a channel without an address is skipped
+type Recipient struct {
+	Username    string
+	FullName    string
+	Email       string
+	PhoneNumber string
+	PushToken   string
+	Locale      string
+}
+
+// NewRecipient combines a user with their notification settings
+func NewRecipient(user db.Users, settings db.NotificationSettings) Recipient {
+	return Recipient{
+		Username:    user.Username,
+		FullName:    user.FullName,
+		Email:       user.Email,
+		PhoneNumber: settings.PhoneNumber,
+		PushToken:   settings.PushToken,
+		Locale:      settings.Locale,
+	}
+}
+
+func (r Recipient) address(channel string) string {
+	switch channel {
+	case ChannelEmail:
+		return r.Email
+	case ChannelSMS:
+		return r.PhoneNumber
+	case ChannelPush:
+		return r.PushToken
+	}
+	return ""
+}
+
+// TransferData is the data of the transfer alerts
+type TransferData struct {
+	TransferID int64     `json:"transfer_id"`
+	AccountID  int64     `json:"account_id"`
+	Amount     int64     `json:"amount"`
+	Currency   string    `json:"currency"`
+	Balance    int64     `json:"balance"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeviceData is the data of the new device alert
+type DeviceData struct {
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip"`
+	LoginAt   time.Time `json:"login_at"`
+}
+
+// EmailSender, SMSSender and PushSender deliver the messages of a channel,
+// a provider only implements the channels it supports
+type EmailSender interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+}
+
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+type PushSender interface {
+	SendPush(ctx context.Context, token, title, body string) error
+}
+
+// Notifier renders alerts in the locale of their recipient and sends them
+// through every channel the recipient enabled
+type Notifier struct {
+	templates *Templates
+	email     EmailSender
+	sms       SMSSender
+	push      PushSender
+}
+
+func NewNotifier(email EmailSender, sms SMSSender, push PushSender) (*Notifier, error) {
+	templates, err := NewTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		templates: templates,
+		email:     email,
+		sms:       sms,
+		push:      push,
+	}, nil
+}
+
+// Notify sends an alert and returns the channels it was sent through. Every channel
+// is tried, an error names the channels that failed.
+func (n *Notifier) Notify(ctx context.Context, recipient Recipient, preferences Preferences, event string, data interface{}) ([]string, error) {
+	var sent, failed []string
+	var lastErr error
+	for _, channel := range Channels {
+		to := recipient.address(channel)
+		if to == "" || !preferences.Enabled(event, channel) {
+			continue
+		}
+
+		if err := n.send(ctx, channel, to, recipient, event, data); err != nil {
+			failed = append(failed, channel)
+			lastErr = err
+			continue
+		}
+		sent = append(sent, channel)
+	}
+
+	if len(failed) > 0 {
+		return sent, fmt.Errorf("cannot send %s through %s: %w", event, strings.Join(failed, ", "), lastErr)
+	}
+	return sent, nil
+}
+
+func (n *Notifier) send(ctx context.Context, channel, to string, recipient Recipient, event string, data interface{}) error {
+	message, err := n.templates.Render(recipient.Locale, event, recipient, data)
+	if err != nil {
+		return err
+	}
+
+	switch channel {
+	case ChannelEmail:
+		return n.email.SendEmail(ctx, to, message.Subject, message.Body)
+	case ChannelSMS:
+		return n.sms.SendSMS(ctx, to, message.Short)
+	case ChannelPush:
+		return n.push.SendPush(ctx, to, message.Subject, message.Short)
+	}
+	return fmt.Errorf("unknown channel %s", channel)
+}
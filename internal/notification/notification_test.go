@@ -0,0 +1,219 @@
+package notification_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSender fails the SMS, the other channels are kept in memory
+type failingSender struct {
+	*notification.MemorySender
+}
+
+func (sender failingSender) SendSMS(ctx context.Context, to, body string) error {
+	return errors.New("provider unavailable")
+}
+
+func TestNotify(t *testing.T) {
+	recipient := notification.Recipient{
+		Username:    "owner",
+		FullName:    "Owner Name",
+		Email:       "owner@email.com",
+		PhoneNumber: "+628123456789",
+		PushToken:   "device-token",
+		Locale:      "en",
+	}
+	data := &notification.TransferData{
+		TransferID: 7,
+		AccountID:  3,
+		Amount:     2500,
+		Currency:   "USD",
+		Balance:    500,
+		CreatedAt:  time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name          string
+		recipient     func() notification.Recipient
+		preferences   []db.NotificationPreferences
+		failSMS       bool
+		checkResponse func(t *testing.T, channels []string, err error, messages []notification.SentMessage)
+	}{
+		// TODO: every channel is enabled by default
+		{
+			name:      "OK",
+			recipient: func() notification.Recipient { return recipient },
+			checkResponse: func(t *testing.T, channels []string, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Equal(t, notification.Channels, channels)
+				require.Len(t, messages, 3)
+
+				email := messages[0]
+				require.Equal(t, notification.ChannelEmail, email.Channel)
+				require.Equal(t, recipient.Email, email.To)
+				require.Equal(t, "Large debit of 2500 USD", email.Subject)
+				require.Contains(t, email.Body, "Hi Owner Name,")
+				require.Contains(t, email.Body, "2023-05-01 10:30 UTC")
+
+				sms := messages[1]
+				require.Equal(t, notification.ChannelSMS, sms.Channel)
+				require.Equal(t, recipient.PhoneNumber, sms.To)
+				require.Empty(t, sms.Subject)
+				require.Contains(t, sms.Body, "2500 USD was sent from account #3")
+
+				push := messages[2]
+				require.Equal(t, notification.ChannelPush, push.Channel)
+				require.Equal(t, recipient.PushToken, push.To)
+				require.Equal(t, email.Subject, push.Subject)
+				require.Equal(t, sms.Body, push.Body)
+			},
+		},
+		// TODO: a disabled channel and a channel without address are skipped
+		{
+			name: "Preferences",
+			recipient: func() notification.Recipient {
+				r := recipient
+				r.PushToken = ""
+				return r
+			},
+			preferences: []db.NotificationPreferences{
+				{Event: notification.EventLargeDebit, Channel: notification.ChannelEmail, Enabled: false},
+				{Event: notification.EventLargeDebit, Channel: notification.ChannelSMS, Enabled: true},
+				{Event: notification.EventNewDevice, Channel: notification.ChannelSMS, Enabled: false},
+			},
+			checkResponse: func(t *testing.T, channels []string, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Equal(t, []string{notification.ChannelSMS}, channels)
+				require.Len(t, messages, 1)
+			},
+		},
+		// TODO: the alert is written in the locale of the recipient
+		{
+			name: "Locale",
+			recipient: func() notification.Recipient {
+				r := recipient
+				r.Locale = "id-ID"
+				return r
+			},
+			checkResponse: func(t *testing.T, channels []string, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Equal(t, "Debit besar 2500 USD", messages[0].Subject)
+				require.Contains(t, messages[0].Body, "Halo Owner Name,")
+			},
+		},
+		// TODO: a locale without templates falls back to the default locale
+		{
+			name: "UnknownLocale",
+			recipient: func() notification.Recipient {
+				r := recipient
+				r.Locale = "fr"
+				return r
+			},
+			checkResponse: func(t *testing.T, channels []string, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Equal(t, "Large debit of 2500 USD", messages[0].Subject)
+			},
+		},
+		// TODO: a failed channel doesn't stop the others
+		{
+			name:      "ChannelFailed",
+			recipient: func() notification.Recipient { return recipient },
+			failSMS:   true,
+			checkResponse: func(t *testing.T, channels []string, err error, messages []notification.SentMessage) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "sms")
+				require.Equal(t, []string{notification.ChannelEmail, notification.ChannelPush}, channels)
+				require.Len(t, messages, 2)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := notification.NewMemorySender()
+			var sms notification.SMSSender = memory
+			if tt.failSMS {
+				sms = failingSender{memory}
+			}
+
+			notifier, err := notification.NewNotifier(memory, sms, memory)
+			require.NoError(t, err)
+
+			channels, err := notifier.Notify(context.Background(), tt.recipient(),
+				notification.NewPreferences(tt.preferences), notification.EventLargeDebit, data)
+			tt.checkResponse(t, channels, err, memory.Messages())
+		})
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := notification.NewTemplates()
+	require.NoError(t, err)
+
+	recipient := notification.Recipient{Username: "owner", FullName: "Owner Name"}
+	data := map[string]interface{}{
+		notification.EventTransferReceived: &notification.TransferData{CreatedAt: time.Now()},
+		notification.EventLargeDebit:       &notification.TransferData{CreatedAt: time.Now()},
+		notification.EventNewDevice:        &notification.DeviceData{UserAgent: "curl/8.0", ClientIP: "10.0.0.1", LoginAt: time.Now()},
+	}
+
+	// every event is rendered in every locale
+	for _, locale := range []string{"en", "id"} {
+		for _, event := range notification.Events {
+			message, err := templates.Render(locale, event, recipient, data[event])
+			require.NoError(t, err, "%s %s", locale, event)
+			require.NotEmpty(t, message.Subject)
+			require.NotEmpty(t, message.Body)
+			require.NotEmpty(t, message.Short)
+		}
+	}
+
+	_, err = templates.Render("en", "unknown", recipient, nil)
+	require.Error(t, err)
+
+	require.True(t, notification.IsSupportLocale("en"))
+	require.True(t, notification.IsSupportLocale("id_ID"))
+	require.False(t, notification.IsSupportLocale("fr"))
+}
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "notifications")
+	sender, err := notification.NewFileSender(dir)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, sender.SendEmail(ctx, "owner@email.com", "subject", "body"))
+	require.NoError(t, sender.SendEmail(ctx, "other@email.com", "subject", "body"))
+	require.NoError(t, sender.SendSMS(ctx, "+628123456789", "text"))
+
+	file, err := os.Open(filepath.Join(dir, "email.log"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	var messages []notification.SentMessage
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var message notification.SentMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &message))
+		messages = append(messages, message)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, messages, 2)
+	require.Equal(t, "owner@email.com", messages[0].To)
+	require.Equal(t, "subject", messages[0].Subject)
+	require.NotZero(t, messages[0].SentAt)
+
+	_, err = os.Stat(filepath.Join(dir, "sms.log"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "push.log"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// templates/<locale>/<event>.tmpl define the "subject" of an email or push title,
+// the "body" of an email and the "short" text of an SMS or push
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Message is an alert rendered for every channel
+type Message struct {
+	Subject string
+	Body    string
+	Short   string
+}
+
+// Templates are the alert templates per locale and event
+type Templates struct {
+	templates map[string]map[string]*template.Template
+}
+
+func NewTemplates() (*Templates, error) {
+	paths, err := fs.Glob(templateFS, "templates/*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{templates: make(map[string]map[string]*template.Template)}
+	for _, p := range paths {
+		locale := path.Base(path.Dir(p))
+		event := strings.TrimSuffix(path.Base(p), ".tmpl")
+
+		tmpl, err := template.ParseFS(templateFS, p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse template %s: %w", p, err)
+		}
+
+		if t.templates[locale] == nil {
+			t.templates[locale] = make(map[string]*template.Template)
+		}
+		t.templates[locale][event] = tmpl
+	}
+
+	for _, event := range Events {
+		if t.templates[DefaultLocale][event] == nil {
+			return nil, fmt.Errorf("missing %s template of %s", DefaultLocale, event)
+		}
+	}
+	return t, nil
+}
+
+// Render renders an alert in the locale of the recipient, a locale without the
+// template of the event falls back to its language and then to DefaultLocale
+func (t *Templates) Render(locale, event string, recipient Recipient, data interface{}) (Message, error) {
+	tmpl := t.lookup(locale, event)
+	if tmpl == nil {
+		return Message{}, fmt.Errorf("unknown event %s", event)
+	}
+
+	values := struct {
+		Recipient Recipient
+		Data      interface{}
+	}{recipient, data}
+
+	var message Message
+	for _, part := range []struct {
+		name string
+		text *string
+	}{
+		{"subject", &message.Subject},
+		{"body", &message.Body},
+		{"short", &message.Short},
+	} {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, part.name, values); err != nil {
+			return Message{}, fmt.Errorf("cannot render %s of %s: %w", part.name, event, err)
+		}
+		*part.text = strings.TrimSpace(buf.String())
+	}
+	return message, nil
+}
+
+func (t *Templates) lookup(locale, event string) *template.Template {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	language, _, _ := strings.Cut(locale, "-")
+
+	for _, l := range []string{locale, language, DefaultLocale} {
+		if tmpl := t.templates[l][event]; tmpl != nil {
+			return tmpl
+		}
+	}
+	return nil
+}
+
+// IsSupportLocale reports whether alerts are rendered in the locale or its language
+func IsSupportLocale(locale string) bool {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	language, _, _ := strings.Cut(locale, "-")
+
+	entries, err := fs.ReadDir(templateFS, "templates")
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && (entry.Name() == locale || entry.Name() == language) {
+			return true
+		}
+	}
+	return false
+}
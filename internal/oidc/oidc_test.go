@@ -0,0 +1,130 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	"github.com/claytten/golang-simplebank/internal/oidc"
+	"github.com/claytten/golang-simplebank/internal/oidc/oidctest"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8080/api/v1/oidc/callback"
+
+// login follows the redirect of the provider back to us and returns the code
+func login(t *testing.T, provider *oidc.Provider, state, nonce, codeChallenge string) string {
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, codeChallenge)
+	require.NoError(t, err)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, location.Query().Get("state"))
+	return location.Query().Get("code")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewProvider(t)
+	user := oidctest.User{
+		Subject:       util.RandomString(10),
+		Email:         util.RandomEmail(),
+		EmailVerified: true,
+		Name:          util.RandomOwner(),
+	}
+	idp.SetUser(user)
+
+	provider := oidc.NewProvider(idp.Config(redirectURL), nil)
+
+	state, err := oidc.RandomValue()
+	require.NoError(t, err)
+	nonce, err := oidc.RandomValue()
+	require.NoError(t, err)
+	codeVerifier, err := oidc.RandomValue()
+	require.NoError(t, err)
+
+	code := login(t, provider, state, nonce, oidc.CodeChallenge(codeVerifier))
+
+	// the code is bound to the verifier
+	_, err = provider.Exchange(context.Background(), code, util.RandomString(43))
+	require.Error(t, err)
+
+	code = login(t, provider, state, nonce, oidc.CodeChallenge(codeVerifier))
+	rawIDToken, err := provider.Exchange(context.Background(), code, codeVerifier)
+	require.NoError(t, err)
+
+	// and redeemed once
+	_, err = provider.Exchange(context.Background(), code, codeVerifier)
+	require.Error(t, err)
+
+	claims, err := provider.Verify(context.Background(), rawIDToken, nonce)
+	require.NoError(t, err)
+	require.Equal(t, idp.Issuer(), claims.Issuer)
+	require.Equal(t, user.Subject, claims.Subject)
+	require.Equal(t, user.Email, claims.Email)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, user.Name, claims.Name)
+
+	_, err = provider.Verify(context.Background(), rawIDToken, "another nonce")
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestVerify(t *testing.T) {
+	idp := oidctest.NewProvider(t)
+	provider := oidc.NewProvider(idp.Config(redirectURL), nil)
+	user := oidctest.User{Subject: util.RandomString(10), Email: util.RandomEmail()}
+	nonce := util.RandomString(10)
+
+	// the audience may be a list
+	claims := idp.Claims(user, nonce)
+	claims["aud"] = []string{"another-client", oidctest.ClientID}
+	_, err := provider.Verify(context.Background(), idp.SignIDToken(t, claims), nonce)
+	require.NoError(t, err)
+
+	claims = idp.Claims(user, nonce)
+	claims["aud"] = "another-client"
+	_, err = provider.Verify(context.Background(), idp.SignIDToken(t, claims), nonce)
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	claims = idp.Claims(user, nonce)
+	claims["iss"] = "https://evil.example.com"
+	_, err = provider.Verify(context.Background(), idp.SignIDToken(t, claims), nonce)
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	claims = idp.Claims(user, nonce)
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	_, err = provider.Verify(context.Background(), idp.SignIDToken(t, claims), nonce)
+	require.ErrorIs(t, err, token.ErrExpiredToken)
+
+	// signed by someone else
+	other := oidctest.NewProvider(t)
+	_, err = provider.Verify(context.Background(), other.SignIDToken(t, idp.Claims(user, nonce)), nonce)
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	// not signed at all
+	_, err = provider.Verify(context.Background(), "eyJhbGciOiJub25lIn0.eyJzdWIiOiIxIn0.", nonce)
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewProvider(t)
+	config := idp.Config(redirectURL)
+	config.Issuer += "/"
+
+	provider := oidc.NewProvider(config, nil)
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+	require.Error(t, err)
+}
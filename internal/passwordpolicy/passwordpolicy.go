@@ -0,0 +1,137 @@
+package passwordpolicy
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/claytten/golang-simplebank/internal/util"
+)
+
+// ErrReused is returned when a new password matches the current or a previous one
+var ErrReused = errors.New("must not reuse a recent password")
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = parseCommonPasswords(commonPasswordList)
+
+// Policy decides which passwords users may choose. A password needs from
+// MinLength to MaxLength characters of at least MinCharacterClasses classes
+// out of lowercase, uppercase, digits and symbols, it can't be a common
+// password, the current one or one of the HistorySize previous ones.
+// A zero value disables its rule.
+type Policy struct {
+	MinLength           int
+	MaxLength           int
+	MinCharacterClasses int
+	HistorySize         int
+}
+
+// NewPolicy creates the policy configured with the PASSWORD_* variables
+func NewPolicy(config util.Config) Policy {
+	return Policy{
+		MinLength:           config.PasswordMinLength,
+		MaxLength:           config.PasswordMaxLength,
+		MinCharacterClasses: config.PasswordMinCharacterClasses,
+		HistorySize:         config.PasswordHistorySize,
+	}
+}
+
+// Validate checks a new password against every rule that doesn't need the old passwords
+func (policy Policy) Validate(password string) error {
+	n := utf8.RuneCountInString(password)
+	if n < policy.MinLength || (policy.MaxLength > 0 && n > policy.MaxLength) {
+		if policy.MaxLength > 0 {
+			return fmt.Errorf("must contain from %d-%d characters", policy.MinLength, policy.MaxLength)
+		}
+		return fmt.Errorf("must contain at least %d characters", policy.MinLength)
+	}
+
+	if classes := characterClasses(password); classes < policy.MinCharacterClasses {
+		return fmt.Errorf("must contain at least %d of lowercase letters, uppercase letters, digits or symbols", policy.MinCharacterClasses)
+	}
+
+	if IsCommon(password) {
+		return fmt.Errorf("is too common")
+	}
+	return nil
+}
+
+// ValidateLogin checks a password entered to log in. Only its length is bounded,
+// the passwords chosen under an older policy are still accepted.
+func (policy Policy) ValidateLogin(password string) error {
+	if password == "" {
+		return fmt.Errorf("must not be empty")
+	}
+
+	if policy.MaxLength > 0 && utf8.RuneCountInString(password) > policy.MaxLength {
+		return fmt.Errorf("must contain at most %d characters", policy.MaxLength)
+	}
+	return nil
+}
+
+// CheckReuse returns ErrReused when password matches one of the hashed passwords,
+// only the current hash and the HistorySize previous ones are compared
+func (policy Policy) CheckReuse(password string, hashedPasswords []string) error {
+	if len(hashedPasswords) > policy.HistorySize+1 {
+		hashedPasswords = hashedPasswords[:policy.HistorySize+1]
+	}
+
+	for _, hashedPassword := range hashedPasswords {
+		err := util.ComparePassword(hashedPassword, password)
+		if err == nil {
+			return ErrReused
+		}
+		if !errors.Is(err, util.ErrMismatchedPassword) {
+			return fmt.Errorf("cannot compare password: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsCommon reports whether password is in the bundled list of common passwords
+func IsCommon(password string) bool {
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+// characterClasses counts the kinds of characters used in password
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			classes++
+		}
+	}
+	return classes
+}
+
+func parseCommonPasswords(list string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return passwords
+}
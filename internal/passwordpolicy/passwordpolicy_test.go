@@ -0,0 +1,100 @@
+package passwordpolicy_test
+
+import (
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/passwordpolicy"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidate(t *testing.T) {
+	policy := passwordpolicy.Policy{
+		MinLength:           8,
+		MaxLength:           20,
+		MinCharacterClasses: 3,
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		isValid  bool
+	}{
+		// TODO: valid password
+		{name: "valid", password: "Tr0ub4dor", isValid: true},
+		// TODO: symbols and non ascii letters count as classes
+		{name: "valid symbols", password: "Grüne wiese!", isValid: true},
+		// TODO: too short
+		{name: "too short", password: "Ab1!", isValid: false},
+		// TODO: too long
+		{name: "too long", password: "Abcdefghij1234567890!", isValid: false},
+		// TODO: length is counted in characters
+		{name: "multibyte length", password: "Ää1Ää1Ää", isValid: true},
+		// TODO: not enough character classes
+		{name: "two classes", password: "abcdefgh123", isValid: false},
+		// TODO: common password in another case
+		{name: "common", password: "Password123", isValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password)
+			if tt.isValid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	// a zero policy still rejects common passwords
+	require.NoError(t, passwordpolicy.Policy{}.Validate("x"))
+	require.Error(t, passwordpolicy.Policy{}.Validate("qwerty"))
+}
+
+func TestValidateLogin(t *testing.T) {
+	policy := passwordpolicy.Policy{MinLength: 8, MaxLength: 20}
+
+	// a password chosen under an older policy still logs in
+	require.NoError(t, policy.ValidateLogin("secret"))
+	require.NoError(t, policy.ValidateLogin("qwerty"))
+	require.Error(t, policy.ValidateLogin(""))
+	require.Error(t, policy.ValidateLogin("Abcdefghij1234567890!"))
+	require.NoError(t, passwordpolicy.Policy{}.ValidateLogin("Abcdefghij1234567890!"))
+}
+
+func TestCheckReuse(t *testing.T) {
+	policy := passwordpolicy.Policy{HistorySize: 1}
+
+	current, err := util.HashingPassword("current")
+	require.NoError(t, err)
+
+	// the previous password still uses bcrypt
+	previous, err := bcrypt.GenerateFromPassword([]byte("previous"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	older, err := util.HashingPassword("older")
+	require.NoError(t, err)
+
+	hashedPasswords := []string{current, string(previous), older}
+
+	require.ErrorIs(t, policy.CheckReuse("current", hashedPasswords), passwordpolicy.ErrReused)
+	require.ErrorIs(t, policy.CheckReuse("previous", hashedPasswords), passwordpolicy.ErrReused)
+	require.NoError(t, policy.CheckReuse("brand new", hashedPasswords))
+
+	// passwords beyond the history size may be used again
+	require.NoError(t, policy.CheckReuse("older", hashedPasswords))
+
+	// the current password is never reused
+	require.ErrorIs(t, passwordpolicy.Policy{}.CheckReuse("current", hashedPasswords), passwordpolicy.ErrReused)
+
+	require.Error(t, policy.CheckReuse("current", []string{"broken"}))
+}
+
+func TestIsCommon(t *testing.T) {
+	require.True(t, passwordpolicy.IsCommon("123456"))
+	require.True(t, passwordpolicy.IsCommon("LetMeIn"))
+	require.False(t, passwordpolicy.IsCommon(""))
+	require.False(t, passwordpolicy.IsCommon("# most common leaked passwords, one per line, compared case-insensitively"))
+	require.False(t, passwordpolicy.IsCommon(util.RandomString(16)))
+}
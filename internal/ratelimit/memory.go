@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// purgeInterval is how often the full buckets are removed
+const purgeInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     Limit
+}
+
+// MemoryStore keeps the buckets in process. Every server instance limits
+// on its own, it is only correct when a single instance is running.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*memoryBucket
+	purgedAt time.Time
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		buckets:  make(map[string]*memoryBucket),
+		purgedAt: time.Now(),
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, float64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.Sub(store.purgedAt) >= purgeInterval {
+		store.purge(now)
+	}
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Requests), updatedAt: now}
+		store.buckets[key] = bucket
+	}
+
+	bucket.tokens = fill(bucket.tokens, bucket.updatedAt, limit, now)
+	bucket.updatedAt = now
+	bucket.limit = limit
+
+	if bucket.tokens < 1 {
+		return false, bucket.tokens, nil
+	}
+	bucket.tokens--
+	return true, bucket.tokens, nil
+}
+
+// purge removes the buckets that are full again, a missing bucket is full.
+// Must be called with mu held.
+func (store *MemoryStore) purge(now time.Time) {
+	for key, bucket := range store.buckets {
+		if now.Sub(bucket.updatedAt) >= bucket.limit.Per {
+			delete(store.buckets, key)
+		}
+	}
+	store.purgedAt = now
+}
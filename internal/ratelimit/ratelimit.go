@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBucket = "default"
+
+// Limit lets Requests through every Per, as a token bucket holding Requests
+// tokens refilled at Requests/Per. A zero Limit doesn't limit.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses a limit written as requests/duration, such as 100/1m
+func ParseLimit(value string) (Limit, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/duration", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid requests in rate limit %q", value)
+	}
+
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid duration in rate limit %q", value)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (limit Limit) unlimited() bool {
+	return limit.Requests <= 0 || limit.Per <= 0
+}
+
+// rate is the number of tokens added every second
+func (limit Limit) rate() float64 {
+	return float64(limit.Requests) / limit.Per.Seconds()
+}
+
+// Limits are the limit of every method. A method without its own limit shares
+// the default bucket of the client with the other methods without one.
+type Limits struct {
+	Default Limit
+	Methods map[string]Limit
+}
+
+// ParseLimits parses the default limit and the method limits written as method=limit,
+// such as LoginUser=10/1m. A method is an RPC name, a full gRPC method or an HTTP path.
+func ParseLimits(defaultLimit string, methods []string) (Limits, error) {
+	limits := Limits{Methods: make(map[string]Limit)}
+
+	if strings.TrimSpace(defaultLimit) != "" {
+		limit, err := ParseLimit(defaultLimit)
+		if err != nil {
+			return Limits{}, err
+		}
+		limits.Default = limit
+	}
+
+	for _, method := range methods {
+		if strings.TrimSpace(method) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(method, "=")
+		if !ok {
+			return Limits{}, fmt.Errorf("invalid method rate limit %q, expected method=requests/duration", method)
+		}
+
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return Limits{}, err
+		}
+		limits.Methods[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}
+
+// lookup returns the bucket and the limit of a method. A full gRPC method
+// is looked up as it is, then by its RPC name.
+func (limits Limits) lookup(method string) (string, Limit) {
+	if limit, ok := limits.Methods[method]; ok {
+		return method, limit
+	}
+
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		if limit, ok := limits.Methods[method[i+1:]]; ok {
+			return method, limit
+		}
+	}
+	return defaultBucket, limits.Default
+}
+
+// Store keeps the token buckets.
+type Store interface {
+	// Take takes a token from the bucket of key, refilled according to limit
+	// until now, and returns whether there was one and the tokens left.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, tokens float64, err error)
+}
+
+// Result tells whether a request may go on and the state of its bucket
+type Result struct {
+	Allowed bool
+	// Limit is the number of requests of the bucket, 0 when the method isn't limited
+	Limit int
+	// Remaining requests that may be made right away
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long to wait before the next request when it isn't allowed
+	RetryAfter time.Duration
+}
+
+// Limiter limits the requests of every client to every method.
+type Limiter struct {
+	store  Store
+	limits Limits
+}
+
+func NewLimiter(store Store, limits Limits) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// Allow takes a token for a request of client to method. The client is a key such as
+// the one returned by UserKey, APIKeyKey or IPKey.
+func (limiter *Limiter) Allow(ctx context.Context, client, method string) (Result, error) {
+	bucket, limit := limiter.limits.lookup(method)
+	if limit.unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	allowed, tokens, err := limiter.store.Take(ctx, client+":"+bucket, limit, time.Now())
+	if err != nil {
+		return Result{}, err
+	}
+
+	rate := limit.rate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result, nil
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// fill adds the tokens of the time elapsed since updatedAt to a bucket
+// holding tokens, up to its size
+func fill(tokens float64, updatedAt time.Time, limit Limit, now time.Time) float64 {
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed <= 0 {
+		return tokens
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed*limit.rate())
+}
+
+// UserKey is the client key of a user authenticated with an access token
+func UserKey(username string) string {
+	return "user:" + username
+}
+
+// APIKeyKey is the client key of a request authenticated with an API key,
+// every key of a user has its own buckets
+func APIKeyKey(id int64) string {
+	return "apikey:" + strconv.FormatInt(id, 10)
+}
+
+// ConnectionKey is the client key of every request of a client IP, checked before the
+// request is authenticated. The port is dropped.
+func ConnectionKey(clientIP string) string {
+	return "conn:" + strings.TrimPrefix(IPKey(clientIP), "ip:")
+}
+
+// IPKey is the client key of an anonymous request. The port is dropped,
+// a direct gRPC client gets a new one on every connection.
+func IPKey(clientIP string) string {
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	return "ip:" + clientIP
+}
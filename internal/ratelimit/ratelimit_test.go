@@ -0,0 +1,158 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/claytten/golang-simplebank/internal/ratelimit"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimits(t *testing.T) {
+	testCases := []struct {
+		name         string
+		defaultLimit string
+		methods      []string
+		check        func(t *testing.T, limits ratelimit.Limits, err error)
+	}{
+		// TODO: default and method limits
+		{
+			name:         "OK",
+			defaultLimit: "300/1m",
+			methods:      []string{"LoginUser=10/1m", " /api/v1/oidc/login = 20/30s", ""},
+			check: func(t *testing.T, limits ratelimit.Limits, err error) {
+				require.NoError(t, err)
+				require.Equal(t, ratelimit.Limit{Requests: 300, Per: time.Minute}, limits.Default)
+				require.Equal(t, ratelimit.Limit{Requests: 10, Per: time.Minute}, limits.Methods["LoginUser"])
+				require.Equal(t, ratelimit.Limit{Requests: 20, Per: 30 * time.Second}, limits.Methods["/api/v1/oidc/login"])
+			},
+		},
+		// TODO: no limit
+		{
+			name:         "Empty",
+			defaultLimit: "",
+			check: func(t *testing.T, limits ratelimit.Limits, err error) {
+				require.NoError(t, err)
+				require.Zero(t, limits.Default)
+				require.Empty(t, limits.Methods)
+			},
+		},
+		// TODO: invalid default limit
+		{
+			name:         "InvalidDefault",
+			defaultLimit: "300",
+			check: func(t *testing.T, limits ratelimit.Limits, err error) {
+				require.Error(t, err)
+			},
+		},
+		// TODO: invalid duration
+		{
+			name:         "InvalidDuration",
+			defaultLimit: "300/minute",
+			check: func(t *testing.T, limits ratelimit.Limits, err error) {
+				require.Error(t, err)
+			},
+		},
+		// TODO: method without limit
+		{
+			name:         "InvalidMethod",
+			defaultLimit: "300/1m",
+			methods:      []string{"LoginUser"},
+			check: func(t *testing.T, limits ratelimit.Limits, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			limits, err := ratelimit.ParseLimits(tc.defaultLimit, tc.methods)
+			tc.check(t, limits, err)
+		})
+	}
+}
+
+var limits = ratelimit.Limits{
+	Default: ratelimit.Limit{Requests: 3, Per: 300 * time.Millisecond},
+	Methods: map[string]ratelimit.Limit{
+		"LoginUser": {Requests: 1, Per: time.Minute},
+	},
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	testLimiter(t, ratelimit.NewMemoryStore())
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	testLimiter(t, ratelimit.NewRedisStore(client))
+}
+
+func testLimiter(t *testing.T, store ratelimit.Store) {
+	ctx := context.Background()
+	limiter := ratelimit.NewLimiter(store, limits)
+	client := ratelimit.UserKey(util.RandomOwner())
+
+	// the bucket starts full
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, client, "/pb.Simplebank/GetAccount")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	// the methods without a limit share the default bucket
+	result, err := limiter.Allow(ctx, client, "/pb.Simplebank/ListAccounts")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.InDelta(t, 100*time.Millisecond, result.RetryAfter, float64(20*time.Millisecond))
+	require.InDelta(t, 300*time.Millisecond, result.Reset, float64(20*time.Millisecond))
+
+	// a method with a limit has its own bucket
+	result, err = limiter.Allow(ctx, client, "/pb.Simplebank/LoginUser")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Limit)
+
+	result, err = limiter.Allow(ctx, client, "/pb.Simplebank/LoginUser")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	// another client has its own buckets
+	result, err = limiter.Allow(ctx, ratelimit.IPKey("10.0.0.1:51000"), "/pb.Simplebank/GetAccount")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// a token is added every 100ms
+	time.Sleep(result.RetryAfter + 110*time.Millisecond)
+	result, err = limiter.Allow(ctx, client, "/pb.Simplebank/GetAccount")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limits{})
+
+	for i := 0; i < 10; i++ {
+		result, err := limiter.Allow(context.Background(), ratelimit.IPKey("10.0.0.1"), "/pb.Simplebank/GetAccount")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Zero(t, result.Limit)
+	}
+}
+
+func TestIPKey(t *testing.T) {
+	require.Equal(t, "ip:10.0.0.1", ratelimit.IPKey("10.0.0.1:51000"))
+	require.Equal(t, "ip:10.0.0.1", ratelimit.IPKey("10.0.0.1"))
+	require.Equal(t, "ip:::1", ratelimit.IPKey("[::1]:51000"))
+}
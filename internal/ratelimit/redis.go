@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisKeyPrefix = "rate_limit:"
+
+// takeScript refills and takes from the bucket in one step, so the instances
+// sharing the bucket never both take its last token. The bucket expires once
+// it is full again, a missing bucket is full.
+var takeScript = redis.NewScript(`
+local size = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated_at")
+local tokens = tonumber(bucket[1])
+local updatedAt = tonumber(bucket[2])
+if tokens == nil or updatedAt == nil then
+	tokens = size
+	updatedAt = now
+end
+
+local elapsed = now - updatedAt
+if elapsed > 0 then
+	tokens = math.min(size, tokens + elapsed * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated_at", tostring(now))
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in Redis so every server instance sharing
+// the same Redis limits the same clients. The instances are expected to
+// have their clocks in sync, the buckets are refilled with their time.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) Store {
+	return &RedisStore{client: client}
+}
+
+func (store *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, float64, error) {
+	ratePerMs := limit.rate() / 1000
+	values, err := takeScript.Run(ctx, store.client, []string{redisKeyPrefix + key},
+		limit.Requests,
+		strconv.FormatFloat(ratePerMs, 'f', -1, 64),
+		now.UnixMilli(),
+		limit.Per.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	if len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[1]), 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("invalid tokens in rate limit script result: %w", err)
+	}
+	return allowed == 1, tokens, nil
+}
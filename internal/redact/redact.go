@@ -0,0 +1,142 @@
+package redact
+
+import (
+	"encoding/json"
+
+	"github.com/claytten/golang-simplebank/pb"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Mask replaces the value of a sensitive field
+const Mask = "[REDACTED]"
+
+// fields are the names of the fields annotated with (pb.sensitive), both the proto
+// and the JSON name. They mask the JSON payloads, such as the tasks, that have no
+// descriptor, the payloads name their fields like the protos.
+var fields = sensitiveFields()
+
+func sensitiveFields() map[string]bool {
+	names := make(map[string]bool)
+	protoregistry.GlobalFiles.RangeFilesByPackage("pb", func(file protoreflect.FileDescriptor) bool {
+		rangeMessages(file.Messages(), func(message protoreflect.MessageDescriptor) {
+			messageFields := message.Fields()
+			for i := 0; i < messageFields.Len(); i++ {
+				field := messageFields.Get(i)
+				if IsSensitive(field) {
+					names[string(field.Name())] = true
+					names[field.JSONName()] = true
+				}
+			}
+		})
+		return true
+	})
+	return names
+}
+
+func rangeMessages(messages protoreflect.MessageDescriptors, f func(protoreflect.MessageDescriptor)) {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		f(message)
+		rangeMessages(message.Messages(), f)
+	}
+}
+
+// IsSensitive reports whether the field is annotated with (pb.sensitive)
+func IsSensitive(field protoreflect.FieldDescriptor) bool {
+	sensitive, _ := proto.GetExtension(field.Options(), pb.E_Sensitive).(bool)
+	return sensitive
+}
+
+// IsSensitiveName reports whether a JSON key names a sensitive field
+func IsSensitiveName(name string) bool {
+	return fields[name]
+}
+
+// Message returns a copy of m with the sensitive fields masked, m is left untouched.
+// A string is replaced by Mask, any other value is cleared.
+func Message(m proto.Message) proto.Message {
+	if m == nil {
+		return nil
+	}
+
+	redacted := proto.Clone(m)
+	redactMessage(redacted.ProtoReflect())
+	return redacted
+}
+
+func redactMessage(message protoreflect.Message) {
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case IsSensitive(field):
+			if field.Kind() == protoreflect.StringKind && !field.IsList() && !field.IsMap() {
+				message.Set(field, protoreflect.ValueOfString(Mask))
+			} else {
+				message.Clear(field)
+			}
+		case field.IsMap():
+			if field.MapValue().Message() != nil {
+				value.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redactMessage(v.Message())
+					return true
+				})
+			}
+		case field.IsList():
+			if field.Message() != nil {
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					redactMessage(list.Get(i).Message())
+				}
+			}
+		case field.Message() != nil:
+			redactMessage(value.Message())
+		}
+		return true
+	})
+}
+
+// MessageJSON returns the JSON of m with the sensitive fields masked, to be logged
+func MessageJSON(m proto.Message) []byte {
+	data, err := protojson.Marshal(Message(m))
+	if err != nil {
+		log.Error().Err(err).Msg("cannot marshal redacted message")
+		return []byte(`"` + Mask + `"`)
+	}
+	return data
+}
+
+// JSON masks the sensitive fields of a JSON payload, at any depth.
+// A payload that isn't JSON is masked whole, it can't be told apart.
+func JSON(data []byte) []byte {
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return []byte(`"` + Mask + `"`)
+	}
+
+	redacted, err := json.Marshal(redactValue(payload))
+	if err != nil {
+		return []byte(`"` + Mask + `"`)
+	}
+	return redacted
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if IsSensitiveName(key) {
+				value[key] = Mask
+				continue
+			}
+			value[key] = redactValue(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = redactValue(v)
+		}
+	}
+	return value
+}
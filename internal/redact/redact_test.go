@@ -0,0 +1,120 @@
+package redact_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/redact"
+	"github.com/claytten/golang-simplebank/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMessage(t *testing.T) {
+	res := &pb.LoginUserResponse{
+		User: &pb.User{
+			Username: "alice",
+			Email:    "alice@email.com",
+		},
+		SessionId:    "f3f5e1c8-0b9a-4f0e-a6a4-5b6d7c8e9f00",
+		AccessToken:  "v2.local.access",
+		RefreshToken: "v2.local.refresh",
+	}
+
+	redacted := redact.Message(res).(*pb.LoginUserResponse)
+	require.Equal(t, redact.Mask, redacted.GetAccessToken())
+	require.Equal(t, redact.Mask, redacted.GetRefreshToken())
+	require.Equal(t, redact.Mask, redacted.GetUser().GetEmail())
+	require.Equal(t, "alice", redacted.GetUser().GetUsername())
+	require.Equal(t, res.GetSessionId(), redacted.GetSessionId())
+
+	// the message itself is still sent to the caller
+	require.Equal(t, "v2.local.access", res.GetAccessToken())
+	require.Equal(t, "alice@email.com", res.GetUser().GetEmail())
+}
+
+func TestMessageRepeated(t *testing.T) {
+	res := &pb.ListUsersResponse{
+		Users: []*pb.User{
+			{Username: "alice", Email: "alice@email.com"},
+			{Username: "bob", Email: "bob@email.com"},
+		},
+	}
+
+	redacted := redact.Message(res).(*pb.ListUsersResponse)
+	for _, user := range redacted.GetUsers() {
+		require.Equal(t, redact.Mask, user.GetEmail())
+		require.NotEmpty(t, user.GetUsername())
+	}
+}
+
+func TestMessageOptional(t *testing.T) {
+	// an unset optional field stays unset
+	req := &pb.UpdateProfileRequest{Username: "alice", FullName: proto.String("Alice")}
+
+	redacted := redact.Message(req).(*pb.UpdateProfileRequest)
+	require.Nil(t, redacted.Email)
+	require.Equal(t, "Alice", redacted.GetFullName())
+
+	req.Email = proto.String("alice@email.com")
+	redacted = redact.Message(req).(*pb.UpdateProfileRequest)
+	require.Equal(t, redact.Mask, redacted.GetEmail())
+}
+
+func TestMessageJSON(t *testing.T) {
+	data := redact.MessageJSON(&pb.LoginUserRequest{Email: "alice@email.com", Password: "secret123"})
+	require.NotContains(t, string(data), "alice@email.com")
+	require.NotContains(t, string(data), "secret123")
+	require.True(t, json.Valid(data))
+}
+
+func TestJSON(t *testing.T) {
+	testCases := []struct {
+		name    string
+		payload string
+		check   func(t *testing.T, redacted []byte)
+	}{
+		// TODO: sensitive field
+		{
+			name:    "Sensitive",
+			payload: `{"email":"alice@email.com","client_ip":"10.0.0.1"}`,
+			check: func(t *testing.T, redacted []byte) {
+				require.JSONEq(t, `{"email":"[REDACTED]","client_ip":"10.0.0.1"}`, string(redacted))
+			},
+		},
+		// TODO: nested sensitive field
+		{
+			name:    "Nested",
+			payload: `{"username":"alice","device":{"push_token":"abc"},"users":[{"email":"bob@email.com"}]}`,
+			check: func(t *testing.T, redacted []byte) {
+				require.JSONEq(t,
+					`{"username":"alice","device":{"push_token":"[REDACTED]"},"users":[{"email":"[REDACTED]"}]}`,
+					string(redacted))
+			},
+		},
+		// TODO: JSON name of the field
+		{
+			name:    "JSONName",
+			payload: `{"accessToken":"v2.local.access"}`,
+			check: func(t *testing.T, redacted []byte) {
+				require.JSONEq(t, `{"accessToken":"[REDACTED]"}`, string(redacted))
+			},
+		},
+		// TODO: not JSON
+		{
+			name:    "Invalid",
+			payload: `email=alice@email.com`,
+			check: func(t *testing.T, redacted []byte) {
+				require.Equal(t, `"[REDACTED]"`, string(redacted))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, redact.JSON([]byte(tc.payload)))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryCache is an in-process revocation cache. It is only correct when a
+// single instance of the server is running.
+type MemoryCache struct {
+	mu      sync.Mutex
+	revoked map[uuid.UUID]time.Time
+}
+
+func NewMemoryCache() Cache {
+	return &MemoryCache{revoked: make(map[uuid.UUID]time.Time)}
+}
+
+func (cache *MemoryCache) Revoke(ctx context.Context, sessionID uuid.UUID, ttl time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+	cache.purge(now)
+
+	expiresAt := now.Add(ttl)
+	if current, ok := cache.revoked[sessionID]; !ok || current.Before(expiresAt) {
+		cache.revoked[sessionID] = expiresAt
+	}
+	return nil
+}
+
+func (cache *MemoryCache) IsRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	expiresAt, ok := cache.revoked[sessionID]
+	if !ok {
+		return false, nil
+	}
+
+	if time.Now().After(expiresAt) {
+		delete(cache.revoked, sessionID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// purge removes the entries whose ttl has passed, must be called with mu held
+func (cache *MemoryCache) purge(now time.Time) {
+	for sessionID, expiresAt := range cache.revoked {
+		if now.After(expiresAt) {
+			delete(cache.revoked, sessionID)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const redisKeyPrefix = "revoked_session:"
+
+// revokeScript sets the revocation unless it already outlives ttl, so a short
+// revocation never shortens a longer one written by another instance.
+var revokeScript = redis.NewScript(`
+local ttl = tonumber(ARGV[1])
+local current = redis.call("PTTL", KEYS[1])
+if current == -1 or current >= ttl then
+	return 0
+end
+
+redis.call("SET", KEYS[1], 1, "PX", ttl)
+return 1
+`)
+
+// RedisCache stores revoked sessions in Redis so every server instance
+// sharing the same Redis sees a revocation immediately.
+type RedisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) Cache {
+	return &RedisCache{client: client}
+}
+
+func (cache *RedisCache) Revoke(ctx context.Context, sessionID uuid.UUID, ttl time.Duration) error {
+	return revokeScript.Run(ctx, cache.client, []string{redisKeyPrefix + sessionID.String()}, ttl.Milliseconds()).Err()
+}
+
+func (cache *RedisCache) IsRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	n, err := cache.client.Exists(ctx, redisKeyPrefix+sessionID.String()).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,21 @@
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cache keeps track of login sessions that were blocked, logged out or
+// invalidated by a password change, so their access tokens can be rejected
+// before they expire on their own.
+type Cache interface {
+	// Revoke marks the session as revoked for ttl. The ttl should be at least
+	// the lifetime of an access token; after that every token of the session
+	// has expired anyway.
+	Revoke(ctx context.Context, sessionID uuid.UUID, ttl time.Duration) error
+
+	// IsRevoked reports whether the session has been revoked.
+	IsRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error)
+}
@@ -0,0 +1,79 @@
+package revocation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	testCache(t, revocation.NewMemoryCache(), func(d time.Duration) { time.Sleep(d) })
+}
+
+func TestRedisCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	testCache(t, revocation.NewRedisCache(client), mr.FastForward)
+}
+
+// testCache is the contract every Cache has to keep, wait lets the ttls of the
+// cache pass
+func testCache(t *testing.T, cache revocation.Cache, wait func(d time.Duration)) {
+	ctx := context.Background()
+	sessionID := uuid.New()
+	otherSessionID := uuid.New()
+
+	revoked, err := cache.IsRevoked(ctx, sessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = cache.Revoke(ctx, sessionID, 50*time.Millisecond)
+	require.NoError(t, err)
+
+	revoked, err = cache.IsRevoked(ctx, sessionID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = cache.IsRevoked(ctx, otherSessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// revocation is forgotten once the ttl passes
+	wait(100 * time.Millisecond)
+	revoked, err = cache.IsRevoked(ctx, sessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// a shorter revocation never shortens a longer one
+	err = cache.Revoke(ctx, otherSessionID, 200*time.Millisecond)
+	require.NoError(t, err)
+
+	err = cache.Revoke(ctx, otherSessionID, 50*time.Millisecond)
+	require.NoError(t, err)
+
+	wait(100 * time.Millisecond)
+	revoked, err = cache.IsRevoked(ctx, otherSessionID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// a longer revocation extends a shorter one
+	err = cache.Revoke(ctx, otherSessionID, 300*time.Millisecond)
+	require.NoError(t, err)
+
+	wait(200 * time.Millisecond)
+	revoked, err = cache.IsRevoked(ctx, otherSessionID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	wait(200 * time.Millisecond)
+	revoked, err = cache.IsRevoked(ctx, otherSessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+)
+
+// AccountService holds the rules of the accounts, the gRPC and the REST handlers
+// only bind the request and answer with what it returns
+type AccountService struct {
+	store db.Store
+}
+
+func NewAccountService(store db.Store) *AccountService {
+	return &AccountService{store: store}
+}
+
+// Get finds an account whoever owns it, a missing one is ErrAccountNotFound
+func (s *AccountService) Get(ctx context.Context, id int64) (db.Accounts, error) {
+	account, err := s.store.GetAccount(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, accountNotFound(id, err)
+		}
+		return account, domain.Internal("cannot find account", err)
+	}
+	return account, nil
+}
+
+// GetOwned finds an account of owner, only admins look at the accounts of other users
+func (s *AccountService) GetOwned(ctx context.Context, owner string, id int64) (db.Accounts, error) {
+	account, err := s.Get(ctx, id)
+	if err != nil {
+		return account, err
+	}
+
+	if account.Owner != owner {
+		return db.Accounts{}, domain.ErrAccountNotOwned.WithMetadata("account_id", strconv.FormatInt(id, 10))
+	}
+	return account, nil
+}
+
+type CreateAccountParams struct {
+	Owner    string
+	Currency string
+	Audit    db.AuditContext
+}
+
+// Create opens an empty account, a user has one account per currency
+func (s *AccountService) Create(ctx context.Context, arg CreateAccountParams) (db.Accounts, error) {
+	var account db.Accounts
+	err := s.store.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		account, err = q.CreateAccount(ctx, db.CreateAccountParams{
+			Owner:    arg.Owner,
+			Currency: arg.Currency,
+			Balance:  0,
+		})
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if err = writeAccountEvent(ctx, q, webhook.EventAccountCreated, account); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "account.create",
+			Target:       fmt.Sprintf("account:%d", account.ID),
+			After:        account,
+		}, nil
+	})
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation:
+			return db.Accounts{}, domain.ErrAccountAlreadyExists.WithMetadata("currency", arg.Currency).Wrap(err)
+		case db.ForeignKeyViolation:
+			return db.Accounts{}, domain.ErrUserNotFound.Wrap(err)
+		}
+		return db.Accounts{}, domain.Internal("cannot create account", err)
+	}
+	return account, nil
+}
+
+type UpdateAccountParams struct {
+	Owner   string
+	ID      int64
+	Balance int64
+	Audit   db.AuditContext
+}
+
+// Update sets the balance of an account of owner, a frozen account is left as it is
+// even when it is frozen while the balance is being set
+func (s *AccountService) Update(ctx context.Context, arg UpdateAccountParams) (db.Accounts, error) {
+	before, err := s.GetOwned(ctx, arg.Owner, arg.ID)
+	if err != nil {
+		return db.Accounts{}, err
+	}
+
+	if before.IsFrozen {
+		return db.Accounts{}, accountFrozen(arg.ID, nil)
+	}
+
+	var account db.Accounts
+	err = s.store.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		account, err = q.UpdateAccount(ctx, db.UpdateAccountParams{
+			ID:        arg.ID,
+			Balance:   arg.Balance,
+			UpdatedAt: time.Now(),
+		})
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.AuditEventParams{}, accountFrozen(arg.ID, err)
+		}
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if err = writeAccountEvent(ctx, q, webhook.EventAccountUpdated, account); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if err = q.NotifyAccountActivity(ctx, account.ID); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "account.update",
+			Target:       fmt.Sprintf("account:%d", account.ID),
+			Before:       before,
+			After:        account,
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrAccountFrozen) {
+			return db.Accounts{}, domain.From(err)
+		}
+		return db.Accounts{}, domain.Internal("cannot update balance account", err)
+	}
+	return account, nil
+}
+
+type DeleteAccountParams struct {
+	Owner string
+	ID    int64
+	Audit db.AuditContext
+}
+
+// Delete closes an account of owner, a frozen account is kept until it is investigated
+// even when it is frozen while it is being deleted
+func (s *AccountService) Delete(ctx context.Context, arg DeleteAccountParams) error {
+	account, err := s.GetOwned(ctx, arg.Owner, arg.ID)
+	if err != nil {
+		return err
+	}
+
+	if account.IsFrozen {
+		return accountFrozen(account.ID, nil)
+	}
+
+	err = s.store.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		deleted, err := q.DeleteAccount(ctx, account.ID)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.AuditEventParams{}, accountFrozen(account.ID, err)
+		}
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+		account = deleted
+
+		if err = writeAccountEvent(ctx, q, webhook.EventAccountDeleted, account); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "account.delete",
+			Target:       fmt.Sprintf("account:%d", account.ID),
+			Before:       account,
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrAccountFrozen) {
+			return domain.From(err)
+		}
+		return domain.Internal("cannot delete account", err)
+	}
+	return nil
+}
+
+// Freeze stops any transfer from or to an account, only admins freeze accounts
+func (s *AccountService) Freeze(ctx context.Context, id int64, audit db.AuditContext) (db.Accounts, error) {
+	var account db.Accounts
+	err := s.store.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		before, err := q.GetAccount(ctx, id)
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		account, err = q.FreezeAccount(ctx, db.FreezeAccountParams{
+			ID:        id,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if err = writeAccountEvent(ctx, q, webhook.EventAccountFrozen, account); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if err = q.NotifyAccountActivity(ctx, account.ID); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: audit,
+			Action:       "account.freeze",
+			Target:       fmt.Sprintf("account:%d", account.ID),
+			Before:       before,
+			After:        account,
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Accounts{}, accountNotFound(id, err)
+		}
+		return db.Accounts{}, domain.Internal("cannot freeze account", err)
+	}
+	return account, nil
+}
+
+// writeAccountEvent writes the webhook event of an account change in the transaction making it
+func writeAccountEvent(ctx context.Context, q db.Querier, eventType string, account db.Accounts) error {
+	event, err := webhook.NewAccountEvent(eventType, account)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.WriteOutboxEvent(ctx, q, event)
+	return err
+}
+
+// accountFrozen is the error of a change refused on a frozen account, err is nil
+// unless the database refused it
+func accountFrozen(id int64, err error) *domain.Error {
+	return domain.ErrAccountFrozen.WithMetadata("account_id", strconv.FormatInt(id, 10)).Wrap(err)
+}
+
+func accountNotFound(id int64, err error) *domain.Error {
+	return domain.ErrAccountNotFound.WithMetadata("account_id", strconv.FormatInt(id, 10)).Wrap(err)
+}
@@ -0,0 +1,464 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"testing"
+
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// expectAuditTx runs the transaction of an audited change on the mock store,
+// the change has to be recorded as action by actor
+func expectAuditTx(store *mockdb.MockStore, action, actor string) {
+	store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+			event, err := fn(store)
+			if err != nil {
+				return err
+			}
+			if event.Action != action || event.Actor != actor {
+				return fmt.Errorf("%s audited %s instead of %s by %s", event.Actor, event.Action, action, actor)
+			}
+			return nil
+		})
+}
+
+func TestGetOwnedAccount(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	account := util.RandomAccount(user.Username)
+
+	tests := []struct {
+		name       string
+		owner      string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name:  "OK",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: account of another user
+		{
+			name:  "NotOwned",
+			owner: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotOwned)
+			},
+		},
+
+		// TODO: account not found
+		{
+			name:  "NotFound",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Accounts{}, sql.ErrNoRows)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotFound)
+				require.Equal(t, fmt.Sprint(account.ID), domain.From(err).Metadata["account_id"])
+			},
+		},
+
+		// TODO: query error
+		{
+			name:  "Internal",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Accounts{}, sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInternal)
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			got, err := service.NewAccountService(store).GetOwned(context.Background(), tc.owner, account.ID)
+			tc.checkError(t, err)
+			if err == nil {
+				require.Equal(t, account, got)
+			}
+		})
+	}
+}
+
+func TestCreateAccount(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	account := util.RandomAccount(user.Username)
+	account.Balance = 0
+
+	tests := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{
+					Owner:    user.Username,
+					Currency: account.Currency,
+					Balance:  0,
+				}
+
+				expectAuditTx(store, "account.create", user.Username)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: an account in the same currency
+		{
+			name: "AlreadyExists",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuditTx(store, "account.create", user.Username)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Accounts{}, &pq.Error{Code: "23505"})
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountAlreadyExists)
+			},
+		},
+
+		// TODO: owner not found
+		{
+			name: "UserNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuditTx(store, "account.create", user.Username)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Accounts{}, &pq.Error{Code: "23503"})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrUserNotFound)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			got, err := service.NewAccountService(store).Create(context.Background(), service.CreateAccountParams{
+				Owner:    user.Username,
+				Currency: account.Currency,
+				Audit:    db.AuditContext{Actor: user.Username},
+			})
+			tc.checkError(t, err)
+			if err == nil {
+				require.Equal(t, account, got)
+			}
+		})
+	}
+}
+
+func TestUpdateAccount(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	account := util.RandomAccount(user.Username)
+	balance := util.RandomMoney()
+
+	updatedAccount := account
+	updatedAccount.Balance = balance
+
+	tests := []struct {
+		name       string
+		owner      string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name:  "OK",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectAuditTx(store, "account.update", user.Username)
+				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(1).Return(updatedAccount, nil)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().NotifyAccountActivity(gomock.Any(), gomock.Eq(account.ID)).Times(1)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: account of another user
+		{
+			name:  "NotOwned",
+			owner: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().AuditTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotOwned)
+			},
+		},
+
+		// TODO: frozen account
+		{
+			name:  "Frozen",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := account
+				frozenAccount.IsFrozen = true
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozenAccount, nil)
+				store.EXPECT().AuditTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountFrozen)
+			},
+		},
+
+		// TODO: account frozen after it was checked, the update finds no row
+		{
+			name:  "FrozenConcurrently",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					AuditTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, fn func(q db.Querier) (db.AuditEventParams, error)) error {
+						_, err := fn(store)
+						return err
+					})
+				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Accounts{}, sql.ErrNoRows)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountFrozen)
+				require.Equal(t, strconv.FormatInt(account.ID, 10), domain.From(err).Metadata["account_id"])
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			got, err := service.NewAccountService(store).Update(context.Background(), service.UpdateAccountParams{
+				Owner:   tc.owner,
+				ID:      account.ID,
+				Balance: balance,
+				Audit:   db.AuditContext{Actor: tc.owner},
+			})
+			tc.checkError(t, err)
+			if err == nil {
+				require.Equal(t, updatedAccount, got)
+			}
+		})
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	user, _ := util.RandomUser(t)
+	account := util.RandomAccount(user.Username)
+
+	tests := []struct {
+		name       string
+		owner      string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name:  "OK",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectAuditTx(store, "account.delete", user.Username)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: account of another user
+		{
+			name:  "NotOwned",
+			owner: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotOwned)
+			},
+		},
+
+		// TODO: frozen account
+		{
+			name:  "Frozen",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := account
+				frozenAccount.IsFrozen = true
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozenAccount, nil)
+				store.EXPECT().AuditTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountFrozen)
+			},
+		},
+
+		// TODO: account frozen after it was read
+		{
+			name:  "FrozenConcurrently",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectAuditTx(store, "account.delete", user.Username)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Accounts{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountFrozen)
+			},
+		},
+
+		// TODO: query error
+		{
+			name:  "Internal",
+			owner: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectAuditTx(store, "account.delete", user.Username)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Accounts{}, sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInternal)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			err := service.NewAccountService(store).Delete(context.Background(), service.DeleteAccountParams{
+				Owner: tc.owner,
+				ID:    account.ID,
+				Audit: db.AuditContext{Actor: tc.owner},
+			})
+			tc.checkError(t, err)
+		})
+	}
+}
+
+func TestFreezeAccount(t *testing.T) {
+	admin, _ := util.RandomUser(t)
+	account := util.RandomAccount(util.RandomOwner())
+
+	frozenAccount := account
+	frozenAccount.IsFrozen = true
+
+	tests := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuditTx(store, "account.freeze", admin.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(1).Return(frozenAccount, nil)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().NotifyAccountActivity(gomock.Any(), gomock.Eq(account.ID)).Times(1)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: account not found
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuditTx(store, "account.freeze", admin.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Accounts{}, sql.ErrNoRows)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotFound)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			got, err := service.NewAccountService(store).Freeze(context.Background(), account.ID, db.AuditContext{Actor: admin.Username})
+			tc.checkError(t, err)
+			if err == nil {
+				require.True(t, got.IsFrozen)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/google/uuid"
+)
+
+// ClientInfo is the device a user logs in from, as the transport sees it
+type ClientInfo struct {
+	UserAgent string
+	ClientIP  string
+}
+
+type LoginParams struct {
+	Email    string
+	Password string
+	Client   ClientInfo
+}
+
+// LoginResult is the session of a user who just logged in and its tokens
+type LoginResult struct {
+	User           db.Users
+	Session        db.Sessions
+	AccessToken    string
+	AccessPayload  *token.Payload
+	RefreshToken   string
+	RefreshPayload *token.Payload
+}
+
+// Login checks the password of a user and creates their session. The failed logins
+// of an email and of a client IP are throttled, an owner is emailed when the failures
+// lock their account.
+func (s *UserService) Login(ctx context.Context, arg LoginParams) (LoginResult, error) {
+	attempt, retryAfter, err := s.loginGuard.Begin(ctx, arg.Email, arg.Client.ClientIP)
+	if err != nil {
+		return LoginResult{}, domain.Internal("cannot reserve login attempt", err)
+	}
+
+	if retryAfter > 0 {
+		return LoginResult{}, domain.ErrTooManyLogins.WithRetryAfter(retryAfter)
+	}
+
+	user, err := s.Authenticate(ctx, arg.Email, arg.Password)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			// only an existing owner is emailed about the lockout
+			notify := !errors.Is(err, domain.ErrUserNotFound)
+			if failErr := s.failLogin(ctx, attempt, arg.Email, arg.Client.ClientIP, notify); failErr != nil {
+				return LoginResult{}, failErr
+			}
+		} else if cancelErr := s.loginGuard.Cancel(ctx, attempt); cancelErr != nil {
+			return LoginResult{}, domain.Internal("cannot cancel login attempt", cancelErr)
+		}
+		return LoginResult{}, err
+	}
+
+	if err = s.loginGuard.Succeed(ctx, attempt); err != nil {
+		return LoginResult{}, domain.Internal("cannot reset login attempts", err)
+	}
+
+	return s.CreateSession(ctx, user, arg.Client)
+}
+
+type ReauthenticateParams struct {
+	User      db.Users
+	SessionID uuid.UUID
+	Password  string
+	Client    ClientInfo
+}
+
+// Reauthenticate checks the password of a logged in user again and issues a short-lived
+// token for the same session. The failures are throttled and lock the account like
+// the failed logins, a stolen session cannot be used to guess the password.
+func (s *UserService) Reauthenticate(ctx context.Context, arg ReauthenticateParams) (string, *token.Payload, error) {
+	attempt, retryAfter, err := s.loginGuard.Begin(ctx, arg.User.Email, arg.Client.ClientIP)
+	if err != nil {
+		return "", nil, domain.Internal("cannot reserve login attempt", err)
+	}
+
+	if retryAfter > 0 {
+		return "", nil, domain.ErrTooManyLogins.WithRetryAfter(retryAfter)
+	}
+
+	if err = s.policy.ValidateLogin(arg.Password); err != nil {
+		if cancelErr := s.loginGuard.Cancel(ctx, attempt); cancelErr != nil {
+			return "", nil, domain.Internal("cannot cancel login attempt", cancelErr)
+		}
+		return "", nil, passwordViolation("password", err)
+	}
+
+	if err = util.ComparePassword(arg.User.HashedPassword, arg.Password); err != nil {
+		if failErr := s.failLogin(ctx, attempt, arg.User.Email, arg.Client.ClientIP, true); failErr != nil {
+			return "", nil, failErr
+		}
+		return "", nil, domain.ErrIncorrectPassword.Wrap(err)
+	}
+
+	if err = s.loginGuard.Succeed(ctx, attempt); err != nil {
+		return "", nil, domain.Internal("cannot reset login attempts", err)
+	}
+
+	elevatedToken, elevatedPayload, err := s.token.CreateScopedToken(
+		arg.User.Email,
+		arg.User.Role,
+		arg.SessionID,
+		token.ScopeSensitive,
+		s.elevatedTokenDuration,
+	)
+	if err != nil {
+		return "", nil, domain.Internal("cannot create elevated token", err)
+	}
+	return elevatedToken, elevatedPayload, nil
+}
+
+// failLogin keeps the attempt as a failed login, unknown emails are throttled too
+// but only an existing owner is emailed when the failure locks the account
+func (s *UserService) failLogin(ctx context.Context, attempt *loginguard.Attempt, email, clientIP string, notify bool) error {
+	result := s.loginGuard.Fail(attempt)
+	if result.Locked && notify {
+		lockoutEmail, err := worker.NewTaskEvent(worker.TaskSendLockoutEmail, &worker.PayloadSendLockoutEmail{
+			Email:       email,
+			ClientIP:    clientIP,
+			LockedUntil: time.Now().Add(result.RetryAfter),
+		})
+		if err != nil {
+			return domain.Internal("cannot create lockout email", err)
+		}
+
+		if _, err = db.WriteOutboxEvent(ctx, s.store, lockoutEmail); err != nil {
+			return domain.Internal("cannot send lockout email", err)
+		}
+	}
+
+	return nil
+}
+
+// CreateSession creates the session of a user who just logged in and issues its tokens,
+// whether the user entered the password or came back from the identity provider
+func (s *UserService) CreateSession(ctx context.Context, user db.Users, client ClientInfo) (LoginResult, error) {
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return LoginResult{}, domain.Internal("cannot create session id", err)
+	}
+
+	accessToken, accessPayload, err := s.token.CreateToken(user.Email, user.Role, sessionID, s.accessTokenDuration)
+	if err != nil {
+		return LoginResult{}, domain.Internal("cannot create access token", err)
+	}
+
+	refreshToken, refreshPayload, err := s.token.CreateRefreshToken(user.Email, user.Role, sessionID, s.refreshTokenDuration)
+	if err != nil {
+		return LoginResult{}, domain.Internal("cannot create refresh token", err)
+	}
+
+	var session db.Sessions
+	err = s.store.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		session, err = q.CreateSession(ctx, db.CreateSessionParams{
+			ID:           sessionID,
+			Email:        user.Email,
+			RefreshToken: refreshToken,
+			UserAgent:    client.UserAgent,
+			ClientIp:     client.ClientIP,
+			IsBlocked:    false,
+			ExpiresAt:    refreshPayload.ExpiredAt,
+			CreatedAt:    time.Now(),
+		})
+		if err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		if err = recordLoginDevice(ctx, q, user, client); err != nil {
+			return db.AuditEventParams{}, err
+		}
+
+		return db.AuditEventParams{
+			AuditContext: db.AuditContext{
+				Actor:     user.Username,
+				ClientIP:  client.ClientIP,
+				UserAgent: client.UserAgent,
+			},
+			Action: "session.create",
+			Target: "session:" + sessionID.String(),
+			After:  db.NewAuditSession(session),
+		}, nil
+	})
+	if err != nil {
+		return LoginResult{}, domain.Internal("cannot create session", err)
+	}
+
+	return LoginResult{
+		User:           user,
+		Session:        session,
+		AccessToken:    accessToken,
+		AccessPayload:  accessPayload,
+		RefreshToken:   refreshToken,
+		RefreshPayload: refreshPayload,
+	}, nil
+}
+
+// recordLoginDevice remembers the user agent a user logged in with, the user is
+// alerted of a new device unless it is the first one they ever logged in from
+func recordLoginDevice(ctx context.Context, q db.Querier, user db.Users, client ClientInfo) error {
+	device, err := q.CreateUserDevice(ctx, db.CreateUserDeviceParams{
+		Username:  user.Username,
+		UserAgent: client.UserAgent,
+		ClientIp:  client.ClientIP,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		_, err = q.TouchUserDevice(ctx, db.TouchUserDeviceParams{
+			Username:  user.Username,
+			UserAgent: client.UserAgent,
+			ClientIp:  client.ClientIP,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	devices, err := q.CountUserDevices(ctx, user.Username)
+	if err != nil || devices == 1 {
+		return err
+	}
+
+	alert, err := worker.NewDeviceNotification(device)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.WriteOutboxEvent(ctx, q, alert)
+	return err
+}
@@ -0,0 +1,272 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// expectCreateSession creates the session of user from a device it logged in from before
+func expectCreateSession(store *mockdb.MockStore, user db.Users) {
+	expectAuditTx(store, "session.create", user.Username)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.CreateSessionParams) (db.Sessions, error) {
+			return db.Sessions{ID: arg.ID, Email: arg.Email, ClientIp: arg.ClientIp, ExpiresAt: arg.ExpiresAt}, nil
+		})
+	store.EXPECT().CreateUserDevice(gomock.Any(), gomock.Any()).Times(1).Return(db.UserDevices{}, sql.ErrNoRows)
+	store.EXPECT().TouchUserDevice(gomock.Any(), gomock.Any()).Times(1).Return(db.UserDevices{}, nil)
+}
+
+func TestLogin(t *testing.T) {
+	user, password := util.RandomUser(t)
+	client := service.ClientInfo{UserAgent: "test-agent", ClientIP: "203.0.113.7"}
+
+	// the first failure locks the email
+	policy := loginguard.Policy{
+		MaxAttempts:     1,
+		LockoutAttempts: 1,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		passwords  []string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, result service.LoginResult, err error)
+	}{
+		// TODO: OK, the session is created with the tokens
+		{
+			name:      "OK",
+			passwords: []string{password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				expectCreateSession(store, user)
+			},
+			checkError: func(t *testing.T, result service.LoginResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, result.User.Username)
+				require.Equal(t, client.ClientIP, result.Session.ClientIp)
+				require.NotEmpty(t, result.AccessToken)
+				require.NotEmpty(t, result.RefreshToken)
+				require.Equal(t, result.Session.ID, result.AccessPayload.SessionID)
+			},
+		},
+
+		// TODO: the failure locking the account emails its owner
+		{
+			name:      "WrongPasswordLocks",
+			passwords: []string{util.RandomString(8)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateOutboxEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.CreateOutboxEventParams) (db.Outbox, error) {
+						require.Equal(t, worker.TaskSendLockoutEmail, arg.Topic)
+						return db.Outbox{}, nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, result service.LoginResult, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidCredentials)
+			},
+		},
+
+		// TODO: an unknown email is throttled without any email
+		{
+			name:      "UnknownEmail",
+			passwords: []string{password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.Users{}, sql.ErrNoRows)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, result service.LoginResult, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidCredentials)
+			},
+		},
+
+		// TODO: a locked email is not checked, even with the right password
+		{
+			name:      "TooManyLogins",
+			passwords: []string{util.RandomString(8), password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1).Return(db.Outbox{}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, result service.LoginResult, err error) {
+				require.ErrorIs(t, err, domain.ErrTooManyLogins)
+				require.Positive(t, domain.From(err).RetryAfter)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			loginGuard := loginguard.NewGuard(loginguard.NewMemoryStore(), policy, loginguard.Policy{})
+			userService := newTestUserService(t, store, revocation.NewMemoryCache(), loginGuard)
+
+			var (
+				result service.LoginResult
+				err    error
+			)
+			for _, password := range tc.passwords {
+				result, err = userService.Login(context.Background(), service.LoginParams{
+					Email:    user.Email,
+					Password: password,
+					Client:   client,
+				})
+			}
+			tc.checkError(t, result, err)
+		})
+	}
+}
+
+func TestCreateSessionNewDevice(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	user, _ := util.RandomUser(t)
+	client := service.ClientInfo{UserAgent: "new-agent", ClientIP: "203.0.113.7"}
+
+	store := mockdb.NewMockStore(controller)
+	expectAuditTx(store, "session.create", user.Username)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.CreateSessionParams) (db.Sessions, error) {
+			return db.Sessions{ID: arg.ID, Email: arg.Email}, nil
+		})
+	store.EXPECT().
+		CreateUserDevice(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.UserDevices{Username: user.Username, UserAgent: client.UserAgent, ClientIp: client.ClientIP}, nil)
+	store.EXPECT().CountUserDevices(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(int64(2), nil)
+	store.EXPECT().
+		CreateOutboxEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.CreateOutboxEventParams) (db.Outbox, error) {
+			require.Equal(t, worker.TaskSendNotification, arg.Topic)
+			return db.Outbox{}, nil
+		})
+
+	_, err := newTestUserService(t, store, revocation.NewMemoryCache(), nil).CreateSession(context.Background(), user, client)
+	require.NoError(t, err)
+}
+
+func TestReauthenticate(t *testing.T) {
+	user, password := util.RandomUser(t)
+	client := service.ClientInfo{UserAgent: "test-agent", ClientIP: "203.0.113.7"}
+
+	// the first failure locks the email
+	policy := loginguard.Policy{
+		MaxAttempts:     1,
+		LockoutAttempts: 1,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		passwords  []string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, elevatedToken string, err error)
+	}{
+		// TODO: OK, the elevated token is issued
+		{
+			name:       "OK",
+			passwords:  []string{password},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkError: func(t *testing.T, elevatedToken string, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, elevatedToken)
+			},
+		},
+
+		// TODO: the failure locking the account emails its owner like a failed login
+		{
+			name:      "IncorrectPassword",
+			passwords: []string{util.RandomString(8)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOutboxEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.CreateOutboxEventParams) (db.Outbox, error) {
+						require.Equal(t, worker.TaskSendLockoutEmail, arg.Topic)
+						return db.Outbox{}, nil
+					})
+			},
+			checkError: func(t *testing.T, elevatedToken string, err error) {
+				require.ErrorIs(t, err, domain.ErrIncorrectPassword)
+			},
+		},
+
+		// TODO: a locked email is refused even with the right password
+		{
+			name:      "TooManyLogins",
+			passwords: []string{util.RandomString(8), password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOutboxEvent(gomock.Any(), gomock.Any()).Times(1).Return(db.Outbox{}, nil)
+			},
+			checkError: func(t *testing.T, elevatedToken string, err error) {
+				require.ErrorIs(t, err, domain.ErrTooManyLogins)
+				require.Positive(t, domain.From(err).RetryAfter)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			loginGuard := loginguard.NewGuard(loginguard.NewMemoryStore(), policy, loginguard.Policy{})
+			userService := newTestUserService(t, store, revocation.NewMemoryCache(), loginGuard)
+
+			var (
+				elevatedToken string
+				err           error
+			)
+			for _, password := range tc.passwords {
+				elevatedToken, _, err = userService.Reauthenticate(context.Background(), service.ReauthenticateParams{
+					User:      user,
+					SessionID: uuid.New(),
+					Password:  password,
+					Client:    client,
+				})
+			}
+			tc.checkError(t, elevatedToken, err)
+		})
+	}
+}
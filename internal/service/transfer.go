@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/metrics"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+	"github.com/claytten/golang-simplebank/internal/worker"
+)
+
+// TransferService moves money between two accounts, the gRPC and the REST
+// API check a transfer the same way
+type TransferService struct {
+	store    db.Store
+	accounts *AccountService
+}
+
+func NewTransferService(store db.Store) *TransferService {
+	return &TransferService{
+		store:    store,
+		accounts: NewAccountService(store),
+	}
+}
+
+type TransferParams struct {
+	// Owner is the user sending the money, the account it is sent from is theirs
+	Owner         string
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64
+	Currency      string
+	Audit         db.AuditContext
+}
+
+// Transfer sends the amount once both accounts are found, in the currency of the
+// transfer and not frozen. Each account is looked up once.
+func (s *TransferService) Transfer(ctx context.Context, arg TransferParams) (db.TransferTxResult, error) {
+	if arg.FromAccountID == arg.ToAccountID {
+		return db.TransferTxResult{}, domain.InvalidArgument(domain.FieldViolation{
+			Field:       "to_account_id",
+			Description: "must be another account than from_account_id",
+		})
+	}
+
+	fromAccount, err := s.accounts.GetOwned(ctx, arg.Owner, arg.FromAccountID)
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	toAccount, err := s.accounts.Get(ctx, arg.ToAccountID)
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	for _, account := range []db.Accounts{fromAccount, toAccount} {
+		if err = checkTransferable(account, arg.Currency); err != nil {
+			return db.TransferTxResult{}, err
+		}
+	}
+
+	result, err := s.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Audit:         arg.Audit,
+		Events:        transferEvents,
+	})
+	if err != nil {
+		// frozen after it was checked
+		var frozen *db.FrozenAccountError
+		if errors.As(err, &frozen) {
+			return db.TransferTxResult{}, accountFrozen(frozen.AccountID, err)
+		}
+		return db.TransferTxResult{}, domain.Internal("cannot transfer", err)
+	}
+	metrics.ObserveTransfer(result.FromAccount.Currency, result.Transfer.Amount)
+
+	return result, nil
+}
+
+// transferEvents are the webhook events and the alerts of a transfer
+func transferEvents(result db.TransferTxResult) ([]db.CreateOutboxEventParams, error) {
+	events, err := webhook.TransferEvents(result)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := worker.TransferNotifications(result)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, notifications...), nil
+}
+
+func checkTransferable(account db.Accounts, currency string) error {
+	accountID := strconv.FormatInt(account.ID, 10)
+	if account.Currency != currency {
+		return domain.ErrCurrencyMismatch.WithMetadata("account_id", accountID)
+	}
+
+	if account.IsFrozen {
+		return domain.ErrAccountFrozen.WithMetadata("account_id", accountID)
+	}
+	return nil
+}
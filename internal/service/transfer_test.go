@@ -0,0 +1,236 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfer(t *testing.T) {
+	user1, _ := util.RandomUser(t)
+	user2, _ := util.RandomUser(t)
+
+	acc1 := util.RandomAccount(user1.Username)
+	acc2 := util.RandomAccount(user2.Username)
+	acc3 := util.RandomAccount(user2.Username)
+
+	acc1.Currency = util.USD
+	acc2.Currency = util.USD
+	acc3.Currency = util.CAD
+
+	amount := int64(10)
+
+	tests := []struct {
+		name       string
+		arg        service.TransferParams
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK, each account is looked up once
+		{
+			name: "OK",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+				Audit:         db.AuditContext{Actor: user1.Username},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Times(1).Return(acc1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Times(1).Return(acc2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.Equal(t, acc1.ID, arg.FromAccountID)
+						require.Equal(t, acc2.ID, arg.ToAccountID)
+						require.Equal(t, amount, arg.Amount)
+						require.Equal(t, user1.Username, arg.Audit.Actor)
+						require.NotNil(t, arg.Events)
+
+						return db.TransferTxResult{
+							Transfer:    db.Transfers{FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: amount},
+							FromAccount: acc1,
+							ToAccount:   acc2,
+						}, nil
+					})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: same account on both sides
+		{
+			name: "SameAccount",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc1.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidArgument)
+				require.Len(t, domain.From(err).Violations, 1)
+			},
+		},
+
+		// TODO: from account of another user
+		{
+			name: "FromAccountNotOwned",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc2.ID,
+				ToAccountID:   acc1.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Times(1).Return(acc2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotOwned)
+			},
+		},
+
+		// TODO: to account not found
+		{
+			name: "ToAccountNotFound",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Times(1).Return(acc1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Times(1).Return(db.Accounts{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountNotFound)
+			},
+		},
+
+		// TODO: to account in another currency
+		{
+			name: "CurrencyMismatch",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc3.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Times(1).Return(acc1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc3.ID)).Times(1).Return(acc3, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+			},
+		},
+
+		// TODO: frozen to account
+		{
+			name: "Frozen",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := acc2
+				frozenAccount.IsFrozen = true
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Times(1).Return(acc1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Times(1).Return(frozenAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountFrozen)
+			},
+		},
+
+		// TODO: to account frozen after it was checked
+		{
+			name: "FrozenConcurrently",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Times(1).Return(acc1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Times(1).Return(acc2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.FrozenAccountError{AccountID: acc2.ID})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrAccountFrozen)
+				require.Equal(t, strconv.FormatInt(acc2.ID, 10), domain.From(err).Metadata["account_id"])
+			},
+		},
+
+		// TODO: transaction error
+		{
+			name: "Internal",
+			arg: service.TransferParams{
+				Owner:         user1.Username,
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).Times(1).Return(acc1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).Times(1).Return(acc2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrTxDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInternal)
+				require.ErrorIs(t, err, sql.ErrTxDone)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			_, err := service.NewTransferService(store).Transfer(context.Background(), tc.arg)
+			tc.checkError(t, err)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/passwordpolicy"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/worker"
+)
+
+// UserService signs users up, logs them in, checks their password and changes their profile
+type UserService struct {
+	store      db.Store
+	token      token.Maker
+	revocation revocation.Cache
+	loginGuard *loginguard.Guard
+	policy     passwordpolicy.Policy
+	// how long the access tokens of a session last, a revoked one stays rejected as long
+	accessTokenDuration   time.Duration
+	refreshTokenDuration  time.Duration
+	elevatedTokenDuration time.Duration
+}
+
+func NewUserService(
+	config util.Config,
+	store db.Store,
+	tokenMaker token.Maker,
+	revocationCache revocation.Cache,
+	loginGuard *loginguard.Guard,
+) *UserService {
+	return &UserService{
+		store:                 store,
+		token:                 tokenMaker,
+		revocation:            revocationCache,
+		loginGuard:            loginGuard,
+		policy:                passwordpolicy.NewPolicy(config),
+		accessTokenDuration:   config.AccessTokenDuration,
+		refreshTokenDuration:  config.RefreshTokenDuration,
+		elevatedTokenDuration: config.ElevatedTokenDuration,
+	}
+}
+
+// CheckOwnUser makes sure the request is made for the authenticated user,
+// the password itself is checked once by Reauthenticate
+func CheckOwnUser(user db.Users, username string) error {
+	if username != user.Username {
+		return domain.ErrNotOwnUser
+	}
+	return nil
+}
+
+type CreateUserParams struct {
+	Username string
+	Email    string
+	FullName string
+	Password string
+	Audit    db.AuditContext
+}
+
+// Create signs a user up, the verification email is only sent once the user is committed
+func (s *UserService) Create(ctx context.Context, arg CreateUserParams) (db.Users, error) {
+	if err := s.policy.Validate(arg.Password); err != nil {
+		return db.Users{}, passwordViolation("password", err)
+	}
+
+	hashedPassword, err := util.HashingPassword(arg.Password)
+	if err != nil {
+		return db.Users{}, domain.Internal("cannot hash password", err)
+	}
+
+	verifyEmail, err := worker.NewTaskEvent(worker.TaskSendVerifyEmail, &worker.PayloadSendVerifyEmail{
+		Email: arg.Email,
+	})
+	if err != nil {
+		return db.Users{}, domain.Internal("cannot create verification email", err)
+	}
+
+	result, err := s.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       arg.Username,
+			Email:          arg.Email,
+			FullName:       arg.FullName,
+			HashedPassword: hashedPassword,
+		},
+		Events: []db.CreateOutboxEventParams{verifyEmail},
+		Audit:  arg.Audit,
+	})
+	if err != nil {
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.Users{}, domain.ErrUserAlreadyExists.Wrap(err)
+		}
+		return db.Users{}, domain.Internal("cannot create user", err)
+	}
+	return result.User, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is hashed once with the default hasher and compared with the
+// password of an unknown email, so it is refused as slowly as a wrong password
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = util.HashingPassword("simplebank unknown email")
+	})
+	return dummyHash
+}
+
+// Authenticate returns the user of an email and password. An unknown email and a
+// wrong password are both ErrInvalidCredentials and both compare a password hash,
+// so neither the error nor the timing tells which emails are registered. Only the
+// unknown email also is ErrUserNotFound.
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (db.Users, error) {
+	if err := s.policy.ValidateLogin(password); err != nil {
+		return db.Users{}, passwordViolation("password", err)
+	}
+
+	user, err := s.store.GetUserUsingEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			_ = util.ComparePassword(dummyPasswordHash(), password)
+			return db.Users{}, domain.ErrInvalidCredentials.Wrap(domain.ErrUserNotFound.Wrap(err))
+		}
+		return db.Users{}, domain.Internal("cannot find user", err)
+	}
+
+	if err = util.ComparePassword(user.HashedPassword, password); err != nil {
+		return db.Users{}, domain.ErrInvalidCredentials.Wrap(err)
+	}
+
+	if util.NeedsRehash(user.HashedPassword) {
+		user = s.rehashPassword(ctx, user, password)
+	}
+	return user, nil
+}
+
+// rehashPassword upgrades the hash of a verified password to the default hasher,
+// the login goes on with the old hash when that fails
+func (s *UserService) rehashPassword(ctx context.Context, user db.Users, password string) db.Users {
+	hashedPassword, err := util.HashingPassword(password)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Str("username", user.Username).Msg("cannot rehash password")
+		return user
+	}
+
+	// the hash is only replaced when the password didn't change since it was verified
+	rehashedUser, err := s.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewHashedPassword: hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	if err != nil {
+		if !errors.Is(err, db.ErrRecordNotFound) {
+			logging.Ctx(ctx).Error().Err(err).Str("username", user.Username).Msg("cannot rehash password")
+		}
+		return user
+	}
+	return rehashedUser
+}
+
+// Get finds a user by username, a missing one is ErrUserNotFound
+func (s *UserService) Get(ctx context.Context, username string) (db.Users, error) {
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Users{}, domain.ErrUserNotFound.Wrap(err)
+		}
+		return db.Users{}, domain.Internal("cannot find user", err)
+	}
+	return user, nil
+}
+
+type UpdateProfileParams struct {
+	User     db.Users
+	FullName string
+	Email    string
+	Audit    db.AuditContext
+}
+
+// UpdateProfile changes the full name and the email of a user, an empty one is kept
+func (s *UserService) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (db.Users, error) {
+	fullName := arg.FullName
+	if fullName == "" {
+		fullName = arg.User.FullName
+	}
+
+	email := arg.Email
+	if email == "" {
+		email = arg.User.Email
+	}
+
+	var updatedUser db.Users
+	err := s.store.AuditTx(ctx, func(q db.Querier) (db.AuditEventParams, error) {
+		var err error
+		updatedUser, err = q.UpdateUser(ctx, db.UpdateUserParams{
+			Username: arg.User.Username,
+			FullName: sql.NullString{
+				String: fullName,
+				Valid:  true,
+			},
+			Email: sql.NullString{
+				String: email,
+				Valid:  true,
+			},
+			UpdatedAt: time.Now(),
+		})
+		return db.AuditEventParams{
+			AuditContext: arg.Audit,
+			Action:       "user.update_profile",
+			Target:       "user:" + arg.User.Username,
+			Before:       db.NewAuditUser(arg.User),
+			After:        db.NewAuditUser(updatedUser),
+		}, err
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Users{}, domain.ErrUserNotFound.Wrap(err)
+		}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.Users{}, domain.ErrUserAlreadyExists.Wrap(err)
+		}
+		return db.Users{}, domain.Internal("cannot update user", err)
+	}
+	return updatedUser, nil
+}
+
+type UpdatePasswordParams struct {
+	Username string
+	Password string
+	Audit    db.AuditContext
+}
+
+// UpdatePassword changes the password of a user unless it was used recently.
+// Every session logged in with the old password has to login again.
+func (s *UserService) UpdatePassword(ctx context.Context, arg UpdatePasswordParams) (db.Users, error) {
+	if err := s.policy.Validate(arg.Password); err != nil {
+		return db.Users{}, passwordViolation("new_password", err)
+	}
+
+	hashedPassword, err := util.HashingPassword(arg.Password)
+	if err != nil {
+		return db.Users{}, domain.Internal("cannot hash password", err)
+	}
+
+	result, err := s.store.UpdatePasswordTx(ctx, db.UpdatePasswordTxParams{
+		Username:       arg.Username,
+		HashedPassword: hashedPassword,
+		HistorySize:    int32(s.policy.HistorySize),
+		BeforeUpdate: func(hashedPasswords []string) error {
+			return s.policy.CheckReuse(arg.Password, hashedPasswords)
+		},
+		Audit: arg.Audit,
+	})
+	if err != nil {
+		if errors.Is(err, passwordpolicy.ErrReused) {
+			return db.Users{}, domain.ErrPasswordReused.WithViolations(domain.FieldViolation{
+				Field:       "new_password",
+				Description: err.Error(),
+			}).Wrap(err)
+		}
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Users{}, domain.ErrUserNotFound.Wrap(err)
+		}
+		return db.Users{}, domain.Internal("cannot update password", err)
+	}
+
+	if err = s.RevokeSessions(ctx, result.Sessions...); err != nil {
+		return db.Users{}, err
+	}
+	return result.User, nil
+}
+
+// passwordViolation is the error of a password field the policy rejects
+func passwordViolation(field string, err error) *domain.Error {
+	return domain.InvalidArgument(domain.FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	})
+}
+
+// RevokeSessions rejects the tokens of blocked sessions right away instead of
+// waiting for them to expire, for as long as their refresh tokens live
+func (s *UserService) RevokeSessions(ctx context.Context, sessions ...db.Sessions) error {
+	for _, session := range sessions {
+		err := s.revocation.Revoke(ctx, session.ID, s.refreshTokenDuration)
+		if err != nil {
+			return domain.Internal("cannot revoke session", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,429 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/claytten/golang-simplebank/internal/api/token"
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/domain"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/service"
+	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestUserService doesn't throttle failed logins unless a login guard is given
+func newTestUserService(t *testing.T, store db.Store, revocationCache revocation.Cache, loginGuard *loginguard.Guard) *service.UserService {
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	if loginGuard == nil {
+		loginGuard = loginguard.NewGuard(loginguard.NewMemoryStore(), loginguard.Policy{}, loginguard.Policy{})
+	}
+
+	config := util.Config{
+		AccessTokenDuration:   time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		ElevatedTokenDuration: time.Minute,
+		PasswordMinLength:     6,
+		PasswordMaxLength:     100,
+		PasswordHistorySize:   3,
+	}
+	return service.NewUserService(config, store, tokenMaker, revocationCache, loginGuard)
+}
+
+func TestCheckOwnUser(t *testing.T) {
+	user, _ := util.RandomUser(t)
+
+	require.NoError(t, service.CheckOwnUser(user, user.Username))
+	require.ErrorIs(t, service.CheckOwnUser(user, util.RandomOwner()), domain.ErrNotOwnUser)
+}
+
+func TestAuthenticate(t *testing.T) {
+	user, password := util.RandomUser(t)
+
+	tests := []struct {
+		name       string
+		password   string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK
+		{
+			name:     "OK",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: unknown email
+		{
+			name:     "UserNotFound",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.Users{}, sql.ErrNoRows)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidCredentials)
+				require.ErrorIs(t, err, domain.ErrUserNotFound)
+			},
+		},
+
+		// TODO: wrong password
+		{
+			name:     "WrongPassword",
+			password: util.RandomString(8),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidCredentials)
+				require.NotErrorIs(t, err, domain.ErrUserNotFound)
+			},
+		},
+
+		// TODO: password longer than the policy allows is not hashed
+		{
+			name:     "PasswordTooLong",
+			password: util.RandomString(101),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidArgument)
+				require.Equal(t, "password", domain.From(err).Violations[0].Field)
+			},
+		},
+
+		// TODO: query error
+		{
+			name:     "Internal",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserUsingEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.Users{}, sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInternal)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			_, err := newTestUserService(t, store, revocation.NewMemoryCache(), nil).Authenticate(context.Background(), user.Email, tc.password)
+			tc.checkError(t, err)
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	user, password := util.RandomUser(t)
+
+	tests := []struct {
+		name       string
+		password   string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK, the verification email is written with the user
+		{
+			name:     "OK",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Username, arg.Audit.Actor)
+						require.Len(t, arg.Events, 1)
+						require.NoError(t, util.ComparePassword(arg.HashedPassword, password))
+
+						return db.CreateUserTxResult{User: user}, nil
+					})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: username or email taken
+		{
+			name:     "AlreadyExists",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrUserAlreadyExists)
+			},
+		},
+
+		// TODO: password the policy rejects
+		{
+			name:     "WeakPassword",
+			password: "qwerty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidArgument)
+				require.Equal(t, "password", domain.From(err).Violations[0].Field)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			_, err := newTestUserService(t, store, revocation.NewMemoryCache(), nil).Create(context.Background(), service.CreateUserParams{
+				Username: user.Username,
+				Email:    user.Email,
+				FullName: user.FullName,
+				Password: tc.password,
+				Audit:    db.AuditContext{Actor: user.Username},
+			})
+			tc.checkError(t, err)
+		})
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	user, password := util.RandomUser(t)
+	session := db.Sessions{ID: uuid.New(), Email: user.Email}
+
+	tests := []struct {
+		name        string
+		newPassword string
+		buildStubs  func(store *mockdb.MockStore)
+		checkResult func(t *testing.T, revocationCache revocation.Cache, err error)
+	}{
+		// TODO: OK, the sessions of the old password are revoked
+		{
+			name:        "OK",
+			newPassword: util.RandomString(8),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdatePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.UpdatePasswordTxParams) (db.UpdatePasswordTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, int32(3), arg.HistorySize)
+						if err := arg.BeforeUpdate([]string{user.HashedPassword}); err != nil {
+							return db.UpdatePasswordTxResult{}, err
+						}
+
+						return db.UpdatePasswordTxResult{User: user, Sessions: []db.Sessions{session}}, nil
+					})
+			},
+			checkResult: func(t *testing.T, revocationCache revocation.Cache, err error) {
+				require.NoError(t, err)
+
+				revoked, err := revocationCache.IsRevoked(context.Background(), session.ID)
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+
+		// TODO: password used recently
+		{
+			name:        "Reused",
+			newPassword: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdatePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.UpdatePasswordTxParams) (db.UpdatePasswordTxResult, error) {
+						if err := arg.BeforeUpdate([]string{user.HashedPassword}); err != nil {
+							return db.UpdatePasswordTxResult{}, err
+						}
+						return db.UpdatePasswordTxResult{User: user, Sessions: []db.Sessions{session}}, nil
+					})
+			},
+			checkResult: func(t *testing.T, revocationCache revocation.Cache, err error) {
+				require.ErrorIs(t, err, domain.ErrPasswordReused)
+				require.Len(t, domain.From(err).Violations, 1)
+
+				revoked, err := revocationCache.IsRevoked(context.Background(), session.ID)
+				require.NoError(t, err)
+				require.False(t, revoked)
+			},
+		},
+
+		// TODO: password the policy rejects
+		{
+			name:        "WeakPassword",
+			newPassword: "qwerty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, revocationCache revocation.Cache, err error) {
+				require.ErrorIs(t, err, domain.ErrInvalidArgument)
+				require.Equal(t, "new_password", domain.From(err).Violations[0].Field)
+			},
+		},
+
+		// TODO: user not found
+		{
+			name:        "UserNotFound",
+			newPassword: util.RandomString(8),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdatePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdatePasswordTxResult{}, sql.ErrNoRows)
+			},
+			checkResult: func(t *testing.T, revocationCache revocation.Cache, err error) {
+				require.ErrorIs(t, err, domain.ErrUserNotFound)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			revocationCache := revocation.NewMemoryCache()
+			_, err := newTestUserService(t, store, revocationCache, nil).UpdatePassword(context.Background(), service.UpdatePasswordParams{
+				Username: user.Username,
+				Password: tc.newPassword,
+				Audit:    db.AuditContext{Actor: user.Username},
+			})
+			tc.checkResult(t, revocationCache, err)
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	user, _ := util.RandomUser(t)
+
+	tests := []struct {
+		name       string
+		fullName   string
+		email      string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		// TODO: OK, an empty field keeps the current value
+		{
+			name:     "OK",
+			fullName: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuditTx(store, "user.update_profile", user.Username)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.UpdateUserParams) (db.Users, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEqual(t, user.FullName, arg.FullName.String)
+						require.Equal(t, user.Email, arg.Email.String)
+
+						updatedUser := user
+						updatedUser.FullName = arg.FullName.String
+						return updatedUser, nil
+					})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+
+		// TODO: email taken
+		{
+			name:  "AlreadyExists",
+			email: util.RandomEmail(),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuditTx(store, "user.update_profile", user.Username)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Users{}, &pq.Error{Code: "23505"})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, domain.ErrUserAlreadyExists)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tc.buildStubs(store)
+
+			_, err := newTestUserService(t, store, revocation.NewMemoryCache(), nil).UpdateProfile(context.Background(), service.UpdateProfileParams{
+				User:     user,
+				FullName: tc.fullName,
+				Email:    tc.email,
+				Audit:    db.AuditContext{Actor: user.Username},
+			})
+			tc.checkError(t, err)
+		})
+	}
+}
+
+func TestRevokeSessionsOutlivesAccessToken(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	redisServer := miniredis.RunT(t)
+	revocationCache := revocation.NewRedisCache(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	userService := newTestUserService(t, mockdb.NewMockStore(controller), revocationCache, nil)
+
+	session := db.Sessions{ID: uuid.New()}
+	require.NoError(t, userService.RevokeSessions(context.Background(), session))
+
+	// the refresh token of the logged out session lives on after its access tokens
+	redisServer.FastForward(2 * time.Minute)
+	revoked, err := revocationCache.IsRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	redisServer.FastForward(time.Hour)
+	revoked, err = revocationCache.IsRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
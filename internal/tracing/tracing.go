@@ -0,0 +1,163 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName names the app in the traces
+const ServiceName = "simplebank"
+
+// exporters the spans are sent through
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+type Config struct {
+	// Exporter is one of the exporters above, nothing is traced without one
+	Exporter string
+	// OTLPEndpoint is the host:port of the collector receiving OTLP over gRPC,
+	// the OTEL_EXPORTER_OTLP_* variables configure the rest of the exporter
+	OTLPEndpoint string
+	// File the spans are written to by the file exporter
+	File string
+	// SampleRatio of the traces started by the app, a trace started by
+	// the caller is sampled as the caller decided
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagation.
+// The returned shutdown flushes the spans not exported yet.
+func Setup(ctx context.Context, config Config) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if config.Exporter == "" || config.Exporter == ExporterNone {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	exporter, closeExporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		defer closeExporter()
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch config.Exporter {
+	case ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if config.OTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(config.OTLPEndpoint))
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create OTLP exporter: %w", err)
+		}
+		return exporter, noClose, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create stdout exporter: %w", err)
+		}
+		return exporter, noClose, nil
+	case ExporterFile:
+		file, err := openFile(config.File)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("cannot create file exporter: %w", err)
+		}
+		return exporter, file.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown tracing exporter %s", config.Exporter)
+}
+
+func openFile(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, fmt.Errorf("cannot create trace dir: %w", err)
+	}
+
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open trace file: %w", err)
+	}
+	return file, nil
+}
+
+// Tracer starts the spans of the app, it follows the global tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer("github.com/claytten/golang-simplebank")
+}
+
+// End records err on the span before ending it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx to carry it across a queue
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract continues the trace context carried across a queue
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// TraceQuery starts the span of a database query, done ends it. The span isn't
+// passed to the driver, lib/pq has nothing to propagate it to.
+func TraceQuery(ctx context.Context, name string) (done func(err error)) {
+	_, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(name),
+		),
+	)
+	return func(err error) {
+		End(span, err)
+	}
+}
@@ -0,0 +1,95 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/claytten/golang-simplebank/internal/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+	return recorder
+}
+
+func TestInjectExtract(t *testing.T) {
+	setupRecorder(t)
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "enqueue")
+	defer span.End()
+
+	carrier := tracing.Inject(ctx)
+	require.Contains(t, carrier, "traceparent")
+
+	extracted := trace.SpanContextFromContext(tracing.Extract(context.Background(), carrier))
+	require.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	require.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+	require.True(t, extracted.IsRemote())
+
+	// nothing to continue without a trace context
+	extracted = trace.SpanContextFromContext(tracing.Extract(context.Background(), map[string]string{}))
+	require.False(t, extracted.IsValid())
+}
+
+func TestTraceQuery(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "TransferTx")
+	tracing.TraceQuery(ctx, "CreateTransfer")(nil)
+	tracing.TraceQuery(ctx, "AddAccountBalance")(errors.New("deadlock detected"))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	require.Equal(t, "CreateTransfer", spans[0].Name())
+	require.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	require.Equal(t, "AddAccountBalance", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "deadlock detected", spans[1].Status().Description)
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "log", "traces.json")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    tracing.ExporterFile,
+		File:        file,
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	_, span := tracing.Tracer().Start(context.Background(), "GetAccount")
+	span.End()
+
+	// the spans are flushed on shutdown
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Contains(t, string(content), `"Name":"GetAccount"`)
+	require.Contains(t, string(content), tracing.ServiceName)
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: "zipkin"})
+	require.EqualError(t, err, "unknown tracing exporter zipkin")
+}
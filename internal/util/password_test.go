@@ -13,6 +13,7 @@ func TestHashingPassword(t *testing.T) {
 	hashedPassword, err := util.HashingPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword)
+	require.False(t, util.NeedsRehash(hashedPassword))
 
 	//reversing
 	err = util.ComparePassword(hashedPassword, password)
@@ -21,7 +22,7 @@ func TestHashingPassword(t *testing.T) {
 	//testing error
 	wrongPassword := util.RandomString(9)
 	err = util.ComparePassword(hashedPassword, wrongPassword)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.ErrorIs(t, err, util.ErrMismatchedPassword)
 
 	//testing the result is not same hash if using same raw string
 	hashedPassword2, err := util.HashingPassword(password)
@@ -33,4 +34,38 @@ func TestHashingPassword(t *testing.T) {
 	_, err = util.HashingPassword("")
 	require.Nil(t, err)
 
+	//testing a broken hash
+	err = util.ComparePassword("$argon2id$v=19$m=1,t=1,p=1$!$!", password)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, util.ErrMismatchedPassword)
+}
+
+func TestComparePasswordBcrypt(t *testing.T) {
+	password := util.RandomString(10)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// accounts created before argon2id keep logging in
+	err = util.ComparePassword(string(hashedPassword), password)
+	require.NoError(t, err)
+	require.True(t, util.NeedsRehash(string(hashedPassword)))
+
+	err = util.ComparePassword(string(hashedPassword), util.RandomString(9))
+	require.ErrorIs(t, err, util.ErrMismatchedPassword)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	params := util.DefaultArgon2idParams
+	defer func() { util.DefaultArgon2idParams = params }()
+
+	hashedPassword, err := util.HashingPassword("secret")
+	require.NoError(t, err)
+	require.False(t, util.NeedsRehash(hashedPassword))
+
+	// raising the cost upgrades the hashes made with the old one
+	util.DefaultArgon2idParams.Iterations++
+	require.True(t, util.NeedsRehash(hashedPassword))
+	require.NoError(t, util.ComparePassword(hashedPassword, "secret"))
+
+	require.True(t, util.NeedsRehash(""))
 }
@@ -74,6 +74,7 @@ func RandomUser(t *testing.T) (user db.Users, password string) {
 		HashedPassword: hashedPassword,
 		FullName:       RandomOwner(),
 		Email:          RandomEmail(),
+		Role:           DepositorRole,
 	}
 	return
 }
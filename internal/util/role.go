@@ -0,0 +1,14 @@
+package util
+
+const (
+	DepositorRole = "depositor"
+	AdminRole     = "admin"
+)
+
+func IsSupportRole(role string) bool {
+	switch role {
+	case DepositorRole, AdminRole:
+		return true
+	}
+	return false
+}
@@ -42,10 +42,6 @@ func ValidateFullName(value string) error {
 	return nil
 }
 
-func ValidatePassword(value string) error {
-	return ValidateString(value, 5, 100)
-}
-
 func ValidateEmail(value string) error {
 	if err := ValidateString(value, 3, 200); err != nil {
 		return err
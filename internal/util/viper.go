@@ -10,16 +10,65 @@ import (
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variable.
 type Config struct {
-	Environment          string        `mapstructure:"ENVIRONMENT"`
-	DBDriver             string        `mapstructure:"DB_DRIVER"`
-	DBSource             string        `mapstructure:"DB_SOURCE"`
-	MigrationURL         string        `mapstructure:"MIGRATION_URL"`
-	RedisAddress         string        `mapstructure:"REDIS_ADDRESS"`
-	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
-	GrpcServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
-	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	Environment                 string        `mapstructure:"ENVIRONMENT"`
+	DBDriver                    string        `mapstructure:"DB_DRIVER"`
+	DBSource                    string        `mapstructure:"DB_SOURCE"`
+	MigrationURL                string        `mapstructure:"MIGRATION_URL"`
+	RedisAddress                string        `mapstructure:"REDIS_ADDRESS"`
+	HTTPServerAddress           string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	GrpcServerAddress           string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TrustedProxies              []string      `mapstructure:"TRUSTED_PROXIES"`
+	TokenType                   string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey           string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenKeyDir                 string        `mapstructure:"TOKEN_KEY_DIR"`
+	TokenActiveKeyID            string        `mapstructure:"TOKEN_ACTIVE_KEY_ID"`
+	TokenRetiredKeyIDs          []string      `mapstructure:"TOKEN_RETIRED_KEY_IDS"`
+	AccessTokenDuration         time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration        time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	ElevatedTokenDuration       time.Duration `mapstructure:"ELEVATED_TOKEN_DURATION"`
+	JWKSMaxAge                  time.Duration `mapstructure:"JWKS_MAX_AGE"`
+	OIDCIssuer                  string        `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID                string        `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret            string        `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL             string        `mapstructure:"OIDC_REDIRECT_URL"`
+	PasswordMinLength           int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength           int           `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordMinCharacterClasses int           `mapstructure:"PASSWORD_MIN_CHARACTER_CLASSES"`
+	PasswordHistorySize         int           `mapstructure:"PASSWORD_HISTORY_SIZE"`
+	OutboxPollInterval          time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	OutboxBatchSize             int32         `mapstructure:"OUTBOX_BATCH_SIZE"`
+	OutboxPublishTimeout        time.Duration `mapstructure:"OUTBOX_PUBLISH_TIMEOUT"`
+	OutboxRetryDelay            time.Duration `mapstructure:"OUTBOX_RETRY_DELAY"`
+	OutboxMaxRetryDelay         time.Duration `mapstructure:"OUTBOX_MAX_RETRY_DELAY"`
+	OutboxRetention             time.Duration `mapstructure:"OUTBOX_RETENTION"`
+	RevocationCache             string        `mapstructure:"REVOCATION_CACHE"`
+	LoginAttemptStore           string        `mapstructure:"LOGIN_ATTEMPT_STORE"`
+	LoginMaxAttempts            int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginLockoutAttempts        int           `mapstructure:"LOGIN_LOCKOUT_ATTEMPTS"`
+	LoginIPMaxAttempts          int           `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginIPLockoutAttempts      int           `mapstructure:"LOGIN_IP_LOCKOUT_ATTEMPTS"`
+	LoginBackoffDelay           time.Duration `mapstructure:"LOGIN_BACKOFF_DELAY"`
+	LoginLockoutDuration        time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginAttemptWindow          time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	NotificationSender          string        `mapstructure:"NOTIFICATION_SENDER"`
+	NotificationDir             string        `mapstructure:"NOTIFICATION_DIR"`
+	ShutdownTimeout             time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	HealthCheckTimeout          time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	HealthWatchInterval         time.Duration `mapstructure:"HEALTH_WATCH_INTERVAL"`
+	TracingExporter             string        `mapstructure:"TRACING_EXPORTER"`
+	TracingOTLPEndpoint         string        `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingFile                 string        `mapstructure:"TRACING_FILE"`
+	TracingSampleRatio          float64       `mapstructure:"TRACING_SAMPLE_RATIO"`
+	LogFile                     string        `mapstructure:"LOG_FILE"`
+	LogMaxSizeMB                int           `mapstructure:"LOG_MAX_SIZE_MB"`
+	LogMaxBackups               int           `mapstructure:"LOG_MAX_BACKUPS"`
+	LogMaxAge                   time.Duration `mapstructure:"LOG_MAX_AGE"`
+	LogCompress                 bool          `mapstructure:"LOG_COMPRESS"`
+	LogRotateDaily              bool          `mapstructure:"LOG_ROTATE_DAILY"`
+	RateLimitStore              string        `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitDefault            string        `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitMethods            []string      `mapstructure:"RATE_LIMIT_METHODS"`
+	RateLimitIP                 string        `mapstructure:"RATE_LIMIT_IP"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
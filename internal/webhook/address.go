@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// ErrForbiddenAddress is returned for a receiver that isn't on the public internet,
+// a subscription must not reach the services next to the server
+var ErrForbiddenAddress = errors.New("receiver address is not public")
+
+// reservedNetworks are not reachable from the internet, besides the loopback,
+// private, link-local and multicast addresses the net package knows of
+var reservedNetworks = mustParseNetworks(
+	"0.0.0.0/8",       // this network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, and the broadcast address
+	"64:ff9b::/96",    // NAT64, it reaches any IPv4 address
+	"64:ff9b:1::/48",  // local NAT64
+	"2001:db8::/32",   // documentation
+)
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// publicIP tells whether ip is reachable from the internet. The cloud metadata
+// endpoints are link-local or private, so they are not.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// localHostname tells whether a hostname only resolves on the server itself
+func localHostname(hostname string) bool {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	return hostname == "localhost" || strings.HasSuffix(hostname, ".localhost")
+}
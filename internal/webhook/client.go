@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+)
+
+// headers sent with every delivery besides the signature
+const (
+	EventIDHeader    = "Simplebank-Event-Id"
+	EventTypeHeader  = "Simplebank-Event-Type"
+	DeliveryIDHeader = "Simplebank-Delivery-Id"
+)
+
+// DefaultTimeout is how long a receiver has to answer a delivery
+const DefaultTimeout = 10 * time.Second
+
+// Client posts signed deliveries to the receivers
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient only connects to public addresses, and to the addresses of allowedNetworks
+// such as a receiver run next to the server in development. The address is checked
+// once the host is resolved, a host can't resolve to a public address when it is
+// validated and to an internal one when the delivery is posted.
+func NewClient(timeout time.Duration, allowedNetworks ...*net.IPNet) *Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return ErrForbiddenAddress
+			}
+
+			if publicIP(ip) {
+				return nil
+			}
+			for _, network := range allowedNetworks {
+				if network.Contains(ip) {
+					return nil
+				}
+			}
+			return ErrForbiddenAddress
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect to the receiver without the address being checked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			// a redirect would send the payload somewhere the owner didn't subscribe
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// DeliveryError is a delivery that got no response. Its message is shown to the
+// owner of the subscription, it only tells what kind of failure it was so the
+// deliveries can't be used to probe the network of the server.
+type DeliveryError struct {
+	Reason string
+	Err    error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Reason
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+func newDeliveryError(err error) *DeliveryError {
+	var (
+		dnsErr         *net.DNSError
+		netErr         net.Error
+		recordErr      tls.RecordHeaderError
+		unknownAuthErr x509.UnknownAuthorityError
+		certErr        x509.CertificateInvalidError
+		hostnameErr    x509.HostnameError
+	)
+
+	reason := "cannot connect to receiver"
+	switch {
+	case errors.Is(err, ErrForbiddenAddress):
+		reason = ErrForbiddenAddress.Error()
+	case errors.As(err, &dnsErr):
+		reason = "cannot resolve receiver host"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		reason = "receiver timed out"
+	case errors.As(err, &recordErr), errors.As(err, &unknownAuthErr),
+		errors.As(err, &certErr), errors.As(err, &hostnameErr):
+		reason = "tls handshake with receiver failed"
+	}
+	return &DeliveryError{Reason: reason, Err: err}
+}
+
+// Deliver posts a delivery to the url of its subscription. It returns the status code
+// of the response, 0 when there was none, and an error unless the status is 2xx.
+// A delivery without a response returns a *DeliveryError.
+func (client *Client) Deliver(ctx context.Context, subscription db.WebhookSubscriptions, delivery db.WebhookDeliveries) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("cannot create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simplebank-webhooks")
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(DeliveryIDHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, time.Now(), delivery.Payload))
+
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return 0, newDeliveryError(err)
+	}
+	defer res.Body.Close()
+
+	// the body is ignored, reading a bit of it lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded with status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,141 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliver(t *testing.T) {
+	delivery := db.WebhookDeliveries{
+		ID:        7,
+		EventID:   "evt_1",
+		EventType: webhook.EventAccountCreated,
+		Payload:   json.RawMessage(`{"id":"evt_1","type":"account.created"}`),
+	}
+
+	tests := []struct {
+		name          string
+		handler       func(t *testing.T, secret string) http.HandlerFunc
+		checkResponse func(t *testing.T, statusCode int, err error)
+	}{
+		// TODO: the receiver verifies the signed payload
+		{
+			name: "OK",
+			handler: func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					body, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					require.Equal(t, http.MethodPost, r.Method)
+					require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+					require.Equal(t, "evt_1", r.Header.Get(webhook.EventIDHeader))
+					require.Equal(t, webhook.EventAccountCreated, r.Header.Get(webhook.EventTypeHeader))
+					require.Equal(t, "7", r.Header.Get(webhook.DeliveryIDHeader))
+					require.JSONEq(t, string(delivery.Payload), string(body))
+
+					err = webhook.VerifySignature(secret, r.Header.Get(webhook.SignatureHeader), body, 5*time.Minute, time.Now())
+					require.NoError(t, err)
+					w.WriteHeader(http.StatusNoContent)
+				}
+			},
+			checkResponse: func(t *testing.T, statusCode int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusNoContent, statusCode)
+			},
+		},
+		// TODO: an error status is retried
+		{
+			name: "ReceiverError",
+			handler: func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			},
+			checkResponse: func(t *testing.T, statusCode int, err error) {
+				require.Error(t, err)
+				require.Equal(t, http.StatusInternalServerError, statusCode)
+			},
+		},
+		// TODO: a redirect isn't followed
+		{
+			name: "Redirect",
+			handler: func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					http.Redirect(w, r, "https://elsewhere.example.com", http.StatusFound)
+				}
+			},
+			checkResponse: func(t *testing.T, statusCode int, err error) {
+				require.Error(t, err)
+				require.Equal(t, http.StatusFound, statusCode)
+			},
+		},
+		// TODO: a receiver slower than the timeout
+		{
+			name: "Timeout",
+			handler: func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					time.Sleep(200 * time.Millisecond)
+				}
+			},
+			checkResponse: func(t *testing.T, statusCode int, err error) {
+				var deliveryErr *webhook.DeliveryError
+				require.ErrorAs(t, err, &deliveryErr)
+				require.Equal(t, "receiver timed out", err.Error())
+				require.Zero(t, statusCode)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := webhook.GenerateSecret()
+			require.NoError(t, err)
+
+			receiver := httptest.NewServer(tt.handler(t, secret))
+			defer receiver.Close()
+
+			subscription := db.WebhookSubscriptions{ID: 1, Url: receiver.URL, Secret: secret}
+			client := webhook.NewClient(100*time.Millisecond, loopback(t))
+
+			statusCode, err := client.Deliver(context.Background(), subscription, delivery)
+			tt.checkResponse(t, statusCode, err)
+		})
+	}
+}
+
+// loopback lets the client post to the httptest receivers
+func loopback(t *testing.T) *net.IPNet {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	return network
+}
+
+func TestDeliverForbiddenAddress(t *testing.T) {
+	var received bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer receiver.Close()
+
+	_, port, err := net.SplitHostPort(receiver.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// localhost passes for a public hostname until it is resolved
+	subscription := db.WebhookSubscriptions{ID: 1, Url: "http://localhost:" + port, Secret: "whsec_test"}
+	client := webhook.NewClient(100 * time.Millisecond)
+
+	statusCode, err := client.Deliver(context.Background(), subscription, db.WebhookDeliveries{ID: 7})
+	require.ErrorIs(t, err, webhook.ErrForbiddenAddress)
+	require.Equal(t, webhook.ErrForbiddenAddress.Error(), err.Error())
+	require.NotContains(t, err.Error(), port)
+	require.Zero(t, statusCode)
+	require.False(t, received)
+}
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/google/uuid"
+)
+
+// TopicEvent is the outbox topic of webhook events, each event is dispatched
+// to the subscriptions of its owner
+const TopicEvent = "webhook:event"
+
+// event types a subscription can be notified of
+const (
+	EventAccountCreated   = "account.created"
+	EventAccountUpdated   = "account.updated"
+	EventAccountFrozen    = "account.frozen"
+	EventAccountDeleted   = "account.deleted"
+	EventTransferSent     = "transfer.sent"
+	EventTransferReceived = "transfer.received"
+)
+
+// delivery statuses, a failed delivery is retried until it is dead
+const (
+	StatusPending   = "pending"
+	StatusFailed    = "failed"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is attempted before it is dead
+	MaxAttempts = 8
+	// the first retry waits baseRetryDelay, every next one twice as long
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = time.Hour
+)
+
+// IsSupportEventType reports whether a subscription may be notified of the event type
+func IsSupportEventType(eventType string) bool {
+	switch eventType {
+	case EventAccountCreated, EventAccountUpdated, EventAccountFrozen,
+		EventAccountDeleted, EventTransferSent, EventTransferReceived:
+		return true
+	}
+	return false
+}
+
+// RetryDelay returns how long a delivery waits after it has been retried n times
+func RetryDelay(n int) time.Duration {
+	delay := baseRetryDelay
+	for i := 0; i < n && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// Event is the payload sent to a receiver. The id stays the same when a
+// delivery is retried or replayed, so receivers can drop duplicates.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Owner     string          `json:"owner"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// AccountData is the data of the account events
+type AccountData struct {
+	Account db.Accounts `json:"account"`
+}
+
+// TransferData is the data of a transfer event, with the account and entry of the notified owner
+type TransferData struct {
+	Transfer db.Transfers `json:"transfer"`
+	Account  db.Accounts  `json:"account"`
+	Entry    db.Entries   `json:"entry"`
+}
+
+// NewEvent creates the outbox event of a webhook event, it is dispatched once
+// the transaction writing it commits
+func NewEvent(eventType, owner string, data interface{}) (db.CreateOutboxEventParams, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	payload, err := json.Marshal(Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Owner:     owner,
+		CreatedAt: time.Now().UTC(),
+		Data:      jsonData,
+	})
+	if err != nil {
+		return db.CreateOutboxEventParams{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return db.CreateOutboxEventParams{
+		Topic:   TopicEvent,
+		Payload: payload,
+	}, nil
+}
+
+// NewAccountEvent creates the event of a change to an account, sent to its owner
+func NewAccountEvent(eventType string, account db.Accounts) (db.CreateOutboxEventParams, error) {
+	return NewEvent(eventType, account.Owner, AccountData{Account: account})
+}
+
+// TransferEvents creates the events of a transfer, the sender and the receiver
+// are only told about their own account
+func TransferEvents(result db.TransferTxResult) ([]db.CreateOutboxEventParams, error) {
+	sent, err := NewEvent(EventTransferSent, result.FromAccount.Owner, TransferData{
+		Transfer: result.Transfer,
+		Account:  result.FromAccount,
+		Entry:    result.FromEntry,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := NewEvent(EventTransferReceived, result.ToAccount.Owner, TransferData{
+		Transfer: result.Transfer,
+		Account:  result.ToAccount,
+		Entry:    result.ToEntry,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []db.CreateOutboxEventParams{sent, received}, nil
+}
+
+// GenerateSecret returns a new secret to sign the deliveries of a subscription
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// ValidateURL checks the URL deliveries are posted to, payloads carry balances
+// so they are only sent over https, and only to public hosts. A hostname is
+// checked again on every delivery, the address it resolves to may change.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("must be a valid url")
+	}
+
+	if u.Scheme != "https" {
+		return fmt.Errorf("must use https")
+	}
+
+	if u.Hostname() == "" {
+		return fmt.Errorf("must have a host")
+	}
+
+	if u.User != nil {
+		return fmt.Errorf("must not contain credentials")
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !publicIP(ip) {
+		return fmt.Errorf("must be a public address")
+	}
+
+	if localHostname(u.Hostname()) {
+		return fmt.Errorf("must be a public host")
+	}
+	return nil
+}
@@ -2,16 +2,46 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/redact"
+	"github.com/claytten/golang-simplebank/internal/tracing"
+	"github.com/claytten/golang-simplebank/internal/webhook"
 	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// taskOptions are the asynq options of every task published from the outbox
+var taskOptions = map[string][]asynq.Option{
+	TaskSendVerifyEmail: {
+		asynq.MaxRetry(10),
+		asynq.ProcessIn(10 * time.Second),
+		asynq.Queue(QueueCritical),
+	},
+	TaskSendLockoutEmail: {
+		asynq.MaxRetry(10),
+		asynq.Queue(QueueCritical),
+	},
+	TaskDeliverWebhook: {
+		// the delivery is marked dead on its last attempt
+		asynq.MaxRetry(webhook.MaxAttempts - 1),
+		asynq.Queue(QueueDefault),
+	},
+	TaskSendNotification: {
+		asynq.MaxRetry(5),
+		asynq.Queue(QueueDefault),
+	},
+}
+
+// TaskDistributor enqueues the tasks of outbox events
 type TaskDistributor interface {
-	DistributeTaskVerifyEmail(
-		ctx context.Context,
-		payload *PayloadSendVerifyEmail,
-		opts ...asynq.Option,
-	) error
+	Sink
 }
 
 type RedisTaskDistributor struct {
@@ -22,3 +52,49 @@ func NewRedisTaskDistributor(redisOpt *asynq.RedisClientOpt) TaskDistributor {
 	client := asynq.NewClient(redisOpt)
 	return &RedisTaskDistributor{client: client}
 }
+
+// Publish enqueues the task of an outbox event, the task id is derived from the event
+// so an event published twice is only enqueued once while asynq retains the task.
+// The enqueue span continues the trace of the request that wrote the event, and the
+// payload carries its trace context and the id of that request.
+func (distributor *RedisTaskDistributor) Publish(ctx context.Context, event db.Outbox) (err error) {
+	ctx, span := startTaskSpan(eventTraceContext(ctx, event), "enqueue "+event.Topic, trace.SpanKindProducer, event.Topic)
+	span.SetAttributes(attribute.Int64("outbox.id", event.ID))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	opts, ok := taskOptions[event.Topic]
+	if !ok {
+		return fmt.Errorf("unknown task %s", event.Topic)
+	}
+
+	opts = append([]asynq.Option{asynq.TaskID(fmt.Sprintf("outbox:%d", event.ID))}, opts...)
+	payload := withRequestID(withTraceContext(ctx, event.Payload), event.RequestID)
+	task := asynq.NewTask(event.Topic, payload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).RawJSON("payload", redact.JSON(task.Payload())).
+		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).Msg("enqueued task")
+	return nil
+}
+
+// NewTaskEvent creates the outbox event of a task, it is enqueued once the transaction
+// writing it commits
+func NewTaskEvent(taskType string, payload interface{}) (db.CreateOutboxEventParams, error) {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return db.CreateOutboxEventParams{
+		Topic:   taskType,
+		Payload: jsonPayload,
+	}, nil
+}
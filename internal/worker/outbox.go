@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/rs/zerolog/log"
+)
+
+// Sink publishes outbox events, an error keeps the event for a later retry
+// so a sink has to tolerate an event published more than once.
+type Sink interface {
+	Publish(ctx context.Context, event db.Outbox) error
+}
+
+// OutboxRelayConfig tunes how the relay polls the outbox. A failed event waits
+// RetryDelay doubled for every failed attempt, never longer than MaxRetryDelay.
+// Every event gets PublishTimeout to be published, the batch being published is
+// leased to the relay for as long as publishing all of it may take.
+// Published events are deleted Retention after they were published.
+type OutboxRelayConfig struct {
+	PollInterval   time.Duration
+	BatchSize      int32
+	PublishTimeout time.Duration
+	RetryDelay     time.Duration
+	MaxRetryDelay  time.Duration
+	Retention      time.Duration
+}
+
+// lease returns how long a batch is hidden from other relays while it is published
+func (config OutboxRelayConfig) lease() time.Duration {
+	return config.PublishTimeout*time.Duration(config.BatchSize) + config.PollInterval
+}
+
+// retryDelay returns how long an event waits after its failed attempts
+func (config OutboxRelayConfig) retryDelay(attempts int32) time.Duration {
+	delay := config.RetryDelay
+	for i := int32(1); i < attempts && delay < config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if config.MaxRetryDelay > 0 && delay > config.MaxRetryDelay {
+		delay = config.MaxRetryDelay
+	}
+	return delay
+}
+
+// OutboxRelay publishes the events written to the outbox with the changes
+// that caused them, every event is delivered at least once to the sink of its topic
+type OutboxRelay struct {
+	store  db.Store
+	config OutboxRelayConfig
+	sinks  map[string]Sink
+}
+
+func NewOutboxRelay(store db.Store, config OutboxRelayConfig) *OutboxRelay {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PublishTimeout <= 0 {
+		config.PublishTimeout = 10 * time.Second
+	}
+
+	return &OutboxRelay{
+		store:  store,
+		config: config,
+		sinks:  make(map[string]Sink),
+	}
+}
+
+// Handle publishes the events of topic to sink
+func (relay *OutboxRelay) Handle(topic string, sink Sink) {
+	relay.sinks[topic] = sink
+}
+
+// HandleTasks publishes the events of every task to the distributor
+func (relay *OutboxRelay) HandleTasks(distributor TaskDistributor) {
+	for taskType := range taskOptions {
+		relay.Handle(taskType, distributor)
+	}
+}
+
+func (relay *OutboxRelay) publish(ctx context.Context, event db.Outbox) error {
+	sink, ok := relay.sinks[event.Topic]
+	if !ok {
+		return fmt.Errorf("no sink for topic %s", event.Topic)
+	}
+
+	err := sink.Publish(ctx, event)
+	if err != nil {
+		log.Error().Err(err).Int64("outbox_id", event.ID).Str("topic", event.Topic).
+			Int32("attempts", event.Attempts+1).Msg("cannot publish outbox event")
+	}
+	return err
+}
+
+// RelayOnce publishes one batch of pending events
+func (relay *OutboxRelay) RelayOnce(ctx context.Context) (db.RelayOutboxTxResult, error) {
+	return relay.store.RelayOutboxTx(ctx, db.RelayOutboxTxParams{
+		Limit:          relay.config.BatchSize,
+		Lease:          relay.config.lease(),
+		PublishTimeout: relay.config.PublishTimeout,
+		Publish:        relay.publish,
+		RetryDelay:     relay.config.retryDelay,
+	})
+}
+
+// Start relays the outbox until ctx is done, a full batch is followed
+// by the next one right away
+func (relay *OutboxRelay) Start(ctx context.Context) error {
+	log.Info().Msg("start outbox relay")
+
+	ticker := time.NewTicker(relay.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := relay.RelayOnce(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot relay outbox")
+		}
+
+		if err == nil && result.Published+result.Failed >= int(relay.config.BatchSize) {
+			continue
+		}
+
+		if relay.config.Retention > 0 {
+			err = relay.store.DeletePublishedOutboxEvents(ctx, sql.NullTime{
+				Time:  time.Now().Add(-relay.config.Retention),
+				Valid: true,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("cannot delete published outbox events")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSink struct {
+	published []db.Outbox
+	err       error
+}
+
+func (sink *fakeSink) Publish(ctx context.Context, event db.Outbox) error {
+	if sink.err != nil {
+		return sink.err
+	}
+	sink.published = append(sink.published, event)
+	return nil
+}
+
+// relayEvents runs the relay callbacks like RelayOutboxTx over events
+func relayEvents(events []db.Outbox, delays map[int64]time.Duration) func(ctx context.Context, arg db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+	return func(ctx context.Context, arg db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+		var result db.RelayOutboxTxResult
+		for _, event := range events {
+			if err := arg.Publish(ctx, event); err != nil {
+				delays[event.ID] = arg.RetryDelay(event.Attempts + 1)
+				result.Failed++
+				continue
+			}
+			result.Published++
+		}
+		return result, nil
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	config := worker.OutboxRelayConfig{
+		BatchSize:     10,
+		RetryDelay:    time.Second,
+		MaxRetryDelay: 5 * time.Second,
+	}
+
+	verifyEmail, err := worker.NewTaskEvent(worker.TaskSendVerifyEmail, &worker.PayloadSendVerifyEmail{Email: "user@email.com"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"email":"user@email.com"}`, string(verifyEmail.Payload))
+
+	events := []db.Outbox{
+		{ID: 1, Topic: verifyEmail.Topic, Payload: verifyEmail.Payload},
+		{ID: 2, Topic: "webhook", Payload: json.RawMessage(`{}`), Attempts: 1},
+		{ID: 3, Topic: "unknown", Payload: json.RawMessage(`{}`), Attempts: 5},
+	}
+
+	tests := []struct {
+		name          string
+		tasksErr      error
+		checkResponse func(t *testing.T, result db.RelayOutboxTxResult, tasks, webhooks *fakeSink, delays map[int64]time.Duration)
+	}{
+		// TODO: every event goes to the sink of its topic
+		{
+			name: "routed",
+			checkResponse: func(t *testing.T, result db.RelayOutboxTxResult, tasks, webhooks *fakeSink, delays map[int64]time.Duration) {
+				require.Equal(t, 2, result.Published)
+				require.Equal(t, 1, result.Failed)
+				require.Len(t, tasks.published, 1)
+				require.Equal(t, int64(1), tasks.published[0].ID)
+				require.Len(t, webhooks.published, 1)
+				require.Equal(t, int64(2), webhooks.published[0].ID)
+
+				// an unknown topic waits like any failure, capped after enough attempts
+				require.Equal(t, map[int64]time.Duration{3: 5 * time.Second}, delays)
+			},
+		},
+
+		// TODO: a failing sink leaves its events pending
+		{
+			name:     "sink error",
+			tasksErr: errors.New("redis is down"),
+			checkResponse: func(t *testing.T, result db.RelayOutboxTxResult, tasks, webhooks *fakeSink, delays map[int64]time.Duration) {
+				require.Equal(t, 1, result.Published)
+				require.Equal(t, 2, result.Failed)
+				require.Empty(t, tasks.published)
+				require.Equal(t, time.Second, delays[1])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			delays := make(map[int64]time.Duration)
+			store := mockdb.NewMockStore(controller)
+			store.EXPECT().
+				RelayOutboxTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(relayEvents(events, delays))
+
+			tasks := &fakeSink{err: tt.tasksErr}
+			webhooks := &fakeSink{}
+
+			relay := worker.NewOutboxRelay(store, config)
+			relay.HandleTasks(tasks)
+			relay.Handle("webhook", webhooks)
+
+			result, err := relay.RelayOnce(context.Background())
+			require.NoError(t, err)
+			tt.checkResponse(t, result, tasks, webhooks, delays)
+		})
+	}
+}
+
+func TestOutboxRelayRetryDelay(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+			require.Equal(t, int32(100), arg.Limit)
+			require.Equal(t, time.Second, arg.RetryDelay(1))
+			require.Equal(t, 2*time.Second, arg.RetryDelay(2))
+			require.Equal(t, 4*time.Second, arg.RetryDelay(3))
+			require.Equal(t, time.Minute, arg.RetryDelay(30))
+			return db.RelayOutboxTxResult{}, nil
+		})
+
+	relay := worker.NewOutboxRelay(store, worker.OutboxRelayConfig{
+		RetryDelay:    time.Second,
+		MaxRetryDelay: time.Minute,
+	})
+	_, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+}
+
+func TestOutboxRelayStart(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).MinTimes(1).Return(db.RelayOutboxTxResult{}, nil)
+	store.EXPECT().DeletePublishedOutboxEvents(gomock.Any(), gomock.Any()).MinTimes(1).Return(nil)
+
+	relay := worker.NewOutboxRelay(store, worker.OutboxRelayConfig{
+		PollInterval: time.Millisecond,
+		Retention:    time.Hour,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Start returns once ctx is done
+	require.NoError(t, relay.Start(ctx))
+}
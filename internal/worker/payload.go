@@ -0,0 +1,48 @@
+package worker
+
+import "encoding/json"
+
+// requestIDField is the payload field carrying the id of the request that wrote
+// the event of a task, the payloads of the handlers ignore it
+const requestIDField = "request_id"
+
+// withPayloadField sets a field of a JSON object payload, another payload or a
+// value that cannot be encoded leaves the payload as it is
+func withPayloadField(payload []byte, field string, value interface{}) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return payload
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return payload
+	}
+	fields[field] = encoded
+
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+// withRequestID adds the id of the request that wrote the event of a task to its payload
+func withRequestID(payload []byte, requestID string) []byte {
+	if requestID == "" {
+		return payload
+	}
+	return withPayloadField(payload, requestIDField, requestID)
+}
+
+// payloadRequestID returns the id of the request that wrote the event of a task,
+// empty when the event was written outside a request
+func payloadRequestID(payload []byte) string {
+	var fields struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return ""
+	}
+	return fields.RequestID
+}
@@ -2,10 +2,17 @@ package worker
 
 import (
 	"context"
+	"time"
 
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/metrics"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/claytten/golang-simplebank/internal/redact"
+	"github.com/claytten/golang-simplebank/internal/webhook"
 	"github.com/go-redis/redis/v8"
 	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,15 +23,29 @@ const (
 
 type TaskProcessor interface {
 	Start() error
+	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendLockoutEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskDeliverWebhook(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendNotification(ctx context.Context, task *asynq.Task) error
 }
 
 type RedisTaskProcessor struct {
-	server *asynq.Server
-	store  db.Store
+	server        *asynq.Server
+	store         db.Store
+	webhookClient *webhook.Client
+	notifier      *notification.Notifier
 }
 
-func NewRedisTaskProcessor(redisOpt *asynq.RedisClientOpt, store db.Store) *RedisTaskProcessor {
+// NewRedisTaskProcessor waits shutdownTimeout for the tasks in progress on shutdown,
+// asynq processes the unfinished tasks again later
+func NewRedisTaskProcessor(
+	redisOpt *asynq.RedisClientOpt,
+	store db.Store,
+	notifier *notification.Notifier,
+	webhookClient *webhook.Client,
+	shutdownTimeout time.Duration,
+) *RedisTaskProcessor {
 	logger := NewLogger()
 	redis.SetLogger(logger)
 
@@ -36,17 +57,71 @@ func NewRedisTaskProcessor(redisOpt *asynq.RedisClientOpt, store db.Store) *Redi
 		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
 			// error handling for failed tasks
 			// repeating report to sentry or log to file
-			log.Error().Err(err).Str("type", task.Type()).Bytes("payload", task.Payload()).Msg("error processing task")
+			taskID, _ := asynq.GetTaskID(ctx)
+			log.Error().Err(err).Str("type", task.Type()).Str("task_id", taskID).
+				RawJSON("payload", redact.JSON(task.Payload())).Msg("error processing task")
 		}),
-		Logger: logger,
+		// webhook receivers get a longer and longer break, the other tasks keep the default
+		RetryDelayFunc: func(n int, err error, task *asynq.Task) time.Duration {
+			if task.Type() == TaskDeliverWebhook {
+				return webhook.RetryDelay(n)
+			}
+			return asynq.DefaultRetryDelayFunc(n, err, task)
+		},
+		Logger:          logger,
+		ShutdownTimeout: shutdownTimeout,
 	})
-	return &RedisTaskProcessor{server: server, store: store}
+	return &RedisTaskProcessor{
+		server:        server,
+		store:         store,
+		webhookClient: webhookClient,
+		notifier:      notifier,
+	}
 }
 
 func (processor *RedisTaskProcessor) Start() error {
 	mux := asynq.NewServeMux()
+	mux.Use(traceTask, logTask, observeTask)
 
 	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
+	mux.HandleFunc(TaskSendLockoutEmail, processor.ProcessTaskSendLockoutEmail)
+	mux.HandleFunc(TaskDeliverWebhook, processor.ProcessTaskDeliverWebhook)
+	mux.HandleFunc(TaskSendNotification, processor.ProcessTaskSendNotification)
 
 	return processor.server.Start(mux)
 }
+
+// observeTask records the outcome and the processing time of every task
+func observeTask(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		startTime := time.Now()
+		err := handler.ProcessTask(ctx, task)
+		metrics.ObserveTask(task.Type(), time.Since(startTime), err)
+		return err
+	})
+}
+
+// logTask gives every task a logger with the task type and id, the id of the request
+// that wrote its event, and the trace continued by traceTask
+func logTask(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		taskID, _ := asynq.GetTaskID(ctx)
+		requestID := payloadRequestID(task.Payload())
+		if requestID != "" {
+			ctx = logging.WithRequestID(ctx, requestID)
+		}
+		ctx = logging.WithLogger(ctx, func(c zerolog.Context) zerolog.Context {
+			c = c.Str("type", task.Type()).Str("task_id", taskID)
+			if requestID != "" {
+				c = c.Str("request_id", requestID)
+			}
+			return c
+		})
+		return handler.ProcessTask(ctx, task)
+	})
+}
+
+// Shutdown stops taking tasks and waits for the tasks in progress
+func (processor *RedisTaskProcessor) Shutdown() {
+	processor.server.Shutdown()
+}
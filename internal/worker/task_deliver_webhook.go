@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+	"github.com/hibiken/asynq"
+)
+
+const TaskDeliverWebhook = "task:deliver_webhook"
+
+type PayloadDeliverWebhook struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// ProcessTaskDeliverWebhook posts a delivery to its receiver. A failed attempt is
+// retried with exponential backoff, after webhook.MaxAttempts the delivery is dead
+// until the owner replays it.
+func (processor *RedisTaskProcessor) ProcessTaskDeliverWebhook(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadDeliverWebhook
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	// the deliveries of a deleted subscription are deleted with it
+	delivery, err := processor.store.GetWebhookDelivery(ctx, payload.DeliveryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("failed to get delivery: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get delivery: %w", err)
+	}
+
+	if delivery.Status == webhook.StatusSucceeded || delivery.Status == webhook.StatusDead {
+		return nil
+	}
+
+	subscription, err := processor.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("failed to get subscription: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	statusCode, deliverErr := processor.webhookClient.Deliver(ctx, subscription, delivery)
+	responseStatus := sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0}
+
+	if deliverErr == nil {
+		_, err = processor.store.MarkWebhookDeliverySucceeded(ctx, db.MarkWebhookDeliverySucceededParams{
+			ResponseStatus: responseStatus,
+			ID:             delivery.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark delivery succeeded: %w", err)
+		}
+
+		logging.Ctx(ctx).Info().Int64("delivery_id", delivery.ID).
+			Int("status_code", statusCode).Msg("processed task")
+		return nil
+	}
+
+	// the owner only sees what kind of failure it was, the cause is logged
+	logging.Ctx(ctx).Warn().Err(deliverErr).AnErr("cause", errors.Unwrap(deliverErr)).
+		Int64("delivery_id", delivery.ID).Int("status_code", statusCode).Msg("cannot deliver webhook")
+
+	arg := db.MarkWebhookDeliveryFailedParams{
+		Status:         webhook.StatusFailed,
+		ResponseStatus: responseStatus,
+		LastError:      sql.NullString{String: deliverErr.Error(), Valid: true},
+		ID:             delivery.ID,
+	}
+
+	// attempts before this one is how many times asynq retried the task
+	dead := delivery.Attempts+1 >= webhook.MaxAttempts
+	if dead {
+		arg.Status = webhook.StatusDead
+	} else {
+		arg.NextAttemptAt = sql.NullTime{
+			Time:  time.Now().Add(webhook.RetryDelay(int(delivery.Attempts))),
+			Valid: true,
+		}
+	}
+
+	if _, err = processor.store.MarkWebhookDeliveryFailed(ctx, arg); err != nil {
+		return fmt.Errorf("failed to mark delivery failed: %w", err)
+	}
+
+	if dead {
+		return fmt.Errorf("delivery is dead after %d attempts: %v: %w", webhook.MaxAttempts, deliverErr, asynq.SkipRetry)
+	}
+	return fmt.Errorf("failed to deliver webhook: %w", deliverErr)
+}
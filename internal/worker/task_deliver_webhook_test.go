@@ -0,0 +1,199 @@
+package worker_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTaskDeliverWebhook(t *testing.T) {
+	receiverStatus := http.StatusOK
+	var received []*http.Request
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r)
+		w.WriteHeader(receiverStatus)
+	}))
+	defer receiver.Close()
+
+	subscription := db.WebhookSubscriptions{ID: 3, Owner: "owner", Url: receiver.URL, Secret: "whsec_test"}
+	newDelivery := func(status string, attempts int32) db.WebhookDeliveries {
+		return db.WebhookDeliveries{
+			ID:             9,
+			SubscriptionID: subscription.ID,
+			EventID:        "evt_1",
+			EventType:      webhook.EventTransferReceived,
+			Payload:        json.RawMessage(`{"id":"evt_1"}`),
+			Status:         status,
+			Attempts:       attempts,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		receiverStatus int
+		buildStubs     func(store *mockdb.MockStore)
+		checkResponse  func(t *testing.T, err error, received []*http.Request)
+	}{
+		// TODO: a delivered webhook succeeds
+		{
+			name:           "OK",
+			receiverStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), int64(9)).Times(1).Return(newDelivery(webhook.StatusPending, 0), nil)
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), subscription.ID).Times(1).Return(subscription, nil)
+				store.EXPECT().
+					MarkWebhookDeliverySucceeded(gomock.Any(), db.MarkWebhookDeliverySucceededParams{
+						ResponseStatus: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+						ID:             9,
+					}).
+					Times(1).
+					Return(newDelivery(webhook.StatusSucceeded, 1), nil)
+			},
+			checkResponse: func(t *testing.T, err error, received []*http.Request) {
+				require.NoError(t, err)
+				require.Len(t, received, 1)
+				require.NotEmpty(t, received[0].Header.Get(webhook.SignatureHeader))
+			},
+		},
+		// TODO: a failed attempt is retried later
+		{
+			name:           "Retry",
+			receiverStatus: http.StatusServiceUnavailable,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), int64(9)).Times(1).Return(newDelivery(webhook.StatusFailed, 2), nil)
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), subscription.ID).Times(1).Return(subscription, nil)
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.MarkWebhookDeliveryFailedParams) (db.WebhookDeliveries, error) {
+						require.Equal(t, webhook.StatusFailed, arg.Status)
+						require.Equal(t, int32(http.StatusServiceUnavailable), arg.ResponseStatus.Int32)
+						require.Contains(t, arg.LastError.String, "503")
+						require.WithinDuration(t, time.Now().Add(webhook.RetryDelay(2)), arg.NextAttemptAt.Time, time.Second)
+						return newDelivery(arg.Status, 3), nil
+					})
+			},
+			checkResponse: func(t *testing.T, err error, received []*http.Request) {
+				require.Error(t, err)
+				require.False(t, errors.Is(err, asynq.SkipRetry))
+				require.Len(t, received, 1)
+			},
+		},
+		// TODO: the last failed attempt kills the delivery
+		{
+			name:           "Dead",
+			receiverStatus: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), int64(9)).Times(1).Return(newDelivery(webhook.StatusFailed, webhook.MaxAttempts-1), nil)
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), subscription.ID).Times(1).Return(subscription, nil)
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.MarkWebhookDeliveryFailedParams) (db.WebhookDeliveries, error) {
+						require.Equal(t, webhook.StatusDead, arg.Status)
+						require.False(t, arg.NextAttemptAt.Valid)
+						return newDelivery(arg.Status, webhook.MaxAttempts), nil
+					})
+			},
+			checkResponse: func(t *testing.T, err error, received []*http.Request) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+				require.Len(t, received, 1)
+			},
+		},
+		// TODO: a succeeded delivery isn't made again
+		{
+			name:           "AlreadySucceeded",
+			receiverStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), int64(9)).Times(1).Return(newDelivery(webhook.StatusSucceeded, 1), nil)
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, err error, received []*http.Request) {
+				require.NoError(t, err)
+				require.Empty(t, received)
+			},
+		},
+		// TODO: the deliveries of a deleted subscription are dropped
+		{
+			name:           "DeletedSubscription",
+			receiverStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), int64(9)).Times(1).Return(db.WebhookDeliveries{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, err error, received []*http.Request) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+				require.Empty(t, received)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			receiverStatus = tt.receiverStatus
+			received = nil
+
+			// the receiver runs on the loopback, which only the tests allow
+			_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+			require.NoError(t, err)
+			webhookClient := webhook.NewClient(webhook.DefaultTimeout, loopback)
+
+			processor := worker.NewRedisTaskProcessor(&asynq.RedisClientOpt{Addr: "localhost:6379"}, store, nil, webhookClient, 0)
+			payload, err := json.Marshal(&worker.PayloadDeliverWebhook{DeliveryID: 9})
+			require.NoError(t, err)
+
+			err = processor.ProcessTaskDeliverWebhook(context.Background(), asynq.NewTask(worker.TaskDeliverWebhook, payload))
+			tt.checkResponse(t, err, received)
+		})
+	}
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	event, err := webhook.NewAccountEvent(webhook.EventAccountCreated, db.Accounts{ID: 1, Owner: "owner"})
+	require.NoError(t, err)
+
+	var payload webhook.Event
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().
+		DispatchWebhookTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.DispatchWebhookTxParams) (db.DispatchWebhookTxResult, error) {
+			require.Equal(t, "owner", arg.Owner)
+			require.Equal(t, payload.ID, arg.EventID)
+			require.Equal(t, webhook.EventAccountCreated, arg.EventType)
+			require.JSONEq(t, string(event.Payload), string(arg.Payload))
+
+			task, err := arg.NewTask(db.WebhookDeliveries{ID: 42})
+			require.NoError(t, err)
+			require.Equal(t, worker.TaskDeliverWebhook, task.Topic)
+			require.JSONEq(t, `{"delivery_id":42}`, string(task.Payload))
+			return db.DispatchWebhookTxResult{Deliveries: []db.WebhookDeliveries{{ID: 42}}}, nil
+		})
+
+	dispatcher := worker.NewWebhookDispatcher(store)
+	err = dispatcher.Publish(context.Background(), db.Outbox{ID: 1, Topic: event.Topic, Payload: event.Payload})
+	require.NoError(t, err)
+}
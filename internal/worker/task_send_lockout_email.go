@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/redact"
+	"github.com/hibiken/asynq"
+)
+
+const TaskSendLockoutEmail = "task:send_lockout_email"
+
+type PayloadSendLockoutEmail struct {
+	Email       string    `json:"email"`
+	ClientIP    string    `json:"client_ip"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (processor *RedisTaskProcessor) ProcessTaskSendLockoutEmail(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendLockoutEmail
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	user, err := processor.store.GetUserUsingEmail(ctx, payload.Email)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", asynq.SkipRetry)
+	}
+
+	logging.Ctx(ctx).Info().RawJSON("payload", redact.JSON(task.Payload())).
+		Str("username", user.Username).Time("locked_until", payload.LockedUntil).Msg("processed task")
+	return nil
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/hibiken/asynq"
+)
+
+const TaskSendNotification = "task:send_notification"
+
+// PayloadSendNotification carries the data of its event, Transfer for the
+// transfer alerts and Device for the new device alert
+type PayloadSendNotification struct {
+	Username string                     `json:"username"`
+	Event    string                     `json:"event"`
+	Transfer *notification.TransferData `json:"transfer,omitempty"`
+	Device   *notification.DeviceData   `json:"device,omitempty"`
+}
+
+// TransferNotifications creates the alert tasks of a transfer. The sender is checked
+// for a large debit once their threshold is known, a transfer between accounts of
+// the same owner isn't alerted as received.
+func TransferNotifications(result db.TransferTxResult) ([]db.CreateOutboxEventParams, error) {
+	largeDebit, err := NewTaskEvent(TaskSendNotification, &PayloadSendNotification{
+		Username: result.FromAccount.Owner,
+		Event:    notification.EventLargeDebit,
+		Transfer: &notification.TransferData{
+			TransferID: result.Transfer.ID,
+			AccountID:  result.FromAccount.ID,
+			Amount:     result.Transfer.Amount,
+			Currency:   result.FromAccount.Currency,
+			Balance:    result.FromAccount.Balance,
+			CreatedAt:  result.Transfer.CreatedAt,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.ToAccount.Owner == result.FromAccount.Owner {
+		return []db.CreateOutboxEventParams{largeDebit}, nil
+	}
+
+	received, err := NewTaskEvent(TaskSendNotification, &PayloadSendNotification{
+		Username: result.ToAccount.Owner,
+		Event:    notification.EventTransferReceived,
+		Transfer: &notification.TransferData{
+			TransferID: result.Transfer.ID,
+			AccountID:  result.ToAccount.ID,
+			Amount:     result.Transfer.Amount,
+			Currency:   result.ToAccount.Currency,
+			Balance:    result.ToAccount.Balance,
+			CreatedAt:  result.Transfer.CreatedAt,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []db.CreateOutboxEventParams{largeDebit, received}, nil
+}
+
+// NewDeviceNotification creates the alert task of a login from a device the user never logged in from
+func NewDeviceNotification(device db.UserDevices) (db.CreateOutboxEventParams, error) {
+	return NewTaskEvent(TaskSendNotification, &PayloadSendNotification{
+		Username: device.Username,
+		Event:    notification.EventNewDevice,
+		Device: &notification.DeviceData{
+			UserAgent: device.UserAgent,
+			ClientIP:  device.ClientIp,
+			LoginAt:   device.FirstSeenAt,
+		},
+	})
+}
+
+// ProcessTaskSendNotification sends an alert through the channels the user enabled for
+// its event. A retry sends it again through the channels that already succeeded.
+func (processor *RedisTaskProcessor) ProcessTaskSendNotification(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendNotification
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	var data interface{}
+	switch payload.Event {
+	case notification.EventTransferReceived, notification.EventLargeDebit:
+		if payload.Transfer != nil {
+			data = payload.Transfer
+		}
+	case notification.EventNewDevice:
+		if payload.Device != nil {
+			data = payload.Device
+		}
+	}
+	if data == nil {
+		return fmt.Errorf("invalid %s notification: %w", payload.Event, asynq.SkipRetry)
+	}
+
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("failed to get user: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	settings, err := processor.store.GetNotificationSettings(ctx, user.Username)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get notification settings: %w", err)
+		}
+		settings = notification.DefaultSettings(user.Username)
+	}
+
+	if payload.Event == notification.EventLargeDebit && payload.Transfer.Amount < settings.LargeDebitThreshold {
+		return nil
+	}
+
+	preferences, err := processor.store.ListNotificationPreferences(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	channels, err := processor.notifier.Notify(ctx,
+		notification.NewRecipient(user, settings),
+		notification.NewPreferences(preferences),
+		payload.Event,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	logging.Ctx(ctx).Info().Str("event", payload.Event).
+		Str("username", user.Username).Strs("channels", channels).Msg("processed task")
+	return nil
+}
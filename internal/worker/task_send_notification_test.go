@@ -0,0 +1,168 @@
+package worker_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/claytten/golang-simplebank/internal/db/mock"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTaskSendNotification(t *testing.T) {
+	user := db.Users{Username: "owner", FullName: "Owner Name", Email: "owner@email.com"}
+	transfer := &notification.TransferData{TransferID: 7, AccountID: 3, Amount: 2500, Currency: "USD", Balance: 500, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name          string
+		payload       worker.PayloadSendNotification
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, err error, messages []notification.SentMessage)
+	}{
+		// TODO: a user who never changed the settings is emailed
+		{
+			name:    "OK",
+			payload: worker.PayloadSendNotification{Username: user.Username, Event: notification.EventTransferReceived, Transfer: transfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().GetNotificationSettings(gomock.Any(), user.Username).Times(1).Return(db.NotificationSettings{}, sql.ErrNoRows)
+				store.EXPECT().ListNotificationPreferences(gomock.Any(), user.Username).Times(1).Return([]db.NotificationPreferences{}, nil)
+			},
+			checkResponse: func(t *testing.T, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Len(t, messages, 1)
+				require.Equal(t, notification.ChannelEmail, messages[0].Channel)
+				require.Equal(t, user.Email, messages[0].To)
+				require.Equal(t, "You received 2500 USD", messages[0].Subject)
+			},
+		},
+		// TODO: the alert goes through the channels and in the locale the user chose
+		{
+			name:    "Settings",
+			payload: worker.PayloadSendNotification{Username: user.Username, Event: notification.EventLargeDebit, Transfer: transfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().GetNotificationSettings(gomock.Any(), user.Username).Times(1).Return(db.NotificationSettings{
+					Username:            user.Username,
+					Locale:              "id",
+					PhoneNumber:         "+628123456789",
+					LargeDebitThreshold: 2000,
+				}, nil)
+				store.EXPECT().ListNotificationPreferences(gomock.Any(), user.Username).Times(1).Return([]db.NotificationPreferences{
+					{Username: user.Username, Event: notification.EventLargeDebit, Channel: notification.ChannelEmail, Enabled: false},
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Len(t, messages, 1)
+				require.Equal(t, notification.ChannelSMS, messages[0].Channel)
+				require.Contains(t, messages[0].Body, "dikirim dari rekening #3")
+			},
+		},
+		// TODO: a debit below the threshold of the user isn't alerted
+		{
+			name:    "BelowThreshold",
+			payload: worker.PayloadSendNotification{Username: user.Username, Event: notification.EventLargeDebit, Transfer: transfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().GetNotificationSettings(gomock.Any(), user.Username).Times(1).Return(db.NotificationSettings{
+					Username:            user.Username,
+					Locale:              "en",
+					LargeDebitThreshold: 5000,
+				}, nil)
+				store.EXPECT().ListNotificationPreferences(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, err error, messages []notification.SentMessage) {
+				require.NoError(t, err)
+				require.Empty(t, messages)
+			},
+		},
+		// TODO: an alert without its data is dropped
+		{
+			name:    "MissingData",
+			payload: worker.PayloadSendNotification{Username: user.Username, Event: notification.EventNewDevice, Transfer: transfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, err error, messages []notification.SentMessage) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+				require.Empty(t, messages)
+			},
+		},
+		// TODO: a database error is retried
+		{
+			name:    "InternalError",
+			payload: worker.PayloadSendNotification{Username: user.Username, Event: notification.EventTransferReceived, Transfer: transfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(db.Users{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, err error, messages []notification.SentMessage) {
+				require.Error(t, err)
+				require.False(t, errors.Is(err, asynq.SkipRetry))
+				require.Empty(t, messages)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			tt.buildStubs(store)
+
+			sender := notification.NewMemorySender()
+			notifier, err := notification.NewNotifier(sender, sender, sender)
+			require.NoError(t, err)
+
+			processor := worker.NewRedisTaskProcessor(&asynq.RedisClientOpt{Addr: "localhost:6379"}, store, notifier, nil, 0)
+			payload, err := json.Marshal(&tt.payload)
+			require.NoError(t, err)
+
+			err = processor.ProcessTaskSendNotification(context.Background(), asynq.NewTask(worker.TaskSendNotification, payload))
+			tt.checkResponse(t, err, sender.Messages())
+		})
+	}
+}
+
+func TestTransferNotifications(t *testing.T) {
+	result := db.TransferTxResult{
+		Transfer:    db.Transfers{ID: 7, Amount: 2500},
+		FromAccount: db.Accounts{ID: 1, Owner: "sender", Currency: "USD", Balance: 500},
+		ToAccount:   db.Accounts{ID: 2, Owner: "receiver", Currency: "USD", Balance: 3500},
+	}
+
+	events, err := worker.TransferNotifications(result)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	var payloads []worker.PayloadSendNotification
+	for _, event := range events {
+		require.Equal(t, worker.TaskSendNotification, event.Topic)
+
+		var payload worker.PayloadSendNotification
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		payloads = append(payloads, payload)
+	}
+
+	require.Equal(t, "sender", payloads[0].Username)
+	require.Equal(t, notification.EventLargeDebit, payloads[0].Event)
+	require.Equal(t, int64(500), payloads[0].Transfer.Balance)
+	require.Equal(t, "receiver", payloads[1].Username)
+	require.Equal(t, notification.EventTransferReceived, payloads[1].Event)
+	require.Equal(t, int64(3500), payloads[1].Transfer.Balance)
+
+	// moving money between own accounts isn't an incoming transfer
+	result.ToAccount.Owner = "sender"
+	events, err = worker.TransferNotifications(result)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/tracing"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContextField is the payload field carrying the trace context of a task,
+// the payloads of the handlers ignore it
+const traceContextField = "trace_context"
+
+// withTraceContext adds the trace context of ctx to a JSON object payload,
+// another payload is left as it is
+func withTraceContext(ctx context.Context, payload []byte) []byte {
+	carrier := tracing.Inject(ctx)
+	if len(carrier) == 0 {
+		return payload
+	}
+	return withPayloadField(payload, traceContextField, carrier)
+}
+
+// traceContext continues the trace the task was enqueued in
+func traceContext(ctx context.Context, payload []byte) context.Context {
+	var fields struct {
+		TraceContext map[string]string `json:"trace_context"`
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil || len(fields.TraceContext) == 0 {
+		return ctx
+	}
+	return tracing.Extract(ctx, fields.TraceContext)
+}
+
+// eventTraceContext continues the trace of the request that wrote an outbox event,
+// an event written outside a trace stays in the trace of ctx
+func eventTraceContext(ctx context.Context, event db.Outbox) context.Context {
+	var carrier map[string]string
+	if err := json.Unmarshal(event.TraceContext, &carrier); err != nil || len(carrier) == 0 {
+		return ctx
+	}
+	return tracing.Extract(ctx, carrier)
+}
+
+func startTaskSpan(ctx context.Context, name string, kind trace.SpanKind, taskType string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(
+			semconv.MessagingSystem("asynq"),
+			attribute.String("task.type", taskType),
+		),
+	)
+}
+
+// traceTask processes every task in a span continuing the trace it was enqueued in
+func traceTask(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		ctx, span := startTaskSpan(traceContext(ctx, task.Payload()), "process "+task.Type(), trace.SpanKindConsumer, task.Type())
+		if taskID, ok := asynq.GetTaskID(ctx); ok {
+			span.SetAttributes(attribute.String("task.id", taskID))
+		}
+		if retryCount, ok := asynq.GetRetryCount(ctx); ok {
+			span.SetAttributes(attribute.Int("task.retry_count", retryCount))
+		}
+
+		err := handler.ProcessTask(ctx, task)
+		tracing.End(span, err)
+		return err
+	})
+}
@@ -0,0 +1,120 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/tracing"
+	"github.com/claytten/golang-simplebank/internal/worker"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPublishTraceContext(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	redisServer := miniredis.RunT(t)
+	redisOpt := asynq.RedisClientOpt{Addr: redisServer.Addr()}
+	distributor := worker.NewRedisTaskDistributor(&redisOpt)
+
+	event, err := worker.NewTaskEvent(worker.TaskSendLockoutEmail, &worker.PayloadSendLockoutEmail{Email: "user@email.com"})
+	require.NoError(t, err)
+
+	err = distributor.Publish(context.Background(), db.Outbox{ID: 1, Topic: event.Topic, Payload: event.Payload})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "enqueue "+worker.TaskSendLockoutEmail, spans[0].Name())
+	require.Equal(t, trace.SpanKindProducer, spans[0].SpanKind())
+
+	inspector := asynq.NewInspector(redisOpt)
+	defer inspector.Close()
+
+	info, err := inspector.GetTaskInfo(worker.QueueCritical, "outbox:1")
+	require.NoError(t, err)
+
+	// the handler still reads its payload, the trace context continues the enqueue span
+	var payload struct {
+		Email        string            `json:"email"`
+		TraceContext map[string]string `json:"trace_context"`
+	}
+	require.NoError(t, json.Unmarshal(info.Payload, &payload))
+	require.Equal(t, "user@email.com", payload.Email)
+
+	extracted := trace.SpanContextFromContext(tracing.Extract(context.Background(), payload.TraceContext))
+	require.Equal(t, spans[0].SpanContext().TraceID(), extracted.TraceID())
+	require.Equal(t, spans[0].SpanContext().SpanID(), extracted.SpanID())
+}
+
+func TestPublishContinuesRequestTrace(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	redisServer := miniredis.RunT(t)
+	distributor := worker.NewRedisTaskDistributor(&asynq.RedisClientOpt{Addr: redisServer.Addr()})
+
+	// the request wrote the event, the relay publishes it later in a trace of its own
+	requestCtx, requestSpan := tracing.Tracer().Start(context.Background(), "request")
+	traceContext, err := json.Marshal(tracing.Inject(requestCtx))
+	require.NoError(t, err)
+	requestSpan.End()
+
+	event, err := worker.NewTaskEvent(worker.TaskSendLockoutEmail, &worker.PayloadSendLockoutEmail{Email: "user@email.com"})
+	require.NoError(t, err)
+
+	relayCtx, relaySpan := tracing.Tracer().Start(context.Background(), "relay")
+	err = distributor.Publish(relayCtx, db.Outbox{ID: 1, Topic: event.Topic, Payload: event.Payload, TraceContext: traceContext})
+	require.NoError(t, err)
+	relaySpan.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	enqueueSpan := spans[1]
+	require.Equal(t, "enqueue "+worker.TaskSendLockoutEmail, enqueueSpan.Name())
+	require.Equal(t, requestSpan.SpanContext().TraceID(), enqueueSpan.SpanContext().TraceID())
+	require.Equal(t, requestSpan.SpanContext().SpanID(), enqueueSpan.Parent().SpanID())
+}
+
+func TestPublishRequestID(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	redisOpt := asynq.RedisClientOpt{Addr: redisServer.Addr()}
+	distributor := worker.NewRedisTaskDistributor(&redisOpt)
+
+	event, err := worker.NewTaskEvent(worker.TaskSendLockoutEmail, &worker.PayloadSendLockoutEmail{Email: "user@email.com"})
+	require.NoError(t, err)
+
+	err = distributor.Publish(context.Background(), db.Outbox{ID: 1, Topic: event.Topic, Payload: event.Payload, RequestID: "request-1"})
+	require.NoError(t, err)
+
+	inspector := asynq.NewInspector(redisOpt)
+	defer inspector.Close()
+
+	info, err := inspector.GetTaskInfo(worker.QueueCritical, "outbox:1")
+	require.NoError(t, err)
+
+	// the task logs with the id of the request that wrote its event
+	var payload struct {
+		Email     string `json:"email"`
+		RequestID string `json:"request_id"`
+	}
+	require.NoError(t, json.Unmarshal(info.Payload, &payload))
+	require.Equal(t, "user@email.com", payload.Email)
+	require.Equal(t, "request-1", payload.RequestID)
+}
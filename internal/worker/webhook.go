@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/webhook"
+)
+
+// WebhookDispatcher publishes webhook events, every subscription of the owner
+// to the event type gets a delivery made by its own task
+type WebhookDispatcher struct {
+	store db.Store
+}
+
+func NewWebhookDispatcher(store db.Store) *WebhookDispatcher {
+	return &WebhookDispatcher{store: store}
+}
+
+func (dispatcher *WebhookDispatcher) Publish(ctx context.Context, event db.Outbox) error {
+	var payload webhook.Event
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook event: %w", err)
+	}
+
+	result, err := dispatcher.store.DispatchWebhookTx(ctx, db.DispatchWebhookTxParams{
+		Owner:     payload.Owner,
+		EventID:   payload.ID,
+		EventType: payload.Type,
+		Payload:   event.Payload,
+		NewTask: func(delivery db.WebhookDeliveries) (db.CreateOutboxEventParams, error) {
+			return NewTaskEvent(TaskDeliverWebhook, &PayloadDeliverWebhook{DeliveryID: delivery.ID})
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dispatch webhook event: %w", err)
+	}
+
+	logging.Ctx(ctx).Info().Str("event_id", payload.ID).Str("event_type", payload.Type).
+		Int("deliveries", len(result.Deliveries)).Msg("dispatched webhook event")
+	return nil
+}
@@ -3,21 +3,42 @@ package main
 import (
 	"context"
 	"database/sql"
-	"net"
+	"fmt"
 	"net/http"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/claytten/golang-simplebank/doc/statik"
+	"github.com/claytten/golang-simplebank/internal/activity"
 	"github.com/claytten/golang-simplebank/internal/api"
 	"github.com/claytten/golang-simplebank/internal/api/routes"
+	"github.com/claytten/golang-simplebank/internal/clientip"
 	db "github.com/claytten/golang-simplebank/internal/db/sqlc"
 	"github.com/claytten/golang-simplebank/internal/gapi"
+	gapiActivity "github.com/claytten/golang-simplebank/internal/gapi/activity"
+	gapiAuthz "github.com/claytten/golang-simplebank/internal/gapi/authz"
+	gapiError "github.com/claytten/golang-simplebank/internal/gapi/error"
 	gapiHandlerSetup "github.com/claytten/golang-simplebank/internal/gapi/handlers"
+	gapiJWKS "github.com/claytten/golang-simplebank/internal/gapi/jwks"
 	gapiLogger "github.com/claytten/golang-simplebank/internal/gapi/logger"
+	gapiOIDC "github.com/claytten/golang-simplebank/internal/gapi/oidc"
+	gapiRateLimit "github.com/claytten/golang-simplebank/internal/gapi/ratelimit"
+	"github.com/claytten/golang-simplebank/internal/health"
+	"github.com/claytten/golang-simplebank/internal/lifecycle"
+	"github.com/claytten/golang-simplebank/internal/logging"
+	"github.com/claytten/golang-simplebank/internal/loginguard"
+	"github.com/claytten/golang-simplebank/internal/metrics"
+	"github.com/claytten/golang-simplebank/internal/notification"
+	"github.com/claytten/golang-simplebank/internal/oidc"
+	"github.com/claytten/golang-simplebank/internal/ratelimit"
+	"github.com/claytten/golang-simplebank/internal/revocation"
+	"github.com/claytten/golang-simplebank/internal/tracing"
 	"github.com/claytten/golang-simplebank/internal/util"
+	"github.com/claytten/golang-simplebank/internal/webhook"
 	"github.com/claytten/golang-simplebank/internal/worker"
 	"github.com/claytten/golang-simplebank/pb"
+	"github.com/go-redis/redis/v8"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -27,7 +48,10 @@ import (
 	"github.com/rakyll/statik/fs"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -42,23 +66,41 @@ func main() {
 	if config.Environment == "development" {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	} else {
-		runLogFile, err := RunLogProduction("myapp", "log")
-		if err != nil {
-			log.Fatal().Err(err).Msg("cannot create log file")
-		}
+		runLogFile := logging.NewRotatingFile(logging.FileConfig{
+			File:       config.LogFile,
+			MaxSizeMB:  config.LogMaxSizeMB,
+			MaxBackups: config.LogMaxBackups,
+			MaxAge:     config.LogMaxAge,
+			Compress:   config.LogCompress,
+			Daily:      config.LogRotateDaily,
+		})
 		multi := zerolog.MultiLevelWriter(os.Stdout, runLogFile)
 
 		log.Logger = zerolog.New(multi)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     config.TracingExporter,
+		OTLPEndpoint: config.TracingOTLPEndpoint,
+		File:         config.TracingFile,
+		SampleRatio:  config.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot set up tracing")
+	}
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot connect to DB")
 	}
 
-	RunDBMigration(config.MigrationURL, config.DBSource)
+	migrationVersion := RunDBMigration(config.MigrationURL, config.DBSource)
 
-	store := db.NewStore(conn)
+	// every query is timed for the metrics and traced
+	store := db.NewObservedStore(conn, metrics.ObserveQuery, tracing.TraceQuery)
+	if err := metrics.RegisterDB(conn, config.DBDriver); err != nil {
+		log.Fatal().Err(err).Msg("cannot register DB metrics")
+	}
 
 	// async redis option
 	redisOpt := asynq.RedisClientOpt{
@@ -66,42 +108,105 @@ func main() {
 	}
 
 	taskDistributor := worker.NewRedisTaskDistributor(&redisOpt)
+	if err := metrics.RegisterQueues(asynq.NewInspector(redisOpt)); err != nil {
+		log.Fatal().Err(err).Msg("cannot register task queue metrics")
+	}
 
-	// RunGinServer(config, store)
-	go RunTaskProcessor(redisOpt, store)
-	go RunGatewayServer(config, store, taskDistributor)
-	RunGrpcServer(config, store, taskDistributor)
-}
+	// the app is ready once it reaches the database and the task queue with the schema it migrated
+	appHealth := health.NewHealth(config.HealthCheckTimeout,
+		health.Postgres(conn),
+		health.Redis(redisOpt.MakeRedisClient().(redis.UniversalClient)),
+		health.MigrationVersion(conn, migrationVersion),
+	)
+
+	// shared by every server so a revoked session is rejected everywhere
+	revocationCache := NewRevocationCache(config)
+	loginGuard := NewLoginGuard(config)
 
-func RunGrpcServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) {
-	server, err := gapi.SetupServer(config, store, taskDistributor)
+	server, err := gapi.SetupServer(config, store, revocationCache, loginGuard)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot create gRPC Server")
 	}
+	rateLimiter := NewRateLimiter(config, server.ClientIP)
 
-	grpcLogger := grpc.UnaryInterceptor(gapiLogger.GrpcLogger)
-	grpcServer := grpc.NewServer(grpcLogger)
-	handlers := gapiHandlerSetup.NewGapiHandlerSetup(server)
-	pb.RegisterSimplebankServer(grpcServer, handlers)
-	reflection.Register(grpcServer)
+	// the services stop on SIGINT or SIGTERM, or once one of them fails
+	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
+	defer stop()
 
-	listener, err := net.Listen("tcp", config.GrpcServerAddress)
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot listen TCP gRPC")
+	group, ctx := lifecycle.NewGroup(ctx, config.ShutdownTimeout)
+
+	// RunGinServer(config, store, revocationCache, loginGuard)
+	RunHealth(ctx, group, appHealth)
+	if err := RunGatewayServer(group, config, server, appHealth, rateLimiter); err != nil {
+		log.Fatal().Err(err).Msg("cannot create HTTP gateway server")
 	}
+	RunGrpcServer(group, config, server, appHealth, rateLimiter)
+	RunTaskProcessor(group, config, redisOpt, store, NewNotifier(config))
+	RunOutboxRelay(ctx, group, config, store, taskDistributor)
+	RunActivityListener(ctx, group, config, server.Activity)
 
-	log.Info().Msgf("start gRPC server at %s", listener.Addr().String())
-	if err := grpcServer.Serve(listener); err != nil {
-		log.Fatal().Err(err).Msg("cannot start gRPC Server")
+	waitErr := group.Wait()
+
+	// the pool is closed last, the services use it until they are stopped
+	closeCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := lifecycle.StopWithin(closeCtx, func() { conn.Close() }, nil); err != nil {
+		log.Error().Err(err).Msg("cannot close DB")
+	}
+	// the spans of the last requests are still exported
+	if err := shutdownTracing(closeCtx); err != nil {
+		log.Error().Err(err).Msg("cannot flush traces")
 	}
-}
 
-func RunGatewayServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) {
-	server, err := gapi.SetupServer(config, store, taskDistributor)
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot create gRPC Server")
+	if waitErr != nil {
+		log.Fatal().Err(waitErr).Msg("stopped with error")
 	}
+	log.Info().Msg("stopped")
+}
+
+// interruptSignals ask the app to shut down
+var interruptSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
+// RunGrpcServer serves gRPC until the group stops, the calls in flight are drained
+func RunGrpcServer(group *lifecycle.Group, config util.Config, server *gapi.Server, appHealth *health.Health, rateLimiter *gapiRateLimit.RateLimiter) {
+	// every RPC is authenticated according to the (pb.auth) option declared in the proto
+	authenticator := gapiAuthz.NewAuthenticator(server, pb.File_service_simplebank_proto.Services().ByName("Simplebank"))
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(),
+			gapiLogger.GrpcRequestID,
+			gapiError.GrpcSanitizer,
+			gapiLogger.GrpcLogger,
+			rateLimiter.UnaryIP(),
+			authenticator.Unary(),
+			rateLimiter.Unary(),
+		),
+		grpc.ChainStreamInterceptor(
+			otelgrpc.StreamServerInterceptor(),
+			gapiLogger.GrpcStreamRequestID,
+			gapiError.GrpcStreamSanitizer,
+			gapiLogger.GrpcStreamLogger,
+			rateLimiter.StreamIP(),
+			authenticator.Stream(),
+			rateLimiter.Stream(),
+		),
+	)
+	handlers := gapiHandlerSetup.NewGapiHandlerSetup(server)
+	pb.RegisterSimplebankServer(grpcServer, handlers)
+	health.NewGRPCServer(appHealth, config.HealthWatchInterval, pb.Simplebank_ServiceDesc.ServiceName).Register(grpcServer)
+	reflection.Register(grpcServer)
 
+	group.Go("gRPC server",
+		lifecycle.ServeGRPC(grpcServer, config.GrpcServerAddress),
+		lifecycle.StopGRPC(grpcServer),
+	)
+}
+
+// RunGatewayServer serves the HTTP gateway until the group stops, the requests in flight are drained
+func RunGatewayServer(group *lifecycle.Group, config util.Config, server *gapi.Server, appHealth *health.Health, rateLimiter *gapiRateLimit.RateLimiter) error {
 	// for snackcase
 	jsonOption := runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
 		MarshalOptions: protojson.MarshalOptions{
@@ -112,51 +217,155 @@ func RunGatewayServer(config util.Config, store db.Store, taskDistributor worker
 		},
 	})
 
-	handlers := gapiHandlerSetup.NewGapiHandlerSetup(server)
-	grpcMux := runtime.NewServeMux(jsonOption)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// the gateway calls the gRPC server instead of the handlers directly,
+	// so every HTTP request goes through the same interceptors and the same trace
+	conn, err := grpc.Dial(config.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor(), gapiLogger.GrpcClientRequestID),
+		grpc.WithChainStreamInterceptor(otelgrpc.StreamClientInterceptor(), gapiLogger.GrpcClientStreamRequestID),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot dial gRPC server: %w", err)
+	}
 
-	err = pb.RegisterSimplebankHandlerServer(ctx, grpcMux, handlers)
+	// the rate limit of every RPC is returned in the RateLimit-* headers
+	grpcMux := runtime.NewServeMux(jsonOption, runtime.WithOutgoingHeaderMatcher(gapiRateLimit.OutgoingHeaderMatcher))
+	err = pb.RegisterSimplebankHandler(context.Background(), grpcMux, conn)
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot register handler server")
+		conn.Close()
+		return fmt.Errorf("cannot register handler server: %w", err)
 	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/", grpcMux)
+	mux.Handle(gapiActivity.Path, gapiActivity.Handler(grpcMux))
+	appHealth.Register(mux)
+	mux.Handle(metrics.Path, metrics.Handler())
 
 	// adding swagger
 	statikFs, err := fs.New()
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot create statik file system")
+		conn.Close()
+		return fmt.Errorf("cannot create statik file system: %w", err)
 	}
 
 	mux.Handle("/swagger/", http.StripPrefix("/swagger/", http.FileServer(statikFs)))
 
-	listener, err := net.Listen("tcp", config.HTTPServerAddress)
+	// verification keys for services checking our tokens offline
+	jwksHandler, err := gapiJWKS.Handler(server.Token, config.JWKSMaxAge)
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot create listener")
+		conn.Close()
+		return fmt.Errorf("cannot create JWKS handler: %w", err)
 	}
 
-	log.Info().Msgf("start HTTP gateway server at %s", listener.Addr().String())
-	handlerLogger := gapiLogger.HttpLogger(mux)
-	err = http.Serve(listener, handlerLogger)
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot start HTTP gateway server")
+	mux.Handle(gapiJWKS.Path, rateLimiter.HTTP(jwksHandler))
+
+	// sign in with an external identity provider when one is configured
+	if config.OIDCIssuer != "" {
+		provider := oidc.NewProvider(oidc.Config{
+			Issuer:       config.OIDCIssuer,
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+			RedirectURL:  config.OIDCRedirectURL,
+		}, nil)
+		oidcMux := http.NewServeMux()
+		gapiOIDC.NewHandler(server, provider).Register(oidcMux)
+		mux.Handle(gapiOIDC.LoginPath, rateLimiter.HTTP(oidcMux))
+		mux.Handle(gapiOIDC.CallbackPath, rateLimiter.HTTP(oidcMux))
 	}
-}
 
-func RunTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store) {
-	taskProcessor := worker.NewRedisTaskProcessor(&redisOpt, store)
-	log.Info().Msg("start task processor")
-	err := taskProcessor.Start()
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot start task processor")
+	httpServer := &http.Server{
+		Addr:    config.HTTPServerAddress,
+		Handler: traceHTTP(gapiLogger.HttpLogger(mux)),
 	}
+
+	shutdown := lifecycle.ShutdownHTTP(httpServer)
+	group.Go("HTTP gateway server",
+		lifecycle.ServeHTTP(httpServer),
+		func(ctx context.Context) error {
+			defer conn.Close()
+			return shutdown(ctx)
+		},
+	)
+	return nil
 }
 
-func RunGinServer(config util.Config, store db.Store) {
-	server, err := api.SetupServer(config, store)
+// traceHTTP starts the trace of every gateway request but the probes and the scrapes
+func traceHTTP(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(handler, "HTTP gateway",
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return "HTTP " + r.Method
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			switch r.URL.Path {
+			case health.LivenessPath, health.ReadinessPath, metrics.Path:
+				return false
+			}
+			return true
+		}),
+	)
+}
+
+// RunHealth makes the app not ready as soon as it starts shutting down
+func RunHealth(ctx context.Context, group *lifecycle.Group, appHealth *health.Health) {
+	group.Go("health",
+		func() error {
+			<-ctx.Done()
+			appHealth.Shutdown()
+			return nil
+		},
+		nil,
+	)
+}
+
+// RunTaskProcessor processes tasks until the group stops, the tasks in progress are finished
+func RunTaskProcessor(group *lifecycle.Group, config util.Config, redisOpt asynq.RedisClientOpt, store db.Store, notifier *notification.Notifier) {
+	taskProcessor := worker.NewRedisTaskProcessor(&redisOpt, store, notifier, webhook.NewClient(webhook.DefaultTimeout), config.ShutdownTimeout)
+	group.Go("task processor",
+		taskProcessor.Start,
+		func(ctx context.Context) error {
+			return lifecycle.StopWithin(ctx, taskProcessor.Shutdown, nil)
+		},
+	)
+}
+
+// RunActivityListener wakes up the account activity streams on the notifications of transfers,
+// the streams are ended once the group stops
+func RunActivityListener(ctx context.Context, group *lifecycle.Group, config util.Config, hub *activity.Hub) {
+	group.Go("account activity listener",
+		func() error {
+			return hub.Listen(ctx, config.DBSource)
+		},
+		func(ctx context.Context) error {
+			hub.Close()
+			return nil
+		},
+	)
+}
+
+// RunOutboxRelay publishes the side effects committed with database changes until the group stops
+func RunOutboxRelay(ctx context.Context, group *lifecycle.Group, config util.Config, store db.Store, taskDistributor worker.TaskDistributor) {
+	relay := worker.NewOutboxRelay(store, worker.OutboxRelayConfig{
+		PollInterval:   config.OutboxPollInterval,
+		BatchSize:      config.OutboxBatchSize,
+		PublishTimeout: config.OutboxPublishTimeout,
+		RetryDelay:     config.OutboxRetryDelay,
+		MaxRetryDelay:  config.OutboxMaxRetryDelay,
+		Retention:      config.OutboxRetention,
+	})
+	relay.HandleTasks(taskDistributor)
+	relay.Handle(webhook.TopicEvent, worker.NewWebhookDispatcher(store))
+
+	group.Go("outbox relay",
+		func() error {
+			return relay.Start(ctx)
+		},
+		nil,
+	)
+}
+
+func RunGinServer(config util.Config, store db.Store, revocationCache revocation.Cache, loginGuard *loginguard.Guard) {
+	server, err := api.SetupServer(config, store, revocationCache, loginGuard)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot create HTTP server")
 	}
@@ -167,33 +376,110 @@ func RunGinServer(config util.Config, store db.Store) {
 	}
 }
 
-func RunDBMigration(migrationURL, dbSource string) {
-	migration, err := migrate.New(migrationURL, dbSource)
+// NewRevocationCache uses Redis unless the in-process cache is configured,
+// which is only suitable for a single instance
+func NewRevocationCache(config util.Config) revocation.Cache {
+	if config.RevocationCache == "memory" {
+		return revocation.NewMemoryCache()
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: config.RedisAddress,
+	})
+	return revocation.NewRedisCache(client)
+}
+
+// NewNotifier sends the alerts of every channel to files in the notification dir
+// unless the in-memory sender is configured, which drops them with the process
+func NewNotifier(config util.Config) *notification.Notifier {
+	var sender interface {
+		notification.EmailSender
+		notification.SMSSender
+		notification.PushSender
+	}
+
+	if config.NotificationSender == "memory" {
+		sender = notification.NewMemorySender()
+	} else {
+		fileSender, err := notification.NewFileSender(config.NotificationDir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create notification sender")
+		}
+		sender = fileSender
+	}
+
+	notifier, err := notification.NewNotifier(sender, sender, sender)
 	if err != nil {
-		log.Fatal().Msg("cannot create new migrate instance")
+		log.Fatal().Err(err).Msg("cannot create notifier")
 	}
+	return notifier
+}
 
-	if err = migration.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatal().Err(err).Msg("cannot run migration")
+// NewLoginGuard throttles failed logins per email and per client IP,
+// attempts are kept in Redis unless the in-process store is configured
+func NewLoginGuard(config util.Config) *loginguard.Guard {
+	emailPolicy := loginguard.Policy{
+		MaxAttempts:     config.LoginMaxAttempts,
+		LockoutAttempts: config.LoginLockoutAttempts,
+		BaseDelay:       config.LoginBackoffDelay,
+		LockoutDuration: config.LoginLockoutDuration,
+		Window:          config.LoginAttemptWindow,
 	}
+	ipPolicy := emailPolicy
+	ipPolicy.MaxAttempts = config.LoginIPMaxAttempts
+	ipPolicy.LockoutAttempts = config.LoginIPLockoutAttempts
 
-	log.Info().Msg("migration successful")
+	if config.LoginAttemptStore == "memory" {
+		return loginguard.NewGuard(loginguard.NewMemoryStore(), emailPolicy, ipPolicy)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: config.RedisAddress,
+	})
+	return loginguard.NewGuard(loginguard.NewRedisStore(client), emailPolicy, ipPolicy)
 }
 
-func RunLogProduction(logName, folder string) (*os.File, error) {
-	// checking file log/myapp.log is exists
-	dayString := time.Now().Format("20060102")
-	fileName := logName + dayString + ".log"
+// NewRateLimiter limits the requests of every user, API key and anonymous client IP,
+// and every client IP before authentication. The buckets are kept in Redis unless
+// the in-process store is configured.
+func NewRateLimiter(config util.Config, resolver *clientip.Resolver) *gapiRateLimit.RateLimiter {
+	limits, err := ratelimit.ParseLimits(config.RateLimitDefault, config.RateLimitMethods)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot parse rate limits")
+	}
 
-	if err := util.ValidateFolderAndFile(folder, fileName); err != nil {
-		return nil, err
+	ipLimits, err := ratelimit.ParseLimits(config.RateLimitIP, nil)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot parse client IP rate limit")
 	}
 
-	runLogFile, err := os.OpenFile(
-		folder+"/"+fileName,
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-		0664,
-	)
+	var store ratelimit.Store
+	if config.RateLimitStore == "memory" {
+		store = ratelimit.NewMemoryStore()
+	} else {
+		store = ratelimit.NewRedisStore(redis.NewClient(&redis.Options{
+			Addr: config.RedisAddress,
+		}))
+	}
+	return gapiRateLimit.NewRateLimiter(ratelimit.NewLimiter(store, limits), ratelimit.NewLimiter(store, ipLimits), resolver)
+}
+
+// RunDBMigration migrates the database up and returns the version it is at
+func RunDBMigration(migrationURL, dbSource string) uint {
+	migration, err := migrate.New(migrationURL, dbSource)
+	if err != nil {
+		log.Fatal().Msg("cannot create new migrate instance")
+	}
+
+	if err = migration.Up(); err != nil && err != migrate.ErrNoChange {
+		log.Fatal().Err(err).Msg("cannot run migration")
+	}
+
+	version, _, err := migration.Version()
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot get migration version")
+	}
 
-	return runLogFile, err
+	log.Info().Msgf("migration successful at version %d", version)
+	return version
 }
@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "is_frozen";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,12 @@
+CREATE TABLE "outbox" (
+  "id" bigserial PRIMARY KEY,
+  "topic" varchar NOT NULL,
+  "payload" json NOT NULL,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "last_error" varchar,
+  "available_at" timestamptz NOT NULL DEFAULT (now()),
+  "published_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "outbox" ("available_at") WHERE "published_at" IS NULL;
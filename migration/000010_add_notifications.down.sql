@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "user_devices";
+DROP TABLE IF EXISTS "notification_preferences";
+DROP TABLE IF EXISTS "notification_settings";
\ No newline at end of file
@@ -0,0 +1,34 @@
+CREATE TABLE "notification_settings" (
+  "username" varchar PRIMARY KEY,
+  "locale" varchar NOT NULL DEFAULT 'en',
+  "phone_number" varchar NOT NULL DEFAULT '',
+  "push_token" varchar NOT NULL DEFAULT '',
+  "large_debit_threshold" bigint NOT NULL DEFAULT 1000,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "notification_preferences" (
+  "username" varchar NOT NULL,
+  "event" varchar NOT NULL,
+  "channel" varchar NOT NULL,
+  "enabled" boolean NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("username", "event", "channel")
+);
+
+CREATE TABLE "user_devices" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "first_seen_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_seen_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "user_devices" ("username", "user_agent");
+
+ALTER TABLE "notification_settings" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "notification_preferences" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "user_devices" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,7 @@
+ALTER TABLE "entries" ADD COLUMN "txid" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "entries" ALTER COLUMN "txid" SET DEFAULT (txid_current());
+
+CREATE INDEX ON "entries" ("account_id", "txid");
+
+COMMENT ON COLUMN "entries"."txid" IS 'the transaction that created the entry, zero before the activity streams';